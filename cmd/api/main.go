@@ -13,11 +13,21 @@ import (
 )
 
 func main() {
-	srv := server.NewServer()
+	// Cancelled on shutdown to stop the background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	srv := server.NewServer(jobsCtx)
 
 	go func() {
-		log.Printf("Server listening on %s\n", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if srv.TLSConfig != nil {
+			// Certificate is already loaded into TLSConfig
+			log.Printf("Server listening on %s (HTTPS)\n", srv.Addr)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("Server listening on %s\n", srv.Addr)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			panic(fmt.Sprintf("http server error: %s", err))
 		}
 	}()
@@ -27,6 +37,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server gracefully ...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,95 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Machine readable error codes, stable across releases so clients can branch on them
+const (
+	CodeInvalidInput        = "invalid_input"
+	CodeUnsupportedDBType   = "unsupported_db_type"
+	CodeProjectNotFound     = "project_not_found"
+	CodeAPIKeyNotFound      = "api_key_not_found"
+	CodeSessionNotFound     = "session_not_found"
+	CodeBackupNotFound      = "backup_not_found"
+	CodeScheduleNotFound    = "backup_schedule_not_found"
+	CodeBackupNotReady      = "backup_not_ready"
+	CodeInstanceNotFound    = "instance_not_found"
+	CodeRowNotFound         = "row_not_found"
+	CodeInstanceNotRunning  = "instance_not_running"
+	CodeInstanceNoContainer = "instance_no_container"
+	CodeInstanceState       = "instance_state_conflict"
+	CodeInstancePaused      = "instance_paused"
+	CodeRestoreFailed       = "restore_failed"
+	CodeDumpTooLarge        = "dump_too_large"
+	CodeRefreshTokenReused  = "refresh_token_reused"
+	CodeSavedQueryNotFound  = "saved_query_not_found"
+	CodeSavedQueryExists    = "saved_query_exists"
+	CodeHistoryNotFound     = "query_history_entry_not_found"
+	CodeQueryRedacted       = "query_text_redacted"
+	CodeInvalidExecutionID  = "invalid_execution_id"
+	CodeExecutionIDInUse    = "execution_id_in_use"
+	CodeInternal            = "internal_error"
+)
+
+// Error is an error meant to reach an API client. Status and Code tell the client what
+// went wrong, Message is safe to show, and Err is the underlying cause, which is only
+// logged.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the given status, code and client message
+func New(status int, code string, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// Wrap returns an error with the given status, code and client message, keeping err as its cause
+func Wrap(err error, status int, code string, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message, Err: err}
+}
+
+// Invalid reports a request that cannot be served as sent
+func Invalid(code string, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+// NotFound reports a resource that does not exist or is not visible to the user
+func NotFound(code string, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+// Conflict reports a request that conflicts with the current state of a resource
+func Conflict(code string, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+// Internal reports a failure of the backend itself. The client only sees message.
+func Internal(err error, message string) *Error {
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, message)
+}
+
+// From returns the Error in err's chain. Errors that are not an Error are reported as
+// internal errors, so their text never reaches the client.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err, "internal server error")
+}
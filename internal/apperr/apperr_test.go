@@ -0,0 +1,71 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestConstructorsMapToStatusAndCode(t *testing.T) {
+	cause := errors.New("connection refused")
+	cases := []struct {
+		name   string
+		err    *Error
+		status int
+		code   string
+	}{
+		{"Invalid", Invalid(CodeInvalidInput, "bad"), http.StatusBadRequest, CodeInvalidInput},
+		{"NotFound", NotFound(CodeProjectNotFound, "missing"), http.StatusNotFound, CodeProjectNotFound},
+		{"Conflict", Conflict(CodeInstanceState, "busy"), http.StatusConflict, CodeInstanceState},
+		{"Internal", Internal(cause, "failed"), http.StatusInternalServerError, CodeInternal},
+		{"New", New(http.StatusRequestEntityTooLarge, CodeDumpTooLarge, "too large"), http.StatusRequestEntityTooLarge, CodeDumpTooLarge},
+		{"Wrap", Wrap(cause, http.StatusUnprocessableEntity, CodeRestoreFailed, "restore failed"), http.StatusUnprocessableEntity, CodeRestoreFailed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.err.Status != tc.status || tc.err.Code != tc.code {
+				t.Errorf("got %d %s, want %d %s", tc.err.Status, tc.err.Code, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(cause, http.StatusBadGateway, CodeInternal, "database unavailable")
+
+	if !errors.Is(err, cause) {
+		t.Error("wrapped error does not match its cause")
+	}
+	if err.Error() != "database unavailable: connection refused" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if New(http.StatusNotFound, CodeRowNotFound, "row not found").Error() != "row not found" {
+		t.Error("Error() of an error without a cause is not its message")
+	}
+}
+
+func TestFromFindsErrorInChain(t *testing.T) {
+	notFound := NotFound(CodeProjectNotFound, "project not found")
+	wrapped := fmt.Errorf("failed to load project: %w", notFound)
+
+	if got := From(wrapped); got != notFound {
+		t.Errorf("From(wrapped) = %v, want the wrapped apperr.Error", got)
+	}
+}
+
+func TestFromHidesOtherErrors(t *testing.T) {
+	cause := errors.New("pq: password authentication failed for user admin")
+	got := From(cause)
+
+	if got.Status != http.StatusInternalServerError || got.Code != CodeInternal {
+		t.Errorf("From(plain error) = %d %s, want 500 %s", got.Status, got.Code, CodeInternal)
+	}
+	if got.Message != "internal server error" {
+		t.Errorf("From(plain error) message = %q, want a generic message", got.Message)
+	}
+	if !errors.Is(got, cause) {
+		t.Error("From(plain error) dropped the cause")
+	}
+}
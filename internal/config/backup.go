@@ -0,0 +1,12 @@
+package config
+
+import "os"
+
+// BackupDir returns the directory project backups are written to, from BACKUP_DIR
+// (default "backups"). Each project gets its own subdirectory.
+func BackupDir() string {
+	if dir := os.Getenv("BACKUP_DIR"); dir != "" {
+		return dir
+	}
+	return "backups"
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CookieSettings holds the attributes applied to the refresh token cookie
+type CookieSettings struct {
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// RefreshCookieSettings reads the refresh cookie attributes from the environment.
+// Secure defaults to true, except when APP_ENV=development so the cookie is still sent
+// over plain HTTP on localhost. COOKIE_SECURE, COOKIE_DOMAIN, COOKIE_SAMESITE
+// (lax, strict or none) and COOKIE_PATH override the defaults.
+func RefreshCookieSettings() (CookieSettings, error) {
+	settings := CookieSettings{
+		Path:     "/",
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		Secure:   !strings.EqualFold(os.Getenv("APP_ENV"), "development"),
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	if path := os.Getenv("COOKIE_PATH"); path != "" {
+		settings.Path = path
+	}
+
+	if secure := os.Getenv("COOKIE_SECURE"); secure != "" {
+		value, err := strconv.ParseBool(secure)
+		if err != nil {
+			return CookieSettings{}, fmt.Errorf("invalid COOKIE_SECURE: %w", err)
+		}
+		settings.Secure = value
+	}
+
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "", "lax":
+		settings.SameSite = http.SameSiteLaxMode
+	case "strict":
+		settings.SameSite = http.SameSiteStrictMode
+	case "none":
+		settings.SameSite = http.SameSiteNoneMode
+	default:
+		return CookieSettings{}, fmt.Errorf("invalid COOKIE_SAMESITE %q: must be lax, strict or none", os.Getenv("COOKIE_SAMESITE"))
+	}
+
+	// Browsers reject SameSite=None cookies that are not Secure
+	if settings.SameSite == http.SameSiteNoneMode && !settings.Secure {
+		return CookieSettings{}, fmt.Errorf("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+
+	return settings, nil
+}
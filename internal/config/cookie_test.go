@@ -0,0 +1,78 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRefreshCookieSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want CookieSettings
+	}{
+		{
+			name: "production defaults",
+			want: CookieSettings{Path: "/", Secure: true, SameSite: http.SameSiteLaxMode},
+		},
+		{
+			name: "development is not secure",
+			env:  map[string]string{"APP_ENV": "development"},
+			want: CookieSettings{Path: "/", Secure: false, SameSite: http.SameSiteLaxMode},
+		},
+		{
+			name: "overrides",
+			env: map[string]string{
+				"APP_ENV":         "development",
+				"COOKIE_SECURE":   "true",
+				"COOKIE_DOMAIN":   "example.com",
+				"COOKIE_SAMESITE": "None",
+				"COOKIE_PATH":     "/api/v1/auth",
+			},
+			want: CookieSettings{Path: "/api/v1/auth", Domain: "example.com", Secure: true, SameSite: http.SameSiteNoneMode},
+		},
+		{
+			name: "strict",
+			env:  map[string]string{"COOKIE_SAMESITE": "strict", "COOKIE_SECURE": "false"},
+			want: CookieSettings{Path: "/", Secure: false, SameSite: http.SameSiteStrictMode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearCookieEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, err := RefreshCookieSettings()
+			if err != nil || got != tt.want {
+				t.Errorf("RefreshCookieSettings() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefreshCookieSettingsRejectsInvalidValues(t *testing.T) {
+	invalid := []map[string]string{
+		{"COOKIE_SECURE": "sometimes"},
+		{"COOKIE_SAMESITE": "loose"},
+		{"COOKIE_SAMESITE": "none", "COOKIE_SECURE": "false"},
+		{"COOKIE_SAMESITE": "none", "APP_ENV": "development"},
+	}
+
+	for _, env := range invalid {
+		clearCookieEnv(t)
+		for key, value := range env {
+			t.Setenv(key, value)
+		}
+		if got, err := RefreshCookieSettings(); err == nil {
+			t.Errorf("RefreshCookieSettings() with %v = %+v, want an error", env, got)
+		}
+	}
+}
+
+func clearCookieEnv(t *testing.T) {
+	for _, key := range []string{"APP_ENV", "COOKIE_SECURE", "COOKIE_DOMAIN", "COOKIE_SAMESITE", "COOKIE_PATH"} {
+		t.Setenv(key, "")
+	}
+}
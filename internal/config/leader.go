@@ -0,0 +1,14 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// LeaderElectionEnabled reports whether background jobs should only run on the
+// replica holding the leader lock. Single-instance deployments can leave
+// LEADER_ELECTION_ENABLED unset.
+func LeaderElectionEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("LEADER_ELECTION_ENABLED"))
+	return err == nil && enabled
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// Bounds for ORCHESTRATOR_MONITOR_INTERVAL, in seconds. A zero or negative
+// interval would make the container monitor busy-loop.
+const (
+	MinMonitorInterval = 1
+	MaxMonitorInterval = 3600
+)
+
+// MonitorInterval reads and validates ORCHESTRATOR_MONITOR_INTERVAL (seconds)
+func MonitorInterval() (int, error) {
+	value := os.Getenv("ORCHESTRATOR_MONITOR_INTERVAL")
+	if value == "" {
+		return 0, fmt.Errorf("ORCHESTRATOR_MONITOR_INTERVAL environment variable is required")
+	}
+
+	interval, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("ORCHESTRATOR_MONITOR_INTERVAL must be a valid integer: %w", err)
+	}
+
+	if interval < MinMonitorInterval || interval > MaxMonitorInterval {
+		return 0, fmt.Errorf("ORCHESTRATOR_MONITOR_INTERVAL must be between %d and %d seconds, got %d",
+			MinMonitorInterval, MaxMonitorInterval, interval)
+	}
+
+	return interval, nil
+}
+
+// MaxEnvironmentNameLength bounds ENV_NAME so prefixed container names stay well within
+// the 63 characters Docker allows for the container's hostname
+const MaxEnvironmentNameLength = 32
+
+// environmentNamePattern matches the characters Docker accepts in container names
+var environmentNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// EnvironmentName reads ENV_NAME, the optional name of the deployment (e.g. "staging")
+// that prefixes the names of the containers it creates. It returns "" when unset.
+func EnvironmentName() (string, error) {
+	name := os.Getenv("ENV_NAME")
+	if name == "" {
+		return "", nil
+	}
+	if len(name) > MaxEnvironmentNameLength {
+		return "", fmt.Errorf("ENV_NAME must be at most %d characters, got %d", MaxEnvironmentNameLength, len(name))
+	}
+	if !environmentNamePattern.MatchString(name) {
+		return "", fmt.Errorf("ENV_NAME may only contain letters, digits, '_', '.' and '-', and must start with a letter or digit")
+	}
+	return name, nil
+}
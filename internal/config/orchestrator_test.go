@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMonitorInterval(t *testing.T) {
+	for _, value := range []string{"1", "30", "3600"} {
+		t.Setenv("ORCHESTRATOR_MONITOR_INTERVAL", value)
+		got, err := MonitorInterval()
+		if err != nil || strconv.Itoa(got) != value {
+			t.Errorf("MonitorInterval() with %q = %d, %v, want it applied", value, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-5", "3601", "ten", "1.5", "30s"} {
+		t.Setenv("ORCHESTRATOR_MONITOR_INTERVAL", value)
+		if got, err := MonitorInterval(); err == nil {
+			t.Errorf("MonitorInterval() with %q = %d, want an error", value, got)
+		}
+	}
+}
+
+func TestEnvironmentName(t *testing.T) {
+	valid := []string{"", "staging", "prod-eu.1", "dev_2", strings.Repeat("a", MaxEnvironmentNameLength)}
+	for _, name := range valid {
+		t.Setenv("ENV_NAME", name)
+		if got, err := EnvironmentName(); err != nil || got != name {
+			t.Errorf("EnvironmentName() with ENV_NAME=%q = %q, %v, want it accepted", name, got, err)
+		}
+	}
+
+	invalid := []string{"-staging", ".hidden", "stag ing", "prod/eu", "staging;rm", strings.Repeat("a", MaxEnvironmentNameLength+1)}
+	for _, name := range invalid {
+		t.Setenv("ENV_NAME", name)
+		if got, err := EnvironmentName(); err == nil {
+			t.Errorf("EnvironmentName() with ENV_NAME=%q = %q, want an error", name, got)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// ProvisionRetryEnabled reports whether the background worker retries creating the
+// containers of recently failed database instances, from PROVISION_RETRY_ENABLED
+func ProvisionRetryEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("PROVISION_RETRY_ENABLED"))
+	return err == nil && enabled
+}
+
+// ProvisionRetryMaxAttempts returns how often the container of a failed instance is
+// retried before it is left failed, from PROVISION_RETRY_MAX_ATTEMPTS (default 3)
+func ProvisionRetryMaxAttempts() (int, error) {
+	return positiveIntEnv("PROVISION_RETRY_MAX_ATTEMPTS", 3)
+}
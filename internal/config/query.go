@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// QueryTimeout reads QUERY_TIMEOUT_MS, the time budget of a single query in the SQL editor.
+// It returns 0 when unset, in which case each project's resource tier decides the budget.
+func QueryTimeout() (time.Duration, error) {
+	if os.Getenv("QUERY_TIMEOUT_MS") == "" {
+		return 0, nil
+	}
+	ms, err := positiveIntEnv("QUERY_TIMEOUT_MS", 0)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
@@ -0,0 +1,24 @@
+package config
+
+import "strings"
+
+// DefaultTierInstanceLimits are the active database instances a user may have per resource tier
+var DefaultTierInstanceLimits = map[string]int{
+	"free":    1,
+	"basic":   5,
+	"premium": 20,
+}
+
+// TierInstanceLimits reads TIER_MAX_INSTANCES_FREE, TIER_MAX_INSTANCES_BASIC and
+// TIER_MAX_INSTANCES_PREMIUM, falling back to DefaultTierInstanceLimits when unset
+func TierInstanceLimits() (map[string]int, error) {
+	limits := make(map[string]int, len(DefaultTierInstanceLimits))
+	for tier, fallback := range DefaultTierInstanceLimits {
+		limit, err := positiveIntEnv("TIER_MAX_INSTANCES_"+strings.ToUpper(tier), fallback)
+		if err != nil {
+			return nil, err
+		}
+		limits[tier] = limit
+	}
+	return limits, nil
+}
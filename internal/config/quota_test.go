@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestTierInstanceLimits(t *testing.T) {
+	t.Setenv("TIER_MAX_INSTANCES_BASIC", "")
+	t.Setenv("TIER_MAX_INSTANCES_PREMIUM", "")
+	t.Setenv("TIER_MAX_INSTANCES_FREE", "3")
+	limits, err := TierInstanceLimits()
+	if err != nil {
+		t.Fatalf("TierInstanceLimits: %v", err)
+	}
+	if limits["free"] != 3 || limits["basic"] != DefaultTierInstanceLimits["basic"] || limits["premium"] != DefaultTierInstanceLimits["premium"] {
+		t.Errorf("limits = %v, want free set to 3 and the other defaults", limits)
+	}
+
+	for _, value := range []string{"0", "-1", "many"} {
+		t.Setenv("TIER_MAX_INSTANCES_PREMIUM", value)
+		if _, err := TierInstanceLimits(); err == nil {
+			t.Errorf("TierInstanceLimits with TIER_MAX_INSTANCES_PREMIUM=%q succeeded, want an error", value)
+		}
+	}
+}
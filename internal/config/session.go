@@ -0,0 +1,13 @@
+package config
+
+import (
+	"os"
+	"strconv"
+)
+
+// SingleActiveSessionEnabled reports whether logging in should revoke every other
+// session of the user, so only the latest refresh token is accepted.
+func SingleActiveSessionEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("SINGLE_ACTIVE_SESSION"))
+	return err == nil && enabled
+}
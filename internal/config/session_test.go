@@ -0,0 +1,12 @@
+package config
+
+import "testing"
+
+func TestSingleActiveSessionEnabled(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "false": false, "0": false, "yes": false, "true": true, "1": true, "TRUE": true} {
+		t.Setenv("SINGLE_ACTIVE_SESSION", value)
+		if got := SingleActiveSessionEnabled(); got != want {
+			t.Errorf("SingleActiveSessionEnabled() with %q = %v, want %v", value, got, want)
+		}
+	}
+}
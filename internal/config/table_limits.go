@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Defaults for the CreateTable limits. Postgres itself caps a table at 1600 columns.
+const (
+	DefaultMaxTableColumns     = 300
+	DefaultMaxTableForeignKeys = 50
+	postgresMaxColumns         = 1600
+)
+
+// TableLimits bounds the size of tables created through the API, keeping the generated DDL small
+type TableLimits struct {
+	MaxColumns     int
+	MaxForeignKeys int
+}
+
+// CreateTableLimits reads TABLE_MAX_COLUMNS and TABLE_MAX_FOREIGN_KEYS, falling back to the defaults when unset
+func CreateTableLimits() (TableLimits, error) {
+	maxColumns, err := positiveIntEnv("TABLE_MAX_COLUMNS", DefaultMaxTableColumns)
+	if err != nil {
+		return TableLimits{}, err
+	}
+	if maxColumns > postgresMaxColumns {
+		return TableLimits{}, fmt.Errorf("TABLE_MAX_COLUMNS must not exceed the Postgres limit of %d, got %d", postgresMaxColumns, maxColumns)
+	}
+
+	maxForeignKeys, err := positiveIntEnv("TABLE_MAX_FOREIGN_KEYS", DefaultMaxTableForeignKeys)
+	if err != nil {
+		return TableLimits{}, err
+	}
+
+	return TableLimits{
+		MaxColumns:     maxColumns,
+		MaxForeignKeys: maxForeignKeys,
+	}, nil
+}
+
+func positiveIntEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a valid integer: %w", name, err)
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("%s must be at least 1, got %d", name, n)
+	}
+	return n, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestCreateTableLimits(t *testing.T) {
+	t.Setenv("TABLE_MAX_COLUMNS", "")
+	t.Setenv("TABLE_MAX_FOREIGN_KEYS", "")
+	if got, err := CreateTableLimits(); err != nil || got != (TableLimits{MaxColumns: DefaultMaxTableColumns, MaxForeignKeys: DefaultMaxTableForeignKeys}) {
+		t.Errorf("CreateTableLimits() = %+v, %v, want the defaults", got, err)
+	}
+
+	t.Setenv("TABLE_MAX_COLUMNS", "1600")
+	t.Setenv("TABLE_MAX_FOREIGN_KEYS", "1")
+	if got, err := CreateTableLimits(); err != nil || got != (TableLimits{MaxColumns: 1600, MaxForeignKeys: 1}) {
+		t.Errorf("CreateTableLimits() = %+v, %v, want 1600 columns and 1 foreign key", got, err)
+	}
+
+	for _, env := range [][2]string{
+		{"TABLE_MAX_COLUMNS", "1601"},
+		{"TABLE_MAX_COLUMNS", "0"},
+		{"TABLE_MAX_COLUMNS", "many"},
+		{"TABLE_MAX_FOREIGN_KEYS", "-1"},
+	} {
+		t.Setenv("TABLE_MAX_COLUMNS", "")
+		t.Setenv("TABLE_MAX_FOREIGN_KEYS", "")
+		t.Setenv(env[0], env[1])
+		if got, err := CreateTableLimits(); err == nil {
+			t.Errorf("CreateTableLimits() with %s=%s = %+v, want an error", env[0], env[1], got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a self-signed certificate and its key to a temporary directory
+func writeSelfSignedCert(t *testing.T) (certFile string, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfigLoadsProvidedCertificate(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	t.Setenv("TLS_CERT_FILE", certFile)
+	t.Setenv("TLS_KEY_FILE", keyFile)
+
+	if !TLSEnabled() {
+		t.Error("TLSEnabled() = false with a certificate and key")
+	}
+	cfg, err := TLSConfig()
+	if err != nil {
+		t.Fatalf("TLSConfig: %v", err)
+	}
+	if cfg == nil || len(cfg.Certificates) != 1 || cfg.MinVersion != tls.VersionTLS12 {
+		t.Errorf("TLSConfig() = %+v, want the certificate with TLS 1.2 or later", cfg)
+	}
+}
+
+func TestTLSConfigDefaultsToPlainHTTP(t *testing.T) {
+	t.Setenv("TLS_CERT_FILE", "")
+	t.Setenv("TLS_KEY_FILE", "")
+
+	if TLSEnabled() {
+		t.Error("TLSEnabled() = true without a certificate")
+	}
+	if cfg, err := TLSConfig(); cfg != nil || err != nil {
+		t.Errorf("TLSConfig() = %v, %v, want nil", cfg, err)
+	}
+}
+
+func TestTLSConfigRejectsIncompleteOrInvalidFiles(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+
+	cases := [][2]string{
+		{certFile, ""},
+		{"", keyFile},
+		{certFile, missing},
+		{keyFile, certFile},
+	}
+	for _, c := range cases {
+		t.Setenv("TLS_CERT_FILE", c[0])
+		t.Setenv("TLS_KEY_FILE", c[1])
+		if cfg, err := TLSConfig(); err == nil {
+			t.Errorf("TLSConfig() with cert %q and key %q = %v, want an error", c[0], c[1], cfg)
+		}
+	}
+}
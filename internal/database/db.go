@@ -160,28 +160,32 @@ func Close() {
 	}
 }
 
-// ConnectToProjectDatabase creates a connection pool to a project's database instance
-// This is used to connect to project databases whose metadata is stored in the main database
-func ConnectToProjectDatabase(endpoint string, port int, username, password, database string) (*pgxpool.Pool, error) {
+// ProjectDatabaseURL builds the connection string of a project's database instance
+func ProjectDatabaseURL(endpoint string, port int, username, password, database string) string {
 	// Build connection string using postgres:// URL format
 	userInfo := url.UserPassword(username, password)
 	encodedDatabase := url.PathEscape(database)
 
-	dsn := fmt.Sprintf(
+	return fmt.Sprintf(
 		"postgres://%s@%s:%d/%s?sslmode=disable",
 		userInfo.String(),
 		endpoint,
 		port,
 		encodedDatabase,
 	)
+}
 
+// ConnectToProjectDatabase creates a connection pool to a project's database instance
+// This is used to connect to project databases whose metadata is stored in the main database.
+// Pools are cached across requests, so an idle pool keeps no connections open.
+func ConnectToProjectDatabase(dsn string) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse connection string: %w", err)
 	}
 
 	config.MaxConns = 5
-	config.MinConns = 1
+	config.MinConns = 0
 	config.MaxConnLifetime = 5 * time.Minute
 	config.MaxConnIdleTime = 1 * time.Minute
 
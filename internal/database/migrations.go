@@ -25,6 +25,25 @@ func RunMigrations(pool *pgxpool.Pool) error {
 		fixQueryHistoryForeignKey,
 		createUsageMetricsTable,
 		preventHardDeleteUsers,
+		addQueryPolicyToProjects,
+		createQueryPlanSnapshotsTable,
+		createDDLAuditTable,
+		addResultMetadataToQueryHistory,
+		createUserQuotasTable,
+		addUpdatedAtToProjects,
+		addRedactQueryTextToProjects,
+		addAPIKeyHashIndex,
+		addProvisionAttemptsToDatabaseInstances,
+		createBackupTables,
+		addDBNameToDatabaseInstances,
+		addEndpointToDatabaseInstances,
+		requireDatabaseInstancePort,
+		createBackupDownloadsTable,
+		addLastUsedAtToAPIKeys,
+		addRefreshTokenRotationToSessions,
+		addClientInfoToSessions,
+		addStatementTypeToQueryHistory,
+		createSavedQueriesTable,
 	}
 
 	for i, migration := range migrations {
@@ -263,3 +282,259 @@ CREATE TABLE IF NOT EXISTS usage_metrics (
 CREATE INDEX IF NOT EXISTS idx_usage_metrics_db_instance_id ON usage_metrics(db_instance_id);
 CREATE INDEX IF NOT EXISTS idx_usage_metrics_timestamp ON usage_metrics(timestamp);
 `
+
+const addQueryPolicyToProjects = `
+-- Add per-project query policy columns
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'projects' AND column_name = 'read_only'
+  ) THEN
+    ALTER TABLE projects ADD COLUMN read_only BOOLEAN NOT NULL DEFAULT FALSE;
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'projects' AND column_name = 'blocked_keywords'
+  ) THEN
+    ALTER TABLE projects ADD COLUMN blocked_keywords TEXT[] NOT NULL DEFAULT '{}';
+  END IF;
+END$$;
+`
+
+const createQueryPlanSnapshotsTable = `
+CREATE TABLE IF NOT EXISTS query_plan_snapshots (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  query_text TEXT NOT NULL,
+  plan JSONB NOT NULL,
+  total_cost DOUBLE PRECISION NOT NULL,
+  plan_rows DOUBLE PRECISION NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  UNIQUE (project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_plan_snapshots_project_id ON query_plan_snapshots(project_id);
+`
+
+const createDDLAuditTable = `
+CREATE TABLE IF NOT EXISTS ddl_audit (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  operation TEXT NOT NULL,
+  target TEXT NOT NULL,
+  sql_text TEXT NOT NULL,
+  success BOOLEAN NOT NULL,
+  error_message TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_project_id ON ddl_audit(project_id);
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_user_id ON ddl_audit(user_id);
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_created_at ON ddl_audit(created_at);
+`
+
+const addResultMetadataToQueryHistory = `
+-- Store the outcome of each query alongside its history entry
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'query_history' AND column_name = 'error_message'
+  ) THEN
+    ALTER TABLE query_history ADD COLUMN error_message TEXT;
+  END IF;
+
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'query_history' AND column_name = 'rows_affected'
+  ) THEN
+    ALTER TABLE query_history ADD COLUMN rows_affected BIGINT;
+  END IF;
+END$$;
+`
+
+const createUserQuotasTable = `
+-- Per-user overrides of the default resource quotas (NULL limits fall back to the defaults)
+CREATE TABLE IF NOT EXISTS user_quotas (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  max_projects INT,
+  max_storage_gb INT,
+  flags TEXT[] NOT NULL DEFAULT '{}',
+  updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+`
+
+const addUpdatedAtToProjects = `
+-- Track when a project last changed, backfilling existing rows with their creation time
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'projects' AND column_name = 'updated_at'
+  ) THEN
+    ALTER TABLE projects ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE;
+    UPDATE projects SET updated_at = created_at;
+    ALTER TABLE projects ALTER COLUMN updated_at SET DEFAULT NOW();
+    ALTER TABLE projects ALTER COLUMN updated_at SET NOT NULL;
+  END IF;
+END$$;
+`
+
+const addRedactQueryTextToProjects = `
+-- Let projects with sensitive data keep raw query text out of query history
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'projects' AND column_name = 'redact_query_text'
+  ) THEN
+    ALTER TABLE projects ADD COLUMN redact_query_text BOOLEAN NOT NULL DEFAULT FALSE;
+  END IF;
+END$$;
+`
+
+const addAPIKeyHashIndex = `
+-- API keys are looked up by their hash on every authenticated request
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+`
+
+const addProvisionAttemptsToDatabaseInstances = `
+-- How often the background worker retried creating the container of a failed instance
+ALTER TABLE database_instances ADD COLUMN IF NOT EXISTS provision_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE database_instances ADD COLUMN IF NOT EXISTS last_provision_attempt_at TIMESTAMP WITH TIME ZONE;
+`
+
+const createBackupTables = `
+-- Scheduled pg_dump backups of project databases and the dumps they produced
+CREATE TABLE IF NOT EXISTS backup_schedules (
+  project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+  interval_minutes INT NOT NULL CHECK (interval_minutes > 0),
+  retention INT NOT NULL CHECK (retention > 0),
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  last_run_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_schedules_next_run_at ON backup_schedules(next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS project_backups (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  file_path TEXT NOT NULL,
+  size_bytes BIGINT,
+  error_message TEXT,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_backups_project_id ON project_backups(project_id, started_at DESC);
+`
+
+const addDBNameToDatabaseInstances = `
+-- Name of the database created inside each instance's container
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'database_instances' AND column_name = 'db_name'
+  ) THEN
+    -- Existing instances keep db_name NULL: their data lives in the default
+    -- postgres database, which DatabaseName falls back to
+    ALTER TABLE database_instances ADD COLUMN db_name TEXT;
+  END IF;
+END$$;
+`
+
+const addEndpointToDatabaseInstances = `
+-- Host the orchestrator reported for each instance's container
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'database_instances' AND column_name = 'endpoint'
+  ) THEN
+    ALTER TABLE database_instances ADD COLUMN endpoint TEXT;
+  END IF;
+END$$;
+`
+
+const requireDatabaseInstancePort = `
+-- Every instance records the port its database listens on; older rows get their type's default
+UPDATE database_instances di
+SET port = CASE p.db_type WHEN 'mongodb' THEN 27017 ELSE 5432 END
+FROM projects p
+WHERE p.id = di.project_id AND di.port IS NULL;
+
+ALTER TABLE database_instances ALTER COLUMN port SET NOT NULL;
+`
+
+const createBackupDownloadsTable = `
+-- On-demand pg_dump downloads streamed to users
+CREATE TABLE IF NOT EXISTS backup_downloads (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  size_bytes BIGINT,
+  error_message TEXT,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_downloads_project_id ON backup_downloads(project_id, started_at DESC);
+`
+
+const addLastUsedAtToAPIKeys = `
+-- When each API key last authenticated a request, updated at most once a minute
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1 FROM information_schema.columns 
+    WHERE table_name = 'api_keys' AND column_name = 'last_used_at'
+  ) THEN
+    ALTER TABLE api_keys ADD COLUMN last_used_at TIMESTAMP WITH TIME ZONE;
+  END IF;
+END$$;
+`
+
+const addRefreshTokenRotationToSessions = `
+-- The jti of each session's refresh token, and when rotation consumed it, to detect reuse
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS jti TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS consumed_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_jti ON sessions(jti);
+`
+
+const addClientInfoToSessions = `
+-- The client each session was issued to, so users can tell their devices apart
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip_address TEXT;
+`
+
+const addStatementTypeToQueryHistory = `
+-- Whether each query was a SELECT, DML, DDL or another statement
+ALTER TABLE query_history ADD COLUMN IF NOT EXISTS statement_type TEXT;
+`
+
+const createSavedQueriesTable = `
+-- Queries a user saved under a name to run again, private to them within a project.
+-- The unique constraint's index also serves listing a user's queries of a project.
+CREATE TABLE IF NOT EXISTS saved_queries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  query_text TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, project_id, name)
+);
+`
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"backend/internal/responses"
+	"backend/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey handles POST /api/v1/keys. The key's value is only returned here.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	var req services.CreateAPIKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var ttl time.Duration
+	if req.ExpiresInDays != nil {
+		ttl = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	key, err := h.apiKeyService.Generate(userUUID, req.Description, ttl)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusCreated, key, "API key created; store it now, it will not be shown again")
+}
+
+// ListAPIKeys handles GET /api/v1/keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	keys, err := h.apiKeyService.List(userUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, keys, "API keys retrieved successfully")
+}
+
+// RevokeAPIKey handles DELETE /api/v1/keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	keyUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid API key ID format")
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(userUUID, keyUUID); err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, nil, "API key revoked successfully")
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"backend/internal/config"
 	"backend/internal/models"
 	"backend/internal/responses"
 	"backend/internal/services"
+	"errors"
 	_ "log"
 
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // Cookie configuration
@@ -19,10 +22,32 @@ const (
 
 type AuthHandler struct {
 	authService *services.AuthService
+	userService *services.UserService
+	cookie      config.CookieSettings
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *services.AuthService, userService *services.UserService, cookie config.CookieSettings) *AuthHandler {
+	return &AuthHandler{authService: authService, userService: userService, cookie: cookie}
+}
+
+// setRefreshCookie sets the refresh token cookie with the configured attributes.
+// A negative maxAge deletes it.
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, value string, maxAge int) {
+	writeRefreshCookie(c, h.cookie, value, maxAge)
+}
+
+// writeRefreshCookie sets the refresh token cookie with the given attributes
+func writeRefreshCookie(c *gin.Context, cookie config.CookieSettings, value string, maxAge int) {
+	c.SetSameSite(cookie.SameSite)
+	c.SetCookie(RefreshTokenCookieName, value, maxAge, cookie.Path, cookie.Domain, cookie.Secure, true)
+}
+
+// clientInfo describes the client of a request, to be recorded on the session it gets
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -41,13 +66,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Email:    req.Email,
 		Password: req.Password,
 	}
-	accessToken, refreshToken, err := h.authService.Register(user)
+	accessToken, refreshToken, err := h.authService.Register(user, clientInfo(c))
 	if err != nil {
 		responses.Fail(c, http.StatusInternalServerError, err, "Could not register user")
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 30*24*3600, "/", "", true, true)
+	h.setRefreshCookie(c, refreshToken, RefreshTokenMaxAge)
 
 	// 4. Return only access token in response body
 	res := gin.H{
@@ -68,13 +93,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.Login(req.Email, req.Password)
+	accessToken, refreshToken, err := h.authService.Login(req.Email, req.Password, clientInfo(c))
 	if err != nil {
 		responses.Fail(c, http.StatusUnauthorized, err, "Failed to login")
 		return
 	}
 
-	c.SetCookie("refresh_token", refreshToken, 30*24*3600, "/", "", true, true)
+	h.setRefreshCookie(c, refreshToken, RefreshTokenMaxAge)
 
 	res := gin.H{
 		"access_token": accessToken,
@@ -84,26 +109,78 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// refreshToken, err := c.Cookie("refresh_token")
-	// if err != nil {
-	// 	responses.Fail(c, http.StatusBadRequest, nil, "Missing refresh token")
-	// 	return
-	// }
+	// Revoke the session of this browser, if it still has one
+	if refreshToken, err := c.Cookie(RefreshTokenCookieName); err == nil && refreshToken != "" {
+		if err := h.authService.Logout(refreshToken); err != nil {
+			responses.Fail(c, http.StatusInternalServerError, err, "Could not revoke token")
+			return
+		}
+	}
 
-	// _, exists := c.Get("userId") // Extracted from access token
-	// if !exists {
-	// 	responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-	// 	return
-	// }
+	h.setRefreshCookie(c, "", -1)
 
-	// if err := h.userService.Logout(refreshToken); err != nil {
-	// 	responses.Fail(c, http.StatusUnauthorized, err, "Could not revoke token")
-	// 	return
-	// }
+	responses.Success(c, http.StatusOK, nil, "Logged out successfully")
+}
 
-	c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+// LogoutAll handles POST /api/v1/auth/logout-all, ending every session of the user so
+// no refresh token issued so far can be used again. Access tokens already issued stay
+// valid until they expire.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
 
-	responses.Success(c, http.StatusOK, nil, "Logged out successfully")
+	if err := h.userService.LogoutByUserID(userUUID); err != nil {
+		responses.Fail(c, http.StatusInternalServerError, err, "Could not revoke sessions")
+		return
+	}
+
+	h.setRefreshCookie(c, "", -1)
+
+	responses.Success(c, http.StatusOK, nil, "Logged out of all sessions successfully")
+}
+
+// ListSessions handles GET /api/v1/auth/sessions, returning the devices the user is
+// signed in on
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userUUID)
+	if err != nil {
+		responses.Fail(c, http.StatusInternalServerError, err, "Could not list sessions")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, sessions, "Sessions retrieved successfully")
+}
+
+// RevokeSession handles DELETE /api/v1/auth/sessions/:id, signing one device out
+// without ending the user's other sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid session ID")
+		return
+	}
+
+	if err := h.authService.RevokeSession(userUUID, sessionID); err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, nil, "Session revoked successfully")
 }
 
 func (h *AuthHandler) Refresh(c *gin.Context) {
@@ -115,14 +192,18 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	}
 
 	// 2. Validate and generate new tokens (with rotation)
-	accessToken, newRefreshToken, err := h.authService.Refresh(refreshToken)
+	accessToken, newRefreshToken, err := h.authService.Refresh(refreshToken, clientInfo(c))
 	if err != nil {
-		c.SetCookie("refresh_token", "", -1, "/", "", true, true)
+		h.setRefreshCookie(c, "", -1)
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			responses.FromError(c, err)
+			return
+		}
 		responses.Fail(c, http.StatusUnauthorized, err, "Invalid or expired refresh token")
 		return
 	}
 
-	c.SetCookie("refresh_token", newRefreshToken, 30*24*3600, "/", "", true, true)
+	h.setRefreshCookie(c, newRefreshToken, RefreshTokenMaxAge)
 
 	res := gin.H{
 		"access_token": accessToken,
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"backend/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The refresh cookie carries the configured attributes, here when Logout clears it
+func TestRefreshCookieAttributesFollowConfiguration(t *testing.T) {
+	settings := []config.CookieSettings{
+		{Path: "/", Secure: false, SameSite: http.SameSiteLaxMode},
+		{Path: "/api/v1/auth", Domain: "example.com", Secure: true, SameSite: http.SameSiteNoneMode},
+		{Path: "/", Domain: "app.example.com", Secure: true, SameSite: http.SameSiteStrictMode},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, cookie := range settings {
+		router := gin.New()
+		router.POST("/logout", NewAuthHandler(nil, nil, cookie).Logout)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/logout", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Logout status = %d, want 200: %s", w.Code, w.Body)
+		}
+
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != RefreshTokenCookieName {
+			t.Fatalf("Logout set cookies %v, want only %s", cookies, RefreshTokenCookieName)
+		}
+		got := cookies[0]
+		if got.Path != cookie.Path || got.Domain != cookie.Domain || got.Secure != cookie.Secure ||
+			got.SameSite != cookie.SameSite || !got.HttpOnly {
+			t.Errorf("cookie = %+v, want the attributes of %+v and HttpOnly", got, cookie)
+		}
+		if got.MaxAge >= 0 || got.Value != "" {
+			t.Errorf("cookie = %+v, want it cleared", got)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"backend/internal/responses"
+	"backend/internal/services"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type BackupHandler struct {
+	backupService *services.BackupService
+}
+
+func NewBackupHandler(backupService *services.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// GetSchedule handles GET /api/v1/projects/:id/backups/schedule
+func (h *BackupHandler) GetSchedule(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	schedule, err := h.backupService.GetSchedule(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, schedule, "Backup schedule retrieved successfully")
+}
+
+// SetSchedule handles PUT /api/v1/projects/:id/backups/schedule
+func (h *BackupHandler) SetSchedule(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.SetBackupScheduleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	schedule, err := h.backupService.SetSchedule(userUUID, projectUUID, req)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, schedule, "Backup schedule saved successfully")
+}
+
+// ListBackups handles GET /api/v1/projects/:id/backups
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	backups, err := h.backupService.ListBackups(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, backups, "Backups retrieved successfully")
+}
+
+// DownloadBackup handles GET /api/v1/projects/:id/backups/:backup_id/download
+func (h *BackupHandler) DownloadBackup(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	backupUUID, err := uuid.Parse(c.Param("backup_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid backup ID format")
+		return
+	}
+
+	backup, err := h.backupService.GetBackup(userUUID, projectUUID, backupUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	c.FileAttachment(backup.FilePath, fmt.Sprintf("%s-%s.dump", projectUUID, backup.StartedAt.UTC().Format("20060102T150405Z")))
+}
+
+// BackupProject handles GET /api/v1/projects/:id/backup
+func (h *BackupHandler) BackupProject(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	stream, err := h.backupService.BackupProject(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	// The dump is streamed as pg_dump writes it, so its length is unknown up front
+	filename := fmt.Sprintf("%s-%s.sql", projectUUID, time.Now().UTC().Format("20060102T150405Z"))
+	c.DataFromReader(http.StatusOK, -1, "application/sql", stream, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+	})
+}
+
+// RestoreProject handles POST /api/v1/projects/:id/restore. The dump is uploaded as the
+// "file" field of a multipart form and streamed into the database without being buffered.
+func (h *BackupHandler) RestoreProject(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	dump, err := multipartFile(c, "file")
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid upload")
+		return
+	}
+
+	if err := h.backupService.RestoreProject(userUUID, projectUUID, dump); err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, nil, "Project restored successfully")
+}
+
+// multipartFile returns the content of the named file field of a multipart request,
+// positioned so it can be read while the upload is still arriving
+func multipartFile(c *gin.Context, field string) (io.Reader, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid upload: missing %q file field", field)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == field && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"backend/internal/responses"
+	"backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DDLAuditHandler struct {
+	auditService *services.DDLAuditService
+}
+
+func NewDDLAuditHandler(auditService *services.DDLAuditService) *DDLAuditHandler {
+	return &DDLAuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetProjectAudit handles GET /api/v1/projects/:id/audit/ddl
+func (h *DDLAuditHandler) GetProjectAudit(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+
+	entries, err := h.auditService.GetProjectAudit(userUUID, projectUUID, auditLimit(c))
+	if err != nil {
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get DDL audit log")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, entries, "DDL audit log retrieved successfully")
+}
+
+// GetAllAudit handles GET /api/v1/audit/ddl (admin only)
+func (h *DDLAuditHandler) GetAllAudit(c *gin.Context) {
+	entries, err := h.auditService.GetAllAudit(auditLimit(c))
+	if err != nil {
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get DDL audit log")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, entries, "DDL audit log retrieved successfully")
+}
+
+// auditLimit reads the ?limit= query param, clamped to 1..100 (default 50)
+func auditLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	return limit
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"backend/internal/config"
 	"backend/internal/responses"
 	"backend/internal/services"
 	"backend/internal/utils"
+	"errors"
 	_ "log"
 
 	"net/http"
@@ -15,12 +17,14 @@ import (
 type GoogleAuthHandler struct {
 	googleAuthService *services.GoogleAuthService
 	googleOauthConfig *oauth2.Config
+	cookie            config.CookieSettings
 }
 
-func NewGoogleAuthHandler(googleAuthService *services.GoogleAuthService, oauthConfig *oauth2.Config) *GoogleAuthHandler {
+func NewGoogleAuthHandler(googleAuthService *services.GoogleAuthService, oauthConfig *oauth2.Config, cookie config.CookieSettings) *GoogleAuthHandler {
 	return &GoogleAuthHandler{
 		googleAuthService: googleAuthService,
 		googleOauthConfig: oauthConfig,
+		cookie:            cookie,
 	}
 }
 
@@ -33,7 +37,7 @@ func (h *GoogleAuthHandler) Login(c *gin.Context) {
 	if err != nil {
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to generate state")
 	}
-	c.SetCookie("oauth_state", oauthState, 3600, "/", "", false, true)
+	c.SetCookie("oauth_state", oauthState, 3600, "/", "", config.TLSEnabled(), true)
 
 	authURL := h.googleOauthConfig.AuthCodeURL(
 		oauthState,
@@ -64,7 +68,7 @@ func (h *GoogleAuthHandler) Callback(c *gin.Context) {
 	}
 
 	// Clear the state cookie
-	c.SetCookie("oauth_state", "", -1, "/", "", false, true)
+	c.SetCookie("oauth_state", "", -1, "/", "", config.TLSEnabled(), true)
 
 	// Get authorization code
 	code := c.Query("code")
@@ -81,12 +85,18 @@ func (h *GoogleAuthHandler) Callback(c *gin.Context) {
 	}
 
 	// Get user info and create/update user
-	accessToken, err := h.googleAuthService.Callback(c.Request.Context(), token)
+	accessToken, refreshToken, err := h.googleAuthService.Callback(c.Request.Context(), token, clientInfo(c))
+	if errors.Is(err, services.ErrAccountDeleted) {
+		responses.Fail(c, http.StatusForbidden, err, "This account has been deleted")
+		return
+	}
 	if err != nil {
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to login")
 		return
 	}
 
+	writeRefreshCookie(c, h.cookie, refreshToken, RefreshTokenMaxAge)
+
 	res := gin.H{
 		"access_token": accessToken,
 	}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"backend/internal/middlewares"
+	"backend/internal/responses"
+	"backend/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// nextCursorHeader carries the cursor of the next page on paginated list responses
+const nextCursorHeader = "X-Next-Cursor"
+
+// totalCountHeader carries the number of items across all pages of offset paginated lists
+const totalCountHeader = "X-Total-Count"
+
+// getUserID extracts the authenticated user's ID from the context (set by Authenticate middleware)
+func getUserID(c *gin.Context) (uuid.UUID, error) {
+	userID, exists := c.Get(middlewares.UserIDKey)
+	if !exists {
+		return uuid.Nil, errors.New("user ID not found in context")
+	}
+
+	switch v := userID.(type) {
+	case uuid.UUID:
+		return v, nil
+	case string:
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid user ID format: %w", err)
+		}
+		return parsed, nil
+	default:
+		return uuid.Nil, fmt.Errorf("invalid user ID type: %T", v)
+	}
+}
+
+// bindJSON binds the JSON request body into obj. On failure it responds with a message
+// telling apart a wrong content type, a missing body, malformed JSON and failed validation,
+// and returns false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if contentType := c.ContentType(); contentType != "" && contentType != binding.MIMEJSON {
+		responses.Fail(c, http.StatusUnsupportedMediaType, nil, "Content-Type must be application/json")
+		return false
+	}
+
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+	switch {
+	case errors.Is(err, io.EOF):
+		responses.Fail(c, http.StatusBadRequest, err, "Request body is required")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		responses.Fail(c, http.StatusBadRequest, err, "Malformed JSON in request body")
+	case errors.As(err, &typeErr):
+		responses.Fail(c, http.StatusBadRequest, err, fmt.Sprintf("Malformed JSON in request body: field '%s' has the wrong type", typeErr.Field))
+	case errors.As(err, &validationErrs):
+		responses.Fail(c, http.StatusBadRequest, err, "Validation failed: "+describeValidationErrors(validationErrs))
+	default:
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+	}
+	return false
+}
+
+// describeValidationErrors turns binding validation errors into a short human readable list
+func describeValidationErrors(errs validator.ValidationErrors) string {
+	messages := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		if fe.Tag() == "required" {
+			messages = append(messages, fmt.Sprintf("%s is required", fe.Field()))
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("%s is invalid (%s)", fe.Field(), fe.Tag()))
+	}
+	return strings.Join(messages, ", ")
+}
+
+// respondUnsupportedDBType responds with 400 when err reports a SQL operation on a non-postgres project
+func respondUnsupportedDBType(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrPostgresOnly) {
+		return false
+	}
+	responses.FromError(c, err)
+	return true
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"backend/internal/middlewares"
+	"backend/internal/responses"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newTestContext(req *http.Request) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	return c, w
+}
+
+func TestGetUserIDAcceptsBothStoredTypes(t *testing.T) {
+	id := uuid.New()
+	for _, stored := range []interface{}{id, id.String()} {
+		c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+		c.Set(middlewares.UserIDKey, stored)
+
+		got, err := getUserID(c)
+		if err != nil || got != id {
+			t.Errorf("getUserID with a %T = %v, %v, want %v", stored, got, err, id)
+		}
+	}
+}
+
+func TestGetUserIDRejectsMissingOrInvalidIDs(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if _, err := getUserID(c); err == nil {
+		t.Error("getUserID succeeded without a user ID")
+	}
+
+	for _, stored := range []interface{}{"not-a-uuid", 42} {
+		c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+		c.Set(middlewares.UserIDKey, stored)
+		if _, err := getUserID(c); err == nil {
+			t.Errorf("getUserID succeeded with %#v", stored)
+		}
+	}
+}
+
+type bindTarget struct {
+	Name  string `json:"name" binding:"required"`
+	Count int    `json:"count"`
+}
+
+func TestBindJSON(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		message     string
+	}{
+		{"valid", "application/json", `{"name":"a","count":2}`, http.StatusOK, ""},
+		{"charset in content type", "application/json; charset=utf-8", `{"name":"a"}`, http.StatusOK, ""},
+		{"empty body", "application/json", ``, http.StatusBadRequest, "Request body is required"},
+		{"wrong content type", "text/plain", `{"name":"a"}`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"form content type", "application/x-www-form-urlencoded", `name=a`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"malformed JSON", "application/json", `{"name": "a",`, http.StatusBadRequest, "Malformed JSON in request body"},
+		{"not JSON", "application/json", `name=a`, http.StatusBadRequest, "Malformed JSON in request body"},
+		{"wrong field type", "application/json", `{"name":"a","count":"two"}`, http.StatusBadRequest, "Malformed JSON in request body: field 'count' has the wrong type"},
+		{"missing required field", "application/json", `{"count":2}`, http.StatusBadRequest, "Validation failed: Name is required"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			c, w := newTestContext(req)
+
+			var target bindTarget
+			ok := bindJSON(c, &target)
+			if ok != (tc.status == http.StatusOK) {
+				t.Fatalf("bindJSON = %v, want %v", ok, tc.status == http.StatusOK)
+			}
+			if ok {
+				return
+			}
+
+			if w.Code != tc.status {
+				t.Errorf("status = %d, want %d", w.Code, tc.status)
+			}
+			var body responses.APIResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+			}
+			if body.Message != tc.message {
+				t.Errorf("message = %q, want %q", body.Message, tc.message)
+			}
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"backend/internal/apperr"
+	"backend/internal/pagination"
 	"backend/internal/responses"
 	"backend/internal/services"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"net/http"
 
@@ -23,33 +29,28 @@ func NewProjectHandler(projectService *services.ProjectService) *ProjectHandler
 
 // CreateProject handles POST /api/v1/projects
 func (h *ProjectHandler) CreateProject(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	var req services.CreateProjectRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// Convert userID to string (it's a uuid.UUID from the JWT claims)
-	userIDStr := ""
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userIDStr = v.String()
-	case string:
-		userIDStr = v
-	default:
-		userIDStr = fmt.Sprintf("%v", v)
-	}
-
-	project, err := h.projectService.CreateProject(userIDStr, req)
+	project, err := h.projectService.CreateProject(c.Request.Context(), userUUID.String(), req)
 	if err != nil {
 		fmt.Printf("ERROR in CreateProject handler: %v\n", err)
+		if errors.Is(err, services.ErrTierLimitReached) {
+			responses.Fail(c, http.StatusPaymentRequired, err, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrQuotaExceeded) {
+			responses.Fail(c, http.StatusForbidden, err, err.Error())
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to create project")
 		return
 	}
@@ -59,28 +60,16 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 
 // GetProject handles GET /api/v1/projects/:id
 func (h *ProjectHandler) GetProject(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	projectID := c.Param("id")
 
-	// Convert userID to string
-	userIDStr := ""
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userIDStr = v.String()
-	case string:
-		userIDStr = v
-	default:
-		userIDStr = fmt.Sprintf("%v", v)
-	}
-
 	// Get project and verify it belongs to the authenticated user
-	project, err := h.projectService.GetProjectByIDAndUserID(projectID, userIDStr)
+	project, err := h.projectService.GetProjectByIDAndUserID(projectID, userUUID.String())
 	if err != nil {
 		responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
 		return
@@ -91,55 +80,97 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 
 // ListProjects handles GET /api/v1/projects
 func (h *ProjectHandler) ListProjects(c *gin.Context) {
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	userIDStr := ""
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userIDStr = v.String()
-	case string:
-		userIDStr = v
-	default:
-		userIDStr = fmt.Sprintf("%v", v)
+	// Without ?limit= or ?cursor= every project is returned, as before
+	if c.Query("limit") == "" && c.Query("cursor") == "" {
+		projects, err := h.projectService.GetProjectsByUserID(userUUID.String())
+		if err != nil {
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve projects")
+			return
+		}
+
+		responses.Success(c, http.StatusOK, projects, "Projects retrieved successfully")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
 	}
 
-	projects, err := h.projectService.GetProjectsByUserID(userIDStr)
+	projects, nextCursor, err := h.projectService.GetProjectsPageByUserID(userUUID.String(), limit, c.Query("cursor"))
 	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid pagination cursor")
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve projects")
 		return
 	}
+	if nextCursor != "" {
+		c.Header(nextCursorHeader, nextCursor)
+	}
 
 	responses.Success(c, http.StatusOK, projects, "Projects retrieved successfully")
 }
 
+// UpdateProject handles PATCH /api/v1/projects/:id
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	var req services.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
+
+	project, err := h.projectService.UpdateProject(userUUID, projectUUID, req)
+	if err != nil {
+		if err.Error() == "project not found or access denied" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "failed to") {
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to update project")
+			return
+		}
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	responses.Success(c, http.StatusOK, project, "Project updated successfully")
+}
+
 // DeleteProject handles DELETE /api/v1/projects/:id
 func (h *ProjectHandler) DeleteProject(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	projectID := c.Param("id")
 
-	// Convert userID to string
-	userIDStr := ""
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userIDStr = v.String()
-	case string:
-		userIDStr = v
-	default:
-		userIDStr = fmt.Sprintf("%v", v)
-	}
-
 	// Delete project and verify it belongs to the authenticated user
-	err := h.projectService.DeleteProjectByIDAndUserID(projectID, userIDStr)
+	err = h.projectService.DeleteProjectByIDAndUserID(projectID, userUUID.String())
 	if err != nil {
 		responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
 		return
@@ -148,34 +179,48 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	responses.Success(c, http.StatusOK, nil, "Project deleted successfully")
 }
 
-// InsertRow handles POST /api/v1/projects/:id/tables/:table_name/rows
-func (h *ProjectHandler) InsertRow(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+// DeleteProjects handles DELETE /api/v1/projects
+func (h *ProjectHandler) DeleteProjects(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	projectID := c.Param("id")
+	var req services.DeleteProjectsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+		return
+	}
 
-	// Convert userID to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
-			return
+	results, err := h.projectService.DeleteProjects(userUUID, req.ProjectIDs)
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	// Report partial success when some projects could not be deleted
+	message := "Projects deleted successfully"
+	for _, result := range results {
+		if !result.Success {
+			message = "Some projects could not be deleted"
+			break
 		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+	}
+
+	responses.Success(c, http.StatusOK, results, message)
+}
+
+// InsertRow handles POST /api/v1/projects/:id/tables/:table_name/rows
+func (h *ProjectHandler) InsertRow(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
+	projectID := c.Param("id")
+
 	projectUUID, err := uuid.Parse(projectID)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
@@ -183,8 +228,7 @@ func (h *ProjectHandler) InsertRow(c *gin.Context) {
 	}
 
 	var req services.InsertRowRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -196,6 +240,9 @@ func (h *ProjectHandler) InsertRow(c *gin.Context) {
 
 	result, err := h.projectService.InsertRow(userUUID, projectUUID, req)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to insert row")
 		return
 	}
@@ -203,35 +250,138 @@ func (h *ProjectHandler) InsertRow(c *gin.Context) {
 	responses.Success(c, http.StatusCreated, result, "Row inserted successfully")
 }
 
-// DeleteRow handles DELETE /api/v1/projects/:id/rows/:row_id
-func (h *ProjectHandler) DeleteRow(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+// UpdateRows handles PATCH /api/v1/projects/:id/tables/:table/rows
+func (h *ProjectHandler) UpdateRows(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	projectID := c.Param("id")
-	rowID := c.Param("row_id")
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+	tableName := c.Param("table")
 
-	// Convert userID to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+	var req services.UpdateRowsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	resp, err := h.projectService.UpdateRows(userUUID, projectUUID, tableName, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
 			return
 		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+		if strings.HasPrefix(err.Error(), "failed to") {
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to update rows")
+			return
+		}
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
 		return
 	}
 
+	responses.Success(c, http.StatusOK, resp, "Rows updated successfully")
+}
+
+// InsertRows handles POST /api/v1/projects/:id/tables/:table/rows/bulk
+func (h *ProjectHandler) InsertRows(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	var req services.InsertRowsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.Table = c.Param("table")
+
+	resp, err := h.projectService.InsertRows(userUUID, projectUUID, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "failed to") {
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to insert rows")
+			return
+		}
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	responses.Success(c, http.StatusCreated, resp, "Rows inserted successfully")
+}
+
+// UpdateRow handles PATCH /api/v1/projects/:id/rows/:row_id
+func (h *ProjectHandler) UpdateRow(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	var req services.UpdateRowRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.PrimaryKeyValue = c.Param("row_id")
+
+	resp, err := h.projectService.UpdateRow(userUUID, projectUUID, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case err.Error() == "row not found":
+			responses.Fail(c, http.StatusNotFound, err, "Row not found")
+		case strings.HasPrefix(err.Error(), "failed to"):
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to update row")
+		default:
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, resp, "Row updated successfully")
+}
+
+// DeleteRow handles DELETE /api/v1/projects/:id/rows/:row_id
+func (h *ProjectHandler) DeleteRow(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectID := c.Param("id")
+	rowID := c.Param("row_id")
+
 	projectUUID, err := uuid.Parse(projectID)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
@@ -246,6 +396,9 @@ func (h *ProjectHandler) DeleteRow(c *gin.Context) {
 
 	err = h.projectService.DeleteRow(userUUID, projectUUID, req, rowID)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
 		if err.Error() == "row not found" {
 			responses.Fail(c, http.StatusNotFound, err, "Row not found")
 			return
@@ -257,34 +410,239 @@ func (h *ProjectHandler) DeleteRow(c *gin.Context) {
 	responses.Success(c, http.StatusNoContent, nil, "Row deleted successfully")
 }
 
-// AddColumn handles POST /api/v1/projects/:id/columns
-func (h *ProjectHandler) AddColumn(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+// RestartInstance handles POST /api/v1/projects/:id/instance/restart
+func (h *ProjectHandler) RestartInstance(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	projectID := c.Param("id")
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
 
-	// Convert userID to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+	result, err := h.projectService.RestartInstance(userUUID, projectUUID)
+	if err != nil {
+		switch err.Error() {
+		case "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case "no running database instance for this project", "database instance container ID not configured":
+			responses.Fail(c, http.StatusConflict, err, "The project's database is not running")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to restart database instance")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, result, "Database instance restarted")
+}
+
+// GetConnectionStats handles GET /api/v1/projects/:id/instance/connections
+func (h *ProjectHandler) GetConnectionStats(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	stats, err := h.projectService.GetConnectionStats(userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
 			return
 		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+		switch err.Error() {
+		case "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case "no running database instance for this project":
+			responses.Fail(c, http.StatusConflict, err, "The project's database is not running")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve connection stats")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, stats, "Connection stats retrieved successfully")
+}
+
+// GetInstanceLogs handles GET /api/v1/projects/:id/instance/logs
+func (h *ProjectHandler) GetInstanceLogs(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
 		return
 	}
 
+	tail, err := strconv.Atoi(c.DefaultQuery("tail", "0"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid tail")
+		return
+	}
+
+	logs, err := h.projectService.GetInstanceLogs(userUUID, projectUUID, tail)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{"logs": logs}, "Instance logs retrieved successfully")
+}
+
+// RotateCredentials handles POST /api/v1/projects/:id/instance/credentials/rotate
+func (h *ProjectHandler) RotateCredentials(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	result, err := h.projectService.RotateCredentials(userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case "no running database instance for this project":
+			responses.Fail(c, http.StatusConflict, err, "The project's database is not running")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to rotate database credentials")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, result, "Database credentials rotated")
+}
+
+// GetUsageMetrics handles GET /api/v1/projects/:id/metrics. from and to are RFC 3339
+// timestamps and granularity a duration such as 5m or 1h.
+func (h *ProjectHandler) GetUsageMetrics(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	var query services.UsageMetricsQuery
+	if from := c.Query("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid from, expected an RFC 3339 timestamp")
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid to, expected an RFC 3339 timestamp")
+			return
+		}
+	}
+	if granularity := c.Query("granularity"); granularity != "" {
+		if query.Granularity, err = time.ParseDuration(granularity); err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid granularity, expected a duration such as 5m or 1h")
+			return
+		}
+	}
+
+	series, err := h.projectService.GetUsageMetrics(userUUID, projectUUID, query)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, series, "Usage metrics retrieved successfully")
+}
+
+// PauseInstance handles POST /api/v1/projects/:id/instance/pause
+func (h *ProjectHandler) PauseInstance(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	result, err := h.projectService.PauseInstance(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	message := "Database instance paused"
+	if !result.Changed {
+		message = "Database instance is already paused"
+	}
+	responses.Success(c, http.StatusOK, result, message)
+}
+
+// ResumeInstance handles POST /api/v1/projects/:id/instance/resume
+func (h *ProjectHandler) ResumeInstance(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	result, err := h.projectService.ResumeInstance(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	message := "Database instance resumed"
+	if !result.Changed {
+		message = "Database instance is already running"
+	}
+	responses.Success(c, http.StatusOK, result, message)
+}
+
+// AddColumn handles POST /api/v1/projects/:id/columns
+func (h *ProjectHandler) AddColumn(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectID := c.Param("id")
+
 	projectUUID, err := uuid.Parse(projectID)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
@@ -297,8 +655,22 @@ func (h *ProjectHandler) AddColumn(c *gin.Context) {
 		return
 	}
 
+	// A dry run only returns the generated SQL and never touches the database
+	if c.Query("dryRun") == "true" {
+		query, err := h.projectService.PreviewAddColumn(req)
+		if err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		responses.Success(c, http.StatusOK, gin.H{"sql": query}, "Generated SQL (not executed)")
+		return
+	}
+
 	result, err := h.projectService.AddColumn(userUUID, projectUUID, req)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to add column")
 		return
 	}
@@ -308,33 +680,15 @@ func (h *ProjectHandler) AddColumn(c *gin.Context) {
 
 // DeleteColumn handles DELETE /api/v1/projects/:id/columns/:column_name
 func (h *ProjectHandler) DeleteColumn(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	projectID := c.Param("id")
 	columnName := c.Param("column_name")
 
-	// Convert userID to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
-		return
-	}
-
 	projectUUID, err := uuid.Parse(projectID)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
@@ -349,9 +703,155 @@ func (h *ProjectHandler) DeleteColumn(c *gin.Context) {
 
 	err = h.projectService.DeleteColumn(userUUID, projectUUID, req, columnName)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to delete column")
 		return
 	}
 
 	responses.Success(c, http.StatusNoContent, nil, "Column deleted successfully")
 }
+
+// GetCellValue handles GET /api/v1/projects/:id/tables/:table/rows/:row_id/cells/:column
+func (h *ProjectHandler) GetCellValue(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectID := c.Param("id")
+	tableName := c.Param("table")
+	rowID := c.Param("row_id")
+	columnName := c.Param("column")
+	pkColumn := c.DefaultQuery("pk", "id") // Primary key column used to locate the row
+
+	projectUUID, err := uuid.Parse(projectID)
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	cell, err := h.projectService.GetCellValue(userUUID, projectUUID, tableName, pkColumn, rowID, columnName)
+	if err != nil {
+		var appErr *apperr.Error
+		switch {
+		case errors.As(err, &appErr):
+			responses.FromError(c, err)
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to fetch cell value")
+		}
+		return
+	}
+
+	// Binary values can be downloaded as-is instead of base64 encoded JSON
+	if cell.Encoding == "base64" && c.Query("format") == "binary" {
+		c.Data(http.StatusOK, "application/octet-stream", cell.Raw)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, cell, "Cell value retrieved successfully")
+}
+
+// ListDatabaseRoles handles GET /api/v1/projects/:id/roles
+func (h *ProjectHandler) ListDatabaseRoles(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	roles, err := h.projectService.ListDatabaseRoles(userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to list database roles")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, roles, "Database roles retrieved successfully")
+}
+
+// GetBlockedQueries handles GET /api/v1/projects/:id/queries/blocked
+func (h *ProjectHandler) GetBlockedQueries(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	blocked, err := h.projectService.GetBlockedQueries(userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve blocked queries")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, blocked, "Blocked queries retrieved successfully")
+}
+
+// CreateDatabaseRole handles POST /api/v1/projects/:id/roles
+func (h *ProjectHandler) CreateDatabaseRole(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid project ID format")
+		return
+	}
+
+	var req services.CreateDatabaseRoleRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	role, err := h.projectService.CreateDatabaseRole(userUUID, projectUUID, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case strings.HasPrefix(err.Error(), "invalid role name"):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case strings.HasSuffix(err.Error(), "already exists"):
+			responses.Fail(c, http.StatusConflict, err, err.Error())
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to create database role")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusCreated, role, "Database role created successfully")
+}
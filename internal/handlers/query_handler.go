@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"backend/internal/pagination"
 	"backend/internal/responses"
 	"backend/internal/services"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -28,15 +31,14 @@ func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 		return
 	}
 
-	userId, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	var req services.ExecuteQueryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body: query is required")
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -44,22 +46,6 @@ func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 		responses.Fail(c, http.StatusBadRequest, nil, "Query is required: Cannot be empty")
 		return
 	}
-	// Convert userID to UUID (handle both uuid.UUID and string types)
-	var userUUID uuid.UUID
-	switch v := userId.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, err, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
-		return
-	}
 	projectUUID, err := uuid.Parse(projectId)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
@@ -67,6 +53,17 @@ func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 	}
 	result, exec, err := h.queryService.ExecuteQuery(userUUID, &req, projectUUID)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidExecutionID) || errors.Is(err, services.ErrExecutionIDInUse) {
+			responses.FromError(c, err)
+			return
+		}
+		if errors.Is(err, services.ErrInstancePaused) {
+			responses.Fail(c, http.StatusConflict, err, err.Error())
+			return
+		}
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to execute query")
 		return
 	}
@@ -79,11 +76,45 @@ func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 	responses.Success(c, http.StatusOK, response, "Query executed successfully")
 }
 
+// CancelQuery handles DELETE /api/v1/projects/:id/query/:execution_id
+func (h *QueryHandler) CancelQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	executionUUID, err := uuid.Parse(c.Param("execution_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid execution ID format")
+		return
+	}
+
+	if err := h.queryService.CancelQuery(userUUID, projectUUID, executionUUID); err != nil {
+		if errors.Is(err, services.ErrQueryNotRunning) {
+			responses.Fail(c, http.StatusNotFound, err, "Query not found or no longer running")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to cancel query")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"execution_id": executionUUID,
+	}, "Query cancelled")
+}
+
 // GetQueryHistory returns query execution history for the authenticated user
 func (h *QueryHandler) GetQueryHistory(c *gin.Context) {
-	userId, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -97,28 +128,379 @@ func (h *QueryHandler) GetQueryHistory(c *gin.Context) {
 		limit = 30 // max
 	}
 
-	// Convert userID to UUID (handle both uuid.UUID and string types)
-	var userUUID uuid.UUID
-	switch v := userId.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+	history, nextCursor, err := h.queryService.GetQueryHistory(userUUID, limit, c.Query("cursor"))
+	if err != nil {
+		if errors.Is(err, pagination.ErrInvalidCursor) {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid pagination cursor")
 			return
 		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusUnauthorized, nil, "Invalid user ID format")
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get query history")
 		return
 	}
+	if nextCursor != "" {
+		c.Header(nextCursorHeader, nextCursor)
+	}
 
-	history, err := h.queryService.GetQueryHistory(userUUID, limit)
+	responses.Success(c, http.StatusOK, history, "Query history retrieved successfully")
+}
+
+// GetQueryHistoryEntry handles GET /api/v1/query/history/:id
+func (h *QueryHandler) GetQueryHistoryEntry(c *gin.Context) {
+	userUUID, err := getUserID(c)
 	if err != nil {
-		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get query history")
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	responses.Success(c, http.StatusOK, history, "Query history retrieved successfully")
+	entryUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid history entry ID format")
+		return
+	}
+
+	entry, err := h.queryService.GetQueryHistoryEntry(userUUID, entryUUID)
+	if err != nil {
+		if err.Error() == "query history entry not found" {
+			responses.Fail(c, http.StatusNotFound, err, "Query history entry not found")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get query history entry")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, entry, "Query history entry retrieved successfully")
+}
+
+// GetProjectQueryHistoryEntry handles GET /api/v1/projects/:id/query/history/:execution_id
+func (h *QueryHandler) GetProjectQueryHistoryEntry(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+	executionUUID, err := uuid.Parse(c.Param("execution_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid execution ID format")
+		return
+	}
+
+	entry, err := h.queryService.GetProjectQueryHistoryEntry(userUUID, projectUUID, executionUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, entry, "Query history entry retrieved successfully")
+}
+
+// RerunQuery handles POST /api/v1/projects/:id/query/history/:execution_id/rerun
+func (h *QueryHandler) RerunQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+	executionUUID, err := uuid.Parse(c.Param("execution_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid execution ID format")
+		return
+	}
+
+	result, exec, err := h.queryService.RerunQuery(userUUID, projectUUID, executionUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"result":            result,
+		"execution_id":      exec.ID,
+		"execution_time_ms": result.ExecutionTime,
+	}, "Query executed successfully")
+}
+
+// CreatePlanSnapshot handles POST /api/v1/projects/:id/query/plans
+func (h *QueryHandler) CreatePlanSnapshot(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.PlanSnapshotRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	snapshot, err := h.queryService.CapturePlanSnapshot(userUUID, projectUUID, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		responses.Fail(c, planErrorStatus(err), err, "Failed to capture query plan")
+		return
+	}
+
+	responses.Success(c, http.StatusCreated, snapshot, "Query plan snapshot saved successfully")
+}
+
+// ListPlanSnapshots handles GET /api/v1/projects/:id/query/plans
+func (h *QueryHandler) ListPlanSnapshots(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	snapshots, err := h.queryService.ListPlanSnapshots(userUUID, projectUUID)
+	if err != nil {
+		responses.Fail(c, planErrorStatus(err), err, "Failed to get query plan snapshots")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, snapshots, "Query plan snapshots retrieved successfully")
+}
+
+// ComparePlans handles POST /api/v1/projects/:id/query/plans/compare
+func (h *QueryHandler) ComparePlans(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.ComparePlansRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	diff, err := h.queryService.ComparePlans(userUUID, projectUUID, req)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		responses.Fail(c, planErrorStatus(err), err, "Failed to compare query plans")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, diff, "Query plans compared successfully")
+}
+
+// ExplainQuery handles POST /api/v1/projects/:id/query/explain
+func (h *QueryHandler) ExplainQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.ExplainQueryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	plan, err := h.queryService.ExplainQuery(userUUID, projectUUID, req.Query, req.Analyze)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		responses.Fail(c, planErrorStatus(err), err, "Failed to explain query")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{"plan": plan}, "Query explained successfully")
+}
+
+// SaveQuery handles POST /api/v1/projects/:id/saved-queries
+func (h *QueryHandler) SaveQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.SaveQueryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	savedQuery, err := h.queryService.SaveQuery(userUUID, projectUUID, req)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusCreated, savedQuery, "Query saved successfully")
+}
+
+// ListSavedQueries handles GET /api/v1/projects/:id/saved-queries
+func (h *QueryHandler) ListSavedQueries(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	savedQueries, err := h.queryService.ListSavedQueries(userUUID, projectUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, savedQueries, "Saved queries retrieved successfully")
+}
+
+// RenameSavedQuery handles PATCH /api/v1/projects/:id/saved-queries/:query_id
+func (h *QueryHandler) RenameSavedQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+	queryUUID, err := uuid.Parse(c.Param("query_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid saved query ID format")
+		return
+	}
+
+	var req services.RenameSavedQueryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	savedQuery, err := h.queryService.RenameSavedQuery(userUUID, projectUUID, queryUUID, req)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, savedQuery, "Saved query renamed successfully")
+}
+
+// DeleteSavedQuery handles DELETE /api/v1/projects/:id/saved-queries/:query_id
+func (h *QueryHandler) DeleteSavedQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+	queryUUID, err := uuid.Parse(c.Param("query_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid saved query ID format")
+		return
+	}
+
+	if err := h.queryService.DeleteSavedQuery(userUUID, projectUUID, queryUUID); err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, nil, "Saved query deleted successfully")
+}
+
+// ExecuteSavedQuery handles POST /api/v1/projects/:id/saved-queries/:query_id/execute
+func (h *QueryHandler) ExecuteSavedQuery(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+	queryUUID, err := uuid.Parse(c.Param("query_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid saved query ID format")
+		return
+	}
+
+	result, exec, err := h.queryService.ExecuteSavedQuery(userUUID, projectUUID, queryUUID)
+	if err != nil {
+		responses.FromError(c, err)
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"result":            result,
+		"execution_id":      exec.ID,
+		"execution_time_ms": result.ExecutionTime,
+	}, "Query executed successfully")
+}
+
+// planErrorStatus maps query plan errors to HTTP status codes
+func planErrorStatus(err error) int {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, services.ErrInstancePaused):
+		return http.StatusConflict
+	case msg == "project not found or not accessible", strings.HasSuffix(msg, "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(msg, "failed to save"), strings.HasPrefix(msg, "failed to get"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
 }
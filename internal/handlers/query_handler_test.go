@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"backend/internal/middlewares"
+	"backend/internal/repositories"
+	"backend/internal/services"
+	"backend/internal/testdb"
+	"backend/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newQueryRouter serves the query routes behind Authenticate, like the server does
+func newQueryRouter(t *testing.T, queryService *services.QueryService) *gin.Engine {
+	t.Helper()
+
+	secret := utils.AccessTokenSecret
+	utils.AccessTokenSecret = []byte("query-handler-test-secret")
+	t.Cleanup(func() { utils.AccessTokenSecret = secret })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewQueryHandler(queryService)
+	group := router.Group("/projects/:id/query", middlewares.Authenticate)
+	group.POST("/execute", handler.ExecuteQuery)
+	group.GET("/history", handler.GetQueryHistory)
+	return router
+}
+
+func accessToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+
+	token, err := utils.GenerateJWT(userID, time.Minute, utils.AccessTokenSecret)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	return token
+}
+
+func serve(router *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// Both handlers read the user ID Authenticate stores. Each request below fails on its own
+// input, which the handlers only look at once they have the user ID.
+func TestQueryHandlersReadUserIDFromAuthenticate(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "query-handler-test-cursor-secret")
+	router := newQueryRouter(t, services.NewQueryService(nil, nil, nil, nil, nil, nil, nil, 0, services.NewConnectionManager()))
+	token := accessToken(t, uuid.New())
+
+	w := serve(router, http.MethodPost, "/projects/not-a-uuid/query/execute", token, `{"query":"SELECT 1"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid projectId format") {
+		t.Errorf("ExecuteQuery = %d %s, want 400 for the project ID", w.Code, w.Body.String())
+	}
+
+	w = serve(router, http.MethodGet, "/projects/"+uuid.NewString()+"/query/history?cursor=garbage", token, "")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid pagination cursor") {
+		t.Errorf("GetQueryHistory = %d %s, want 400 for the cursor", w.Code, w.Body.String())
+	}
+}
+
+func TestQueryHandlersRequireToken(t *testing.T) {
+	router := newQueryRouter(t, services.NewQueryService(nil, nil, nil, nil, nil, nil, nil, 0, services.NewConnectionManager()))
+
+	for _, path := range []string{"/projects/" + uuid.NewString() + "/query/execute", "/projects/" + uuid.NewString() + "/query/history"} {
+		method := http.MethodGet
+		if strings.HasSuffix(path, "execute") {
+			method = http.MethodPost
+		}
+		if w := serve(router, method, path, "", `{"query":"SELECT 1"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token = %d, want 401", method, path, w.Code)
+		}
+		if w := serve(router, method, path, "not-a-token", `{"query":"SELECT 1"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s with an invalid token = %d, want 401", method, path, w.Code)
+		}
+	}
+}
+
+func TestGetQueryHistoryThroughAuthenticate(t *testing.T) {
+	pool := testdb.Pool(t)
+	queryService := services.NewQueryService(
+		repositories.NewProjectRepository(pool),
+		repositories.NewDatabaseInstanceRepository(pool),
+		repositories.NewDatabaseCredentialRepository(pool),
+		repositories.NewQueryHistoryRepository(pool),
+		repositories.NewQueryPlanSnapshotRepository(pool),
+		repositories.NewSavedQueryRepository(pool),
+		nil, 0, services.NewConnectionManager(),
+	)
+	router := newQueryRouter(t, queryService)
+
+	w := serve(router, http.MethodGet, "/projects/"+uuid.NewString()+"/query/history", accessToken(t, uuid.New()), "")
+	if w.Code != http.StatusOK {
+		t.Errorf("GetQueryHistory = %d %s, want 200", w.Code, w.Body.String())
+	}
+}
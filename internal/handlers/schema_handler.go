@@ -3,7 +3,9 @@ package handlers
 import (
 	"backend/internal/responses"
 	"backend/internal/services"
+	"errors"
 	"fmt"
+	"strings"
 
 	"net/http"
 
@@ -23,32 +25,16 @@ func NewSchemaHandler(schemaService *services.SchemaService) *SchemaHandler {
 
 // VisualizeSchema handles GET /api/v1/projects/:id/schema/visualize
 func (h *SchemaHandler) VisualizeSchema(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	projectID := c.Param("id")
 	schema := c.DefaultQuery("schema", "public") // Default to "public" schema
-
-	// Convert userID to uuid.UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, err, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID type")
-		return
-	}
+	withStats := c.Query("withStats") == "true"  // Row counts cost an extra query per table
+	format := c.DefaultQuery("format", "mermaid")
 
 	// Parse project ID
 	projectUUID, err := uuid.Parse(projectID)
@@ -57,16 +43,391 @@ func (h *SchemaHandler) VisualizeSchema(c *gin.Context) {
 		return
 	}
 
+	if format != "mermaid" && format != "json" {
+		responses.Fail(c, http.StatusBadRequest, fmt.Errorf("unsupported visualization format %q", format), "Unsupported visualization format, expected mermaid or json")
+		return
+	}
+
 	// Generate visualization
-	mermaidDiagram, err := h.schemaService.VisualizeSchema(userUUID, projectUUID, schema)
+	visualization, err := h.schemaService.VisualizeSchema(userUUID, projectUUID, schema, withStats)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
 		fmt.Printf("ERROR in VisualizeSchema handler: %v\n", err)
 		responses.Fail(c, http.StatusInternalServerError, err, fmt.Sprintf("Failed to visualize schema: %v", err))
 		return
 	}
 
+	if format == "json" {
+		responses.Success(c, http.StatusOK, visualization, "Schema visualization generated successfully")
+		return
+	}
+
 	responses.Success(c, http.StatusOK, gin.H{
-		"mermaid": mermaidDiagram,
+		"mermaid": services.RenderMermaid(visualization),
 		"schema":  schema,
 	}, "Schema visualization generated successfully")
 }
+
+// GetTableDependents handles GET /api/v1/projects/:id/tables/:table/dependents
+func (h *SchemaHandler) GetTableDependents(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectID := c.Param("id")
+	tableName := c.Param("table")
+	schema := c.DefaultQuery("schema", "public") // Default to "public" schema
+
+	// Parse project ID
+	projectUUID, err := uuid.Parse(projectID)
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+
+	dependents, err := h.schemaService.GetTableDependents(userUUID, projectUUID, schema, tableName)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get table dependents")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, dependents, "Table dependents retrieved successfully")
+}
+
+// ListIndexes handles GET /api/v1/projects/:id/tables/:table/indexes
+func (h *SchemaHandler) ListIndexes(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public")
+
+	indexes, err := h.schemaService.ListIndexes(userUUID, projectUUID, schema, c.Param("table"))
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		if err.Error() == "table not found" {
+			responses.Fail(c, http.StatusNotFound, err, "Table not found")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to list indexes")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{"indexes": indexes}, "Indexes retrieved successfully")
+}
+
+// GetTableDDL handles GET /api/v1/projects/:id/tables/:table/ddl
+func (h *SchemaHandler) GetTableDDL(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public")
+	table := c.Param("table")
+
+	ddl, err := h.schemaService.GetTableDDL(userUUID, projectUUID, schema, table)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		case err.Error() == "table not found":
+			responses.Fail(c, http.StatusNotFound, err, "Table not found")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to generate table DDL")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"schema": schema,
+		"table":  table,
+		"ddl":    ddl,
+	}, "Table DDL generated successfully")
+}
+
+// ExportSchema handles GET /api/v1/projects/:id/schema/export
+func (h *SchemaHandler) ExportSchema(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public")
+
+	if format := c.DefaultQuery("format", "sql"); format != "sql" {
+		responses.Fail(c, http.StatusBadRequest, fmt.Errorf("unsupported export format %q", format), "Unsupported export format, expected sql")
+		return
+	}
+
+	script, err := h.schemaService.ExportSchemaSQL(userUUID, projectUUID, schema)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to export schema")
+		}
+		return
+	}
+
+	// Serve the script as a file download
+	filename := fmt.Sprintf("%s-%s.sql", projectUUID, schema)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/sql", []byte(script))
+}
+
+// DiffSchema handles GET /api/v1/projects/:id/schema/diff. The diff lists the changes from
+// the project given by "against" to this project.
+func (h *SchemaHandler) DiffSchema(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	againstUUID, err := uuid.Parse(c.Query("against"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid or missing against project ID")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public")
+
+	diff, err := h.schemaService.DiffSchemas(userUUID, againstUUID, projectUUID, schema)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to diff schemas")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, diff, "Schema diff generated successfully")
+}
+
+// RenameSchema handles PATCH /api/v1/projects/:id/schemas/:schema
+func (h *SchemaHandler) RenameSchema(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.Param("schema")
+
+	var req services.RenameSchemaRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err = h.schemaService.RenameSchema(userUUID, projectUUID, schema, req.Name)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "project not found or not accessible", strings.HasSuffix(err.Error(), "not found"):
+			responses.Fail(c, http.StatusNotFound, err, err.Error())
+		case strings.HasSuffix(err.Error(), "already exists"):
+			responses.Fail(c, http.StatusConflict, err, err.Error())
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to rename schema")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"schema": req.Name,
+	}, "Schema renamed successfully")
+}
+
+// ApplyDDL handles POST /api/v1/projects/:id/schema/apply
+func (h *SchemaHandler) ApplyDDL(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+
+	var req services.ApplyDDLRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.schemaService.ApplyDDL(userUUID, projectUUID, req.Script)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		var stmtErr *services.DDLStatementError
+		switch {
+		case errors.As(err, &stmtErr):
+			responses.JSON(c, http.StatusBadRequest, "error", gin.H{
+				"statement_index": stmtErr.Index,
+				"statement":       stmtErr.Statement,
+				"error":           stmtErr.Err.Error(),
+			}, fmt.Sprintf("Statement %d failed, no changes were applied", stmtErr.Index), nil)
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to apply schema")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, result, "Schema applied successfully")
+}
+
+// GetTableStats handles GET /api/v1/projects/:id/schema/stats
+func (h *SchemaHandler) GetTableStats(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public") // Default to "public" schema
+
+	stats, err := h.schemaService.GetTableStats(userUUID, projectUUID, schema)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to get table statistics")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"schema": schema,
+		"tables": stats,
+	}, "Table statistics retrieved successfully")
+}
+
+// IntrospectSchema handles GET /api/v1/projects/:id/schema/introspect
+func (h *SchemaHandler) IntrospectSchema(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid project ID format")
+		return
+	}
+	schema := c.DefaultQuery("schema", "public") // Default to "public" schema
+
+	introspection, err := h.schemaService.IntrospectSchema(userUUID, projectUUID, schema)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found or access denied")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to introspect schema")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, introspection, "Schema introspected successfully")
+}
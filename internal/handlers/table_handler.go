@@ -3,10 +3,14 @@ package handlers
 import (
 	"backend/internal/responses"
 	"backend/internal/services"
+	"errors"
 	"fmt"
+	"io"
 	_ "log"
 
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -29,32 +33,43 @@ func (h *TableHandler) CreateTable(c *gin.Context) {
 		return
 	}
 
-	userId, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
 	var req services.CreateTableRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		responses.Fail(c, http.StatusBadRequest, err, "Invalid request body")
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	userUUID, err := h.toUUID(userId)
+	projectUUID, err := uuid.Parse(projectId)
 	if err != nil {
-		responses.Fail(c, http.StatusUnauthorized, err, "Invalid user ID format")
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
 		return
 	}
 
-	projectUUID, err := uuid.Parse(projectId)
-	if err != nil {
-		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+	// A dry run only returns the generated SQL and never touches the database
+	if c.Query("dryRun") == "true" {
+		query, err := h.tableService.PreviewCreateTable(&req)
+		if err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		responses.Success(c, http.StatusOK, gin.H{"sql": query}, "Generated SQL (not executed)")
 		return
 	}
 
 	result, err := h.tableService.CreateTable(&req, userUUID, projectUUID)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
 		responses.Fail(c, http.StatusBadRequest, err, "Error while creating the table")
 		return
 	}
@@ -66,6 +81,86 @@ func (h *TableHandler) CreateTable(c *gin.Context) {
 	responses.Success(c, http.StatusOK, response, "Table created successfully")
 }
 
+// ListTables handles GET /api/v1/projects/:id/tables
+func (h *TableHandler) ListTables(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	tables, err := h.tableService.ListTables(userUUID, projectUUID, c.DefaultQuery("schema", "public"))
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to list tables")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{"tables": tables}, "Tables retrieved successfully")
+}
+
+// GetTableRows handles GET /api/v1/projects/:id/tables/:table/rows
+func (h *TableHandler) GetTableRows(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid limit")
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid offset")
+		return
+	}
+
+	result, err := h.tableService.GetTableRows(userUUID, projectUUID, c.DefaultQuery("schema", "public"), c.Param("table"), limit, offset, c.Query("order_by"))
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found")
+		case err.Error() == "table not found":
+			responses.Fail(c, http.StatusNotFound, err, "Table not found")
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to read table rows")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, result, "Rows retrieved successfully")
+}
+
 func (h *TableHandler) DeleteTable(c *gin.Context) {
 	projectId := c.Param("id")
 	if projectId == "" {
@@ -73,9 +168,9 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 		return
 	}
 
-	userId, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -85,12 +180,6 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 		return
 	}
 
-	userUUID, err := h.toUUID(userId)
-	if err != nil {
-		responses.Fail(c, http.StatusUnauthorized, err, "Invalid user Id format")
-		return
-	}
-
 	projectUUID, err := uuid.Parse(projectId)
 	if err != nil {
 		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
@@ -99,6 +188,16 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 
 	result, err := h.tableService.DeleteTable(&req, userUUID, projectUUID)
 	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		var confirmErr *services.RowCountConfirmationError
+		if errors.As(err, &confirmErr) {
+			responses.JSON(c, http.StatusConflict, "error", gin.H{
+				"row_count": confirmErr.RowCount,
+			}, fmt.Sprintf("This will delete %d rows; resend with confirm_row_count set to %d to proceed", confirmErr.RowCount, confirmErr.RowCount), nil)
+			return
+		}
 		responses.Fail(c, http.StatusBadRequest, err, "Cannot delete the given table")
 		return
 	}
@@ -110,6 +209,345 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 	responses.Success(c, http.StatusOK, response, "Table deleted successfully")
 }
 
+// CreateIndex handles POST /api/v1/projects/:id/indexes and
+// POST /api/v1/projects/:id/tables/:table/indexes
+func (h *TableHandler) CreateIndex(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.CreateIndexRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if table := c.Param("table"); table != "" {
+		req.Table = table
+	}
+
+	result, err := h.tableService.CreateIndex(&req, userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		if err.Error() == "project not found or not accessible" {
+			responses.Fail(c, http.StatusNotFound, err, "Project not found")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "validation failed") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "already exists") {
+			responses.Fail(c, http.StatusConflict, err, err.Error())
+			return
+		}
+		responses.Fail(c, http.StatusBadRequest, err, "Error while creating the index")
+		return
+	}
+
+	response := gin.H{
+		"result": result,
+	}
+
+	responses.Success(c, http.StatusCreated, response, "Index created successfully")
+}
+
+// DropIndex handles DELETE /api/v1/projects/:id/tables/:table/indexes/:index
+func (h *TableHandler) DropIndex(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	req := services.DropIndexRequest{
+		Schema: c.DefaultQuery("schema", "public"),
+		Table:  c.Param("table"),
+		Name:   c.Param("index"),
+	}
+
+	result, err := h.tableService.DropIndex(&req, userUUID, projectUUID)
+	if err != nil {
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found")
+		case err.Error() == "index not found":
+			responses.Fail(c, http.StatusNotFound, err, "Index not found")
+		case strings.HasPrefix(err.Error(), "invalid "):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		case strings.HasSuffix(err.Error(), "cannot be dropped on its own"):
+			responses.Fail(c, http.StatusConflict, err, err.Error())
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to drop index")
+		}
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"result": result,
+	}, "Index dropped successfully")
+}
+
+// RenameTable handles PATCH /api/v1/projects/:id/tables/:table
+func (h *TableHandler) RenameTable(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.RenameTableRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.Table = c.Param("table")
+
+	result, err := h.tableService.RenameTable(&req, userUUID, projectUUID)
+	if err != nil {
+		respondRenameError(c, err, "Failed to rename table")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"result": result,
+		"table":  req.NewName,
+	}, "Table renamed successfully")
+}
+
+// RenameColumn handles PATCH /api/v1/projects/:id/tables/:table/columns/:column
+func (h *TableHandler) RenameColumn(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.RenameColumnRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	req.Table = c.Param("table")
+	req.Column = c.Param("column")
+
+	result, err := h.tableService.RenameColumn(&req, userUUID, projectUUID)
+	if err != nil {
+		respondRenameError(c, err, "Failed to rename column")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, gin.H{
+		"result": result,
+		"column": req.NewName,
+	}, "Column renamed successfully")
+}
+
+// respondRenameError maps the errors shared by the rename endpoints to a response
+func respondRenameError(c *gin.Context, err error, message string) {
+	if respondUnsupportedDBType(c, err) {
+		return
+	}
+	switch {
+	case err.Error() == "project not found or not accessible":
+		responses.Fail(c, http.StatusNotFound, err, "Project not found")
+	case err.Error() == "table not found":
+		responses.Fail(c, http.StatusNotFound, err, "Table not found")
+	case err.Error() == "column not found":
+		responses.Fail(c, http.StatusNotFound, err, "Column not found")
+	case strings.HasPrefix(err.Error(), "invalid "):
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
+	case strings.HasSuffix(err.Error(), "already exists"):
+		responses.Fail(c, http.StatusConflict, err, err.Error())
+	default:
+		responses.Fail(c, http.StatusInternalServerError, err, message)
+	}
+}
+
+// ImportCSV handles POST /api/v1/projects/:id/tables/:table/import. The CSV file is the
+// request body or the "file" field of a multipart upload; ?header=false imports a file
+// without a header row. Progress is streamed back as server-sent events; a client
+// disconnecting cancels the import and rolls it back.
+func (h *TableHandler) ImportCSV(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	// The CSV is either the raw body or the "file" field of a multipart form
+	body := io.Reader(c.Request.Body)
+	if c.ContentType() == "multipart/form-data" {
+		body, err = multipartFile(c, "file")
+		if err != nil {
+			responses.Fail(c, http.StatusBadRequest, err, "Invalid upload")
+			return
+		}
+	}
+	hasHeader := c.DefaultQuery("header", "true") != "false"
+
+	// Until the first event is sent, failures still get a regular JSON error response
+	streaming := false
+	sendEvent := func(name string, data interface{}) {
+		if !streaming {
+			c.Header("Cache-Control", "no-cache")
+			c.Header("X-Accel-Buffering", "no")
+			streaming = true
+		}
+		c.SSEvent(name, data)
+		c.Writer.Flush()
+	}
+
+	result, err := h.tableService.ImportCSV(c.Request.Context(), userUUID, projectUUID,
+		// The content length of a multipart upload slightly overstates the file size
+		c.DefaultQuery("schema", "public"), c.Param("table"), body, hasHeader, c.Request.ContentLength,
+		func(progress services.ImportProgress) {
+			sendEvent("progress", progress)
+		})
+	if err != nil {
+		if streaming {
+			sendEvent("error", gin.H{"message": err.Error()})
+			return
+		}
+		if respondUnsupportedDBType(c, err) {
+			return
+		}
+		switch {
+		case err.Error() == "project not found or not accessible":
+			responses.Fail(c, http.StatusNotFound, err, "Project not found")
+		case err.Error() == "table not found":
+			responses.Fail(c, http.StatusNotFound, err, "Table not found")
+		case strings.HasPrefix(err.Error(), "invalid "), strings.HasPrefix(err.Error(), "failed to import"):
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+		default:
+			responses.Fail(c, http.StatusInternalServerError, err, "Failed to import CSV")
+		}
+		return
+	}
+
+	sendEvent("done", result)
+}
+
+// ValidateForeignKey handles POST /api/v1/projects/:id/foreign-keys/validate
+func (h *TableHandler) ValidateForeignKey(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.AddForeignKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	validation, err := h.tableService.ValidateForeignKey(userUUID, projectUUID, &req)
+	if err != nil {
+		respondForeignKeyError(c, err, "Failed to validate the foreign key")
+		return
+	}
+
+	message := "Existing rows satisfy the foreign key"
+	if !validation.Valid {
+		message = "Existing rows violate the foreign key"
+	}
+	responses.Success(c, http.StatusOK, validation, message)
+}
+
+// AddForeignKey handles POST /api/v1/projects/:id/foreign-keys
+func (h *TableHandler) AddForeignKey(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	projectUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
+		return
+	}
+
+	var req services.AddForeignKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.tableService.AddForeignKey(userUUID, projectUUID, &req)
+	if err != nil {
+		var violationErr *services.ForeignKeyViolationError
+		if errors.As(err, &violationErr) {
+			responses.JSON(c, http.StatusConflict, "error", violationErr.Validation,
+				"Existing rows violate the foreign key; fix or remove them and try again", nil)
+			return
+		}
+		respondForeignKeyError(c, err, "Error while adding the foreign key")
+		return
+	}
+
+	response := gin.H{
+		"result": result,
+	}
+
+	responses.Success(c, http.StatusCreated, response, "Foreign key added successfully")
+}
+
+// respondForeignKeyError maps the errors shared by the foreign key endpoints to a response
+func respondForeignKeyError(c *gin.Context, err error, message string) {
+	if respondUnsupportedDBType(c, err) {
+		return
+	}
+	switch {
+	case err.Error() == "project not found or not accessible":
+		responses.Fail(c, http.StatusNotFound, err, "Project not found")
+	case err.Error() == "table not found":
+		responses.Fail(c, http.StatusNotFound, err, "Table not found")
+	case strings.HasPrefix(err.Error(), "validation failed"):
+		responses.Fail(c, http.StatusBadRequest, err, err.Error())
+	default:
+		responses.Fail(c, http.StatusInternalServerError, err, message)
+	}
+}
+
 // func (h *TableHandler) UpdateTable(c *gin.Context) {
 // 	projectId := c.Param("id")
 // 	if projectId == "" {
@@ -117,9 +555,9 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 // 		return
 // 	}
 
-// 	userId, exists := c.Get("userId")
-// 	if !exists {
-// 		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
+// 	userUUID, err := getUserID(c)
+// 	if err != nil {
+// 		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 // 		return
 // 	}
 
@@ -129,12 +567,6 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 // 		return
 // 	}
 
-// 	userUUID, err := h.toUUID(userId)
-// 	if err != nil {
-// 		responses.Fail(c, http.StatusUnauthorized, err, "Invalid user Id format")
-// 		return
-// 	}
-
 // 	projectUUID, err := uuid.Parse(projectId)
 // 	if err != nil {
 // 		responses.Fail(c, http.StatusBadRequest, err, "Invalid projectId format")
@@ -153,18 +585,3 @@ func (h *TableHandler) DeleteTable(c *gin.Context) {
 
 // 	responses.Success(c, http.StatusOK, response, "Table updated successfully")
 // }
-
-func (h *TableHandler) toUUID(userId any) (uuid.UUID, error) {
-	switch v := userId.(type) {
-	case uuid.UUID:
-		return v, nil
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			return uuid.Nil, err
-		}
-		return parsed, nil
-	default:
-		return uuid.Nil, fmt.Errorf("invalid user Id type: %T", v)
-	}
-}
@@ -3,43 +3,29 @@ package handlers
 import (
 	"backend/internal/responses"
 	"backend/internal/services"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	userService *services.UserService
+	userService  *services.UserService
+	quotaService *services.QuotaService
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService *services.UserService, quotaService *services.QuotaService) *UserHandler {
+	return &UserHandler{userService: userService, quotaService: quotaService}
 }
 
 // GetMe handles GET /api/v1/users/me
 func (h *UserHandler) GetMe(c *gin.Context) {
-	// Get authenticated user ID from context (set by Authenticate middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-		return
-	}
-
-	// Convert to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -81,27 +67,9 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 // UpdateMe handles PATCH /api/v1/users/me
 func (h *UserHandler) UpdateMe(c *gin.Context) {
-	// Get authenticated user ID from context (set by Authenticate middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-		return
-	}
-
-	// Convert to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -132,27 +100,9 @@ func (h *UserHandler) UpdateMe(c *gin.Context) {
 
 // UpdateUser handles PATCH /api/v1/users/:user_id (admin only)
 func (h *UserHandler) UpdateUser(c *gin.Context) {
-	// Get authenticated user ID from context (set by Authenticate middleware)
-	authenticatedUserID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-		return
-	}
-
-	// Convert authenticated user ID to UUID
-	var authenticatedUUID uuid.UUID
-	switch v := authenticatedUserID.(type) {
-	case uuid.UUID:
-		authenticatedUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
-			return
-		}
-		authenticatedUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+	authenticatedUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -191,31 +141,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 // DeleteMe handles DELETE /api/v1/users/me
 func (h *UserHandler) DeleteMe(c *gin.Context) {
-	// Get authenticated user ID from context (set by Authenticate middleware)
-	userID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-		return
-	}
-
-	// Convert to UUID
-	var userUUID uuid.UUID
-	switch v := userID.(type) {
-	case uuid.UUID:
-		userUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
-			return
-		}
-		userUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
-	err := h.userService.DeleteUser(userUUID, userUUID)
+	err = h.userService.DeleteUser(userUUID, userUUID)
 	if err != nil {
 		if err.Error() == "user not found" {
 			responses.Fail(c, http.StatusNotFound, err, "User not found")
@@ -242,27 +174,9 @@ func (h *UserHandler) DeleteMe(c *gin.Context) {
 
 // DeleteUser handles DELETE /api/v1/users/:user_id (admin only)
 func (h *UserHandler) DeleteUser(c *gin.Context) {
-	// Get authenticated user ID from context (set by Authenticate middleware)
-	authenticatedUserID, exists := c.Get("userId")
-	if !exists {
-		responses.Fail(c, http.StatusUnauthorized, nil, "Unauthorized")
-		return
-	}
-
-	// Convert authenticated user ID to UUID
-	var authenticatedUUID uuid.UUID
-	switch v := authenticatedUserID.(type) {
-	case uuid.UUID:
-		authenticatedUUID = v
-	case string:
-		parsed, err := uuid.Parse(v)
-		if err != nil {
-			responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
-			return
-		}
-		authenticatedUUID = parsed
-	default:
-		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+	authenticatedUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
 		return
 	}
 
@@ -295,11 +209,127 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 // ListUsers handles GET /api/v1/users
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	users, total, err := h.userService.GetAllUsers(limit, offset, strings.TrimSpace(c.Query("search")))
 	if err != nil {
 		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve users")
 		return
 	}
+	c.Header(totalCountHeader, strconv.Itoa(total))
 
 	responses.Success(c, http.StatusOK, users, "Users retrieved successfully")
 }
+
+// ExportMe handles GET /api/v1/users/me/export
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	export, err := h.userService.ExportUserData(userUUID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			responses.Fail(c, http.StatusNotFound, err, "User not found")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to export user data")
+		return
+	}
+
+	// Serve the archive as a file download
+	filename := fmt.Sprintf("user-data-%s.json", userUUID.String())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// GetMyUsage handles GET /api/v1/users/me/usage
+func (h *UserHandler) GetMyUsage(c *gin.Context) {
+	userUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(userUUID)
+	if err != nil {
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve usage")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, usage, "Usage retrieved successfully")
+}
+
+// GetUserQuota handles GET /api/v1/users/:user_id/quota (admin only)
+func (h *UserHandler) GetUserQuota(c *gin.Context) {
+	userUUID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+		return
+	}
+
+	if _, err := h.userService.GetUser(userUUID); err != nil {
+		if err.Error() == "user not found" {
+			responses.Fail(c, http.StatusNotFound, err, "User not found")
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve user")
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(userUUID)
+	if err != nil {
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to retrieve quota")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, usage, "Quota retrieved successfully")
+}
+
+// SetUserQuota handles PUT /api/v1/users/:user_id/quota (admin only)
+func (h *UserHandler) SetUserQuota(c *gin.Context) {
+	authenticatedUUID, err := getUserID(c)
+	if err != nil {
+		responses.Fail(c, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	userUUID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		responses.Fail(c, http.StatusBadRequest, nil, "Invalid user ID format")
+		return
+	}
+
+	var req services.SetUserQuotaRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	quota, err := h.quotaService.SetUserQuota(userUUID, authenticatedUUID, req)
+	if err != nil {
+		if err.Error() == "user not found" {
+			responses.Fail(c, http.StatusNotFound, err, "User not found")
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid ") {
+			responses.Fail(c, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		responses.Fail(c, http.StatusInternalServerError, err, "Failed to update quota")
+		return
+	}
+
+	responses.Success(c, http.StatusOK, quota, "Quota updated successfully")
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is a periodic background task that must only run on one replica at a time
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Start runs each job on its own ticker until ctx is cancelled. On every tick the
+// job only runs if this replica is the leader.
+func Start(ctx context.Context, elector *LeaderElector, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, elector, job)
+	}
+
+	go func() {
+		<-ctx.Done()
+		elector.Release()
+	}()
+}
+
+func run(ctx context.Context, elector *LeaderElector, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !elector.IsLeader(ctx) {
+				continue
+			}
+			if err := job.Run(ctx); err != nil {
+				log.Printf("Background job %s failed: %v", job.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// leaderLockKey is the Postgres advisory lock key guarding background jobs
+const leaderLockKey int64 = 0x4b696c6c7561 // "Killua"
+
+// LeaderElector decides which replica runs the background jobs. The leader holds a
+// session-level advisory lock on a dedicated connection of the control database;
+// if that replica dies its connection closes, the lock is released and another
+// replica takes over on its next attempt.
+type LeaderElector struct {
+	pool    *pgxpool.Pool
+	enabled bool
+
+	mu   sync.Mutex
+	conn *pgxpool.Conn // held while this replica is the leader
+}
+
+// NewLeaderElector creates an elector. When disabled every caller is treated as the leader.
+func NewLeaderElector(pool *pgxpool.Pool, enabled bool) *LeaderElector {
+	return &LeaderElector{pool: pool, enabled: enabled}
+}
+
+// IsLeader reports whether this replica currently holds the leader lock, trying
+// to acquire it if not. A held lock is renewed by checking its connection is alive.
+func (e *LeaderElector) IsLeader(ctx context.Context) bool {
+	if !e.enabled {
+		return true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn != nil {
+		err := e.conn.Ping(ctx)
+		if err == nil {
+			return true
+		}
+		// The lock died with the connection; drop it and compete again
+		log.Printf("Lost leader lock: %v", err)
+		e.conn.Conn().Close(context.Background())
+		e.conn.Release()
+		e.conn = nil
+	}
+
+	conn, err := e.pool.Acquire(ctx)
+	if err != nil {
+		log.Printf("Failed to acquire connection for leader election: %v", err)
+		return false
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, leaderLockKey).Scan(&acquired); err != nil {
+		log.Printf("Failed to try leader lock: %v", err)
+		conn.Release()
+		return false
+	}
+	if !acquired {
+		conn.Release()
+		return false
+	}
+
+	log.Println("Acquired leader lock, running background jobs on this instance")
+	e.conn = conn
+	return true
+}
+
+// Release gives up leadership so another replica can take over immediately
+func (e *LeaderElector) Release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return
+	}
+	if _, err := e.conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, leaderLockKey); err != nil {
+		log.Printf("Failed to release leader lock: %v", err)
+	}
+	e.conn.Release()
+	e.conn = nil
+}
@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"backend/internal/testdb"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newReplicaPool connects to the database the way one backend replica does
+func newReplicaPool(t *testing.T, url string) *pgxpool.Pool {
+	t.Helper()
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestDisabledElectorIsAlwaysLeader(t *testing.T) {
+	elector := NewLeaderElector(nil, false)
+	if !elector.IsLeader(context.Background()) {
+		t.Error("disabled elector is not the leader")
+	}
+	elector.Release()
+}
+
+func TestOnlyOneReplicaRunsJobs(t *testing.T) {
+	server, name := testdb.Create(t)
+	first := NewLeaderElector(newReplicaPool(t, server.URL(name)), true)
+	second := NewLeaderElector(newReplicaPool(t, server.URL(name)), true)
+	t.Cleanup(first.Release)
+	t.Cleanup(second.Release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !first.IsLeader(ctx) {
+		t.Fatal("first replica did not become the leader")
+	}
+	if second.IsLeader(ctx) {
+		t.Fatal("second replica became the leader while the first holds the lock")
+	}
+
+	var firstRuns, secondRuns atomic.Int32
+	job := func(runs *atomic.Int32) Job {
+		return Job{Name: "count", Interval: 10 * time.Millisecond, Run: func(context.Context) error {
+			runs.Add(1)
+			return nil
+		}}
+	}
+	jobCtx, stopJobs := context.WithCancel(ctx)
+	Start(jobCtx, first, job(&firstRuns))
+	Start(jobCtx, second, job(&secondRuns))
+
+	waitFor(t, func() bool { return firstRuns.Load() >= 3 })
+	if n := secondRuns.Load(); n != 0 {
+		t.Errorf("second replica ran the job %d times while not the leader", n)
+	}
+
+	// The leader stops, so the other replica takes over
+	stopJobs()
+	waitFor(t, func() bool { return second.IsLeader(ctx) })
+	if first.IsLeader(ctx) {
+		t.Error("both replicas are the leader")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,13 +1,34 @@
 package middlewares
 
 import (
+	"backend/internal/repositories"
 	"backend/internal/utils"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// UserIDKey is the context key under which Authenticate stores the user's uuid.UUID
+const UserIDKey = "userId"
+
+// AuthMethodKey is the context key under which the authentication method of a request is stored
+const AuthMethodKey = "authMethod"
+
+// APIKeyHeader carries an API key as an alternative to the Authorization header
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyTouchInterval is how stale an API key's last_used_at may get before it is updated
+const apiKeyTouchInterval = time.Minute
+
+// Authentication methods stored under AuthMethodKey
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
 func Authenticate(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
@@ -22,8 +43,73 @@ func Authenticate(c *gin.Context) {
 		return
 	}
 
-	tokenStr := parts[1]
+	authenticateJWT(c, parts[1])
+}
+
+// AuthenticateJWTOrAPIKey accepts an access token as "Bearer <token>", or an API key as
+// "Bearer kdb_...", "ApiKey <key>" or in the X-API-Key header, so browser and
+// programmatic clients can share routes
+func AuthenticateJWTOrAPIKey(apiKeyRepo *repositories.APIKeyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			authenticateAPIKey(c, apiKeyRepo, key)
+			return
+		}
+
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Missing Authorization header"})
+			return
+		}
+
+		// Expected format: "Bearer <token>", "Bearer <API key>" or "ApiKey <key>"
+		parts := strings.Split(authHeader, " ")
+		if len(parts) != 2 || parts[1] == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid Authorization format"})
+			return
+		}
+
+		switch {
+		case parts[0] == "Bearer" && strings.HasPrefix(parts[1], utils.APIKeyPrefix):
+			authenticateAPIKey(c, apiKeyRepo, parts[1])
+		case parts[0] == "Bearer":
+			authenticateJWT(c, parts[1])
+		case parts[0] == "ApiKey":
+			authenticateAPIKey(c, apiKeyRepo, parts[1])
+		default:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid Authorization format"})
+		}
+	}
+}
+
+// authenticateAPIKey looks up an API key by its hash and stores its owner's user ID in the context
+func authenticateAPIKey(c *gin.Context, apiKeyRepo *repositories.APIKeyRepository, apiKey string) {
+	key, err := apiKeyRepo.GetActiveByHash(utils.HashAPIKey(apiKey))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Failed to verify API key"})
+		return
+	}
+	if key == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid, revoked or expired API key"})
+		return
+	}
+
+	// Recording the use must not slow the request down, and a minute's precision is enough
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > apiKeyTouchInterval {
+		go func() {
+			if err := apiKeyRepo.TouchLastUsed(key.ID, apiKeyTouchInterval); err != nil {
+				log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+			}
+		}()
+	}
+
+	c.Set(UserIDKey, key.UserID)
+	c.Set(AuthMethodKey, AuthMethodAPIKey)
+	c.Next()
+}
 
+// authenticateJWT verifies an access token and stores its user ID in the context
+func authenticateJWT(c *gin.Context, tokenStr string) {
 	// Verify token using the same secret you used for generating access tokens
 	claims, err := utils.VerifyJWT(tokenStr, utils.AccessTokenSecret)
 	if err != nil {
@@ -31,8 +117,9 @@ func Authenticate(c *gin.Context) {
 		return
 	}
 
-	// Store the user ID in context for handlers
-	c.Set("userId", claims.UserID)
+	// Store the user ID in context for handlers (always as uuid.UUID)
+	c.Set(UserIDKey, claims.UserID)
+	c.Set(AuthMethodKey, AuthMethodJWT)
 
 	c.Next()
 }
@@ -0,0 +1,120 @@
+package middlewares
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"backend/internal/testdb"
+	"backend/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newAuthRouter serves a route behind AuthenticateJWTOrAPIKey that echoes what the
+// middleware stored in the context
+func newAuthRouter(t *testing.T, apiKeyRepo *repositories.APIKeyRepository) *gin.Engine {
+	t.Helper()
+
+	secret := utils.AccessTokenSecret
+	utils.AccessTokenSecret = []byte("auth-middleware-test-secret")
+	t.Cleanup(func() { utils.AccessTokenSecret = secret })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", AuthenticateJWTOrAPIKey(apiKeyRepo), func(c *gin.Context) {
+		c.String(http.StatusOK, "%s %s", c.MustGet(UserIDKey), c.MustGet(AuthMethodKey))
+	})
+	return router
+}
+
+func get(router *gin.Engine, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticateJWTOrAPIKeyRejectsMalformedSchemes(t *testing.T) {
+	// None of these requests get as far as looking up an API key
+	router := newAuthRouter(t, nil)
+	token, err := utils.GenerateJWT(uuid.New(), time.Minute, utils.AccessTokenSecret)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+
+	for _, header := range []string{
+		"",
+		token,
+		"Bearer",
+		"Bearer ",
+		"bearer " + token,
+		"Basic dXNlcjpwYXNz",
+		"Token " + token,
+		"Bearer " + token + " extra",
+		"ApiKey",
+		"Bearer not-a-jwt",
+	} {
+		w := get(router, map[string]string{"Authorization": header})
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", header, w.Code)
+		}
+	}
+}
+
+func TestAuthenticateJWTOrAPIKeyAcceptsBoth(t *testing.T) {
+	pool := testdb.Pool(t)
+	apiKeys := repositories.NewAPIKeyRepository(pool)
+	router := newAuthRouter(t, apiKeys)
+
+	user := &models.User{Email: "auth-middleware@example.com", PasswordHash: "hash"}
+	if err := repositories.NewUserRepository(pool).Create(user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	token, err := utils.GenerateJWT(user.ID, time.Minute, utils.AccessTokenSecret)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	apiKey, err := utils.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey: %v", err)
+	}
+	key := &models.APIKey{UserID: user.ID, KeyHash: utils.HashAPIKey(apiKey)}
+	if err := apiKeys.Create(key); err != nil {
+		t.Fatalf("Create API key: %v", err)
+	}
+
+	accepted := []struct {
+		headers map[string]string
+		method  string
+	}{
+		{map[string]string{"Authorization": "Bearer " + token}, AuthMethodJWT},
+		{map[string]string{"Authorization": "Bearer " + apiKey}, AuthMethodAPIKey},
+		{map[string]string{"Authorization": "ApiKey " + apiKey}, AuthMethodAPIKey},
+		{map[string]string{APIKeyHeader: apiKey}, AuthMethodAPIKey},
+	}
+	for _, tt := range accepted {
+		w := get(router, tt.headers)
+		if want := user.ID.String() + " " + tt.method; w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%v: %d %q, want 200 %q", tt.headers, w.Code, w.Body, want)
+		}
+	}
+
+	// Unknown and revoked keys are refused
+	if w := get(router, map[string]string{"Authorization": "ApiKey " + utils.APIKeyPrefix + "unknown"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown API key: status = %d, want 401", w.Code)
+	}
+	if revoked, err := apiKeys.Revoke(key.ID, user.ID); err != nil || !revoked {
+		t.Fatalf("Revoke = %v, %v", revoked, err)
+	}
+	if w := get(router, map[string]string{"Authorization": "ApiKey " + apiKey}); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked API key: status = %d, want 401", w.Code)
+	}
+}
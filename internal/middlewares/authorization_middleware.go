@@ -13,25 +13,15 @@ import (
 func RequireAdmin(userRepo *repositories.UserRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get authenticated user ID from context (set by Authenticate middleware)
-		userID, exists := c.Get("userId")
+		userID, exists := c.Get(UserIDKey)
 		if !exists {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
 			return
 		}
 
-		// Convert userID to UUID
-		var authenticatedUserID uuid.UUID
-		switch v := userID.(type) {
-		case uuid.UUID:
-			authenticatedUserID = v
-		case string:
-			parsed, err := uuid.Parse(v)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid user ID format"})
-				return
-			}
-			authenticatedUserID = parsed
-		default:
+		// Authenticate always stores the user ID as uuid.UUID
+		authenticatedUserID, ok := userID.(uuid.UUID)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid user ID format"})
 			return
 		}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backup statuses
+const (
+	BackupStatusRunning   = "running"
+	BackupStatusCompleted = "completed"
+	BackupStatusFailed    = "failed"
+)
+
+// BackupSchedule makes a project's database be dumped every IntervalMinutes,
+// keeping the latest Retention completed backups
+type BackupSchedule struct {
+	ProjectID       uuid.UUID  `json:"project_id"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Retention       int        `json:"retention"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       time.Time  `json:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ProjectBackup is one pg_dump of a project's database
+type ProjectBackup struct {
+	ID           uuid.UUID  `json:"id"`
+	ProjectID    uuid.UUID  `json:"project_id"`
+	Status       string     `json:"status"`
+	FilePath     string     `json:"-"` // Location of the dump on the backup volume
+	SizeBytes    *int64     `json:"size_bytes,omitempty"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+func (b *ProjectBackup) Prepare() {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	if b.StartedAt.IsZero() {
+		b.StartedAt = time.Now()
+	}
+}
+
+// BackupDownload is one pg_dump streamed straight to a user
+type BackupDownload struct {
+	ID           uuid.UUID  `json:"id"`
+	ProjectID    uuid.UUID  `json:"project_id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Status       string     `json:"status"`
+	SizeBytes    *int64     `json:"size_bytes,omitempty"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	StartedAt    time.Time  `json:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+func (d *BackupDownload) Prepare() {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.StartedAt.IsZero() {
+		d.StartedAt = time.Now()
+	}
+}
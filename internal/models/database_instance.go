@@ -13,8 +13,10 @@ type DatabaseInstance struct {
 	RAMMB       *int      `json:"ram_mb,omitempty"`
 	StorageGB   *int      `json:"storage_gb,omitempty"`
 	Status      string    `json:"status"` // 'creating', 'running', 'failed', 'paused', 'deleted'
-	Port        *int      `json:"port,omitempty"`
+	Port        int       `json:"port"`
 	ContainerID *string   `json:"container_id,omitempty"`
+	Endpoint    *string   `json:"endpoint,omitempty"`
+	DBName      *string   `json:"db_name,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -28,3 +30,11 @@ func (d *DatabaseInstance) Prepare() {
 	}
 }
 
+// DatabaseName is the database to connect to inside the instance. Instances created
+// before the name was recorded fall back to the default "postgres" database.
+func (d *DatabaseInstance) DatabaseName() string {
+	if d.DBName != nil && *d.DBName != "" {
+		return *d.DBName
+	}
+	return "postgres"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DDL audit operations
+const (
+	DDLOperationCreateTable   = "CREATE_TABLE"
+	DDLOperationCreateIndex   = "CREATE_INDEX"
+	DDLOperationDropIndex     = "DROP_INDEX"
+	DDLOperationAddForeignKey = "ADD_FOREIGN_KEY"
+	DDLOperationDropTable     = "DROP_TABLE"
+	DDLOperationRenameTable   = "RENAME_TABLE"
+	DDLOperationAddColumn     = "ADD_COLUMN"
+	DDLOperationDropColumn    = "DROP_COLUMN"
+	DDLOperationRenameColumn  = "RENAME_COLUMN"
+	DDLOperationCreateRole    = "CREATE_ROLE"
+	DDLOperationRenameSchema  = "RENAME_SCHEMA"
+	DDLOperationApplyScript   = "APPLY_SCRIPT"
+)
+
+type DDLAudit struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       *uuid.UUID `json:"user_id,omitempty"`
+	ProjectID    uuid.UUID  `json:"project_id"`
+	Operation    string     `json:"operation"`
+	Target       string     `json:"target"` // Object the operation was applied to, e.g. "public.users.email"
+	SQLText      string     `json:"sql_text"`
+	Success      bool       `json:"success"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (d *DDLAudit) Prepare() {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+}
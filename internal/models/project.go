@@ -14,6 +14,16 @@ type Project struct {
 	DBType       string     `json:"db_type"`        // 'postgres' or 'mongodb'
 	ResourceTier string     `json:"resource_tier"`  // 'free', 'basic', or 'premium'
 	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`     // Bumped on every change to the project
+
+	// Query policy applied on top of the global SQL validator
+	ReadOnly        bool     `json:"read_only"`
+	BlockedKeywords []string `json:"blocked_keywords"`
+	RedactQueryText bool     `json:"redact_query_text"` // Store only a hash of query text in history
+
+	// Not stored in DB - computed from the project's database instance
+	InstanceStatus string `json:"instance_status,omitempty"`
+	Ready          *bool  `json:"ready,omitempty"`
 }
 
 func (p *Project) Prepare() {
@@ -23,4 +33,7 @@ func (p *Project) Prepare() {
 	if p.ResourceTier == "" {
 		p.ResourceTier = "free"
 	}
+	if p.BlockedKeywords == nil {
+		p.BlockedKeywords = []string{}
+	}
 }
\ No newline at end of file
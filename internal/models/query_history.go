@@ -11,9 +11,12 @@ type QueryHistory struct {
 	DBInstanceID    uuid.UUID `json:"db_instance_id"`
 	UserID          uuid.UUID `json:"user_id"`
 	QueryText       string    `json:"query_text"`
+	StatementType   *string   `json:"statement_type,omitempty"` // SELECT, DML, DDL or OTHER; unset on older entries
 	ExecutedAt      time.Time `json:"executed_at"`
 	Success         *bool     `json:"success,omitempty"`
 	ExecutionTimeMs *int      `json:"execution_time_ms,omitempty"`
+	ErrorMessage    *string   `json:"error_message,omitempty"`
+	RowsAffected    *int64    `json:"rows_affected,omitempty"`
 }
 
 func (q *QueryHistory) Prepare() {
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QueryPlanSnapshot struct {
+	ID        uuid.UUID       `json:"id"`
+	ProjectID uuid.UUID       `json:"project_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Name      string          `json:"name"`
+	QueryText string          `json:"query_text"`
+	Plan      json.RawMessage `json:"plan"`       // Raw EXPLAIN (FORMAT JSON) output
+	TotalCost float64         `json:"total_cost"` // Estimated total cost of the root plan node
+	PlanRows  float64         `json:"plan_rows"`  // Estimated rows returned by the root plan node
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (q *QueryPlanSnapshot) Prepare() {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	if q.CreatedAt.IsZero() {
+		q.CreatedAt = time.Now()
+	}
+}
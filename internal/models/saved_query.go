@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedQuery is a query a user saved under a name to run again in a project
+type SavedQuery struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Name      string    `json:"name"`
+	QueryText string    `json:"query_text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *SavedQuery) Prepare() {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	if q.CreatedAt.IsZero() {
+		q.CreatedAt = time.Now()
+	}
+}
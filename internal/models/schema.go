@@ -1,27 +1,167 @@
 package models
 
+import "time"
+
 type Column struct {
-	Name     string
-	DataType string
-	Nullable bool
+	Name     string `json:"name"`
+	DataType string `json:"data_type"`
+	Nullable bool   `json:"nullable"`
+	Unique   bool   `json:"unique"` // Part of a unique constraint
 }
 
 type ForeignKey struct {
-	ConstraintName string
-	FromColumn     string
-	ToTable        string
-	ToColumn       string
+	ConstraintName string `json:"constraint_name"`
+	FromColumn     string `json:"from_column"`
+	ToTable        string `json:"to_table"`
+	ToColumn       string `json:"to_column"`
 }
 
 type Table struct {
-	Name        string
-	Columns     []Column
-	PrimaryKeys []string
-	ForeignKeys []ForeignKey
+	Name        string       `json:"name"`
+	Columns     []Column     `json:"columns"`
+	PrimaryKeys []string     `json:"primary_keys"`
+	ForeignKeys []ForeignKey `json:"foreign_keys"`
+	RowCount    *int64       `json:"row_count,omitempty"` // Only set when statistics were requested
 }
 
 type Relationship struct {
-	FromTable string
-	ToTable   string
-	Type      string // "||--o{", "||--||", etc.
+	FromTable string `json:"from_table"`
+	ToTable   string `json:"to_table"`
+	Type      string `json:"type"`  // "}o--||", "||--||", etc.
+	Label     string `json:"label"` // "belongs_to" or "has_many"
+}
+
+// SchemaVisualization is the parsed model of a schema that diagrams are rendered from
+type SchemaVisualization struct {
+	Schema        string         `json:"schema"`
+	Tables        []Table        `json:"tables"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// TableDependents lists the database objects that reference a table
+type TableDependents struct {
+	Schema      string            `json:"schema"`
+	Table       string            `json:"table"`
+	ForeignKeys []DependentObject `json:"foreign_keys"`
+	Views       []DependentObject `json:"views"`
+	Functions   []DependentObject `json:"functions"`
+}
+
+// DependentObject is a single object depending on a table
+type DependentObject struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Table      string `json:"table,omitempty"`      // Referencing table (foreign keys only)
+	Definition string `json:"definition,omitempty"` // Constraint definition or function signature
+	Kind       string `json:"kind,omitempty"`       // "view" or "materialized view" (views only)
+}
+
+// TableSummary is a lightweight description of a table for listings
+type TableSummary struct {
+	Name          string `json:"name"`
+	ColumnCount   int    `json:"column_count"`
+	EstimatedRows *int64 `json:"estimated_rows"` // From planner statistics; nil if the table was never analyzed
+}
+
+// TableStats is a per-table statistics and health summary from pg_stat_user_tables
+type TableStats struct {
+	Table            string     `json:"table"`
+	LiveTuples       int64      `json:"live_tuples"`
+	DeadTuples       int64      `json:"dead_tuples"`
+	DeadTupleRatio   float64    `json:"dead_tuple_ratio"`
+	SeqScans         int64      `json:"seq_scans"`
+	IndexScans       int64      `json:"index_scans"`
+	SeqScanRatio     float64    `json:"seq_scan_ratio"`
+	ModsSinceAnalyze int64      `json:"mods_since_analyze"`
+	LastVacuum       *time.Time `json:"last_vacuum,omitempty"`
+	LastAutovacuum   *time.Time `json:"last_autovacuum,omitempty"`
+	LastAnalyze      *time.Time `json:"last_analyze,omitempty"`
+	LastAutoanalyze  *time.Time `json:"last_autoanalyze,omitempty"`
+	Suggestions      []string   `json:"suggestions"`
+}
+
+// SchemaIntrospection is a machine-readable description of a schema for ORM model generation
+type SchemaIntrospection struct {
+	Schema string              `json:"schema"`
+	Tables []IntrospectedTable `json:"tables"`
+}
+
+// IntrospectedTable describes a table with all its columns, keys and indexes
+type IntrospectedTable struct {
+	Name              string                   `json:"name"`
+	Columns           []IntrospectedColumn     `json:"columns"`
+	PrimaryKey        []string                 `json:"primary_key"`
+	ForeignKeys       []IntrospectedForeignKey `json:"foreign_keys"`
+	UniqueConstraints []UniqueConstraint       `json:"unique_constraints"`
+	Checks            []CheckConstraint        `json:"checks"`
+	Indexes           []IntrospectedIndex      `json:"indexes"`
+}
+
+// IntrospectedColumn describes a table column
+type IntrospectedColumn struct {
+	Name     string  `json:"name"`
+	DataType string  `json:"data_type"` // Full type including modifiers, e.g. "character varying(50)"
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"`
+	Identity *string `json:"identity"` // "ALWAYS" or "BY DEFAULT" for identity columns
+}
+
+// IntrospectedForeignKey describes a (possibly composite) foreign key constraint
+type IntrospectedForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update"`
+	OnDelete          string   `json:"on_delete"`
+}
+
+// UniqueConstraint describes a unique constraint
+type UniqueConstraint struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+// CheckConstraint describes a check constraint
+type CheckConstraint struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"` // e.g. "CHECK ((price >= (0)::numeric))"
+}
+
+// IntrospectedIndex describes an index on a table
+type IntrospectedIndex struct {
+	Name        string   `json:"name"`
+	Columns     []string `json:"columns"`     // Empty for purely expression-based indexes
+	Expressions *string  `json:"expressions"` // Indexed expressions, for expression indexes
+	Predicate   *string  `json:"predicate"`   // WHERE clause, for partial indexes
+	Unique      bool     `json:"unique"`
+	Primary     bool     `json:"primary"`
+	Definition  string   `json:"definition"`
+}
+
+// SchemaDiff lists the changes turning a schema of one project into the same schema of another
+type SchemaDiff struct {
+	Schema         string             `json:"schema"`
+	AddedTables    []string           `json:"added_tables"`
+	RemovedTables  []string           `json:"removed_tables"`
+	AddedColumns   []ColumnDiff       `json:"added_columns"`   // Of tables found on both sides
+	RemovedColumns []ColumnDiff       `json:"removed_columns"` // Of tables found on both sides
+	TypeChanges    []ColumnTypeChange `json:"type_changes"`
+	Summary        string             `json:"summary"` // One line per change, e.g. "+ column users.age integer"
+}
+
+// ColumnDiff is a column found on one side of a schema diff only
+type ColumnDiff struct {
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	DataType string `json:"data_type"`
+}
+
+// ColumnTypeChange is a column whose type differs between the two sides of a schema diff
+type ColumnTypeChange struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	From   string `json:"from"`
+	To     string `json:"to"`
 }
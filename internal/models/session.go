@@ -7,12 +7,16 @@ import (
 )
 
 type Session struct {
-	ID           uuid.UUID `json:"id"`
-	UserID       uuid.UUID `json:"user_id"`
-	RefreshToken string    `json:"refresh_token"`
-	IsRevoked    bool      `json:"is_revoked"`
-	CreatedAt    time.Time `json:"created_at"`
-	ExpiresAt    time.Time `json:"expires_at"`
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	RefreshToken string     `json:"-"`
+	IsRevoked    bool       `json:"is_revoked"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	JTI          *string    `json:"-"`                     // ID of the refresh token, for sessions created since rotation tracking
+	ConsumedAt   *time.Time `json:"consumed_at,omitempty"` // When the refresh token was rotated
+	UserAgent    *string    `json:"user_agent,omitempty"`  // Client that logged in, as it identified itself
+	IPAddress    *string    `json:"ip_address,omitempty"`
 }
 
 func (s *Session) Prepare() {
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageMetric is a sample of the resource usage of a database instance's container
+type UsageMetric struct {
+	ID             uuid.UUID `json:"id"`
+	DBInstanceID   uuid.UUID `json:"db_instance_id"`
+	Timestamp      time.Time `json:"timestamp"`
+	CPUPercent     *float32  `json:"cpu_percent,omitempty"`
+	RAMPercent     *float32  `json:"ram_percent,omitempty"`
+	StorageUsedGB  *float32  `json:"storage_used_gb,omitempty"`
+	BandwidthInGB  *float32  `json:"bandwidth_in_gb,omitempty"`  // Received since the container started
+	BandwidthOutGB *float32  `json:"bandwidth_out_gb,omitempty"` // Sent since the container started
+}
+
+func (m *UsageMetric) Prepare() {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now()
+	}
+}
+
+// UsageMetricBucket aggregates the samples of an instance taken in one time bucket
+type UsageMetricBucket struct {
+	Start         time.Time `json:"start"`
+	CPUPercent    *float64  `json:"cpu_percent"`     // Average
+	RAMPercent    *float64  `json:"ram_percent"`     // Average
+	StorageUsedGB *float64  `json:"storage_used_gb"` // Maximum
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserQuota is an admin-set override of a user's resource quotas.
+// A nil limit means the default applies.
+type UserQuota struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	MaxProjects  *int       `json:"max_projects"`
+	MaxStorageGB *int       `json:"max_storage_gb"`
+	Flags        []string   `json:"flags"`
+	UpdatedBy    *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// EffectiveQuota is the quota that applies to a user after overrides
+type EffectiveQuota struct {
+	MaxProjects  int      `json:"max_projects"`
+	MaxStorageGB int      `json:"max_storage_gb"`
+	Flags        []string `json:"flags"`
+	Custom       bool     `json:"custom"` // Whether an admin override is in place
+}
+
+// UserUsage is a user's current resource usage against their quota
+type UserUsage struct {
+	Projects  int            `json:"projects"`
+	StorageGB int            `json:"storage_gb"`
+	Quota     EffectiveQuota `json:"quota"`
+}
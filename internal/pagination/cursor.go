@@ -0,0 +1,102 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or its signature does not match
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// ErrMissingSecret is returned when neither CURSOR_SECRET nor ACCESS_TOKEN_SECRET is set.
+// Cursors are never signed with an empty key, which anyone could forge.
+var ErrMissingSecret = errors.New("pagination cursor secret is not configured")
+
+// Cursor marks the last row of a page in a (timestamp DESC, id DESC) keyset ordering
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   uuid.UUID `json:"id"`
+}
+
+// cursorSecret returns the HMAC key used to sign cursors.
+// CURSOR_SECRET is used when set, otherwise the access token secret.
+func cursorSecret() []byte {
+	secret := os.Getenv("CURSOR_SECRET")
+	if secret == "" {
+		secret = os.Getenv("ACCESS_TOKEN_SECRET")
+	}
+	return []byte(secret)
+}
+
+func sign(payload []byte) ([]byte, error) {
+	secret := cursorSecret()
+	if len(secret) == 0 {
+		return nil, ErrMissingSecret
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+// Encode serializes a cursor into an opaque, signed, URL-safe string
+func Encode(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Decode verifies and parses a cursor produced by Encode.
+// An empty string decodes to nil, meaning the first page.
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	expected, err := sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(sig, expected) {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Time.IsZero() || cursor.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
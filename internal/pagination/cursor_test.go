@@ -0,0 +1,109 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-test-secret")
+
+	want := Cursor{
+		Time: time.Date(2024, 5, 17, 13, 4, 5, 123456000, time.UTC),
+		ID:   uuid.New(),
+	}
+	token, err := Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	got, err := Decode(token)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !got.Time.Equal(want.Time) || got.ID != want.ID {
+		t.Fatalf("Decode = %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeEmptyTokenIsFirstPage(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-test-secret")
+
+	got, err := Decode("")
+	if err != nil || got != nil {
+		t.Fatalf("Decode(\"\") = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestDecodeRejectsTamperedCursors(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "cursor-test-secret")
+
+	token, err := Encode(Cursor{Time: time.Now().UTC(), ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	encodedPayload, encodedSig, _ := strings.Cut(token, ".")
+
+	forged := `{"t":"2020-01-01T00:00:00Z","id":"` + uuid.NewString() + `"}`
+	sig, _ := base64.RawURLEncoding.DecodeString(encodedSig)
+	sig[0] ^= 0xff
+
+	cases := map[string]string{
+		"swapped payload":   base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + encodedSig,
+		"flipped signature": encodedPayload + "." + base64.RawURLEncoding.EncodeToString(sig),
+		"missing signature": encodedPayload,
+		"empty signature":   encodedPayload + ".",
+		"invalid base64":    "!!!." + encodedSig,
+		"garbage":           "not-a-cursor",
+	}
+	for name, tampered := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(tampered); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("Decode(%q) error = %v, want ErrInvalidCursor", tampered, err)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsCursorSignedWithAnotherSecret(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "first-secret")
+	token, err := Encode(Cursor{Time: time.Now().UTC(), ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	t.Setenv("CURSOR_SECRET", "second-secret")
+	if _, err := Decode(token); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("Decode error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestSecretFallsBackToAccessTokenSecret(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "")
+	t.Setenv("ACCESS_TOKEN_SECRET", "access-secret")
+
+	token, err := Encode(Cursor{Time: time.Now().UTC(), ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := Decode(token); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+}
+
+func TestEmptySecretIsRefused(t *testing.T) {
+	t.Setenv("CURSOR_SECRET", "")
+	t.Setenv("ACCESS_TOKEN_SECRET", "")
+
+	if _, err := Encode(Cursor{Time: time.Now().UTC(), ID: uuid.New()}); !errors.Is(err, ErrMissingSecret) {
+		t.Fatalf("Encode error = %v, want ErrMissingSecret", err)
+	}
+	if _, err := Decode("eyJ0IjoiMjAyNC0wMS0wMVQwMDowMDowMFoifQ.c2ln"); !errors.Is(err, ErrMissingSecret) {
+		t.Fatalf("Decode error = %v, want ErrMissingSecret", err)
+	}
+}
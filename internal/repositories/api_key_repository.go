@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{pool: pool}
+}
+
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	ctx := context.Background()
+
+	query := `
+		INSERT INTO api_keys (user_id, key_hash, description, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, revoked
+	`
+
+	return r.pool.QueryRow(ctx, query,
+		key.UserID,
+		key.KeyHash,
+		key.Description,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt, &key.Revoked)
+}
+
+// ListByUserID returns every API key of a user, including revoked and expired ones, newest first
+func (r *APIKeyRepository) ListByUserID(userID uuid.UUID) ([]models.APIKey, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, user_id, key_hash, description, created_at, expires_at, revoked, last_used_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.KeyHash,
+			&key.Description,
+			&key.CreatedAt,
+			&key.ExpiresAt,
+			&key.Revoked,
+			&key.LastUsedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke marks an API key of a user as revoked. It returns false when the user has no such key.
+func (r *APIKeyRepository) Revoke(id uuid.UUID, userID uuid.UUID) (bool, error) {
+	ctx := context.Background()
+
+	query := `UPDATE api_keys SET revoked = TRUE WHERE id = $1 AND user_id = $2`
+	tag, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetActiveByHash returns the unrevoked, unexpired API key with the given hash, or nil if there is none
+func (r *APIKeyRepository) GetActiveByHash(keyHash string) (*models.APIKey, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, user_id, key_hash, description, created_at, expires_at, revoked, last_used_at
+		FROM api_keys
+		WHERE key_hash = $1 AND NOT revoked AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	var key models.APIKey
+	err := r.pool.QueryRow(ctx, query, keyHash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.KeyHash,
+		&key.Description,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.Revoked,
+		&key.LastUsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// TouchLastUsed records that an API key was just used, unless that was already recorded
+// less than interval ago
+func (r *APIKeyRepository) TouchLastUsed(id uuid.UUID, interval time.Duration) error {
+	ctx := context.Background()
+
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 second')
+	`
+	_, err := r.pool.Exec(ctx, query, id, int(interval.Seconds()))
+	return err
+}
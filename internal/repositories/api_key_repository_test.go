@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"backend/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestTouchLastUsedWritesAtMostOncePerInterval(t *testing.T) {
+	pool := testdb.Pool(t)
+	repo := NewAPIKeyRepository(pool)
+
+	user := &models.User{Email: "api-key-user@example.com", PasswordHash: "hash"}
+	if err := NewUserRepository(pool).Create(user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	key := &models.APIKey{UserID: user.ID, KeyHash: "touch-test-hash"}
+	if err := repo.Create(key); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	lastUsed := func() *time.Time {
+		t.Helper()
+		stored, err := repo.GetActiveByHash(key.KeyHash)
+		if err != nil || stored == nil {
+			t.Fatalf("GetActiveByHash = %v, %v", stored, err)
+		}
+		return stored.LastUsedAt
+	}
+
+	if used := lastUsed(); used != nil {
+		t.Fatalf("new key last used at %v, want nil", used)
+	}
+	if err := repo.TouchLastUsed(key.ID, time.Minute); err != nil {
+		t.Fatalf("TouchLastUsed: %v", err)
+	}
+	first := lastUsed()
+	if first == nil {
+		t.Fatal("last_used_at not set by TouchLastUsed")
+	}
+
+	// A second use within the interval leaves the recorded time alone
+	if err := repo.TouchLastUsed(key.ID, time.Minute); err != nil {
+		t.Fatalf("TouchLastUsed: %v", err)
+	}
+	if second := lastUsed(); second == nil || !second.Equal(*first) {
+		t.Errorf("last used at %v after a second use, want %v", second, first)
+	}
+}
@@ -0,0 +1,302 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BackupRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBackupRepository(pool *pgxpool.Pool) *BackupRepository {
+	return &BackupRepository{pool: pool}
+}
+
+const backupScheduleColumns = `project_id, interval_minutes, retention, enabled, next_run_at, last_run_at, created_at, updated_at`
+
+const projectBackupColumns = `id, project_id, status, file_path, size_bytes, error_message, started_at, completed_at`
+
+func scanBackupSchedule(row pgx.Row) (*models.BackupSchedule, error) {
+	var schedule models.BackupSchedule
+	err := row.Scan(
+		&schedule.ProjectID,
+		&schedule.IntervalMinutes,
+		&schedule.Retention,
+		&schedule.Enabled,
+		&schedule.NextRunAt,
+		&schedule.LastRunAt,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func scanProjectBackup(row pgx.Row) (*models.ProjectBackup, error) {
+	var backup models.ProjectBackup
+	err := row.Scan(
+		&backup.ID,
+		&backup.ProjectID,
+		&backup.Status,
+		&backup.FilePath,
+		&backup.SizeBytes,
+		&backup.ErrorMessage,
+		&backup.StartedAt,
+		&backup.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// GetSchedule returns the backup schedule of a project, or nil if none is set
+func (r *BackupRepository) GetSchedule(projectID uuid.UUID) (*models.BackupSchedule, error) {
+	ctx := context.Background()
+
+	query := `SELECT ` + backupScheduleColumns + ` FROM backup_schedules WHERE project_id = $1`
+
+	schedule, err := scanBackupSchedule(r.pool.QueryRow(ctx, query, projectID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UpsertSchedule creates or replaces the backup schedule of a project
+func (r *BackupRepository) UpsertSchedule(schedule *models.BackupSchedule) error {
+	ctx := context.Background()
+
+	query := `
+		INSERT INTO backup_schedules (project_id, interval_minutes, retention, enabled, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (project_id) DO UPDATE SET
+			interval_minutes = EXCLUDED.interval_minutes,
+			retention = EXCLUDED.retention,
+			enabled = EXCLUDED.enabled,
+			next_run_at = EXCLUDED.next_run_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING ` + backupScheduleColumns
+
+	saved, err := scanBackupSchedule(r.pool.QueryRow(ctx, query,
+		schedule.ProjectID,
+		schedule.IntervalMinutes,
+		schedule.Retention,
+		schedule.Enabled,
+		schedule.NextRunAt,
+	))
+	if err != nil {
+		return err
+	}
+	*schedule = *saved
+	return nil
+}
+
+// GetDueSchedules returns the enabled schedules whose next run is at or before now
+func (r *BackupRepository) GetDueSchedules(now time.Time) ([]models.BackupSchedule, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT ` + backupScheduleColumns + `
+		FROM backup_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.BackupSchedule{}
+	for rows.Next() {
+		schedule, err := scanBackupSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// MarkScheduleRun records that a schedule ran at lastRun and moves its next run to nextRun
+func (r *BackupRepository) MarkScheduleRun(projectID uuid.UUID, lastRun time.Time, nextRun time.Time) error {
+	ctx := context.Background()
+
+	query := `UPDATE backup_schedules SET last_run_at = $2, next_run_at = $3 WHERE project_id = $1`
+
+	_, err := r.pool.Exec(ctx, query, projectID, lastRun, nextRun)
+	return err
+}
+
+// CreateBackup stores a new backup record
+func (r *BackupRepository) CreateBackup(backup *models.ProjectBackup) error {
+	ctx := context.Background()
+
+	backup.Prepare()
+
+	query := `
+		INSERT INTO project_backups (id, project_id, status, file_path, started_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		backup.ID,
+		backup.ProjectID,
+		backup.Status,
+		backup.FilePath,
+		backup.StartedAt,
+	)
+	return err
+}
+
+// FinishBackup stores the outcome of a backup
+func (r *BackupRepository) FinishBackup(backup *models.ProjectBackup) error {
+	ctx := context.Background()
+
+	query := `
+		UPDATE project_backups
+		SET status = $2, size_bytes = $3, error_message = $4, completed_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		backup.ID,
+		backup.Status,
+		backup.SizeBytes,
+		backup.ErrorMessage,
+		backup.CompletedAt,
+	)
+	return err
+}
+
+// GetBackupsByProjectID returns the backups of a project, newest first
+func (r *BackupRepository) GetBackupsByProjectID(projectID uuid.UUID) ([]models.ProjectBackup, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT ` + projectBackupColumns + `
+		FROM project_backups WHERE project_id = $1
+		ORDER BY started_at DESC
+	`
+
+	return r.queryBackups(ctx, query, projectID)
+}
+
+// GetBackupByIDAndProjectID returns a backup of a project, or nil if it does not exist
+func (r *BackupRepository) GetBackupByIDAndProjectID(id uuid.UUID, projectID uuid.UUID) (*models.ProjectBackup, error) {
+	ctx := context.Background()
+
+	query := `SELECT ` + projectBackupColumns + ` FROM project_backups WHERE id = $1 AND project_id = $2`
+
+	backup, err := scanProjectBackup(r.pool.QueryRow(ctx, query, id, projectID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return backup, nil
+}
+
+// GetBackupsBeyondRetention returns the backups of a project that fall outside its
+// retention: every finished backup after the newest keep completed ones
+func (r *BackupRepository) GetBackupsBeyondRetention(projectID uuid.UUID, keep int) ([]models.ProjectBackup, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT ` + projectBackupColumns + `
+		FROM project_backups
+		WHERE project_id = $1 AND status <> 'running' AND started_at < COALESCE((
+			SELECT started_at FROM project_backups
+			WHERE project_id = $1 AND status = 'completed'
+			ORDER BY started_at DESC
+			OFFSET $2 - 1 LIMIT 1
+		), '-infinity')
+		ORDER BY started_at
+	`
+
+	return r.queryBackups(ctx, query, projectID, keep)
+}
+
+// DeleteBackup removes a backup record
+func (r *BackupRepository) DeleteBackup(id uuid.UUID) error {
+	ctx := context.Background()
+
+	_, err := r.pool.Exec(ctx, `DELETE FROM project_backups WHERE id = $1`, id)
+	return err
+}
+
+func (r *BackupRepository) queryBackups(ctx context.Context, query string, args ...interface{}) ([]models.ProjectBackup, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backups := []models.ProjectBackup{}
+	for rows.Next() {
+		backup, err := scanProjectBackup(rows)
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, *backup)
+	}
+
+	return backups, rows.Err()
+}
+
+// CreateDownload stores a new backup download record
+func (r *BackupRepository) CreateDownload(download *models.BackupDownload) error {
+	ctx := context.Background()
+
+	download.Prepare()
+
+	query := `
+		INSERT INTO backup_downloads (id, project_id, user_id, status, started_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		download.ID,
+		download.ProjectID,
+		download.UserID,
+		download.Status,
+		download.StartedAt,
+	)
+	return err
+}
+
+// FinishDownload stores the outcome of a backup download
+func (r *BackupRepository) FinishDownload(download *models.BackupDownload) error {
+	ctx := context.Background()
+
+	query := `
+		UPDATE backup_downloads
+		SET status = $2, size_bytes = $3, error_message = $4, completed_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		download.ID,
+		download.Status,
+		download.SizeBytes,
+		download.ErrorMessage,
+		download.CompletedAt,
+	)
+	return err
+}
@@ -37,8 +37,12 @@ func (r *DatabaseCredentialRepository) Create(credential *models.DatabaseCredent
 		credential.PasswordEncrypted,
 		now,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	credential.CreatedAt = now
+	return nil
 }
 
 func (r *DatabaseCredentialRepository) GetByInstanceID(instanceID uuid.UUID) ([]models.DatabaseCredential, error) {
@@ -138,3 +142,31 @@ func (r *DatabaseCredentialRepository) Delete(id uuid.UUID) error {
 	_, err := r.pool.Exec(ctx, query, id)
 	return err
 }
+
+// DeleteAllExceptLatest deletes an instance's credentials except the newest keep rows
+// and returns how many were deleted
+func (r *DatabaseCredentialRepository) DeleteAllExceptLatest(instanceID uuid.UUID, keep int) (int64, error) {
+	ctx := context.Background()
+
+	if keep < 1 {
+		keep = 1 // Never delete the credential currently in use
+	}
+
+	query := `
+		DELETE FROM database_credentials
+		WHERE db_instance_id = $1
+			AND id NOT IN (
+				SELECT id FROM database_credentials
+				WHERE db_instance_id = $1
+				ORDER BY created_at DESC
+				LIMIT $2
+			)
+	`
+
+	tag, err := r.pool.Exec(ctx, query, instanceID, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -20,17 +20,24 @@ func NewDatabaseInstanceRepository(pool *pgxpool.Pool) *DatabaseInstanceReposito
 }
 
 func (r *DatabaseInstanceRepository) Create(instance *models.DatabaseInstance) error {
-	ctx := context.Background()
+	return r.create(context.Background(), r.pool, instance)
+}
 
+// CreateTx inserts a database instance as part of the caller's transaction
+func (r *DatabaseInstanceRepository) CreateTx(ctx context.Context, tx pgx.Tx, instance *models.DatabaseInstance) error {
+	return r.create(ctx, tx, instance)
+}
+
+func (r *DatabaseInstanceRepository) create(ctx context.Context, db execer, instance *models.DatabaseInstance) error {
 	instance.Prepare()
 
 	query := `
-		INSERT INTO database_instances (id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO database_instances (id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 
 	now := time.Now()
-	_, err := r.pool.Exec(ctx, query,
+	_, err := db.Exec(ctx, query,
 		instance.ID,
 		instance.ProjectID,
 		instance.CPUCores,
@@ -39,6 +46,8 @@ func (r *DatabaseInstanceRepository) Create(instance *models.DatabaseInstance) e
 		instance.Status,
 		instance.Port,
 		instance.ContainerID,
+		instance.Endpoint,
+		instance.DBName,
 		now,
 		now,
 	)
@@ -50,7 +59,7 @@ func (r *DatabaseInstanceRepository) GetByID(id uuid.UUID) (*models.DatabaseInst
 	ctx := context.Background()
 
 	query := `
-		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, created_at, updated_at
+		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at
 		FROM database_instances WHERE id = $1
 	`
 
@@ -64,6 +73,8 @@ func (r *DatabaseInstanceRepository) GetByID(id uuid.UUID) (*models.DatabaseInst
 		&instance.Status,
 		&instance.Port,
 		&instance.ContainerID,
+		&instance.Endpoint,
+		&instance.DBName,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -82,7 +93,7 @@ func (r *DatabaseInstanceRepository) GetByProjectID(projectID uuid.UUID) (*model
 	ctx := context.Background()
 
 	query := `
-		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, created_at, updated_at
+		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at
 		FROM database_instances WHERE project_id = $1
 		ORDER BY created_at DESC
 		LIMIT 1
@@ -98,6 +109,8 @@ func (r *DatabaseInstanceRepository) GetByProjectID(projectID uuid.UUID) (*model
 		&instance.Status,
 		&instance.Port,
 		&instance.ContainerID,
+		&instance.Endpoint,
+		&instance.DBName,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -138,6 +151,24 @@ func (r *DatabaseInstanceRepository) UpdateContainerID(id uuid.UUID, containerID
 	return err
 }
 
+// UpdateConnectionInfo records where the instance's container listens and the database
+// created inside it. Empty values leave the stored ones untouched.
+func (r *DatabaseInstanceRepository) UpdateConnectionInfo(id uuid.UUID, endpoint string, port int, dbName string) error {
+	ctx := context.Background()
+
+	query := `
+		UPDATE database_instances 
+		SET endpoint = COALESCE(NULLIF($2, ''), endpoint),
+			port = COALESCE(NULLIF($3, 0), port),
+			db_name = COALESCE(NULLIF($4, ''), db_name),
+			updated_at = $5
+		WHERE id = $1
+	`
+
+	_, err := r.pool.Exec(ctx, query, id, endpoint, port, dbName, time.Now())
+	return err
+}
+
 func (r *DatabaseInstanceRepository) UpdateResources(id uuid.UUID, cpuCores int, ramMB int, storageGB int) error {
 	ctx := context.Background()
 
@@ -155,7 +186,7 @@ func (r *DatabaseInstanceRepository) GetRunningByProjectID(projectID uuid.UUID)
 	ctx := context.Background()
 
 	query := `
-		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, created_at, updated_at
+		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at
 		FROM database_instances WHERE project_id = $1 AND status = 'running'
 		ORDER BY created_at DESC
 		LIMIT 1
@@ -171,6 +202,8 @@ func (r *DatabaseInstanceRepository) GetRunningByProjectID(projectID uuid.UUID)
 		&instance.Status,
 		&instance.Port,
 		&instance.ContainerID,
+		&instance.Endpoint,
+		&instance.DBName,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
 	)
@@ -185,6 +218,152 @@ func (r *DatabaseInstanceRepository) GetRunningByProjectID(projectID uuid.UUID)
 	return &instance, nil
 }
 
+// ListRetryableFailed returns the failed instances that failed after since, were retried
+// fewer than maxAttempts times, and whose backoff has passed. The backoff starts at
+// backoff after the failure and doubles with every attempt.
+func (r *DatabaseInstanceRepository) ListRetryableFailed(since time.Time, maxAttempts int, backoff time.Duration) ([]models.DatabaseInstance, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at
+		FROM database_instances
+		WHERE status = 'failed' AND updated_at > $1 AND provision_attempts < $2
+		  AND updated_at + make_interval(secs => $3 * power(2, provision_attempts)) <= NOW()
+		ORDER BY updated_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, since, maxAttempts, backoff.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []models.DatabaseInstance{}
+	for rows.Next() {
+		var instance models.DatabaseInstance
+		err := rows.Scan(
+			&instance.ID,
+			&instance.ProjectID,
+			&instance.CPUCores,
+			&instance.RAMMB,
+			&instance.StorageGB,
+			&instance.Status,
+			&instance.Port,
+			&instance.ContainerID,
+			&instance.Endpoint,
+			&instance.DBName,
+			&instance.CreatedAt,
+			&instance.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// StartProvisionAttempt moves a failed instance back to creating and records the attempt.
+// It reports false when the instance is no longer failed, e.g. it was deleted or another
+// attempt claimed it.
+func (r *DatabaseInstanceRepository) StartProvisionAttempt(id uuid.UUID) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+		UPDATE database_instances
+		SET status = 'creating', provision_attempts = provision_attempts + 1,
+		    last_provision_attempt_at = $2, updated_at = $2
+		WHERE id = $1 AND status = 'failed'
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListRunning returns every running database instance that has a container
+func (r *DatabaseInstanceRepository) ListRunning() ([]models.DatabaseInstance, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, project_id, cpu_cores, ram_mb, storage_gb, status, port, container_id, endpoint, db_name, created_at, updated_at
+		FROM database_instances WHERE status = 'running' AND container_id IS NOT NULL
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instances := []models.DatabaseInstance{}
+	for rows.Next() {
+		var instance models.DatabaseInstance
+		err := rows.Scan(
+			&instance.ID,
+			&instance.ProjectID,
+			&instance.CPUCores,
+			&instance.RAMMB,
+			&instance.StorageGB,
+			&instance.Status,
+			&instance.Port,
+			&instance.ContainerID,
+			&instance.Endpoint,
+			&instance.DBName,
+			&instance.CreatedAt,
+			&instance.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// SumStorageByUserID returns the storage allocated to all database instances of a user's projects
+func (r *DatabaseInstanceRepository) SumStorageByUserID(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT COALESCE(SUM(di.storage_gb), 0)
+		FROM database_instances di
+		JOIN projects p ON p.id = di.project_id
+		WHERE p.user_id = $1 AND di.status <> 'deleted'
+	`
+
+	var total int
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// CountActiveByUserIDAndTier counts the database instances of a user's projects on a
+// resource tier that are not deleted or failed
+func (r *DatabaseInstanceRepository) CountActiveByUserIDAndTier(userID uuid.UUID, tier string) (int, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT COUNT(*)
+		FROM database_instances di
+		JOIN projects p ON p.id = di.project_id
+		WHERE p.user_id = $1 AND p.resource_tier = $2 AND di.status NOT IN ('deleted', 'failed')
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, userID, tier).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (r *DatabaseInstanceRepository) Delete(id uuid.UUID) error {
 	ctx := context.Background()
 
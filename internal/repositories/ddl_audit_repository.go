@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DDLAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDDLAuditRepository(pool *pgxpool.Pool) *DDLAuditRepository {
+	return &DDLAuditRepository{pool: pool}
+}
+
+func (r *DDLAuditRepository) Create(entry *models.DDLAudit) error {
+	ctx := context.Background()
+
+	entry.Prepare()
+
+	query := `
+		INSERT INTO ddl_audit (id, user_id, project_id, operation, target, sql_text, success, error_message, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		entry.ID,
+		entry.UserID,
+		entry.ProjectID,
+		entry.Operation,
+		entry.Target,
+		entry.SQLText,
+		entry.Success,
+		entry.ErrorMessage,
+		entry.CreatedAt,
+	)
+
+	return err
+}
+
+func (r *DDLAuditRepository) GetByProjectID(projectID uuid.UUID, limit int) ([]models.DDLAudit, error) {
+	ctx := context.Background()
+
+	if limit <= 0 {
+		limit = 100 // Default limit
+	}
+
+	query := `
+		SELECT id, user_id, project_id, operation, target, sql_text, success, error_message, created_at
+		FROM ddl_audit WHERE project_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	return r.query(ctx, query, projectID, limit)
+}
+
+func (r *DDLAuditRepository) GetAll(limit int) ([]models.DDLAudit, error) {
+	ctx := context.Background()
+
+	if limit <= 0 {
+		limit = 100 // Default limit
+	}
+
+	query := `
+		SELECT id, user_id, project_id, operation, target, sql_text, success, error_message, created_at
+		FROM ddl_audit
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+
+	return r.query(ctx, query, limit)
+}
+
+func (r *DDLAuditRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.DDLAudit, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.DDLAudit{}
+	for rows.Next() {
+		var entry models.DDLAudit
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.ProjectID,
+			&entry.Operation,
+			&entry.Target,
+			&entry.SQLText,
+			&entry.Success,
+			&entry.ErrorMessage,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
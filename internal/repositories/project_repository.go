@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"backend/internal/models"
+	"backend/internal/pagination"
 	"context"
 	"errors"
 	"time"
@@ -19,35 +20,55 @@ func NewProjectRepository(pool *pgxpool.Pool) *ProjectRepository {
 	return &ProjectRepository{pool: pool}
 }
 
+// WithTx runs fn inside a transaction on the control-plane database
+func (r *ProjectRepository) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return WithTx(ctx, r.pool, fn)
+}
+
 func (r *ProjectRepository) Create(project *models.Project) error {
-	ctx := context.Background()
+	return r.create(context.Background(), r.pool, project)
+}
 
+// CreateTx inserts a project as part of the caller's transaction
+func (r *ProjectRepository) CreateTx(ctx context.Context, tx pgx.Tx, project *models.Project) error {
+	return r.create(ctx, tx, project)
+}
+
+func (r *ProjectRepository) create(ctx context.Context, db execer, project *models.Project) error {
 	project.Prepare()
 
 	query := `
-		INSERT INTO projects (id, user_id, name, description, db_type, resource_tier, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO projects (id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
 	`
 
 	now := time.Now()
-	_, err := r.pool.Exec(ctx, query,
+	_, err := db.Exec(ctx, query,
 		project.ID,
 		project.UserID,
 		project.Name,
 		project.Description,
 		project.DBType,
 		project.ResourceTier,
+		project.ReadOnly,
+		project.BlockedKeywords,
+		project.RedactQueryText,
 		now,
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	project.CreatedAt = now
+	project.UpdatedAt = now
+	return nil
 }
 
 func (r *ProjectRepository) GetByID(id uuid.UUID) (*models.Project, error) {
 	ctx := context.Background()
 
 	query := `
-		SELECT id, user_id, name, description, db_type, resource_tier, created_at
+		SELECT id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at
 		FROM projects WHERE id = $1
 	`
 
@@ -59,7 +80,11 @@ func (r *ProjectRepository) GetByID(id uuid.UUID) (*models.Project, error) {
 		&project.Description,
 		&project.DBType,
 		&project.ResourceTier,
+		&project.ReadOnly,
+		&project.BlockedKeywords,
+		&project.RedactQueryText,
 		&project.CreatedAt,
+		&project.UpdatedAt,
 	)
 
 	if err != nil {
@@ -76,7 +101,7 @@ func (r *ProjectRepository) GetByIDAndUserID(id uuid.UUID, userID uuid.UUID) (*m
 	ctx := context.Background()
 
 	query := `
-		SELECT id, user_id, name, description, db_type, resource_tier, created_at
+		SELECT id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at
 		FROM projects WHERE id = $1 AND user_id = $2
 	`
 
@@ -88,7 +113,11 @@ func (r *ProjectRepository) GetByIDAndUserID(id uuid.UUID, userID uuid.UUID) (*m
 		&project.Description,
 		&project.DBType,
 		&project.ResourceTier,
+		&project.ReadOnly,
+		&project.BlockedKeywords,
+		&project.RedactQueryText,
 		&project.CreatedAt,
+		&project.UpdatedAt,
 	)
 
 	if err != nil {
@@ -105,7 +134,7 @@ func (r *ProjectRepository) GetByUserID(userID uuid.UUID) ([]models.Project, err
 	ctx := context.Background()
 
 	query := `
-		SELECT id, user_id, name, description, db_type, resource_tier, created_at
+		SELECT id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at
 		FROM projects WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
@@ -126,7 +155,11 @@ func (r *ProjectRepository) GetByUserID(userID uuid.UUID) ([]models.Project, err
 			&project.Description,
 			&project.DBType,
 			&project.ResourceTier,
+			&project.ReadOnly,
+			&project.BlockedKeywords,
+			&project.RedactQueryText,
 			&project.CreatedAt,
+			&project.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -137,22 +170,96 @@ func (r *ProjectRepository) GetByUserID(userID uuid.UUID) ([]models.Project, err
 	return projects, rows.Err()
 }
 
+// GetPageByUserID returns up to limit projects of a user, newest first, starting after the cursor when one is given
+func (r *ProjectRepository) GetPageByUserID(userID uuid.UUID, limit int, after *pagination.Cursor) ([]models.Project, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at
+		FROM projects WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	args := []interface{}{userID, limit}
+	if after != nil {
+		query = `
+			SELECT id, user_id, name, description, db_type, resource_tier, read_only, blocked_keywords, redact_query_text, created_at, updated_at
+			FROM projects WHERE user_id = $1 AND (created_at, id) < ($3, $4)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		`
+		args = append(args, after.Time, after.ID)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []models.Project
+	for rows.Next() {
+		var project models.Project
+		err := rows.Scan(
+			&project.ID,
+			&project.UserID,
+			&project.Name,
+			&project.Description,
+			&project.DBType,
+			&project.ResourceTier,
+			&project.ReadOnly,
+			&project.BlockedKeywords,
+			&project.RedactQueryText,
+			&project.CreatedAt,
+			&project.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// CountByUserID returns the number of projects owned by a user
+func (r *ProjectRepository) CountByUserID(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+
+	query := `SELECT COUNT(*) FROM projects WHERE user_id = $1`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (r *ProjectRepository) Update(project *models.Project) error {
 	ctx := context.Background()
 
 	query := `
 		UPDATE projects SET
-			name = $2, description = $3, db_type = $4, resource_tier = $5
+			name = $2, description = $3, db_type = $4, resource_tier = $5,
+			read_only = $6, blocked_keywords = $7, redact_query_text = $8, updated_at = NOW()
 		WHERE id = $1
+		RETURNING updated_at
 	`
 
-	_, err := r.pool.Exec(ctx, query,
+	err := r.pool.QueryRow(ctx, query,
 		project.ID,
 		project.Name,
 		project.Description,
 		project.DBType,
 		project.ResourceTier,
-	)
+		project.ReadOnly,
+		project.BlockedKeywords,
+		project.RedactQueryText,
+	).Scan(&project.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("project not found")
+	}
 
 	return err
 }
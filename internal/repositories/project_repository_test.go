@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/testdb"
+)
+
+// A project without a description has a NULL description, which every read must accept
+func TestProjectWithoutDescriptionIsReadBack(t *testing.T) {
+	pool := testdb.Pool(t)
+	projects := NewProjectRepository(pool)
+
+	user := &models.User{Email: "no-description@example.com", PasswordHash: "hash"}
+	if err := NewUserRepository(pool).Create(user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	project := &models.Project{UserID: user.ID, Name: "plain", DBType: "postgres", ResourceTier: "basic"}
+	if err := projects.Create(project); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	byID, err := projects.GetByID(project.ID)
+	if err != nil || byID == nil {
+		t.Fatalf("GetByID = %v, %v", byID, err)
+	}
+	if byID.Description != nil {
+		t.Errorf("GetByID Description = %q, want nil", *byID.Description)
+	}
+
+	owned, err := projects.GetByIDAndUserID(project.ID, user.ID)
+	if err != nil || owned == nil {
+		t.Fatalf("GetByIDAndUserID = %v, %v", owned, err)
+	}
+	if owned.Description != nil {
+		t.Errorf("GetByIDAndUserID Description = %q, want nil", *owned.Description)
+	}
+
+	all, err := projects.GetByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != project.ID || all[0].Description != nil {
+		t.Errorf("GetByUserID = %+v, want only %s with a nil Description", all, project.ID)
+	}
+
+	page, err := projects.GetPageByUserID(user.ID, 10, nil)
+	if err != nil {
+		t.Fatalf("GetPageByUserID: %v", err)
+	}
+	if len(page) != 1 || page[0].Description != nil {
+		t.Errorf("GetPageByUserID = %+v, want one project with a nil Description", page)
+	}
+
+	// Setting and clearing the description round-trips too
+	description := "orders"
+	owned.Description = &description
+	if err := projects.Update(owned); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if byID, err = projects.GetByID(project.ID); err != nil || byID.Description == nil || *byID.Description != description {
+		t.Fatalf("GetByID after setting the description = %+v, %v", byID, err)
+	}
+	owned.Description = nil
+	if err := projects.Update(owned); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if byID, err = projects.GetByID(project.ID); err != nil || byID.Description != nil {
+		t.Errorf("GetByID after clearing the description = %+v, %v, want a nil Description", byID, err)
+	}
+}
@@ -2,12 +2,19 @@ package repositories
 
 import (
 	"backend/internal/models"
+	"backend/internal/pagination"
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrDuplicateHistoryID is returned by Create when an entry with the same ID exists
+var ErrDuplicateHistoryID = errors.New("a query history entry with this ID already exists")
+
 type QueryHistoryRepository struct {
 	pool *pgxpool.Pool
 }
@@ -22,8 +29,8 @@ func (r *QueryHistoryRepository) Create(queryHistory *models.QueryHistory) error
 	queryHistory.Prepare()
 
 	query := `
-		INSERT INTO query_history (id, db_instance_id, user_id, query_text, executed_at, success, execution_time_ms)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO query_history (id, db_instance_id, user_id, query_text, statement_type, executed_at, success, execution_time_ms, error_message, rows_affected)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 
 	_, err := r.pool.Exec(ctx, query,
@@ -31,15 +38,32 @@ func (r *QueryHistoryRepository) Create(queryHistory *models.QueryHistory) error
 		queryHistory.DBInstanceID,
 		queryHistory.UserID,
 		queryHistory.QueryText,
+		queryHistory.StatementType,
 		queryHistory.ExecutedAt,
 		queryHistory.Success,
 		queryHistory.ExecutionTimeMs,
+		queryHistory.ErrorMessage,
+		queryHistory.RowsAffected,
 	)
 
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateHistoryID
+	}
 	return err
 }
 
-func (r *QueryHistoryRepository) GetByUserID(userID uuid.UUID, limit int) ([]models.QueryHistory, error) {
+// Exists reports whether an entry with the given ID exists, whoever it belongs to
+func (r *QueryHistoryRepository) Exists(id uuid.UUID) (bool, error) {
+	ctx := context.Background()
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM query_history WHERE id = $1)`, id).Scan(&exists)
+	return exists, err
+}
+
+// GetByUserID returns up to limit entries for a user, newest first, starting after the cursor when one is given
+func (r *QueryHistoryRepository) GetByUserID(userID uuid.UUID, limit int, after *pagination.Cursor) ([]models.QueryHistory, error) {
 	ctx := context.Background()
 
 	if limit <= 0 {
@@ -47,13 +71,23 @@ func (r *QueryHistoryRepository) GetByUserID(userID uuid.UUID, limit int) ([]mod
 	}
 
 	query := `
-		SELECT id, db_instance_id, user_id, query_text, executed_at, success, execution_time_ms
+		SELECT id, db_instance_id, user_id, query_text, statement_type, executed_at, success, execution_time_ms, error_message, rows_affected
 		FROM query_history WHERE user_id = $1
-		ORDER BY executed_at DESC
+		ORDER BY executed_at DESC, id DESC
 		LIMIT $2
 	`
+	args := []interface{}{userID, limit}
+	if after != nil {
+		query = `
+			SELECT id, db_instance_id, user_id, query_text, statement_type, executed_at, success, execution_time_ms, error_message, rows_affected
+			FROM query_history WHERE user_id = $1 AND (executed_at, id) < ($3, $4)
+			ORDER BY executed_at DESC, id DESC
+			LIMIT $2
+		`
+		args = append(args, after.Time, after.ID)
+	}
 
-	rows, err := r.pool.Query(ctx, query, userID, limit)
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +101,12 @@ func (r *QueryHistoryRepository) GetByUserID(userID uuid.UUID, limit int) ([]mod
 			&qh.DBInstanceID,
 			&qh.UserID,
 			&qh.QueryText,
+			&qh.StatementType,
 			&qh.ExecutedAt,
 			&qh.Success,
 			&qh.ExecutionTimeMs,
+			&qh.ErrorMessage,
+			&qh.RowsAffected,
 		)
 		if err != nil {
 			return nil, err
@@ -79,3 +116,112 @@ func (r *QueryHistoryRepository) GetByUserID(userID uuid.UUID, limit int) ([]mod
 
 	return queries, rows.Err()
 }
+
+// GetAllByUserID returns every query history entry for a user, newest first
+func (r *QueryHistoryRepository) GetAllByUserID(userID uuid.UUID) ([]models.QueryHistory, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, db_instance_id, user_id, query_text, statement_type, executed_at, success, execution_time_ms, error_message, rows_affected
+		FROM query_history WHERE user_id = $1
+		ORDER BY executed_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []models.QueryHistory
+	for rows.Next() {
+		var qh models.QueryHistory
+		err := rows.Scan(
+			&qh.ID,
+			&qh.DBInstanceID,
+			&qh.UserID,
+			&qh.QueryText,
+			&qh.StatementType,
+			&qh.ExecutedAt,
+			&qh.Success,
+			&qh.ExecutionTimeMs,
+			&qh.ErrorMessage,
+			&qh.RowsAffected,
+		)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, qh)
+	}
+
+	return queries, rows.Err()
+}
+
+// GetByID returns a query history entry if it belongs to the given user
+func (r *QueryHistoryRepository) GetByID(id uuid.UUID, userID uuid.UUID) (*models.QueryHistory, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, db_instance_id, user_id, query_text, statement_type, executed_at, success, execution_time_ms, error_message, rows_affected
+		FROM query_history WHERE id = $1 AND user_id = $2
+	`
+
+	var qh models.QueryHistory
+	err := r.pool.QueryRow(ctx, query, id, userID).Scan(
+		&qh.ID,
+		&qh.DBInstanceID,
+		&qh.UserID,
+		&qh.QueryText,
+		&qh.StatementType,
+		&qh.ExecutedAt,
+		&qh.Success,
+		&qh.ExecutionTimeMs,
+		&qh.ErrorMessage,
+		&qh.RowsAffected,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &qh, nil
+}
+
+// GetByIDAndProjectID returns a query history entry if it belongs to the given user and
+// was run on one of the project's database instances
+func (r *QueryHistoryRepository) GetByIDAndProjectID(id uuid.UUID, userID uuid.UUID, projectID uuid.UUID) (*models.QueryHistory, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT qh.id, qh.db_instance_id, qh.user_id, qh.query_text, qh.statement_type, qh.executed_at, qh.success, qh.execution_time_ms, qh.error_message, qh.rows_affected
+		FROM query_history qh
+		JOIN database_instances di ON di.id = qh.db_instance_id
+		WHERE qh.id = $1 AND qh.user_id = $2 AND di.project_id = $3
+	`
+
+	var qh models.QueryHistory
+	err := r.pool.QueryRow(ctx, query, id, userID, projectID).Scan(
+		&qh.ID,
+		&qh.DBInstanceID,
+		&qh.UserID,
+		&qh.QueryText,
+		&qh.StatementType,
+		&qh.ExecutedAt,
+		&qh.Success,
+		&qh.ExecutionTimeMs,
+		&qh.ErrorMessage,
+		&qh.RowsAffected,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &qh, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/testdb"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryHistoryGetByIDIsScopedToOwner(t *testing.T) {
+	pool := testdb.Pool(t)
+	users := NewUserRepository(pool)
+	history := NewQueryHistoryRepository(pool)
+
+	owner := &models.User{Email: "owner@example.com", PasswordHash: "hash"}
+	other := &models.User{Email: "other@example.com", PasswordHash: "hash"}
+	for _, user := range []*models.User{owner, other} {
+		if err := users.Create(user); err != nil {
+			t.Fatalf("Create user: %v", err)
+		}
+	}
+	project := &models.Project{UserID: owner.ID, Name: "history", DBType: "postgres", ResourceTier: "basic"}
+	if err := NewProjectRepository(pool).Create(project); err != nil {
+		t.Fatalf("Create project: %v", err)
+	}
+	instance := &models.DatabaseInstance{ProjectID: project.ID, Port: 5432}
+	if err := NewDatabaseInstanceRepository(pool).Create(instance); err != nil {
+		t.Fatalf("Create instance: %v", err)
+	}
+
+	success := false
+	message := `relation "missing" does not exist`
+	rowsAffected := int64(0)
+	entry := &models.QueryHistory{
+		DBInstanceID: instance.ID,
+		UserID:       owner.ID,
+		QueryText:    "SELECT * FROM missing",
+		Success:      &success,
+		ErrorMessage: &message,
+		RowsAffected: &rowsAffected,
+	}
+	if err := history.Create(entry); err != nil {
+		t.Fatalf("Create history entry: %v", err)
+	}
+
+	got, err := history.GetByID(entry.ID, owner.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetByID as owner = %v, %v", got, err)
+	}
+	if got.QueryText != entry.QueryText || got.ErrorMessage == nil || *got.ErrorMessage != message ||
+		got.RowsAffected == nil || *got.RowsAffected != 0 || got.Success == nil || *got.Success {
+		t.Errorf("GetByID as owner = %+v, want the stored entry with its error and rows affected", got)
+	}
+
+	if got, err := history.GetByID(entry.ID, other.ID); err != nil || got != nil {
+		t.Errorf("GetByID as another user = %+v, %v, want nil", got, err)
+	}
+	if got, err := history.GetByID(uuid.New(), owner.ID); err != nil || got != nil {
+		t.Errorf("GetByID of a missing entry = %+v, %v, want nil", got, err)
+	}
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type QueryPlanSnapshotRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewQueryPlanSnapshotRepository(pool *pgxpool.Pool) *QueryPlanSnapshotRepository {
+	return &QueryPlanSnapshotRepository{pool: pool}
+}
+
+// Save stores a snapshot, replacing any existing snapshot with the same name in the project
+func (r *QueryPlanSnapshotRepository) Save(snapshot *models.QueryPlanSnapshot) error {
+	ctx := context.Background()
+
+	snapshot.Prepare()
+
+	query := `
+		INSERT INTO query_plan_snapshots (id, project_id, user_id, name, query_text, plan, total_cost, plan_rows, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (project_id, name) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			query_text = EXCLUDED.query_text,
+			plan = EXCLUDED.plan,
+			total_cost = EXCLUDED.total_cost,
+			plan_rows = EXCLUDED.plan_rows,
+			created_at = EXCLUDED.created_at
+		RETURNING id
+	`
+
+	return r.pool.QueryRow(ctx, query,
+		snapshot.ID,
+		snapshot.ProjectID,
+		snapshot.UserID,
+		snapshot.Name,
+		snapshot.QueryText,
+		snapshot.Plan,
+		snapshot.TotalCost,
+		snapshot.PlanRows,
+		snapshot.CreatedAt,
+	).Scan(&snapshot.ID)
+}
+
+func (r *QueryPlanSnapshotRepository) GetByProjectIDAndName(projectID uuid.UUID, name string) (*models.QueryPlanSnapshot, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, project_id, user_id, name, query_text, plan, total_cost, plan_rows, created_at
+		FROM query_plan_snapshots WHERE project_id = $1 AND name = $2
+	`
+
+	var snapshot models.QueryPlanSnapshot
+	err := r.pool.QueryRow(ctx, query, projectID, name).Scan(
+		&snapshot.ID,
+		&snapshot.ProjectID,
+		&snapshot.UserID,
+		&snapshot.Name,
+		&snapshot.QueryText,
+		&snapshot.Plan,
+		&snapshot.TotalCost,
+		&snapshot.PlanRows,
+		&snapshot.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func (r *QueryPlanSnapshotRepository) GetByProjectID(projectID uuid.UUID) ([]models.QueryPlanSnapshot, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, project_id, user_id, name, query_text, plan, total_cost, plan_rows, created_at
+		FROM query_plan_snapshots WHERE project_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []models.QueryPlanSnapshot{}
+	for rows.Next() {
+		var snapshot models.QueryPlanSnapshot
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.ProjectID,
+			&snapshot.UserID,
+			&snapshot.Name,
+			&snapshot.QueryText,
+			&snapshot.Plan,
+			&snapshot.TotalCost,
+			&snapshot.PlanRows,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SavedQueryRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSavedQueryRepository(pool *pgxpool.Pool) *SavedQueryRepository {
+	return &SavedQueryRepository{pool: pool}
+}
+
+func (r *SavedQueryRepository) Create(savedQuery *models.SavedQuery) error {
+	ctx := context.Background()
+
+	savedQuery.Prepare()
+
+	query := `
+		INSERT INTO saved_queries (id, user_id, project_id, name, query_text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		savedQuery.ID,
+		savedQuery.UserID,
+		savedQuery.ProjectID,
+		savedQuery.Name,
+		savedQuery.QueryText,
+		savedQuery.CreatedAt,
+	)
+
+	return err
+}
+
+// GetByUserAndProject returns a user's saved queries of a project, sorted by name
+func (r *SavedQueryRepository) GetByUserAndProject(userID uuid.UUID, projectID uuid.UUID) ([]models.SavedQuery, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, user_id, project_id, name, query_text, created_at
+		FROM saved_queries WHERE user_id = $1 AND project_id = $2
+		ORDER BY name
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	savedQueries := []models.SavedQuery{}
+	for rows.Next() {
+		var sq models.SavedQuery
+		err := rows.Scan(
+			&sq.ID,
+			&sq.UserID,
+			&sq.ProjectID,
+			&sq.Name,
+			&sq.QueryText,
+			&sq.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		savedQueries = append(savedQueries, sq)
+	}
+
+	return savedQueries, rows.Err()
+}
+
+// GetByID returns a saved query if it belongs to the given user and project
+func (r *SavedQueryRepository) GetByID(id uuid.UUID, userID uuid.UUID, projectID uuid.UUID) (*models.SavedQuery, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, user_id, project_id, name, query_text, created_at
+		FROM saved_queries WHERE id = $1 AND user_id = $2 AND project_id = $3
+	`
+
+	var sq models.SavedQuery
+	err := r.pool.QueryRow(ctx, query, id, userID, projectID).Scan(
+		&sq.ID,
+		&sq.UserID,
+		&sq.ProjectID,
+		&sq.Name,
+		&sq.QueryText,
+		&sq.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &sq, nil
+}
+
+// NameExists reports whether the user already saved a query of the project under name
+func (r *SavedQueryRepository) NameExists(userID uuid.UUID, projectID uuid.UUID, name string) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM saved_queries WHERE user_id = $1 AND project_id = $2 AND name = $3
+		)
+	`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, query, userID, projectID, name).Scan(&exists)
+	return exists, err
+}
+
+// Rename renames a saved query of the user and project, reporting whether it was found
+func (r *SavedQueryRepository) Rename(id uuid.UUID, userID uuid.UUID, projectID uuid.UUID, name string) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+		UPDATE saved_queries SET name = $4
+		WHERE id = $1 AND user_id = $2 AND project_id = $3
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, userID, projectID, name)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Delete deletes a saved query of the user and project, reporting whether it was found
+func (r *SavedQueryRepository) Delete(id uuid.UUID, userID uuid.UUID, projectID uuid.UUID) (bool, error) {
+	ctx := context.Background()
+
+	query := `DELETE FROM saved_queries WHERE id = $1 AND user_id = $2 AND project_id = $3`
+
+	tag, err := r.pool.Exec(ctx, query, id, userID, projectID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -218,3 +218,411 @@ func (r *SchemaRepository) GetUniqueConstraintsBatch(ctx context.Context, schema
 
 	return uniqueMap, nil
 }
+
+// GetReferencingForeignKeys returns foreign keys in any schema that point at the given table
+func (r *SchemaRepository) GetReferencingForeignKeys(ctx context.Context, schema, table string) ([]models.DependentObject, error) {
+	query := `
+		SELECT 
+			con.conname,
+			src_ns.nspname,
+			src.relname,
+			pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class src ON src.oid = con.conrelid
+		JOIN pg_namespace src_ns ON src_ns.oid = src.relnamespace
+		JOIN pg_class tgt ON tgt.oid = con.confrelid
+		JOIN pg_namespace tgt_ns ON tgt_ns.oid = tgt.relnamespace
+		WHERE con.contype = 'f'
+			AND tgt_ns.nspname = $1
+			AND tgt.relname = $2
+		ORDER BY src_ns.nspname, src.relname, con.conname
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fks := []models.DependentObject{}
+	for rows.Next() {
+		var fk models.DependentObject
+		if err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Definition); err != nil {
+			return nil, err
+		}
+		fks = append(fks, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fks, nil
+}
+
+// GetDependentViews returns views and materialized views whose definitions reference the given table
+func (r *SchemaRepository) GetDependentViews(ctx context.Context, schema, table string) ([]models.DependentObject, error) {
+	query := `
+		SELECT DISTINCT
+			v_ns.nspname,
+			v.relname,
+			CASE v.relkind WHEN 'm' THEN 'materialized view' ELSE 'view' END
+		FROM pg_depend d
+		JOIN pg_rewrite rw ON rw.oid = d.objid
+		JOIN pg_class v ON v.oid = rw.ev_class
+		JOIN pg_namespace v_ns ON v_ns.oid = v.relnamespace
+		JOIN pg_class t ON t.oid = d.refobjid
+		JOIN pg_namespace t_ns ON t_ns.oid = t.relnamespace
+		WHERE d.classid = 'pg_rewrite'::regclass
+			AND d.refclassid = 'pg_class'::regclass
+			AND t_ns.nspname = $1
+			AND t.relname = $2
+			AND v.oid <> t.oid
+		ORDER BY 1, 2
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []models.DependentObject{}
+	for rows.Next() {
+		var view models.DependentObject
+		if err := rows.Scan(&view.Schema, &view.Name, &view.Kind); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+// GetDependentFunctions returns user functions that reference the given table.
+// pg_depend only tracks SQL-standard function bodies, so function sources are
+// also searched for the table name.
+func (r *SchemaRepository) GetDependentFunctions(ctx context.Context, schema, table string) ([]models.DependentObject, error) {
+	query := `
+		SELECT DISTINCT
+			n.nspname,
+			p.proname,
+			p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')'
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND (
+				p.oid IN (
+					SELECT d.objid
+					FROM pg_depend d
+					JOIN pg_class t ON t.oid = d.refobjid
+					JOIN pg_namespace t_ns ON t_ns.oid = t.relnamespace
+					WHERE d.classid = 'pg_proc'::regclass
+						AND d.refclassid = 'pg_class'::regclass
+						AND t_ns.nspname = $1
+						AND t.relname = $2
+				)
+				OR p.prosrc ~* ('\m' || $2 || '\M')
+			)
+		ORDER BY 1, 2
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	functions := []models.DependentObject{}
+	for rows.Next() {
+		var fn models.DependentObject
+		if err := rows.Scan(&fn.Schema, &fn.Name, &fn.Definition); err != nil {
+			return nil, err
+		}
+		functions = append(functions, fn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return functions, nil
+}
+
+// CountRows returns the exact number of rows in a table
+func (r *SchemaRepository) CountRows(ctx context.Context, schema, table string) (int64, error) {
+	var count int64
+	if err := r.pool.QueryRow(ctx, CountRowsQuery(schema, table)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows of %s: %w", table, err)
+	}
+	return count, nil
+}
+
+// GetTableStats returns the activity statistics of every user table in the schema
+func (r *SchemaRepository) GetTableStats(ctx context.Context, schema string) ([]models.TableStats, error) {
+	query := `
+		SELECT 
+			relname,
+			n_live_tup,
+			n_dead_tup,
+			seq_scan,
+			COALESCE(idx_scan, 0),
+			n_mod_since_analyze,
+			last_vacuum,
+			last_autovacuum,
+			last_analyze,
+			last_autoanalyze
+		FROM pg_stat_user_tables
+		WHERE schemaname = $1
+		ORDER BY relname
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.TableStats{}
+	for rows.Next() {
+		var stat models.TableStats
+		err := rows.Scan(
+			&stat.Table,
+			&stat.LiveTuples,
+			&stat.DeadTuples,
+			&stat.SeqScans,
+			&stat.IndexScans,
+			&stat.ModsSinceAnalyze,
+			&stat.LastVacuum,
+			&stat.LastAutovacuum,
+			&stat.LastAnalyze,
+			&stat.LastAutoanalyze,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetSchemaColumns returns the columns of every table in the schema, keyed by table name, in column order
+func (r *SchemaRepository) GetSchemaColumns(ctx context.Context, schema string) (map[string][]models.IntrospectedColumn, error) {
+	query := `
+		SELECT
+			c.relname,
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			NOT a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid),
+			CASE a.attidentity WHEN 'a' THEN 'ALWAYS' WHEN 'd' THEN 'BY DEFAULT' END
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = $1
+			AND c.relkind IN ('r', 'p')
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]models.IntrospectedColumn)
+	for rows.Next() {
+		var table string
+		var col models.IntrospectedColumn
+		if err := rows.Scan(&table, &col.Name, &col.DataType, &col.Nullable, &col.Default, &col.Identity); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns[table] = append(columns[table], col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// SchemaConstraint is a primary key, unique, foreign key or check constraint of a table.
+// Referenced fields are only set for foreign keys, Definition only for checks.
+type SchemaConstraint struct {
+	Table             string
+	Name              string
+	Type              string // "PRIMARY KEY", "UNIQUE", "FOREIGN KEY" or "CHECK"
+	Columns           []string
+	ReferencedSchema  *string
+	ReferencedTable   *string
+	ReferencedColumns []string
+	OnUpdate          *string
+	OnDelete          *string
+	Definition        *string
+}
+
+// GetSchemaConstraints returns the primary key, unique, foreign key and check constraints of every table in the schema
+func (r *SchemaRepository) GetSchemaConstraints(ctx context.Context, schema string) ([]SchemaConstraint, error) {
+	query := `
+		SELECT
+			c.relname,
+			con.conname,
+			CASE con.contype WHEN 'p' THEN 'PRIMARY KEY' WHEN 'u' THEN 'UNIQUE' WHEN 'c' THEN 'CHECK' ELSE 'FOREIGN KEY' END,
+			ARRAY(
+				SELECT a.attname
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			fn.nspname,
+			fc.relname,
+			ARRAY(
+				SELECT a.attname
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			CASE con.confupdtype WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' END,
+			CASE con.confdeltype WHEN 'a' THEN 'NO ACTION' WHEN 'r' THEN 'RESTRICT' WHEN 'c' THEN 'CASCADE'
+				WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' END,
+			CASE con.contype WHEN 'c' THEN pg_get_constraintdef(con.oid) END
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_class fc ON fc.oid = con.confrelid
+		LEFT JOIN pg_namespace fn ON fn.oid = fc.relnamespace
+		WHERE n.nspname = $1
+			AND con.contype IN ('p', 'u', 'f', 'c')
+		ORDER BY c.relname, con.conname
+	`
+
+	rows, err := r.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query constraints: %w", err)
+	}
+	defer rows.Close()
+
+	var constraints []SchemaConstraint
+	for rows.Next() {
+		var con SchemaConstraint
+		if err := rows.Scan(
+			&con.Table,
+			&con.Name,
+			&con.Type,
+			&con.Columns,
+			&con.ReferencedSchema,
+			&con.ReferencedTable,
+			&con.ReferencedColumns,
+			&con.OnUpdate,
+			&con.OnDelete,
+			&con.Definition,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan constraint: %w", err)
+		}
+		constraints = append(constraints, con)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating constraints: %w", err)
+	}
+
+	return constraints, nil
+}
+
+// indexesQuery lists the indexes of the tables in schema $1, restricted to table $2 unless it is NULL
+const indexesQuery = `
+	SELECT
+		t.relname,
+		i.relname,
+		ARRAY(
+			SELECT a.attname
+			FROM unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = k.attnum
+			ORDER BY k.ord
+		),
+		ix.indisunique,
+		ix.indisprimary,
+		pg_get_expr(ix.indexprs, ix.indrelid),
+		pg_get_expr(ix.indpred, ix.indrelid),
+		pg_get_indexdef(ix.indexrelid)
+	FROM pg_index ix
+	JOIN pg_class t ON t.oid = ix.indrelid
+	JOIN pg_class i ON i.oid = ix.indexrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	WHERE n.nspname = $1
+		AND t.relkind IN ('r', 'p')
+		AND ($2::text IS NULL OR t.relname = $2)
+	ORDER BY t.relname, i.relname
+`
+
+// GetSchemaIndexes returns the indexes of every table in the schema, keyed by table name
+func (r *SchemaRepository) GetSchemaIndexes(ctx context.Context, schema string) (map[string][]models.IntrospectedIndex, error) {
+	return r.queryIndexes(ctx, schema, nil)
+}
+
+// GetTableIndexes returns the indexes of a single table
+func (r *SchemaRepository) GetTableIndexes(ctx context.Context, schema, table string) ([]models.IntrospectedIndex, error) {
+	indexes, err := r.queryIndexes(ctx, schema, &table)
+	if err != nil {
+		return nil, err
+	}
+	if indexes[table] == nil {
+		return []models.IntrospectedIndex{}, nil
+	}
+	return indexes[table], nil
+}
+
+func (r *SchemaRepository) queryIndexes(ctx context.Context, schema string, table *string) (map[string][]models.IntrospectedIndex, error) {
+	rows, err := r.pool.Query(ctx, indexesQuery, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]models.IntrospectedIndex)
+	for rows.Next() {
+		var tableName string
+		var idx models.IntrospectedIndex
+		if err := rows.Scan(&tableName, &idx.Name, &idx.Columns, &idx.Unique, &idx.Primary, &idx.Expressions, &idx.Predicate, &idx.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		indexes[tableName] = append(indexes[tableName], idx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// TableExists reports whether a table or partitioned table exists in the schema
+func (r *SchemaRepository) TableExists(ctx context.Context, schema, table string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2 AND c.relkind IN ('r', 'p')
+		)
+	`
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, schema, table).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check table existence: %w", err)
+	}
+	return exists, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -24,8 +25,8 @@ func (r *SessionRepository) Create(session *models.Session) error {
 	session.Prepare()
 
 	query := `
-		INSERT INTO sessions (id, user_id, refresh_token, is_revoked, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO sessions (id, user_id, refresh_token, is_revoked, created_at, expires_at, jti, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	_, err := r.pool.Exec(ctx, query,
@@ -35,27 +36,60 @@ func (r *SessionRepository) Create(session *models.Session) error {
 		session.IsRevoked,
 		time.Now(),
 		session.ExpiresAt,
+		session.JTI,
+		session.UserAgent,
+		session.IPAddress,
 	)
 
 	return err
 }
 
+const sessionColumns = `id, user_id, refresh_token, is_revoked, created_at, expires_at, jti, consumed_at, user_agent, ip_address`
+
 func (r *SessionRepository) FindByToken(token string) (*models.Session, error) {
+	return r.findOne(`SELECT `+sessionColumns+`
+		FROM sessions WHERE refresh_token = $1`, token)
+}
+
+// FindByJTI returns the session of the refresh token with the given jti, or nil if there is none
+func (r *SessionRepository) FindByJTI(jti string) (*models.Session, error) {
+	return r.findOne(`SELECT `+sessionColumns+`
+		FROM sessions WHERE jti = $1`, jti)
+}
+
+// FindActiveByUserID returns the sessions of a user that are neither revoked nor expired,
+// newest first
+func (r *SessionRepository) FindActiveByUserID(userID uuid.UUID) ([]models.Session, error) {
 	ctx := context.Background()
 
-	query := `SELECT id, user_id, refresh_token, is_revoked, created_at, expires_at
-		FROM sessions WHERE refresh_token = $1`
+	query := `SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND NOT is_revoked AND expires_at > NOW()
+		ORDER BY created_at DESC`
 
-	var session models.Session
-	err := r.pool.QueryRow(ctx, query, token).Scan(
-		&session.ID,
-		&session.UserID,
-		&session.RefreshToken,
-		&session.IsRevoked,
-		&session.CreatedAt,
-		&session.ExpiresAt,
-	)
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := scanSession(rows, &session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
 
+func (r *SessionRepository) findOne(query string, args ...interface{}) (*models.Session, error) {
+	ctx := context.Background()
+
+	var session models.Session
+	err := scanSession(r.pool.QueryRow(ctx, query, args...), &session)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -66,6 +100,21 @@ func (r *SessionRepository) FindByToken(token string) (*models.Session, error) {
 	return &session, nil
 }
 
+func scanSession(row pgx.Row, session *models.Session) error {
+	return row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.RefreshToken,
+		&session.IsRevoked,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&session.JTI,
+		&session.ConsumedAt,
+		&session.UserAgent,
+		&session.IPAddress,
+	)
+}
+
 func (r *SessionRepository) Revoke(token string) error {
 	ctx := context.Background()
 
@@ -74,6 +123,47 @@ func (r *SessionRepository) Revoke(token string) error {
 	return err
 }
 
+// RevokeByID revokes one active session of a user. It returns false when the user has
+// no such active session.
+func (r *SessionRepository) RevokeByID(id uuid.UUID, userID uuid.UUID) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+		UPDATE sessions SET is_revoked = true
+		WHERE id = $1 AND user_id = $2 AND NOT is_revoked AND expires_at > NOW()
+	`
+	tag, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Consume marks the active session of a refresh token as rotated. It returns false when
+// the token has no active session, so concurrent callers cannot both rotate the same token.
+func (r *SessionRepository) Consume(jti string) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+		UPDATE sessions SET is_revoked = true, consumed_at = NOW()
+		WHERE jti = $1 AND NOT is_revoked AND expires_at > NOW()
+	`
+	tag, err := r.pool.Exec(ctx, query, jti)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeAllForUser revokes every active session of a user
+func (r *SessionRepository) RevokeAllForUser(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	query := `UPDATE sessions SET is_revoked = true WHERE user_id = $1 AND NOT is_revoked`
+	_, err := r.pool.Exec(ctx, query, userID)
+	return err
+}
+
 func (r *SessionRepository) DeleteExpired() error {
 	ctx := context.Background()
 
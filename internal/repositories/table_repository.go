@@ -1,10 +1,12 @@
 package repositories
 
 import (
+	"backend/internal/models"
 	"database/sql"
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lib/pq"
 )
 
 type TableRepository struct {
@@ -17,9 +19,14 @@ func NewTableRepository(pool *pgxpool.Pool) *TableRepository {
 	}
 }
 
-func (r *TableRepository) Delete(tx *sql.Tx, schema string, table string) (sql.Result, error) {
+// DropTableQuery builds the statement used by Delete to drop a table
+func DropTableQuery(schema string, table string) string {
 	// Use quoted identifiers to prevent SQL injection
-	query := fmt.Sprintf("DROP TABLE \"%s\".\"%s\" CASCADE", schema, table)
+	return fmt.Sprintf("DROP TABLE \"%s\".\"%s\" CASCADE", schema, table)
+}
+
+func (r *TableRepository) Delete(tx *sql.Tx, schema string, table string) (sql.Result, error) {
+	query := DropTableQuery(schema, table)
 
 	result, err := tx.Exec(query)
 	if err != nil {
@@ -29,10 +36,240 @@ func (r *TableRepository) Delete(tx *sql.Tx, schema string, table string) (sql.R
 	return result, nil
 }
 
-// func (r *TableRepository) UpdateTableName(userDb *sql.DB, schema string, oldTable string, newtable string) (sql.Result, error) {
-// 	query := fmt.Sprintf("ALTER TABLE %s.%s RENAME TO %s", schema, oldTable, newtable)
+// CountRowsQuery builds the statement used to count the rows of a table
+func CountRowsQuery(schema string, table string) string {
+	return fmt.Sprintf("SELECT count(*) FROM \"%s\".\"%s\"", schema, table)
+}
+
+// CountRows returns the number of rows currently in a table
+func (r *TableRepository) CountRows(tx *sql.Tx, schema string, table string) (int64, error) {
+	query := CountRowsQuery(schema, table)
 
-// 	result, err := userDb.Exec(query)
-	
-// 	return result, err
-// }
\ No newline at end of file
+	var count int64
+	if err := tx.QueryRow(query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	return count, nil
+}
+
+// TableExists reports whether a table exists in the given schema
+func (r *TableRepository) TableExists(tx *sql.Tx, schema string, table string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.tables
+			WHERE table_schema = $1 AND table_name = $2
+		)
+	`
+
+	var exists bool
+	if err := tx.QueryRow(query, schema, table).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check table existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListTables returns the tables of a schema with their column count and estimated row count.
+// Row counts come from pg_class.reltuples, so no table is scanned.
+func (r *TableRepository) ListTables(tx *sql.Tx, schema string) ([]models.TableSummary, error) {
+	query := `
+		SELECT
+			c.relname,
+			(SELECT count(*) FROM pg_attribute a
+				WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped),
+			CASE WHEN c.reltuples < 0 THEN NULL ELSE c.reltuples::bigint END
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p')
+		ORDER BY c.relname
+	`
+
+	rows, err := tx.Query(query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []models.TableSummary{}
+	for rows.Next() {
+		var table models.TableSummary
+		if err := rows.Scan(&table.Name, &table.ColumnCount, &table.EstimatedRows); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// ColumnKeyInfo reports whether a column exists and whether it alone is covered by
+// a primary key or unique constraint, i.e. whether a foreign key may reference it
+func (r *TableRepository) ColumnKeyInfo(tx *sql.Tx, schema string, table string, column string) (exists bool, unique bool, err error) {
+	query := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = $1 AND table_name = $2 AND column_name = $3
+			),
+			EXISTS (
+				SELECT 1
+				FROM information_schema.table_constraints tc
+				JOIN information_schema.key_column_usage kcu
+					ON kcu.constraint_schema = tc.constraint_schema
+					AND kcu.constraint_name = tc.constraint_name
+				WHERE tc.table_schema = $1 AND tc.table_name = $2
+					AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+				GROUP BY tc.constraint_schema, tc.constraint_name
+				HAVING count(*) = 1 AND bool_and(kcu.column_name = $3)
+			)
+	`
+
+	if err := tx.QueryRow(query, schema, table, column).Scan(&exists, &unique); err != nil {
+		return false, false, fmt.Errorf("failed to inspect column %s: %w", column, err)
+	}
+
+	return exists, unique, nil
+}
+
+// WritableColumns returns the columns of a table in their order, leaving out generated
+// columns, which cannot be written to
+func (r *TableRepository) WritableColumns(tx *sql.Tx, schema string, table string) ([]string, error) {
+	query := `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND is_generated = 'NEVER'
+		ORDER BY ordinal_position
+	`
+
+	rows, err := tx.Query(query, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s.%s: %w", schema, table, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// ColumnsUnique reports whether the given columns together are exactly the columns of a
+// primary key or unique constraint of a table, i.e. whether a composite foreign key may
+// reference them
+func (r *TableRepository) ColumnsUnique(tx *sql.Tx, schema string, table string, columns []string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_constraint con
+			JOIN pg_class tbl ON tbl.oid = con.conrelid
+			JOIN pg_namespace ns ON ns.oid = tbl.relnamespace
+			WHERE ns.nspname = $1 AND tbl.relname = $2
+				AND con.contype IN ('p', 'u')
+				AND (
+					SELECT array_agg(att.attname::text ORDER BY att.attname::text)
+					FROM pg_attribute att
+					WHERE att.attrelid = tbl.oid AND att.attnum = ANY (con.conkey)
+				) = (
+					SELECT array_agg(col ORDER BY col) FROM unnest($3::text[]) AS col
+				)
+		)
+	`
+
+	var unique bool
+	if err := tx.QueryRow(query, schema, table, pq.Array(columns)).Scan(&unique); err != nil {
+		return false, fmt.Errorf("failed to inspect the keys of %s.%s: %w", schema, table, err)
+	}
+
+	return unique, nil
+}
+
+// IndexInfo reports whether an index exists on a table and whether it backs a primary key,
+// unique or exclusion constraint, in which case only dropping the constraint removes it
+func (r *TableRepository) IndexInfo(tx *sql.Tx, schema string, table string, index string) (exists bool, constraint bool, err error) {
+	query := `
+		SELECT
+			EXISTS (
+				SELECT 1 FROM pg_indexes
+				WHERE schemaname = $1 AND tablename = $2 AND indexname = $3
+			),
+			EXISTS (
+				SELECT 1
+				FROM pg_constraint con
+				JOIN pg_class idx ON idx.oid = con.conindid
+				JOIN pg_namespace ns ON ns.oid = idx.relnamespace
+				WHERE ns.nspname = $1 AND idx.relname = $3
+			)
+	`
+
+	if err := tx.QueryRow(query, schema, table, index).Scan(&exists, &constraint); err != nil {
+		return false, false, fmt.Errorf("failed to inspect index %s: %w", index, err)
+	}
+
+	return exists, constraint, nil
+}
+
+// DropIndexQuery builds the statement used by DropIndex to drop an index
+func DropIndexQuery(schema string, index string) string {
+	return fmt.Sprintf("DROP INDEX \"%s\".\"%s\"", schema, index)
+}
+
+// DropIndex drops an index
+func (r *TableRepository) DropIndex(tx *sql.Tx, schema string, index string) (sql.Result, error) {
+	query := DropIndexQuery(schema, index)
+
+	result, err := tx.Exec(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to drop index: %w", err)
+	}
+
+	return result, nil
+}
+
+// RenameTableQuery builds the statement used by UpdateTableName to rename a table
+func RenameTableQuery(schema string, oldTable string, newTable string) string {
+	return fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" RENAME TO \"%s\"", schema, oldTable, newTable)
+}
+
+// UpdateTableName renames a table within its schema
+func (r *TableRepository) UpdateTableName(tx *sql.Tx, schema string, oldTable string, newTable string) (sql.Result, error) {
+	query := RenameTableQuery(schema, oldTable, newTable)
+
+	result, err := tx.Exec(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename table: %w", err)
+	}
+
+	return result, nil
+}
+
+// RenameColumnQuery builds the statement used by UpdateColumnName to rename a column
+func RenameColumnQuery(schema string, table string, oldColumn string, newColumn string) string {
+	return fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" RENAME COLUMN \"%s\" TO \"%s\"", schema, table, oldColumn, newColumn)
+}
+
+// UpdateColumnName renames a column of a table
+func (r *TableRepository) UpdateColumnName(tx *sql.Tx, schema string, table string, oldColumn string, newColumn string) (sql.Result, error) {
+	query := RenameColumnQuery(schema, table, oldColumn, newColumn)
+
+	result, err := tx.Exec(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename column: %w", err)
+	}
+
+	return result, nil
+}
\ No newline at end of file
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/testdb"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A failure after the instance insert rolls back the project too, and a successful
+// transaction commits both rows
+func TestWithTxCommitsProjectAndInstanceTogether(t *testing.T) {
+	pool := testdb.Pool(t)
+	users := NewUserRepository(pool)
+	projects := NewProjectRepository(pool)
+	instances := NewDatabaseInstanceRepository(pool)
+	ctx := context.Background()
+
+	user := &models.User{Email: "tx@example.com", PasswordHash: "hash"}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+
+	create := func(fail error) (*models.Project, error) {
+		project := &models.Project{UserID: user.ID, Name: "tx", DBType: "postgres", ResourceTier: "basic"}
+		err := projects.WithTx(ctx, func(tx pgx.Tx) error {
+			if err := projects.CreateTx(ctx, tx, project); err != nil {
+				return err
+			}
+			instance := &models.DatabaseInstance{ProjectID: project.ID, Status: "creating", Port: 5432}
+			if err := instances.CreateTx(ctx, tx, instance); err != nil {
+				return err
+			}
+			return fail
+		})
+		return project, err
+	}
+
+	injected := errors.New("injected failure")
+	project, err := create(injected)
+	if !errors.Is(err, injected) {
+		t.Fatalf("WithTx = %v, want the injected failure", err)
+	}
+	if stored, err := projects.GetByID(project.ID); err != nil || stored != nil {
+		t.Errorf("GetByID after rollback = %+v, %v, want no project", stored, err)
+	}
+	if instance, err := instances.GetByProjectID(project.ID); err != nil || instance != nil {
+		t.Errorf("GetByProjectID after rollback = %+v, %v, want no instance", instance, err)
+	}
+
+	project, err = create(nil)
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	if stored, err := projects.GetByID(project.ID); err != nil || stored == nil {
+		t.Errorf("GetByID after commit = %v, %v, want the project", stored, err)
+	}
+	if instance, err := instances.GetByProjectID(project.ID); err != nil || instance == nil {
+		t.Errorf("GetByProjectID after commit = %v, %v, want the instance", instance, err)
+	}
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UsageMetricsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUsageMetricsRepository(pool *pgxpool.Pool) *UsageMetricsRepository {
+	return &UsageMetricsRepository{pool: pool}
+}
+
+func (r *UsageMetricsRepository) Create(metric *models.UsageMetric) error {
+	ctx := context.Background()
+
+	metric.Prepare()
+
+	query := `
+		INSERT INTO usage_metrics (id, db_instance_id, timestamp, cpu_percent, ram_percent, storage_used_gb, bandwidth_in_gb, bandwidth_out_gb)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		metric.ID,
+		metric.DBInstanceID,
+		metric.Timestamp,
+		metric.CPUPercent,
+		metric.RAMPercent,
+		metric.StorageUsedGB,
+		metric.BandwidthInGB,
+		metric.BandwidthOutGB,
+	)
+
+	return err
+}
+
+// GetByInstanceID returns the samples of an instance taken in [from, to), oldest first
+func (r *UsageMetricsRepository) GetByInstanceID(instanceID uuid.UUID, from time.Time, to time.Time) ([]models.UsageMetric, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT id, db_instance_id, timestamp, cpu_percent, ram_percent, storage_used_gb, bandwidth_in_gb, bandwidth_out_gb
+		FROM usage_metrics
+		WHERE db_instance_id = $1 AND timestamp >= $2 AND timestamp < $3
+		ORDER BY timestamp ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, instanceID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metrics := []models.UsageMetric{}
+	for rows.Next() {
+		var metric models.UsageMetric
+		err := rows.Scan(
+			&metric.ID,
+			&metric.DBInstanceID,
+			&metric.Timestamp,
+			&metric.CPUPercent,
+			&metric.RAMPercent,
+			&metric.StorageUsedGB,
+			&metric.BandwidthInGB,
+			&metric.BandwidthOutGB,
+		)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+
+	return metrics, rows.Err()
+}
+
+// AggregateByInstanceID groups the samples of an instance taken in [from, to) into
+// buckets of bucketSeconds, aligned to the Unix epoch. Buckets without samples are omitted.
+func (r *UsageMetricsRepository) AggregateByInstanceID(instanceID uuid.UUID, from time.Time, to time.Time, bucketSeconds int64) ([]models.UsageMetricBucket, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT to_timestamp(floor(extract(epoch FROM timestamp) / $4) * $4) AS bucket,
+			avg(cpu_percent)::float8, avg(ram_percent)::float8, max(storage_used_gb)::float8
+		FROM usage_metrics
+		WHERE db_instance_id = $1 AND timestamp >= $2 AND timestamp < $3
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+
+	rows, err := r.pool.Query(ctx, query, instanceID, from, to, bucketSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.UsageMetricBucket{}
+	for rows.Next() {
+		var bucket models.UsageMetricBucket
+		if err := rows.Scan(&bucket.Start, &bucket.CPUPercent, &bucket.RAMPercent, &bucket.StorageUsedGB); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
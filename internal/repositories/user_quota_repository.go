@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserQuotaRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserQuotaRepository(pool *pgxpool.Pool) *UserQuotaRepository {
+	return &UserQuotaRepository{pool: pool}
+}
+
+// GetByUserID returns the quota override of a user, or nil if none is set
+func (r *UserQuotaRepository) GetByUserID(userID uuid.UUID) (*models.UserQuota, error) {
+	ctx := context.Background()
+
+	query := `
+		SELECT user_id, max_projects, max_storage_gb, flags, updated_by, updated_at
+		FROM user_quotas WHERE user_id = $1
+	`
+
+	var quota models.UserQuota
+	err := r.pool.QueryRow(ctx, query, userID).Scan(
+		&quota.UserID,
+		&quota.MaxProjects,
+		&quota.MaxStorageGB,
+		&quota.Flags,
+		&quota.UpdatedBy,
+		&quota.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+// Upsert creates or replaces the quota override of a user
+func (r *UserQuotaRepository) Upsert(quota *models.UserQuota) error {
+	ctx := context.Background()
+
+	query := `
+		INSERT INTO user_quotas (user_id, max_projects, max_storage_gb, flags, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			max_projects = EXCLUDED.max_projects,
+			max_storage_gb = EXCLUDED.max_storage_gb,
+			flags = EXCLUDED.flags,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+
+	return r.pool.QueryRow(ctx, query,
+		quota.UserID,
+		quota.MaxProjects,
+		quota.MaxStorageGB,
+		quota.Flags,
+		quota.UpdatedBy,
+	).Scan(&quota.UpdatedAt)
+}
@@ -4,6 +4,7 @@ import (
 	"backend/internal/models"
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +12,9 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// likeEscaper escapes the wildcards of a LIKE pattern, so user input matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type UserRepository struct {
 	pool *pgxpool.Pool
 }
@@ -108,6 +112,21 @@ func (r *UserRepository) FindUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+// ExistsDeletedByEmail reports whether an email belongs to a soft-deleted user. Emails
+// stay unique across deleted users, so such an email cannot be registered again.
+func (r *UserRepository) ExistsDeletedByEmail(email string) (bool, error) {
+	ctx := context.Background()
+
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND deleted_at IS NOT NULL)`
+
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, email).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
 func (r *UserRepository) FindUserByName(username string) (*models.User, error) {
 	// This method is not used but kept for compatibility
 	// If you need it, you can implement it similar to FindUserByEmail
@@ -122,6 +141,15 @@ func (r *UserRepository) DeleteRefreshTokensByUserID(userID uuid.UUID) error {
 	return err
 }
 
+// UpdateLastLogin records that a user logged in just now
+func (r *UserRepository) UpdateLastLogin(userID uuid.UUID) error {
+	ctx := context.Background()
+
+	query := `UPDATE users SET last_login_at = NOW() WHERE id = $1`
+	_, err := r.pool.Exec(ctx, query, userID)
+	return err
+}
+
 func (r *UserRepository) Update(user *models.User) error {
 	ctx := context.Background()
 
@@ -155,21 +183,32 @@ func (r *UserRepository) Delete(id uuid.UUID) error {
 	return err
 }
 
-func (r *UserRepository) FindAll() ([]models.User, error) {
+// FindAll returns a page of the active users, newest first, and the number of matching users
+// in total. A non-empty emailSearch only keeps users whose email contains it, ignoring case.
+func (r *UserRepository) FindAll(limit, offset int, emailSearch string) ([]models.User, int, error) {
 	ctx := context.Background()
 
+	filter := `WHERE deleted_at IS NULL AND ($1 = '' OR email ILIKE '%' || $1 || '%' ESCAPE '\')`
+	pattern := likeEscaper.Replace(emailSearch)
+
+	var total int
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users `+filter, pattern).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
 	query := `SELECT id, email, password_hash, role, status, created_at, last_login_at, deleted_at
 		FROM users
-		WHERE deleted_at IS NULL
-		ORDER BY created_at DESC`
+		` + filter + `
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3`
 
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.pool.Query(ctx, query, pattern, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
 		var user models.User
 		err := rows.Scan(
@@ -183,16 +222,16 @@ func (r *UserRepository) FindAll() ([]models.User, error) {
 			&user.DeletedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return users, nil
+	return users, total, nil
 }
 
 // CountUsers returns the total number of active (non-deleted) users
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"backend/internal/models"
+	"backend/internal/testdb"
+	"testing"
+)
+
+// A user who never logged in has a NULL last_login_at, which every read must accept
+func TestUserNeverLoggedInIsReadBack(t *testing.T) {
+	repo := NewUserRepository(testdb.Pool(t))
+
+	user := &models.User{Email: "never-logged-in@example.com", PasswordHash: "hash"}
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	byID, err := repo.FindUserByID(user.ID)
+	if err != nil || byID == nil {
+		t.Fatalf("FindUserByID = %v, %v", byID, err)
+	}
+	if byID.LastLoginAt != nil {
+		t.Errorf("FindUserByID LastLoginAt = %v, want nil", byID.LastLoginAt)
+	}
+
+	byEmail, err := repo.FindUserByEmail(user.Email)
+	if err != nil || byEmail == nil {
+		t.Fatalf("FindUserByEmail = %v, %v", byEmail, err)
+	}
+	if byEmail.LastLoginAt != nil {
+		t.Errorf("FindUserByEmail LastLoginAt = %v, want nil", byEmail.LastLoginAt)
+	}
+
+	users, total, err := repo.FindAll(10, 0, "")
+	if err != nil {
+		t.Fatalf("FindAll: %v", err)
+	}
+	if total != 1 || len(users) != 1 {
+		t.Fatalf("FindAll returned %d of %d users, want 1 of 1", len(users), total)
+	}
+	if users[0].ID != user.ID || users[0].LastLoginAt != nil {
+		t.Errorf("FindAll = %+v, want %s with a nil LastLoginAt", users[0], user.ID)
+	}
+
+	if err := repo.UpdateLastLogin(user.ID); err != nil {
+		t.Fatalf("UpdateLastLogin: %v", err)
+	}
+	byID, err = repo.FindUserByID(user.ID)
+	if err != nil || byID == nil || byID.LastLoginAt == nil {
+		t.Fatalf("FindUserByID after login = %+v, %v, want a LastLoginAt", byID, err)
+	}
+}
+
+func TestFindAllPagesAndSearchesEmails(t *testing.T) {
+	repo := NewUserRepository(testdb.Pool(t))
+	for _, email := range []string{"ada@example.com", "grace@example.com", "alan@other.org", "under_score@example.com"} {
+		if err := repo.Create(&models.User{Email: email, PasswordHash: "hash"}); err != nil {
+			t.Fatalf("Create %s: %v", email, err)
+		}
+	}
+
+	page, total, err := repo.FindAll(2, 0, "")
+	if err != nil || total != 4 || len(page) != 2 {
+		t.Fatalf("first page = %d users of %d, %v, want 2 of 4", len(page), total, err)
+	}
+	rest, _, err := repo.FindAll(2, 2, "")
+	if err != nil || len(rest) != 2 || rest[0].ID == page[0].ID || rest[0].ID == page[1].ID {
+		t.Fatalf("second page = %+v, %v, want the two other users", rest, err)
+	}
+
+	// The search is case-insensitive and its wildcards are taken literally
+	cases := map[string]int{"EXAMPLE.COM": 3, "other": 1, "_": 1, "%": 0}
+	for search, want := range cases {
+		users, total, err := repo.FindAll(10, 0, search)
+		if err != nil || total != want || len(users) != want {
+			t.Errorf("FindAll searching %q = %d users of %d, %v, want %d", search, len(users), total, err, want)
+		}
+	}
+}
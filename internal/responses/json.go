@@ -1,6 +1,7 @@
 package responses
 
 import (
+	"backend/internal/apperr"
 	"log"
 
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,7 @@ type APIResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
 }
 
 func JSON(c *gin.Context, statusCode int, status string, data interface{}, message string, err error) {
@@ -44,3 +46,17 @@ func Fail(c *gin.Context, statusCode int, err error, message string) {
 		Message: message,
 	})
 }
+
+// FromError responds with the status, code and message of an apperr.Error, logging its
+// cause. Any other error is answered with a generic 500 and only logged.
+func FromError(c *gin.Context, err error) {
+	appErr := apperr.From(err)
+	if appErr.Err != nil || appErr.Status >= 500 {
+		log.Printf("Error: %v", err)
+	}
+	c.JSON(appErr.Status, APIResponse{
+		Status:  "error",
+		Message: appErr.Message,
+		Code:    appErr.Code,
+	})
+}
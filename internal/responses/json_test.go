@@ -0,0 +1,64 @@
+package responses
+
+import (
+	"backend/internal/apperr"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func respondWithError(t *testing.T, err error) (*httptest.ResponseRecorder, APIResponse) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	FromError(c, err)
+
+	var body APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response body %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+func TestFromErrorUsesStatusAndCode(t *testing.T) {
+	err := fmt.Errorf("lookup: %w", apperr.NotFound(apperr.CodeProjectNotFound, "project not found"))
+	w, body := respondWithError(t, err)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if body.Status != "error" || body.Code != apperr.CodeProjectNotFound || body.Message != "project not found" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestFromErrorHidesCause(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.3:5432: connection refused")
+	w, body := respondWithError(t, apperr.Wrap(cause, http.StatusBadGateway, apperr.CodeInternal, "database unavailable"))
+
+	if w.Code != http.StatusBadGateway || body.Message != "database unavailable" {
+		t.Errorf("got %d %+v, want 502 with the client message", w.Code, body)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.3") {
+		t.Errorf("response %q leaks the cause", w.Body.String())
+	}
+}
+
+func TestFromErrorAnswersOtherErrorsWith500(t *testing.T) {
+	w, body := respondWithError(t, errors.New("pq: relation \"secrets\" does not exist"))
+
+	if w.Code != http.StatusInternalServerError || body.Code != apperr.CodeInternal {
+		t.Errorf("got %d %+v, want 500 %s", w.Code, body, apperr.CodeInternal)
+	}
+	if strings.Contains(w.Body.String(), "secrets") {
+		t.Errorf("response %q leaks the error", w.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"backend/internal/handlers"
+	"backend/internal/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyRoutes struct {
+	handler *handlers.APIKeyHandler
+}
+
+func NewAPIKeyRoutes(handler *handlers.APIKeyHandler) *APIKeyRoutes {
+	return &APIKeyRoutes{handler: handler}
+}
+
+func (r *APIKeyRoutes) RegisterRoutes(router *gin.RouterGroup) {
+	keys := router.Group("/keys")
+	// Keys are managed from a logged-in session only, so a leaked key cannot mint new ones
+	keys.Use(middlewares.Authenticate)
+	{
+		keys.POST("", r.handler.CreateAPIKey)
+		keys.GET("", r.handler.ListAPIKeys)
+		keys.DELETE("/:id", r.handler.RevokeAPIKey)
+	}
+}
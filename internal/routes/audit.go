@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"backend/internal/handlers"
+	"backend/internal/middlewares"
+	"backend/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRoutes struct {
+	handler      *handlers.DDLAuditHandler
+	userRepo     *repositories.UserRepository
+	authenticate gin.HandlerFunc
+}
+
+func NewAuditRoutes(handler *handlers.DDLAuditHandler, userRepo *repositories.UserRepository, authenticate gin.HandlerFunc) *AuditRoutes {
+	return &AuditRoutes{
+		handler:      handler,
+		userRepo:     userRepo,
+		authenticate: authenticate,
+	}
+}
+
+func (r *AuditRoutes) RegisterRoutes(router *gin.RouterGroup) {
+	// Project owners see the DDL history of their own project
+	projects := router.Group("/projects/:id/audit")
+	projects.Use(r.authenticate)
+	{
+		projects.GET("/ddl", r.handler.GetProjectAudit)
+	}
+
+	// Admin-only view across all projects
+	audit := router.Group("/audit")
+	audit.Use(middlewares.Authenticate, middlewares.RequireAdmin(r.userRepo))
+	{
+		audit.GET("/ddl", r.handler.GetAllAudit)
+	}
+}
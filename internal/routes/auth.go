@@ -32,6 +32,9 @@ func (r *AuthRoutes) RegisterRoutes(router *gin.RouterGroup) {
 		protected := auth.Group("/")
 		protected.Use(middlewares.Authenticate)
 		protected.POST("/logout", r.handler.Logout)
+		protected.POST("/logout-all", r.handler.LogoutAll)
+		protected.GET("/sessions", r.handler.ListSessions)
+		protected.DELETE("/sessions/:id", r.handler.RevokeSession)
 		auth.POST("/refresh", r.handler.Refresh)
 	}
 }
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupRoutes struct {
+	handler      *handlers.BackupHandler
+	authenticate gin.HandlerFunc
+}
+
+func NewBackupRoutes(handler *handlers.BackupHandler, authenticate gin.HandlerFunc) *BackupRoutes {
+	return &BackupRoutes{handler: handler, authenticate: authenticate}
+}
+
+func (r *BackupRoutes) RegisterRoutes(router *gin.RouterGroup) {
+	backups := router.Group("/projects/:id/backups")
+	backups.Use(r.authenticate)
+	{
+		backups.GET("", r.handler.ListBackups)
+		backups.GET("/:backup_id/download", r.handler.DownloadBackup)
+
+		// Scheduled pg_dump backups, run by the leader replica
+		backups.GET("/schedule", r.handler.GetSchedule)
+		backups.PUT("/schedule", r.handler.SetSchedule)
+	}
+
+	// Stream a pg_dump of the database straight to the client
+	router.GET("/projects/:id/backup", r.authenticate, r.handler.BackupProject)
+
+	// Restore an uploaded plain SQL dump into the database
+	router.POST("/projects/:id/restore", r.authenticate, r.handler.RestoreProject)
+}
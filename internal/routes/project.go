@@ -2,34 +2,72 @@ package routes
 
 import (
 	"backend/internal/handlers"
-	"backend/internal/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ProjectRoutes struct {
-	handler *handlers.ProjectHandler
+	handler      *handlers.ProjectHandler
+	authenticate gin.HandlerFunc
 }
 
-func NewProjectRoutes(handler *handlers.ProjectHandler) *ProjectRoutes {
-	return &ProjectRoutes{handler: handler}
+func NewProjectRoutes(handler *handlers.ProjectHandler, authenticate gin.HandlerFunc) *ProjectRoutes {
+	return &ProjectRoutes{handler: handler, authenticate: authenticate}
 }
 
 func (r *ProjectRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	projects := router.Group("/projects")
-	projects.Use(middlewares.Authenticate) // All project routes require authentication
+	projects.Use(r.authenticate) // All project routes require authentication
 	{
 		projects.POST("", r.handler.CreateProject)
 		projects.GET("", r.handler.ListProjects)
+		projects.DELETE("", r.handler.DeleteProjects)
 		projects.GET("/:id", r.handler.GetProject)
+		projects.PATCH("/:id", r.handler.UpdateProject)
 		projects.DELETE("/:id", r.handler.DeleteProject)
 
-		// Insert / Delete ROW(S)
+		// Insert / Update / Delete ROW(S)
 		projects.POST("/:id/rows", r.handler.InsertRow)
+		projects.PATCH("/:id/rows/:row_id", r.handler.UpdateRow)
 		projects.DELETE("/:id/rows/:row_id", r.handler.DeleteRow)
 
+		// Bulk update rows matching a mandatory filter
+		projects.PATCH("/:id/tables/:table/rows", r.handler.UpdateRows)
+
+		// Insert many rows in one statement
+		projects.POST("/:id/tables/:table/rows/bulk", r.handler.InsertRows)
+
+		// Fetch the full value of a single cell
+		projects.GET("/:id/tables/:table/rows/:row_id/cells/:column", r.handler.GetCellValue)
+
 		// Insert / Delete COLUMN(S)
 		projects.POST("/:id/columns", r.handler.AddColumn)
 		projects.DELETE("/:id/columns/:column_name", r.handler.DeleteColumn)
+
+		// Database roles inside the project's instance
+		projects.GET("/:id/roles", r.handler.ListDatabaseRoles)
+		projects.POST("/:id/roles", r.handler.CreateDatabaseRole)
+
+		// Queries waiting on locks and what is blocking them
+		projects.GET("/:id/queries/blocked", r.handler.GetBlockedQueries)
+
+		// Restart the project's database server without touching its data
+		projects.POST("/:id/instance/restart", r.handler.RestartInstance)
+
+		// Freeze the project's database container and bring it back
+		projects.POST("/:id/instance/pause", r.handler.PauseInstance)
+		projects.POST("/:id/instance/resume", r.handler.ResumeInstance)
+
+		// Open connections against the server's limit
+		projects.GET("/:id/instance/connections", r.handler.GetConnectionStats)
+
+		// Output of the project's database container
+		projects.GET("/:id/instance/logs", r.handler.GetInstanceLogs)
+
+		// Replace the password the backend connects to the project's database with
+		projects.POST("/:id/instance/credentials/rotate", r.handler.RotateCredentials)
+
+		// Resource usage of the project's database over time
+		projects.GET("/:id/metrics", r.handler.GetUsageMetrics)
 	}
 }
@@ -2,25 +2,52 @@ package routes
 
 import (
 	"backend/internal/handlers"
-	"backend/internal/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
 
 type QueryRoutes struct {
-	handler *handlers.QueryHandler
+	handler      *handlers.QueryHandler
+	authenticate gin.HandlerFunc
 }
 
-func NewQueryRoutes(handler *handlers.QueryHandler) *QueryRoutes {
-	return &QueryRoutes{handler: handler}
+func NewQueryRoutes(handler *handlers.QueryHandler, authenticate gin.HandlerFunc) *QueryRoutes {
+	return &QueryRoutes{handler: handler, authenticate: authenticate}
 }
 
 func (r *QueryRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	query := router.Group("/projects/:id/query")
-	query.Use(middlewares.Authenticate)
+	query.Use(r.authenticate)
 	{
 		// Query execution endpoints
 		query.POST("/execute", r.handler.ExecuteQuery)
 		query.GET("/history", r.handler.GetQueryHistory)
+		query.GET("/history/:execution_id", r.handler.GetProjectQueryHistoryEntry)
+		query.POST("/history/:execution_id/rerun", r.handler.RerunQuery)
+		query.DELETE("/:execution_id", r.handler.CancelQuery)
+		query.POST("/explain", r.handler.ExplainQuery)
+
+		// Query plan snapshots for comparing plans across schema/index changes
+		query.POST("/plans", r.handler.CreatePlanSnapshot)
+		query.GET("/plans", r.handler.ListPlanSnapshots)
+		query.POST("/plans/compare", r.handler.ComparePlans)
+	}
+
+	// Queries saved by name, private to the user who saved them
+	saved := router.Group("/projects/:id/saved-queries")
+	saved.Use(r.authenticate)
+	{
+		saved.POST("", r.handler.SaveQuery)
+		saved.GET("", r.handler.ListSavedQueries)
+		saved.PATCH("/:query_id", r.handler.RenameSavedQuery)
+		saved.DELETE("/:query_id", r.handler.DeleteSavedQuery)
+		saved.POST("/:query_id/execute", r.handler.ExecuteSavedQuery)
+	}
+
+	// History entries are looked up by their own ID, scoped to the owner
+	history := router.Group("/query/history")
+	history.Use(r.authenticate)
+	{
+		history.GET("/:id", r.handler.GetQueryHistoryEntry)
 	}
 }
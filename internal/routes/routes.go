@@ -2,33 +2,46 @@ package routes
 
 import (
 	"backend/internal/handlers"
+	"backend/internal/middlewares"
 	"backend/internal/repositories"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, googleAuthHandler *handlers.GoogleAuthHandler, userHandler *handlers.UserHandler, userRepo *repositories.UserRepository, projectHandler *handlers.ProjectHandler, queryHandler *handlers.QueryHandler, schemaHandler *handlers.SchemaHandler, tableHandler *handlers.TableHandler) {
+func RegisterRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, googleAuthHandler *handlers.GoogleAuthHandler, userHandler *handlers.UserHandler, userRepo *repositories.UserRepository, apiKeyRepo *repositories.APIKeyRepository, projectHandler *handlers.ProjectHandler, queryHandler *handlers.QueryHandler, schemaHandler *handlers.SchemaHandler, tableHandler *handlers.TableHandler, ddlAuditHandler *handlers.DDLAuditHandler, backupHandler *handlers.BackupHandler, apiKeyHandler *handlers.APIKeyHandler) {
 	api := router.Group("/api/v1")
 
+	// Project-scoped routes accept API keys as well as access tokens for programmatic clients
+	authenticate := middlewares.AuthenticateJWTOrAPIKey(apiKeyRepo)
+
 	authRoutes := NewAuthRoutes(authHandler, googleAuthHandler)
 	authRoutes.RegisterRoutes(api)
 
 	userRoutes := NewUserRoutes(userHandler, userRepo)
 	userRoutes.RegisterRoutes(api)
 
-	queryRoutes := NewQueryRoutes(queryHandler)
+	apiKeyRoutes := NewAPIKeyRoutes(apiKeyHandler)
+	apiKeyRoutes.RegisterRoutes(api)
+
+	queryRoutes := NewQueryRoutes(queryHandler, authenticate)
 	queryRoutes.RegisterRoutes(api)
 
-	projectRoutes := NewProjectRoutes(projectHandler)
+	projectRoutes := NewProjectRoutes(projectHandler, authenticate)
 	projectRoutes.RegisterRoutes(api)
 
-	schemaRoutes := NewSchemaRoutes(schemaHandler)
+	schemaRoutes := NewSchemaRoutes(schemaHandler, authenticate)
 	schemaRoutes.RegisterRoutes(api)
 
-	tableRoutes := NewTableRoutes(tableHandler)
+	tableRoutes := NewTableRoutes(tableHandler, authenticate)
 	tableRoutes.RegisterRoutes(api)
 
+	auditRoutes := NewAuditRoutes(ddlAuditHandler, userRepo, authenticate)
+	auditRoutes.RegisterRoutes(api)
+
+	backupRoutes := NewBackupRoutes(backupHandler, authenticate)
+	backupRoutes.RegisterRoutes(api)
+
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ok",
@@ -2,23 +2,46 @@ package routes
 
 import (
 	"backend/internal/handlers"
-	"backend/internal/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SchemaRoutes struct {
-	handler *handlers.SchemaHandler
+	handler      *handlers.SchemaHandler
+	authenticate gin.HandlerFunc
 }
 
-func NewSchemaRoutes(handler *handlers.SchemaHandler) *SchemaRoutes {
-	return &SchemaRoutes{handler: handler}
+func NewSchemaRoutes(handler *handlers.SchemaHandler, authenticate gin.HandlerFunc) *SchemaRoutes {
+	return &SchemaRoutes{handler: handler, authenticate: authenticate}
 }
 
 func (r *SchemaRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	schema := router.Group("/projects/:id/schema")
-	schema.Use(middlewares.Authenticate)
+	schema.Use(r.authenticate)
 	{
 		schema.GET("/visualize", r.handler.VisualizeSchema)
+		schema.GET("/stats", r.handler.GetTableStats)
+		schema.GET("/introspect", r.handler.IntrospectSchema)
+		schema.POST("/apply", r.handler.ApplyDDL)
+		// Executable script recreating every table of a schema
+		schema.GET("/export", r.handler.ExportSchema)
+		// Changes from the project given by ?against= to this one
+		schema.GET("/diff", r.handler.DiffSchema)
+	}
+
+	tables := router.Group("/projects/:id/tables")
+	tables.Use(r.authenticate)
+	{
+		// Objects referencing a table, used to warn before dropping it
+		tables.GET("/:table/dependents", r.handler.GetTableDependents)
+		tables.GET("/:table/indexes", r.handler.ListIndexes)
+		// Executable CREATE TABLE statement of a table
+		tables.GET("/:table/ddl", r.handler.GetTableDDL)
+	}
+
+	schemas := router.Group("/projects/:id/schemas")
+	schemas.Use(r.authenticate)
+	{
+		schemas.PATCH("/:schema", r.handler.RenameSchema)
 	}
 }
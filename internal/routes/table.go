@@ -2,28 +2,49 @@ package routes
 
 import (
 	"backend/internal/handlers"
-	"backend/internal/middlewares"
 
 	"github.com/gin-gonic/gin"
 )
 
 type TableRoutes struct {
 	tableHandler *handlers.TableHandler
+	authenticate gin.HandlerFunc
 }
 
-func NewTableRoutes(tableHandler *handlers.TableHandler) *TableRoutes {
+func NewTableRoutes(tableHandler *handlers.TableHandler, authenticate gin.HandlerFunc) *TableRoutes {
 	return &TableRoutes{
 		tableHandler: tableHandler,
+		authenticate: authenticate,
 	}
 }
 
 func (r *TableRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	projects := router.Group("projects/:id")
-	projects.Use(middlewares.Authenticate)
+	projects.Use(r.authenticate)
 	{
-		// REST conventions: POST /tables (create), DELETE /tables (delete)
+		// REST conventions: GET /tables (list), POST /tables (create), DELETE /tables (delete)
+		projects.GET("/tables", r.tableHandler.ListTables)
 		projects.POST("/tables", r.tableHandler.CreateTable)
 		projects.DELETE("/tables", r.tableHandler.DeleteTable)
-		// Future: PUT /tables for updates, GET /tables for listing
+		projects.POST("/indexes", r.tableHandler.CreateIndex)
+
+		// Check existing rows against a foreign key, then add it
+		projects.POST("/foreign-keys/validate", r.tableHandler.ValidateForeignKey)
+		projects.POST("/foreign-keys", r.tableHandler.AddForeignKey)
+		// Future: PUT /tables for updates
+
+		// Browse a table's rows a page at a time
+		projects.GET("/tables/:table/rows", r.tableHandler.GetTableRows)
+
+		// Indexes of a table; they are listed by the schema routes
+		projects.POST("/tables/:table/indexes", r.tableHandler.CreateIndex)
+		projects.DELETE("/tables/:table/indexes/:index", r.tableHandler.DropIndex)
+
+		// Rename a table or one of its columns in place
+		projects.PATCH("/tables/:table", r.tableHandler.RenameTable)
+		projects.PATCH("/tables/:table/columns/:column", r.tableHandler.RenameColumn)
+
+		// Load a CSV file into a table, streaming progress as server-sent events
+		projects.POST("/tables/:table/import", r.tableHandler.ImportCSV)
 	}
 }
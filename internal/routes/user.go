@@ -28,11 +28,15 @@ func (r *UserRoutes) RegisterRoutes(router *gin.RouterGroup) {
 		users.GET("/me", r.userHandler.GetMe)
 		users.PATCH("/me", r.userHandler.UpdateMe)
 		users.DELETE("/me", r.userHandler.DeleteMe)
+		users.GET("/me/export", r.userHandler.ExportMe)
+		users.GET("/me/usage", r.userHandler.GetMyUsage)
 
 		// Admin-only routes
 		users.GET("", middlewares.RequireAdmin(r.userRepo), r.userHandler.ListUsers)
 		users.GET("/:user_id", middlewares.RequireAdmin(r.userRepo), r.userHandler.GetUser)
 		users.PATCH("/:user_id", middlewares.RequireAdmin(r.userRepo), r.userHandler.UpdateUser)
 		users.DELETE("/:user_id", middlewares.RequireAdmin(r.userRepo), r.userHandler.DeleteUser)
+		users.GET("/:user_id/quota", middlewares.RequireAdmin(r.userRepo), r.userHandler.GetUserQuota)
+		users.PUT("/:user_id/quota", middlewares.RequireAdmin(r.userRepo), r.userHandler.SetUserQuota)
 	}
 }
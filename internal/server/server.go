@@ -4,9 +4,11 @@ import (
 	"backend/internal/config"
 	"backend/internal/database"
 	"backend/internal/handlers"
+	"backend/internal/jobs"
 	"backend/internal/repositories"
 	"backend/internal/routes"
 	"backend/internal/services"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -20,12 +22,22 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 )
 
+// sessionCleanupInterval is how often expired sessions are deleted
+const sessionCleanupInterval = time.Hour
+
+// backupSchedulerInterval is how often backup schedules are checked for due runs
+const backupSchedulerInterval = time.Minute
+
+// provisionRetryInterval is how often failed database instances are checked for a retry
+const provisionRetryInterval = time.Minute
+
 type Server struct {
 	port int
 	pool *pgxpool.Pool
 }
 
-func NewServer() *http.Server {
+// NewServer builds the HTTP server and starts the background jobs, which run until ctx is cancelled
+func NewServer(ctx context.Context) *http.Server {
 	// Validate required environment variables
 	if err := validateRequiredEnvVars(); err != nil {
 		log.Fatalf("Missing required environment variable: %v", err)
@@ -40,6 +52,12 @@ func NewServer() *http.Server {
 		log.Fatalf("PORT must be between 1 and 65535, got: %d", port)
 	}
 
+	// Optional TLS termination (plain HTTP when no certificate is configured)
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		log.Fatalf("invalid TLS configuration: %v", err)
+	}
+
 	// Ensure database exists (create if it doesn't)
 	if err := database.EnsureDatabaseExists(); err != nil {
 		log.Fatalf("failed to ensure database exists: %v", err)
@@ -64,58 +82,133 @@ func NewServer() *http.Server {
 	// Dependency injection
 	userRepo := repositories.NewUserRepository(pool)
 	sessionRepo := repositories.NewSessionRepository(pool)
-	userService := services.NewUserService(userRepo, sessionRepo)
-	authService := services.NewAuthService(userRepo)
-	authHandler := handlers.NewAuthHandler(authService)
-	userHandler := handlers.NewUserHandler(userService)
+	projectRepo := repositories.NewProjectRepository(pool)
+	queryHistoryRepo := repositories.NewQueryHistoryRepository(pool)
+	dbInstanceRepo := repositories.NewDatabaseInstanceRepository(pool)
+	userQuotaRepo := repositories.NewUserQuotaRepository(pool)
+	apiKeyRepo := repositories.NewAPIKeyRepository(pool)
+	userService := services.NewUserService(userRepo, sessionRepo, projectRepo, queryHistoryRepo)
+	tierLimits, err := config.TierInstanceLimits()
+	if err != nil {
+		log.Fatalf("failed to load tier instance limits: %v", err)
+	}
+	quotaService := services.NewQuotaService(userQuotaRepo, userRepo, projectRepo, dbInstanceRepo, tierLimits)
+	authService := services.NewAuthService(userRepo, sessionRepo, config.SingleActiveSessionEnabled())
+	cookieSettings, err := config.RefreshCookieSettings()
+	if err != nil {
+		log.Fatalf("failed to load cookie configuration: %v", err)
+	}
+	authHandler := handlers.NewAuthHandler(authService, userService, cookieSettings)
+	userHandler := handlers.NewUserHandler(userService, quotaService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 
 	// Google Auth dependencies
-	googleAuthService := services.NewGoogleAuthService(userRepo)
+	googleAuthService := services.NewGoogleAuthService(userRepo, authService)
 	oauthConfig, err := config.OAuthConfig()
 	if err != nil {
 		log.Fatalf("failed to initialize OAuth config: %v", err)
 	}
-	googleAuthHandler := handlers.NewGoogleAuthHandler(googleAuthService, oauthConfig)
+	googleAuthHandler := handlers.NewGoogleAuthHandler(googleAuthService, oauthConfig, cookieSettings)
 
 	// Project dependencies
-	projectRepo := repositories.NewProjectRepository(pool)
-	dbInstanceRepo := repositories.NewDatabaseInstanceRepository(pool)
 	dbCredentialRepo := repositories.NewDatabaseCredentialRepository(pool)
 	orchestratorService, err := services.NewOrchestratorService()
 	if err != nil {
 		log.Fatalf("failed to initialize orchestrator: %v", err)
 	}
-	projectService := services.NewProjectService(projectRepo, orchestratorService, dbInstanceRepo, dbCredentialRepo)
+	// Connection pools to project databases, shared by the services that query them
+	connectionManager := services.NewConnectionManager()
+	ddlAuditRepo := repositories.NewDDLAuditRepository(pool)
+	ddlAuditService := services.NewDDLAuditService(ddlAuditRepo, projectRepo)
+	ddlAuditHandler := handlers.NewDDLAuditHandler(ddlAuditService)
+	usageMetricsRepo := repositories.NewUsageMetricsRepository(pool)
+	projectService := services.NewProjectService(projectRepo, orchestratorService, dbInstanceRepo, dbCredentialRepo, ddlAuditService, quotaService, connectionManager, usageMetricsRepo)
 	projectHandler := handlers.NewProjectHandler(projectService)
 
 	// Query dependencies
-	queryHistoryRepo := repositories.NewQueryHistoryRepository(pool)
-	queryService := services.NewQueryService(projectRepo, dbInstanceRepo, dbCredentialRepo, queryHistoryRepo, orchestratorService)
+	queryPlanSnapshotRepo := repositories.NewQueryPlanSnapshotRepository(pool)
+	savedQueryRepo := repositories.NewSavedQueryRepository(pool)
+	queryTimeout, err := config.QueryTimeout()
+	if err != nil {
+		log.Fatalf("failed to load query timeout: %v", err)
+	}
+	queryService := services.NewQueryService(projectRepo, dbInstanceRepo, dbCredentialRepo, queryHistoryRepo, queryPlanSnapshotRepo, savedQueryRepo, orchestratorService, queryTimeout, connectionManager)
 	queryHandler := handlers.NewQueryHandler(queryService)
 
 	//
 	tableRepo := repositories.NewTableRepository(pool)
-	tableService := services.NewTableService(projectRepo, dbInstanceRepo, dbCredentialRepo, queryHistoryRepo, tableRepo, orchestratorService)
+	tableLimits, err := config.CreateTableLimits()
+	if err != nil {
+		log.Fatalf("failed to load table limits: %v", err)
+	}
+	tableService := services.NewTableService(projectRepo, dbInstanceRepo, dbCredentialRepo, queryHistoryRepo, tableRepo, orchestratorService, ddlAuditService, tableLimits, connectionManager)
 	tableHandler := handlers.NewTableHandler(tableService)
 
 	// Schema dependencies
-	schemaService := services.NewSchemaService(projectRepo, dbInstanceRepo, dbCredentialRepo, orchestratorService)
+	schemaService := services.NewSchemaService(projectRepo, dbInstanceRepo, dbCredentialRepo, orchestratorService, ddlAuditService, connectionManager)
 	schemaHandler := handlers.NewSchemaHandler(schemaService)
 
+	// Backup dependencies
+	backupRepo := repositories.NewBackupRepository(pool)
+	backupService := services.NewBackupService(projectRepo, dbInstanceRepo, dbCredentialRepo, backupRepo, orchestratorService, config.BackupDir())
+	backupHandler := handlers.NewBackupHandler(backupService)
+
+	// Usage metrics are sampled from the containers every ORCHESTRATOR_MONITOR_INTERVAL seconds
+	monitorInterval, err := config.MonitorInterval()
+	if err != nil {
+		log.Fatalf("failed to load monitor interval: %v", err)
+	}
+	metricsCollector := services.NewMetricsCollector(dbInstanceRepo, usageMetricsRepo, orchestratorService)
+
+	// Background jobs run on a single replica when leader election is enabled
+	leaderElector := jobs.NewLeaderElector(pool, config.LeaderElectionEnabled())
+	backgroundJobs := []jobs.Job{{
+		Name:     "session-cleanup",
+		Interval: sessionCleanupInterval,
+		Run: func(ctx context.Context) error {
+			return sessionRepo.DeleteExpired()
+		},
+	}, {
+		Name:     "scheduled-backups",
+		Interval: backupSchedulerInterval,
+		Run:      backupService.RunDueBackups,
+	}, {
+		Name:     "usage-metrics",
+		Interval: time.Duration(monitorInterval) * time.Second,
+		Run:      metricsCollector.Collect,
+	}}
+
+	// Retrying failed container creations is opt-in
+	if config.ProvisionRetryEnabled() {
+		maxAttempts, err := config.ProvisionRetryMaxAttempts()
+		if err != nil {
+			log.Fatalf("failed to load provision retry attempts: %v", err)
+		}
+		backgroundJobs = append(backgroundJobs, jobs.Job{
+			Name:     "provision-retry",
+			Interval: provisionRetryInterval,
+			Run: func(ctx context.Context) error {
+				return projectService.RetryFailedProvisions(ctx, maxAttempts)
+			},
+		})
+	}
+	jobs.Start(ctx, leaderElector, backgroundJobs...)
+
 	// Initialize Gin router
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key"},
+		ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor", "X-Total-Count"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	// Register all routes
-	routes.RegisterRoutes(router, authHandler, googleAuthHandler, userHandler, userRepo, projectHandler, queryHandler, schemaHandler, tableHandler)
+	routes.RegisterRoutes(router, authHandler, googleAuthHandler, userHandler, userRepo, apiKeyRepo, projectHandler, queryHandler, schemaHandler, tableHandler, ddlAuditHandler, backupHandler, apiKeyHandler)
 	// Create and configure the HTTP server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", s.port),
@@ -123,6 +216,7 @@ func NewServer() *http.Server {
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 5 * time.Minute, // Increased to handle long-running queries
+		TLSConfig:    tlsConfig,
 	}
 
 	return server
@@ -156,5 +250,9 @@ func validateRequiredEnvVars() error {
 		}
 	}
 
+	if _, err := config.MonitorInterval(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyService issues and revokes the API keys programmatic clients authenticate with
+type APIKeyService struct {
+	apiKeyRepo *repositories.APIKeyRepository
+}
+
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo}
+}
+
+// CreateAPIKeyRequest creates an API key. Keys without expires_in_days never expire.
+type CreateAPIKeyRequest struct {
+	Description   *string `json:"description" binding:"omitempty,max=200"`
+	ExpiresInDays *int    `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// GeneratedAPIKey is a new API key together with its plaintext value, which is only
+// available in the response that created it
+type GeneratedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// Generate creates an API key for a user, valid for ttl or forever when ttl is 0. Only
+// the hash of the key is stored.
+func (s *APIKeyService) Generate(userID uuid.UUID, description *string, ttl time.Duration) (*GeneratedAPIKey, error) {
+	plaintext, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to generate API key")
+	}
+
+	key := models.APIKey{
+		UserID:      userID,
+		KeyHash:     utils.HashAPIKey(plaintext),
+		Description: normalizeDescription(description),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.apiKeyRepo.Create(&key); err != nil {
+		return nil, apperr.Internal(err, "failed to save API key")
+	}
+	return &GeneratedAPIKey{APIKey: key, Key: plaintext}, nil
+}
+
+// List returns every API key of a user, newest first, without their values
+func (s *APIKeyService) List(userID uuid.UUID) ([]models.APIKey, error) {
+	keys, err := s.apiKeyRepo.ListByUserID(userID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get API keys")
+	}
+	return keys, nil
+}
+
+// Revoke permanently disables an API key of a user. Revoking a revoked key succeeds.
+func (s *APIKeyService) Revoke(userID uuid.UUID, keyID uuid.UUID) error {
+	found, err := s.apiKeyRepo.Revoke(keyID, userID)
+	if err != nil {
+		return apperr.Internal(err, "failed to revoke API key")
+	}
+	if !found {
+		return apperr.NotFound(apperr.CodeAPIKeyNotFound, "API key not found")
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	repo := repositories.NewAPIKeyRepository(env.pool)
+	keys := NewAPIKeyService(repo)
+	user := env.createUser(t)
+	other := env.createUser(t)
+
+	description := "  ci  "
+	generated, err := keys.Generate(user.ID, &description, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.HasPrefix(generated.Key, utils.APIKeyPrefix) || generated.KeyHash != utils.HashAPIKey(generated.Key) {
+		t.Errorf("generated key %q with hash %q, want a %s key stored by its hash", generated.Key, generated.KeyHash, utils.APIKeyPrefix)
+	}
+	if generated.Description == nil || *generated.Description != "ci" || generated.ExpiresAt == nil {
+		t.Errorf("generated key = %+v, want the trimmed description and an expiry", generated.APIKey)
+	}
+	if stored, err := repo.GetActiveByHash(utils.HashAPIKey(generated.Key)); err != nil || stored == nil || stored.ID != generated.ID {
+		t.Fatalf("GetActiveByHash = %+v, %v, want the generated key", stored, err)
+	}
+
+	listed, err := keys.List(user.ID)
+	if err != nil || len(listed) != 1 || listed[0].ID != generated.ID {
+		t.Fatalf("List = %+v, %v, want the generated key", listed, err)
+	}
+	if others, err := keys.List(other.ID); err != nil || len(others) != 0 {
+		t.Errorf("List of another user = %+v, %v, want none", others, err)
+	}
+
+	// Only the owner can revoke a key, and revoking twice succeeds
+	if err := keys.Revoke(other.ID, generated.ID); apperr.From(err).Code != apperr.CodeAPIKeyNotFound {
+		t.Errorf("Revoke by another user = %v, want %s", err, apperr.CodeAPIKeyNotFound)
+	}
+	for i := 0; i < 2; i++ {
+		if err := keys.Revoke(user.ID, generated.ID); err != nil {
+			t.Fatalf("Revoke #%d: %v", i+1, err)
+		}
+	}
+	if stored, err := repo.GetActiveByHash(utils.HashAPIKey(generated.Key)); err != nil || stored != nil {
+		t.Errorf("GetActiveByHash after revoking = %+v, %v, want nil", stored, err)
+	}
+}
@@ -1,11 +1,17 @@
 package services
 
 import (
+	"backend/internal/apperr"
 	"backend/internal/models"
 	"backend/internal/repositories"
 	"backend/internal/utils"
 	"errors"
+	"fmt"
+	"log"
+	"net/http"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -13,17 +19,34 @@ const (
 	RefreshTokenDuration = 30 * 24 * time.Hour // 7 days
 )
 
+// ErrRefreshTokenReused is returned when an already rotated refresh token is presented
+// again. All of the user's sessions have been revoked; the client must log in again.
+var ErrRefreshTokenReused = apperr.New(http.StatusUnauthorized, apperr.CodeRefreshTokenReused,
+	"refresh token was already used; all sessions have been revoked, please log in again")
+
+// ClientInfo identifies the client a session is issued to
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
 type AuthService struct {
-	userRepo *repositories.UserRepository
+	userRepo    *repositories.UserRepository
+	sessionRepo *repositories.SessionRepository
+
+	// singleSession makes logging in revoke every earlier session of the user
+	singleSession bool
 }
 
-func NewAuthService(userRepo *repositories.UserRepository) *AuthService {
+func NewAuthService(userRepo *repositories.UserRepository, sessionRepo *repositories.SessionRepository, singleSession bool) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		singleSession: singleSession,
 	}
 }
 
-func (s *AuthService) Register(user *models.User) (string, string, error) {
+func (s *AuthService) Register(user *models.User, client ClientInfo) (string, string, error) {
 	// 1. Check if user already exists
 	existing, _ := s.userRepo.FindUserByEmail(user.Email)
 	if existing != nil {
@@ -47,21 +70,11 @@ func (s *AuthService) Register(user *models.User) (string, string, error) {
 		return "", "", err
 	}
 
-	// 4. Generate tokens (no database session - tokens are self-contained)
-	accessToken, err := utils.GenerateJWT(user.ID, AccessTokenDuration, utils.AccessTokenSecret)
-	if err != nil {
-		return "", "", err
-	}
-
-	refreshToken, err := utils.GenerateJWT(user.ID, RefreshTokenDuration, utils.RefreshTokenSecret)
-	if err != nil {
-		return "", "", err
-	}
-
-	return accessToken, refreshToken, nil
+	// 4. Generate tokens
+	return s.issueTokens(user.ID, client)
 }
 
-func (s *AuthService) Login(email, password string) (string, string, error) {
+func (s *AuthService) Login(email, password string, client ClientInfo) (string, string, error) {
 	user, err := s.userRepo.FindUserByEmail(email)
 	if err != nil {
 		return "", "", errors.New("user not found")
@@ -76,23 +89,30 @@ func (s *AuthService) Login(email, password string) (string, string, error) {
 		return "", "", errors.New("invalid password")
 	}
 
-	// Generate access + refresh tokens (no database session - tokens are self-contained)
-	accessToken, err := utils.GenerateJWT(user.ID, AccessTokenDuration, utils.AccessTokenSecret)
-	if err != nil {
-		return "", "", err
+	return s.startSession(user.ID, client)
+}
+
+// startSession issues the tokens of a new login and records it as the user's last login.
+// With the single session policy, the new login replaces every other session of the user.
+func (s *AuthService) startSession(userID uuid.UUID, client ClientInfo) (string, string, error) {
+	// The login succeeds even if it cannot be recorded
+	if err := s.userRepo.UpdateLastLogin(userID); err != nil {
+		log.Printf("Failed to update last login of user %s: %v", userID, err)
 	}
 
-	refreshToken, err := utils.GenerateJWT(user.ID, RefreshTokenDuration, utils.RefreshTokenSecret)
-	if err != nil {
-		return "", "", err
+	if s.singleSession {
+		if err := s.sessionRepo.RevokeAllForUser(userID); err != nil {
+			return "", "", fmt.Errorf("failed to revoke existing sessions: %w", err)
+		}
 	}
 
-	return accessToken, refreshToken, nil
+	return s.issueTokens(userID, client)
 }
 
-// Refresh validates the refresh token from cookie and issues a new access token.
-// Since tokens are stored in HttpOnly cookies (not database), validation is done via JWT signature only.
-func (s *AuthService) Refresh(refreshToken string) (string, string, error) {
+// Refresh validates the refresh token from cookie and issues a new token pair. Besides its
+// signature, the token's session must be active, so tokens revoked by a logout are rejected.
+// The rotated session is recorded for client, the one refreshing.
+func (s *AuthService) Refresh(refreshToken string, client ClientInfo) (string, string, error) {
 	// 1. Validate refresh token signature and expiration
 	claims, err := utils.VerifyJWT(refreshToken, utils.RefreshTokenSecret)
 	if err != nil {
@@ -105,16 +125,93 @@ func (s *AuthService) Refresh(refreshToken string) (string, string, error) {
 		return "", "", errors.New("user not found")
 	}
 
-	// 3. Generate new token pair (token rotation for security)
-	newAccessToken, err := utils.GenerateJWT(claims.UserID, AccessTokenDuration, utils.AccessTokenSecret)
+	// 3. The token's session must still be active; it is consumed by the rotation below
+	consumed, err := s.sessionRepo.Consume(claims.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to consume session: %w", err)
+	}
+	if !consumed {
+		return "", "", s.rejectRefreshToken(claims.ID)
+	}
+
+	// 4. Generate new token pair (token rotation for security)
+	return s.issueTokens(claims.UserID, client)
+}
+
+// rejectRefreshToken explains why a refresh token without an active session was refused.
+// A token that was already rotated is being replayed, by an attacker or by the client it
+// was stolen from, so every session of the user is revoked.
+func (s *AuthService) rejectRefreshToken(jti string) error {
+	session, err := s.sessionRepo.FindByJTI(jti)
+	if err != nil {
+		return fmt.Errorf("failed to look up session: %w", err)
+	}
+	if session == nil || session.ConsumedAt == nil {
+		return errors.New("invalid or expired refresh token")
+	}
+
+	log.Printf("Refresh token reuse detected for user %s, revoking all of their sessions", session.UserID)
+	if err := s.sessionRepo.RevokeAllForUser(session.UserID); err != nil {
+		return fmt.Errorf("failed to revoke sessions after refresh token reuse: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// Logout revokes the session of a refresh token
+func (s *AuthService) Logout(refreshToken string) error {
+	return s.sessionRepo.Revoke(refreshToken)
+}
+
+// ListSessions returns the active sessions of a user, newest first. A session is replaced
+// by a new one each time its refresh token is rotated.
+func (s *AuthService) ListSessions(userID uuid.UUID) ([]models.Session, error) {
+	return s.sessionRepo.FindActiveByUserID(userID)
+}
+
+// RevokeSession ends one active session of a user, signing that device out
+func (s *AuthService) RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	revoked, err := s.sessionRepo.RevokeByID(sessionID, userID)
+	if err != nil {
+		return apperr.Internal(err, "failed to revoke session")
+	}
+	if !revoked {
+		return apperr.NotFound(apperr.CodeSessionNotFound, "session not found")
+	}
+	return nil
+}
+
+// issueTokens generates an access and refresh token pair for a user and stores a session
+// for the refresh token, so it can be revoked
+func (s *AuthService) issueTokens(userID uuid.UUID, client ClientInfo) (string, string, error) {
+	accessToken, err := utils.GenerateJWT(userID, AccessTokenDuration, utils.AccessTokenSecret)
 	if err != nil {
 		return "", "", errors.New("could not generate new access token")
 	}
 
-	newRefreshToken, err := utils.GenerateJWT(claims.UserID, RefreshTokenDuration, utils.RefreshTokenSecret)
+	refreshToken, jti, err := utils.GenerateJWTWithID(userID, RefreshTokenDuration, utils.RefreshTokenSecret)
 	if err != nil {
 		return "", "", errors.New("could not generate new refresh token")
 	}
 
-	return newAccessToken, newRefreshToken, nil
+	session := &models.Session{
+		UserID:       userID,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(RefreshTokenDuration),
+		JTI:          &jti,
+		UserAgent:    optionalString(client.UserAgent),
+		IPAddress:    optionalString(client.IPAddress),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// optionalString returns nil for an empty string, so it is stored as NULL
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
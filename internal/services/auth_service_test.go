@@ -0,0 +1,219 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"backend/internal/testdb"
+	"backend/internal/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newAuthService returns an AuthService on a fresh backend database with a registered user
+func newAuthService(t *testing.T, singleSession bool) (*AuthService, *models.User) {
+	t.Helper()
+
+	pool := testdb.Pool(t)
+	access, refresh := utils.AccessTokenSecret, utils.RefreshTokenSecret
+	utils.AccessTokenSecret, utils.RefreshTokenSecret = []byte("auth-test-access"), []byte("auth-test-refresh")
+	t.Cleanup(func() { utils.AccessTokenSecret, utils.RefreshTokenSecret = access, refresh })
+
+	auth := NewAuthService(repositories.NewUserRepository(pool), repositories.NewSessionRepository(pool), singleSession)
+	user := &models.User{Email: "sessions@example.com", Password: "correct horse battery staple"}
+	if _, _, err := auth.Register(user, ClientInfo{UserAgent: "register"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return auth, user
+}
+
+func TestSingleActiveSessionRevokesEarlierLogins(t *testing.T) {
+	auth, user := newAuthService(t, true)
+
+	_, laptop, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, phone, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "phone"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	sessions, err := auth.ListSessions(user.ID)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].UserAgent == nil || *sessions[0].UserAgent != "phone" {
+		t.Errorf("sessions = %+v, want only the phone's", sessions)
+	}
+
+	// The earlier login's refresh token was revoked, not rotated, so it is refused without
+	// being treated as a replay that would also end the phone's session
+	if _, _, err := auth.Refresh(laptop, ClientInfo{UserAgent: "laptop"}); err == nil || errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("Refresh with the first login's token = %v, want it refused as invalid", err)
+	}
+	if _, _, err := auth.Refresh(phone, ClientInfo{UserAgent: "phone"}); err != nil {
+		t.Errorf("Refresh with the latest login's token: %v", err)
+	}
+}
+
+func TestConcurrentSessionsByDefault(t *testing.T) {
+	auth, user := newAuthService(t, false)
+
+	_, laptop, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "laptop"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, phone, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "phone"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	for client, token := range map[string]string{"laptop": laptop, "phone": phone} {
+		if _, _, err := auth.Refresh(token, ClientInfo{UserAgent: client}); err != nil {
+			t.Errorf("Refresh of the %s session: %v", client, err)
+		}
+	}
+}
+
+func TestRefreshTokensEndWithTheirSession(t *testing.T) {
+	auth, user := newAuthService(t, false)
+
+	_, first, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, rotated, err := auth.Refresh(first, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// Logging out ends the session at once, long before the token expires
+	if err := auth.Logout(rotated); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, _, err := auth.Refresh(rotated, ClientInfo{}); err == nil {
+		t.Error("Refresh after logging out succeeded")
+	}
+
+	// Logging out everywhere ends every session of the user
+	_, laptop, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, phone, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if err := NewUserService(auth.userRepo, auth.sessionRepo, nil, nil).LogoutByUserID(user.ID); err != nil {
+		t.Fatalf("LogoutByUserID: %v", err)
+	}
+	for client, token := range map[string]string{"laptop": laptop, "phone": phone} {
+		if _, _, err := auth.Refresh(token, ClientInfo{}); err == nil {
+			t.Errorf("Refresh of the %s session after logging out everywhere succeeded", client)
+		}
+	}
+}
+
+func TestRefreshTokenReuseRevokesEverySession(t *testing.T) {
+	auth, user := newAuthService(t, false)
+
+	_, stolen, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, other, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, rotated, err := auth.Refresh(stolen, ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if _, _, err := auth.Refresh(stolen, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Refresh with a rotated token = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	for client, token := range map[string]string{"rotated": rotated, "other": other} {
+		if _, _, err := auth.Refresh(token, ClientInfo{}); err == nil {
+			t.Errorf("Refresh of the %s session after a reuse succeeded, want every session revoked", client)
+		}
+	}
+}
+
+func TestRevokeSessionEndsOnlyThatSession(t *testing.T) {
+	auth, user := newAuthService(t, false)
+
+	_, laptop, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "laptop", IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	_, phone, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{UserAgent: "phone"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	sessions, err := auth.ListSessions(user.ID)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	var laptopSession *models.Session
+	for i := range sessions {
+		if sessions[i].UserAgent != nil && *sessions[i].UserAgent == "laptop" {
+			laptopSession = &sessions[i]
+		}
+	}
+	// The registration's session is active too
+	if len(sessions) != 3 || laptopSession == nil || laptopSession.IPAddress == nil || *laptopSession.IPAddress != "192.0.2.1" {
+		t.Fatalf("sessions = %+v, want three including the laptop's with its address", sessions)
+	}
+
+	// Other users cannot revoke it
+	if err := auth.RevokeSession(uuid.New(), laptopSession.ID); apperr.From(err).Code != apperr.CodeSessionNotFound {
+		t.Errorf("RevokeSession by another user = %v, want %s", err, apperr.CodeSessionNotFound)
+	}
+	if err := auth.RevokeSession(user.ID, laptopSession.ID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if _, _, err := auth.Refresh(laptop, ClientInfo{UserAgent: "laptop"}); err == nil {
+		t.Error("Refresh of the revoked session succeeded")
+	}
+	if _, _, err := auth.Refresh(phone, ClientInfo{UserAgent: "phone"}); err != nil {
+		t.Errorf("Refresh of the phone session: %v", err)
+	}
+}
+
+func TestLoginRecordsLastLogin(t *testing.T) {
+	auth, user := newAuthService(t, false)
+	lastLogin := func() *time.Time {
+		t.Helper()
+		stored, err := auth.userRepo.FindUserByID(user.ID)
+		if err != nil || stored == nil {
+			t.Fatalf("FindUserByID = %+v, %v", stored, err)
+		}
+		return stored.LastLoginAt
+	}
+
+	if at := lastLogin(); at != nil {
+		t.Fatalf("last login at %v before any login, want nil", at)
+	}
+	_, refresh, err := auth.Login(user.Email, "correct horse battery staple", ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	loggedIn := lastLogin()
+	if loggedIn == nil {
+		t.Fatal("Login did not record the last login")
+	}
+
+	// Refreshing is not a login
+	if _, _, err := auth.Refresh(refresh, ClientInfo{}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if at := lastLogin(); at == nil || !at.Equal(*loggedIn) {
+		t.Errorf("last login at %v after a refresh, want %v", at, loggedIn)
+	}
+}
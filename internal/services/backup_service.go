@@ -0,0 +1,490 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"backend/internal/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// backupTimeout bounds a single pg_dump run
+const backupTimeout = 30 * time.Minute
+
+// defaultBackupRetention is how many completed backups a schedule keeps when none is given
+const defaultBackupRetention = 7
+
+type BackupService struct {
+	projectRepo  *repositories.ProjectRepository
+	instanceRepo *repositories.DatabaseInstanceRepository
+	credRepo     *repositories.DatabaseCredentialRepository
+	backupRepo   *repositories.BackupRepository
+	orchestrator Orchestrator
+	dir          string
+
+	// now tells the time schedules and backups are measured against
+	now func() time.Time
+}
+
+func NewBackupService(
+	projectRepo *repositories.ProjectRepository,
+	instanceRepo *repositories.DatabaseInstanceRepository,
+	credRepo *repositories.DatabaseCredentialRepository,
+	backupRepo *repositories.BackupRepository,
+	orchestrator Orchestrator,
+	dir string,
+) *BackupService {
+	return &BackupService{
+		projectRepo:  projectRepo,
+		instanceRepo: instanceRepo,
+		credRepo:     credRepo,
+		backupRepo:   backupRepo,
+		orchestrator: orchestrator,
+		dir:          dir,
+		now:          time.Now,
+	}
+}
+
+// SetBackupScheduleRequest replaces a project's backup schedule. Enabled defaults to true
+// and Retention to the latest 7 completed backups.
+type SetBackupScheduleRequest struct {
+	IntervalMinutes int   `json:"interval_minutes" binding:"required,min=60,max=43200"`
+	Retention       int   `json:"retention" binding:"omitempty,min=1,max=100"`
+	Enabled         *bool `json:"enabled"`
+}
+
+// GetSchedule returns the backup schedule of a project
+func (s *BackupService) GetSchedule(userID uuid.UUID, projectID uuid.UUID) (*models.BackupSchedule, error) {
+	if _, err := s.getProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.backupRepo.GetSchedule(projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get backup schedule")
+	}
+	if schedule == nil {
+		return nil, apperr.NotFound(apperr.CodeScheduleNotFound, "backup schedule not found")
+	}
+	return schedule, nil
+}
+
+// SetSchedule creates or replaces the backup schedule of a project. The first backup
+// runs one interval from now.
+func (s *BackupService) SetSchedule(userID uuid.UUID, projectID uuid.UUID, req SetBackupScheduleRequest) (*models.BackupSchedule, error) {
+	project, err := s.getProject(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePostgres(project); err != nil {
+		return nil, err
+	}
+
+	schedule := &models.BackupSchedule{
+		ProjectID:       projectID,
+		IntervalMinutes: req.IntervalMinutes,
+		Retention:       req.Retention,
+		Enabled:         true,
+		NextRunAt:       s.now().Add(time.Duration(req.IntervalMinutes) * time.Minute),
+	}
+	if schedule.Retention == 0 {
+		schedule.Retention = defaultBackupRetention
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+
+	if err := s.backupRepo.UpsertSchedule(schedule); err != nil {
+		return nil, apperr.Internal(err, "failed to save backup schedule")
+	}
+	return schedule, nil
+}
+
+// ListBackups returns the backups of a project, newest first
+func (s *BackupService) ListBackups(userID uuid.UUID, projectID uuid.UUID) ([]models.ProjectBackup, error) {
+	if _, err := s.getProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	backups, err := s.backupRepo.GetBackupsByProjectID(projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get backups")
+	}
+	return backups, nil
+}
+
+// GetBackup returns a completed backup of a project so its dump can be downloaded
+func (s *BackupService) GetBackup(userID uuid.UUID, projectID uuid.UUID, backupID uuid.UUID) (*models.ProjectBackup, error) {
+	if _, err := s.getProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	backup, err := s.backupRepo.GetBackupByIDAndProjectID(backupID, projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get backup")
+	}
+	if backup == nil {
+		return nil, apperr.NotFound(apperr.CodeBackupNotFound, "backup not found")
+	}
+	if backup.Status != models.BackupStatusCompleted {
+		return nil, apperr.Conflict(apperr.CodeBackupNotReady, fmt.Sprintf("backup is %s", backup.Status))
+	}
+	return backup, nil
+}
+
+// RunDueBackups backs up every project whose schedule is due and prunes the backups
+// beyond its retention. It runs as a leader-only background job; a failed backup is
+// recorded and the schedule still moves on to its next interval.
+func (s *BackupService) RunDueBackups(ctx context.Context) error {
+	now := s.now()
+	schedules, err := s.backupRepo.GetDueSchedules(now)
+	if err != nil {
+		return fmt.Errorf("failed to get due backup schedules: %w", err)
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Advance first so a crash mid-dump does not retry the schedule on every tick
+		next := now.Add(time.Duration(schedule.IntervalMinutes) * time.Minute)
+		if err := s.backupRepo.MarkScheduleRun(schedule.ProjectID, now, next); err != nil {
+			log.Printf("Failed to advance backup schedule of project %s: %v", schedule.ProjectID, err)
+			continue
+		}
+
+		backup, err := s.runBackup(ctx, schedule.ProjectID)
+		if err != nil {
+			log.Printf("Scheduled backup of project %s failed: %v", schedule.ProjectID, err)
+			continue
+		}
+		if backup.Status == models.BackupStatusCompleted {
+			if err := s.pruneBackups(schedule.ProjectID, schedule.Retention); err != nil {
+				log.Printf("Failed to prune backups of project %s: %v", schedule.ProjectID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// runBackup dumps a project's database with pg_dump in custom format. The backup is
+// recorded before the dump starts; its outcome, including a pg_dump failure, is stored
+// on the record. The returned error only covers failures to record the backup.
+func (s *BackupService) runBackup(ctx context.Context, projectID uuid.UUID) (*models.ProjectBackup, error) {
+	backup := &models.ProjectBackup{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Status:    models.BackupStatusRunning,
+		StartedAt: s.now(),
+	}
+	backup.FilePath = filepath.Join(s.dir, projectID.String(), backup.ID.String()+".dump")
+	if err := s.backupRepo.CreateBackup(backup); err != nil {
+		return nil, fmt.Errorf("failed to record backup: %w", err)
+	}
+
+	size, dumpErr := s.dump(ctx, projectID, backup.FilePath)
+
+	completedAt := s.now()
+	backup.CompletedAt = &completedAt
+	if dumpErr != nil {
+		message := dumpErr.Error()
+		backup.Status = models.BackupStatusFailed
+		backup.ErrorMessage = &message
+		_ = os.Remove(backup.FilePath)
+	} else {
+		backup.Status = models.BackupStatusCompleted
+		backup.SizeBytes = &size
+	}
+
+	if err := s.backupRepo.FinishBackup(backup); err != nil {
+		return nil, fmt.Errorf("failed to record backup result: %w", err)
+	}
+	return backup, nil
+}
+
+// dump runs pg_dump against the project's running instance into path and returns the dump size
+func (s *BackupService) dump(ctx context.Context, projectID uuid.UUID, path string) (int64, error) {
+	inst, err := s.instanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return 0, err
+	}
+	if inst == nil {
+		return 0, errors.New("no running database instance for this project")
+	}
+	if inst.ContainerID == nil || *inst.ContainerID == "" {
+		return 0, errors.New("database instance container ID not configured")
+	}
+
+	cred, err := s.credRepo.GetLatestByInstanceID(inst.ID)
+	if err != nil {
+		return 0, err
+	}
+	if cred == nil {
+		return 0, errors.New("no credentials configured for this database instance")
+	}
+
+	ip, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get container IP from orchestrator: %w", err)
+	}
+
+	dbPassword, err := utils.DecryptString(cred.PasswordEncrypted)
+	if err != nil {
+		return 0, errors.New("failed to decrypt database credentials")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backupTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "pg_dump",
+		"--format=custom",
+		"--host", ip,
+		"--port", strconv.Itoa(inst.Port),
+		"--username", cred.Username,
+		"--dbname", inst.DatabaseName(),
+		"--file", path,
+	)
+	// The password goes through the environment so it never shows up in the process list
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+dbPassword)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return 0, fmt.Errorf("pg_dump failed: %s", msg)
+		}
+		return 0, fmt.Errorf("pg_dump failed: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat backup file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// pruneBackups deletes the backups of a project beyond its newest keep completed ones
+func (s *BackupService) pruneBackups(projectID uuid.UUID, keep int) error {
+	expired, err := s.backupRepo.GetBackupsBeyondRetention(projectID, keep)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range expired {
+		if err := os.Remove(backup.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove backup file %s: %w", backup.FilePath, err)
+		}
+		if err := s.backupRepo.DeleteBackup(backup.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BackupProject starts a plain SQL pg_dump of a project's running database and returns it
+// as a stream, so large dumps are never held in memory. The download is recorded in
+// backup_downloads when it starts and its outcome when the stream is closed.
+func (s *BackupService) BackupProject(userID uuid.UUID, projectID uuid.UUID) (io.ReadCloser, error) {
+	project, err := s.getProject(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePostgres(project); err != nil {
+		return nil, err
+	}
+
+	inst, err := s.runningInstance(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &models.BackupDownload{
+		ProjectID: projectID,
+		UserID:    userID,
+		Status:    models.BackupStatusRunning,
+	}
+	if err := s.backupRepo.CreateDownload(download); err != nil {
+		return nil, apperr.Internal(err, "failed to record backup download")
+	}
+
+	stream, err := s.orchestrator.BackupDatabase(*inst.ContainerID, inst.DatabaseName())
+	if err != nil {
+		s.finishDownload(download, 0, err)
+		return nil, err
+	}
+	return &downloadStream{ReadCloser: stream, finish: func(size int64, err error) {
+		s.finishDownload(download, size, err)
+	}}, nil
+}
+
+// finishDownload records the outcome of a backup download
+func (s *BackupService) finishDownload(download *models.BackupDownload, size int64, streamErr error) {
+	completedAt := time.Now()
+	download.CompletedAt = &completedAt
+	download.SizeBytes = &size
+	if streamErr != nil {
+		message := streamErr.Error()
+		download.Status = models.BackupStatusFailed
+		download.ErrorMessage = &message
+	} else {
+		download.Status = models.BackupStatusCompleted
+	}
+
+	if err := s.backupRepo.FinishDownload(download); err != nil {
+		log.Printf("Failed to record the outcome of backup download %s: %v", download.ID, err)
+	}
+}
+
+// downloadStream counts the bytes read from a dump and reports on Close whether it was
+// read to the end
+type downloadStream struct {
+	io.ReadCloser
+	size   int64
+	err    error
+	done   bool
+	finish func(size int64, err error)
+}
+
+func (d *downloadStream) Read(p []byte) (int, error) {
+	n, err := d.ReadCloser.Read(p)
+	d.size += int64(n)
+	if err == io.EOF {
+		d.done = true
+	} else if err != nil {
+		d.err = err
+	}
+	return n, err
+}
+
+func (d *downloadStream) Close() error {
+	err := d.ReadCloser.Close()
+	switch {
+	case d.err != nil:
+		d.finish(d.size, d.err)
+	case !d.done:
+		d.finish(d.size, errors.New("download interrupted before the dump completed"))
+	default:
+		d.finish(d.size, nil)
+	}
+	return err
+}
+
+// restoreSizeLimits caps the size of an uploaded SQL dump per resource tier
+var restoreSizeLimits = map[string]int64{
+	"free":    100 << 20,
+	"basic":   1 << 30,
+	"premium": 10 << 30,
+}
+
+// restoreSizeLimitForTier returns the dump size limit of a tier, defaulting to the free tier
+func restoreSizeLimitForTier(tier string) int64 {
+	if limit, ok := restoreSizeLimits[tier]; ok {
+		return limit
+	}
+	return restoreSizeLimits["free"]
+}
+
+// RestoreProject pipes a plain SQL dump, such as one from BackupProject, into a project's
+// running database. The restore runs in a single transaction, so a dump that fails
+// halfway, or exceeds the size limit of the project's tier, leaves the database unchanged.
+func (s *BackupService) RestoreProject(userID uuid.UUID, projectID uuid.UUID, dump io.Reader) error {
+	project, err := s.getProject(userID, projectID)
+	if err != nil {
+		return err
+	}
+	if err := requirePostgres(project); err != nil {
+		return err
+	}
+
+	inst, err := s.runningInstance(projectID)
+	if err != nil {
+		return err
+	}
+
+	limited := &limitedDump{r: dump, remaining: restoreSizeLimitForTier(project.ResourceTier)}
+	if err := s.orchestrator.RestoreDatabase(*inst.ContainerID, inst.DatabaseName(), limited); err != nil {
+		if limited.exceeded {
+			return apperr.New(http.StatusRequestEntityTooLarge, apperr.CodeDumpTooLarge,
+				fmt.Sprintf("dump exceeds the restore size limit of %d bytes", restoreSizeLimitForTier(project.ResourceTier)))
+		}
+		return err
+	}
+
+	log.Printf("Restored a SQL dump into project %s", projectID)
+	return nil
+}
+
+// errDumpTooLarge aborts reading a dump past its size limit
+var errDumpTooLarge = errors.New("dump exceeds the restore size limit")
+
+// limitedDump fails, instead of ending early, once more than remaining bytes are read,
+// so an oversized dump is never restored truncated
+type limitedDump struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedDump) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errDumpTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.exceeded = true
+		return 0, errDumpTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// runningInstance returns the running database instance of a project, which must have a container
+func (s *BackupService) runningInstance(projectID uuid.UUID) (*models.DatabaseInstance, error) {
+	inst, err := s.instanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get database instance")
+	}
+	if inst == nil {
+		return nil, apperr.Conflict(apperr.CodeInstanceNotRunning, "no running database instance for this project")
+	}
+	if inst.ContainerID == nil || *inst.ContainerID == "" {
+		return nil, apperr.Internal(nil, "database instance container ID not configured")
+	}
+	return inst, nil
+}
+
+// getProject verifies a project exists and belongs to the user
+func (s *BackupService) getProject(userID uuid.UUID, projectID uuid.UUID) (*models.Project, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get project")
+	}
+	if project == nil {
+		return nil, apperr.NotFound(apperr.CodeProjectNotFound, "project not found or not accessible")
+	}
+	return project, nil
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRunDueBackupsFollowsSchedule(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	clock := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	env.backups.now = func() time.Time { return clock }
+	runDue := func() []models.ProjectBackup {
+		t.Helper()
+		if err := env.backups.RunDueBackups(context.Background()); err != nil {
+			t.Fatalf("RunDueBackups: %v", err)
+		}
+		backups, err := env.backups.ListBackups(user.ID, project.ID)
+		if err != nil {
+			t.Fatalf("ListBackups: %v", err)
+		}
+		return backups
+	}
+
+	schedule, err := env.backups.SetSchedule(user.ID, project.ID, SetBackupScheduleRequest{IntervalMinutes: 60})
+	if err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+	if !schedule.NextRunAt.Equal(clock.Add(time.Hour)) || schedule.Retention != defaultBackupRetention {
+		t.Fatalf("schedule = %+v, want the first run in an hour and the default retention", schedule)
+	}
+
+	clock = clock.Add(59 * time.Minute)
+	if backups := runDue(); len(backups) != 0 {
+		t.Fatalf("%d backups before the schedule was due", len(backups))
+	}
+
+	// Whether pg_dump succeeds here or not, the run is recorded and the schedule moves on
+	clock = clock.Add(time.Minute)
+	backups := runDue()
+	if len(backups) != 1 || !backups[0].StartedAt.Equal(clock) || backups[0].Status == models.BackupStatusRunning {
+		t.Fatalf("backups = %+v, want one finished backup started at %v", backups, clock)
+	}
+	if schedule, err = env.backups.GetSchedule(user.ID, project.ID); err != nil {
+		t.Fatalf("GetSchedule: %v", err)
+	}
+	if schedule.LastRunAt == nil || !schedule.LastRunAt.Equal(clock) || !schedule.NextRunAt.Equal(clock.Add(time.Hour)) {
+		t.Errorf("schedule after the run = %+v, want it last run now and next in an hour", schedule)
+	}
+
+	if backups := runDue(); len(backups) != 1 {
+		t.Errorf("%d backups after running again at the same time, want 1", len(backups))
+	}
+
+	// A disabled schedule never runs
+	disabled := false
+	if _, err := env.backups.SetSchedule(user.ID, project.ID, SetBackupScheduleRequest{IntervalMinutes: 60, Enabled: &disabled}); err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+	clock = clock.Add(24 * time.Hour)
+	if backups := runDue(); len(backups) != 1 {
+		t.Errorf("%d backups after a day with the schedule disabled, want 1", len(backups))
+	}
+}
+
+func TestPruneBackupsKeepsRetention(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	dir := t.TempDir()
+
+	// Oldest first
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	statuses := []string{
+		models.BackupStatusCompleted,
+		models.BackupStatusFailed,
+		models.BackupStatusCompleted,
+		models.BackupStatusCompleted,
+		models.BackupStatusFailed,
+		models.BackupStatusCompleted,
+		models.BackupStatusRunning,
+	}
+	created := make([]models.ProjectBackup, len(statuses))
+	for i, status := range statuses {
+		backup := models.ProjectBackup{
+			ID:        uuid.New(),
+			ProjectID: project.ID,
+			Status:    status,
+			StartedAt: start.Add(time.Duration(i) * time.Hour),
+		}
+		backup.FilePath = filepath.Join(dir, backup.ID.String()+".dump")
+		if err := os.WriteFile(backup.FilePath, []byte("dump"), 0o600); err != nil {
+			t.Fatalf("write dump: %v", err)
+		}
+		if err := env.backupRepo.CreateBackup(&backup); err != nil {
+			t.Fatalf("CreateBackup: %v", err)
+		}
+		if status != models.BackupStatusRunning {
+			if err := env.backupRepo.FinishBackup(&backup); err != nil {
+				t.Fatalf("FinishBackup: %v", err)
+			}
+		}
+		created[i] = backup
+	}
+
+	if err := env.backups.pruneBackups(project.ID, 2); err != nil {
+		t.Fatalf("pruneBackups: %v", err)
+	}
+
+	// The two newest completed backups are kept, with what ran after the older of them
+	kept := map[uuid.UUID]bool{created[3].ID: true, created[4].ID: true, created[5].ID: true, created[6].ID: true}
+	backups, err := env.backups.ListBackups(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(backups) != len(kept) {
+		t.Errorf("%d backups left, want %d", len(backups), len(kept))
+	}
+	for _, backup := range backups {
+		if !kept[backup.ID] {
+			t.Errorf("backup started at %v was not pruned", backup.StartedAt)
+		}
+	}
+	for _, backup := range created {
+		_, err := os.Stat(backup.FilePath)
+		if exists := err == nil; exists != kept[backup.ID] {
+			t.Errorf("dump of the backup started at %v exists: %v, want %v", backup.StartedAt, exists, kept[backup.ID])
+		}
+	}
+}
+
+func TestBackupProjectRecordsDownloads(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+
+	const dump = "CREATE TABLE notes (id integer);\n"
+	env.orchestrator.backup = func(containerID string, dbName string) (io.ReadCloser, error) {
+		if containerID != *inst.ContainerID || dbName != inst.DatabaseName() {
+			t.Errorf("BackupDatabase(%s, %s), want the project's container and database", containerID, dbName)
+		}
+		return io.NopCloser(strings.NewReader(dump)), nil
+	}
+	download := func(read func(io.Reader)) (status string, size int64) {
+		t.Helper()
+		stream, err := env.backups.BackupProject(user.ID, project.ID)
+		if err != nil {
+			t.Fatalf("BackupProject: %v", err)
+		}
+		read(stream)
+		if err := stream.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		err = env.pool.QueryRow(context.Background(),
+			`SELECT status, size_bytes FROM backup_downloads WHERE project_id = $1 ORDER BY started_at DESC LIMIT 1`,
+			project.ID).Scan(&status, &size)
+		if err != nil {
+			t.Fatalf("read backup download: %v", err)
+		}
+		return status, size
+	}
+
+	status, size := download(func(r io.Reader) {
+		if got, err := io.ReadAll(r); err != nil || string(got) != dump {
+			t.Errorf("dump = %q, %v, want %q", got, err, dump)
+		}
+	})
+	if status != models.BackupStatusCompleted || size != int64(len(dump)) {
+		t.Errorf("completed download recorded as %s of %d bytes, want %s of %d", status, size, models.BackupStatusCompleted, len(dump))
+	}
+
+	// A client that goes away before the end leaves a failed record
+	status, size = download(func(r io.Reader) { r.Read(make([]byte, 6)) })
+	if status != models.BackupStatusFailed || size != 6 {
+		t.Errorf("interrupted download recorded as %s of %d bytes, want %s of 6", status, size, models.BackupStatusFailed)
+	}
+}
+
+func TestRestoreProjectEnforcesTierLimit(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	var restored []byte
+	env.orchestrator.restore = func(containerID string, dbName string, dump io.Reader) error {
+		var err error
+		restored, err = io.ReadAll(dump)
+		return err
+	}
+
+	const dump = "CREATE TABLE notes (id integer);\n"
+	if err := env.backups.RestoreProject(user.ID, project.ID, strings.NewReader(dump)); err != nil {
+		t.Fatalf("RestoreProject: %v", err)
+	}
+	if string(restored) != dump {
+		t.Errorf("restored %q, want %q", restored, dump)
+	}
+
+	limit := restoreSizeLimits[project.ResourceTier]
+	restoreSizeLimits[project.ResourceTier] = int64(len(dump)) - 1
+	t.Cleanup(func() { restoreSizeLimits[project.ResourceTier] = limit })
+
+	err := env.backups.RestoreProject(user.ID, project.ID, strings.NewReader(dump))
+	if appErr := apperr.From(err); appErr.Status != http.StatusRequestEntityTooLarge || appErr.Code != apperr.CodeDumpTooLarge {
+		t.Fatalf("RestoreProject of an oversized dump = %v, want %s", err, apperr.CodeDumpTooLarge)
+	}
+}
+
+func TestLimitedDumpFailsPastLimit(t *testing.T) {
+	dump := &limitedDump{r: strings.NewReader("12345"), remaining: 5}
+	if got, err := io.ReadAll(dump); err != nil || string(got) != "12345" {
+		t.Errorf("read of a dump at the limit = %q, %v, want it whole", got, err)
+	}
+
+	dump = &limitedDump{r: strings.NewReader("123456"), remaining: 5}
+	if _, err := io.ReadAll(dump); !errors.Is(err, errDumpTooLarge) || !dump.exceeded {
+		t.Errorf("read of a dump past the limit = %v, want errDumpTooLarge", err)
+	}
+}
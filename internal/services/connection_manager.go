@@ -0,0 +1,163 @@
+package services
+
+import (
+	"backend/internal/database"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Limits of the cached database/sql pools, matching the pgx pools of project databases
+const (
+	projectDBMaxOpenConns    = 5
+	projectDBMaxIdleConns    = 2
+	projectDBConnMaxIdleTime = time.Minute
+	projectDBConnMaxLifetime = 5 * time.Minute
+)
+
+// ConnectionManager caches connection pools to project databases by database instance ID,
+// so requests reuse connections instead of opening a new pool each time. A pool is
+// replaced when the instance's connection string changes, e.g. after its container got a
+// new IP, and dropped with Invalidate when the instance goes away.
+type ConnectionManager struct {
+	mu    sync.Mutex
+	dbs   map[uuid.UUID]managedDB
+	pools map[uuid.UUID]managedPool
+}
+
+type managedDB struct {
+	dsn string
+	db  *sql.DB
+}
+
+type managedPool struct {
+	dsn  string
+	pool *pgxpool.Pool
+}
+
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{
+		dbs:   make(map[uuid.UUID]managedDB),
+		pools: make(map[uuid.UUID]managedPool),
+	}
+}
+
+// Get returns the database/sql pool of an instance for dsn. Callers must not close it.
+func (m *ConnectionManager) Get(instanceID uuid.UUID, dsn string) (*sql.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cached, ok := m.dbs[instanceID]; ok {
+		if cached.dsn == dsn {
+			return cached.db, nil
+		}
+		// The instance moved or its credentials changed
+		closeDB(cached.db)
+		delete(m.dbs, instanceID)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	db.SetMaxOpenConns(projectDBMaxOpenConns)
+	db.SetMaxIdleConns(projectDBMaxIdleConns)
+	db.SetConnMaxIdleTime(projectDBConnMaxIdleTime)
+	db.SetConnMaxLifetime(projectDBConnMaxLifetime)
+
+	m.dbs[instanceID] = managedDB{dsn: dsn, db: db}
+	return db, nil
+}
+
+// GetPool returns the pgx pool of an instance for dsn. Callers must not close it.
+func (m *ConnectionManager) GetPool(instanceID uuid.UUID, dsn string) (*pgxpool.Pool, error) {
+	m.mu.Lock()
+	cached, ok := m.pools[instanceID]
+	m.mu.Unlock()
+	if ok && cached.dsn == dsn {
+		return cached.pool, nil
+	}
+
+	// Connecting pings the database, so it happens without holding the lock
+	pool, err := database.ConnectToProjectDatabase(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.pools[instanceID]; ok {
+		if current.dsn == dsn {
+			// Another request connected first
+			go pool.Close()
+			return current.pool, nil
+		}
+		closePool(current.pool)
+	}
+	m.pools[instanceID] = managedPool{dsn: dsn, pool: pool}
+	return pool, nil
+}
+
+// PoolStats is the usage of the connections the backend holds to one instance
+type PoolStats struct {
+	Open    int `json:"open"`
+	InUse   int `json:"in_use"`
+	Idle    int `json:"idle"`
+	MaxOpen int `json:"max_open"`
+}
+
+// Stats sums the usage of both cached pools of an instance. Instances without a
+// cached pool report zero connections.
+func (m *ConnectionManager) Stats(instanceID uuid.UUID) PoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stats PoolStats
+	if cached, ok := m.dbs[instanceID]; ok {
+		dbStats := cached.db.Stats()
+		stats.Open += dbStats.OpenConnections
+		stats.InUse += dbStats.InUse
+		stats.Idle += dbStats.Idle
+		stats.MaxOpen += dbStats.MaxOpenConnections
+	}
+	if cached, ok := m.pools[instanceID]; ok {
+		poolStats := cached.pool.Stat()
+		stats.Open += int(poolStats.TotalConns())
+		stats.InUse += int(poolStats.AcquiredConns())
+		stats.Idle += int(poolStats.IdleConns())
+		stats.MaxOpen += int(poolStats.MaxConns())
+	}
+	return stats
+}
+
+// Invalidate closes and forgets the pools of an instance. Queries already running on
+// them finish; later calls to Get or GetPool connect again.
+func (m *ConnectionManager) Invalidate(instanceID uuid.UUID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cached, ok := m.dbs[instanceID]; ok {
+		closeDB(cached.db)
+		delete(m.dbs, instanceID)
+	}
+	if cached, ok := m.pools[instanceID]; ok {
+		closePool(cached.pool)
+		delete(m.pools, instanceID)
+	}
+}
+
+// closeDB closes a database/sql pool in the background, so closing its idle
+// connections does not hold up other instances' requests waiting on the lock
+func closeDB(db *sql.DB) {
+	go db.Close()
+}
+
+// closePool closes a pgx pool in the background, since Close waits for every
+// acquired connection to be released
+func closePool(pool *pgxpool.Pool) {
+	go pool.Close()
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// databasePasswordBytes is the amount of randomness in a rotated database password
+const databasePasswordBytes = 24
+
+// rotateCredentialsTimeout bounds the password change inside the project's database
+const rotateCredentialsTimeout = 10 * time.Second
+
+// RotateCredentialsResult reports the credential that replaced the previous one
+type RotateCredentialsResult struct {
+	Username  string    `json:"username"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+// RotateCredentials replaces the password the backend uses to connect to a project's
+// database. The password is changed inside the database and stored as a new credential
+// in the same step, then connections opened with the old password are closed and
+// superseded credentials are pruned.
+func (s *ProjectService) RotateCredentials(userID uuid.UUID, projectID uuid.UUID) (*RotateCredentialsResult, error) {
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, err := s.dbInstanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	if inst == nil {
+		return nil, errors.New("no running database instance for this project")
+	}
+	current, err := s.dbCredentialRepo.GetLatestByInstanceID(inst.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database credentials: %w", err)
+	}
+	if current == nil {
+		return nil, errors.New("no credentials configured for this database instance")
+	}
+
+	password, err := generateDatabasePassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	encryptedPassword, err := utils.EncryptString(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rotateCredentialsTimeout)
+	defer cancel()
+
+	// The password only changes when the transaction commits, which happens once the new
+	// credential is stored, so the stored credential and the database never disagree
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	alterRole := fmt.Sprintf("ALTER ROLE %s WITH PASSWORD %s", pq.QuoteIdentifier(current.Username), pq.QuoteLiteral(password))
+	if _, err := tx.ExecContext(ctx, alterRole); err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	credential := &models.DatabaseCredential{
+		DBInstanceID:      inst.ID,
+		Username:          current.Username,
+		PasswordEncrypted: encryptedPassword,
+	}
+	if err := s.dbCredentialRepo.Create(credential); err != nil {
+		return nil, fmt.Errorf("failed to save database credentials: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		// The database kept the old password, so the new credential must not become the latest
+		if delErr := s.dbCredentialRepo.Delete(credential.ID); delErr != nil {
+			fmt.Printf("Warning: failed to delete unused database credential %s: %v\n", credential.ID, delErr)
+		}
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	s.pruneCredentials(inst.ID)
+
+	return &RotateCredentialsResult{Username: credential.Username, RotatedAt: credential.CreatedAt}, nil
+}
+
+// generateDatabasePassword returns a random password made of hex digits, which need no
+// quoting in connection strings
+func generateDatabasePassword() (string, error) {
+	b := make([]byte, databasePasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"testing"
+)
+
+func TestPruneCredentialsKeepsTheLatest(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+
+	original, err := env.credentials.GetLatestByInstanceID(inst.ID)
+	if err != nil || original == nil {
+		t.Fatalf("GetLatestByInstanceID = %v, %v", original, err)
+	}
+
+	for i := 0; i < credentialsToKeep; i++ {
+		superseding := &models.DatabaseCredential{
+			DBInstanceID:      inst.ID,
+			Username:          original.Username,
+			PasswordEncrypted: original.PasswordEncrypted,
+		}
+		if err := env.credentials.Create(superseding); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	env.projects.pruneCredentials(inst.ID)
+
+	credentials, err := env.credentials.GetByInstanceID(inst.ID)
+	if err != nil {
+		t.Fatalf("GetByInstanceID: %v", err)
+	}
+	if len(credentials) != credentialsToKeep {
+		t.Errorf("%d credentials kept, want %d", len(credentials), credentialsToKeep)
+	}
+	for _, credential := range credentials {
+		if credential.ID == original.ID {
+			t.Error("the original credential was not pruned")
+		}
+	}
+
+	// The latest credential is the one in use
+	var one int
+	if err := env.projectDB(t, user, project).QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Errorf("query with the latest credential: %v", err)
+	}
+}
+
+func TestRotateCredentialsPrunesOldCredentials(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+
+	original, err := env.credentials.GetLatestByInstanceID(inst.ID)
+	if err != nil || original == nil {
+		t.Fatalf("GetLatestByInstanceID = %v, %v", original, err)
+	}
+	env.orchestrator.mu.Lock()
+	server := env.orchestrator.containers[*inst.ContainerID].server
+	env.orchestrator.mu.Unlock()
+
+	for i := 0; i <= credentialsToKeep; i++ {
+		if _, err := env.projects.RotateCredentials(user.ID, project.ID); err != nil {
+			t.Fatalf("RotateCredentials #%d: %v", i+1, err)
+		}
+	}
+
+	credentials, err := env.credentials.GetByInstanceID(inst.ID)
+	if err != nil {
+		t.Fatalf("GetByInstanceID: %v", err)
+	}
+	if len(credentials) != credentialsToKeep {
+		t.Errorf("%d credentials kept, want %d", len(credentials), credentialsToKeep)
+	}
+	for _, credential := range credentials {
+		if credential.ID == original.ID {
+			t.Error("the original credential was not pruned")
+		}
+	}
+
+	// The latest credential is the one in use
+	var one int
+	if err := env.projectDB(t, user, project).QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Errorf("query with the latest credential: %v", err)
+	}
+
+	// The original password no longer works
+	db, err := sql.Open("postgres", server.URL(*inst.DBName))
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err == nil {
+		t.Error("connected with the original password after rotation")
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// importProgressInterval is the number of rows between two progress reports
+const importProgressInterval = 1000
+
+// ImportProgress reports how far a CSV import has come
+type ImportProgress struct {
+	Rows       int64   `json:"rows"`
+	BytesRead  int64   `json:"bytes_read"`
+	TotalBytes int64   `json:"total_bytes,omitempty"` // 0 when the upload size is unknown
+	Percent    float64 `json:"percent,omitempty"`
+}
+
+// ImportCSV copies the rows of a CSV file into an existing table. With hasHeader the first
+// row names the columns to fill, otherwise the fields fill the table's columns in order,
+// generated columns aside. Empty fields are stored as NULL. All rows are imported in one
+// transaction, so cancelling ctx, e.g. because the client went away, or any bad row rolls
+// the whole import back; the error names the first bad row. progress is called every
+// importProgressInterval rows.
+func (s *TableService) ImportCSV(ctx context.Context, userId uuid.UUID, projectId uuid.UUID, schema string, table string, body io.Reader, hasHeader bool, totalBytes int64, progress func(ImportProgress)) (*ImportProgress, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if !isValidIdentifier(schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(table) {
+		return nil, errors.New("invalid table name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exists, err := s.tableRepo.TableExists(tx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("table not found")
+	}
+
+	counter := &countingReader{r: body}
+	reader := csv.NewReader(counter)
+
+	header, err := s.importColumns(tx, schema, table, reader, hasHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema(schema, table, header...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start import: %w", err)
+	}
+	defer stmt.Close()
+
+	state := ImportProgress{TotalBytes: totalBytes}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, importError(ctx, fmt.Errorf("invalid csv: %w", err))
+		}
+
+		values := make([]interface{}, len(record))
+		for i, value := range record {
+			if value != "" {
+				values[i] = value
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, importError(ctx, fmt.Errorf("failed to import row %d (line %d): %w", state.Rows+1, line, err))
+		}
+
+		state.Rows++
+		if state.Rows%importProgressInterval == 0 && progress != nil {
+			state.update(counter.n)
+			progress(state)
+		}
+	}
+
+	// Flush the rows still buffered by COPY; constraint violations usually surface here
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return nil, importError(ctx, copyError(err))
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, importError(ctx, copyError(err))
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, importError(ctx, fmt.Errorf("failed to commit transaction: %w", err))
+	}
+
+	state.update(counter.n)
+	return &state, nil
+}
+
+// importColumns returns the columns the fields of each CSV row fill. With hasHeader they
+// are read from the first row and must exist in the table, otherwise they are the
+// table's writable columns and the first row must have one field for each.
+func (s *TableService) importColumns(tx *sql.Tx, schema string, table string, reader *csv.Reader, hasHeader bool) ([]string, error) {
+	if !hasHeader {
+		columns, err := s.tableRepo.WritableColumns(tx, schema, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("invalid csv: %s.%s has no columns to import into", schema, table)
+		}
+		// The reader then requires the same number of fields in every row
+		reader.FieldsPerRecord = len(columns)
+		return columns, nil
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("invalid csv: the header row is missing")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	for _, column := range header {
+		if !isValidIdentifier(column) {
+			return nil, fmt.Errorf("invalid csv: invalid column name %q", column)
+		}
+		columnExists, _, err := s.tableRepo.ColumnKeyInfo(tx, schema, table, column)
+		if err != nil {
+			return nil, err
+		}
+		if !columnExists {
+			return nil, fmt.Errorf("invalid csv: column %s does not exist in %s.%s", column, schema, table)
+		}
+	}
+	return header, nil
+}
+
+// copyErrorLine finds the row a COPY error occurred on in the error context
+var copyErrorLine = regexp.MustCompile(`COPY [^,]+, line (\d+)`)
+
+// copyError reports a failure of the buffered COPY with the row that caused it, which
+// PostgreSQL counts from the first data row
+func copyError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if m := copyErrorLine.FindStringSubmatch(pqErr.Where); m != nil {
+			return fmt.Errorf("failed to import row %s: %w", m[1], err)
+		}
+	}
+	return fmt.Errorf("failed to import rows: %w", err)
+}
+
+// update refreshes the byte count and percentage of a progress report
+func (p *ImportProgress) update(bytesRead int64) {
+	p.BytesRead = bytesRead
+	if p.TotalBytes > 0 {
+		p.Percent = math.Min(100, math.Round(float64(bytesRead)*1000/float64(p.TotalBytes))/10)
+	}
+}
+
+// importError reports a cancelled import as such rather than as the failure it caused
+func importError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("import cancelled, no rows were imported: %w", ctx.Err())
+	}
+	return err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
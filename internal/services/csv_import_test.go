@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// csvRows returns a CSV file with a header and n rows of the items table
+func csvRows(n int) string {
+	var sb strings.Builder
+	sb.WriteString("id,name\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "%d,item %d\n", i, i)
+	}
+	return sb.String()
+}
+
+func TestImportProgressUpdate(t *testing.T) {
+	progress := ImportProgress{TotalBytes: 3000}
+	progress.update(1000)
+	if progress.BytesRead != 1000 || progress.Percent != 33.3 {
+		t.Errorf("progress = %+v, want 1000 bytes at 33.3%%", progress)
+	}
+	// A multipart upload is slightly larger than the file it carries
+	progress.update(3100)
+	if progress.Percent != 100 {
+		t.Errorf("percent = %v past the total, want 100", progress.Percent)
+	}
+
+	unknown := ImportProgress{}
+	unknown.update(1000)
+	if unknown.BytesRead != 1000 || unknown.Percent != 0 {
+		t.Errorf("progress of an upload of unknown size = %+v, want no percentage", unknown)
+	}
+}
+
+func TestImportCSVReportsProgress(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE items (id integer PRIMARY KEY, name text)`)
+
+	file := csvRows(2500)
+	var events []ImportProgress
+	result, err := env.tables.ImportCSV(context.Background(), user.ID, project.ID, "public", "items",
+		strings.NewReader(file), true, int64(len(file)), func(progress ImportProgress) {
+			events = append(events, progress)
+		})
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+
+	if len(events) != 2 || events[0].Rows != 1000 || events[1].Rows != 2000 {
+		t.Fatalf("progress events = %+v, want one at 1000 and one at 2000 rows", events)
+	}
+	for i, event := range events {
+		if event.TotalBytes != int64(len(file)) || event.BytesRead <= 0 || event.Percent <= 0 || event.Percent >= 100 {
+			t.Errorf("progress event %d = %+v, want a partial read of the file", i, event)
+		}
+	}
+	if events[1].BytesRead < events[0].BytesRead {
+		t.Errorf("bytes read went back from %d to %d", events[0].BytesRead, events[1].BytesRead)
+	}
+	if result.Rows != 2500 || result.BytesRead != int64(len(file)) || result.Percent != 100 {
+		t.Errorf("result = %+v, want all 2500 rows and the whole file", result)
+	}
+
+	var count int
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT count(*) FROM items`).Scan(&count); err != nil {
+		t.Fatalf("count items: %v", err)
+	}
+	if count != 2500 {
+		t.Errorf("%d rows imported, want 2500", count)
+	}
+}
+
+// stallingReader serves a CSV file and then blocks until the import is cancelled, like
+// an upload whose client went away
+type stallingReader struct {
+	ctx  context.Context
+	file io.Reader
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	if n, err := r.file.Read(p); err != io.EOF {
+		return n, err
+	}
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestImportCSVRollsBack(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE items (id integer PRIMARY KEY, name text)`)
+	countItems := func() int {
+		t.Helper()
+		var count int
+		if err := env.projectDB(t, user, project).QueryRow(`SELECT count(*) FROM items`).Scan(&count); err != nil {
+			t.Fatalf("count items: %v", err)
+		}
+		return count
+	}
+
+	// The client disconnects once the first progress event arrived
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	body := &stallingReader{ctx: ctx, file: strings.NewReader(csvRows(1500))}
+	progressed := false
+	_, err := env.tables.ImportCSV(ctx, user.ID, project.ID, "public", "items", body, true, 0, func(ImportProgress) {
+		progressed = true
+		cancel()
+	})
+	if !progressed || !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "import cancelled") {
+		t.Fatalf("ImportCSV = %v after progress %v, want it cancelled after a progress event", err, progressed)
+	}
+	if count := countItems(); count != 0 {
+		t.Errorf("%d rows left by a cancelled import, want 0", count)
+	}
+
+	// A bad row rolls back the rows before it
+	file := csvRows(10) + "eleven,item 11\n"
+	if _, err := env.tables.ImportCSV(context.Background(), user.ID, project.ID, "public", "items", strings.NewReader(file), true, 0, nil); err == nil || !strings.Contains(err.Error(), "row 11") {
+		t.Fatalf("ImportCSV = %v, want it to fail on row 11", err)
+	}
+	if count := countItems(); count != 0 {
+		t.Errorf("%d rows left by a failed import, want 0", count)
+	}
+}
+
+func TestImportCSVWithoutHeader(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE items (id integer PRIMARY KEY, name text)`)
+
+	// Without a header the values fill the table's columns in order
+	file := strings.TrimPrefix(csvRows(3), "id,name\n")
+	result, err := env.tables.ImportCSV(context.Background(), user.ID, project.ID, "public", "items", strings.NewReader(file), false, 0, nil)
+	if err != nil {
+		t.Fatalf("ImportCSV: %v", err)
+	}
+	if result.Rows != 3 {
+		t.Errorf("rows imported = %d, want 3", result.Rows)
+	}
+
+	var name string
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT name FROM items WHERE id = 1`).Scan(&name); err != nil || name != "item 1" {
+		t.Errorf("first row name = %q, %v, want item 1", name, err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// DDLAuditService records schema-changing operations run against project databases
+type DDLAuditService struct {
+	auditRepo   *repositories.DDLAuditRepository
+	projectRepo *repositories.ProjectRepository
+}
+
+func NewDDLAuditService(auditRepo *repositories.DDLAuditRepository, projectRepo *repositories.ProjectRepository) *DDLAuditService {
+	return &DDLAuditService{
+		auditRepo:   auditRepo,
+		projectRepo: projectRepo,
+	}
+}
+
+// Record stores the outcome of a DDL operation. opErr is the error the operation
+// failed with, or nil on success. Audit failures are logged and never fail the operation.
+func (s *DDLAuditService) Record(userID uuid.UUID, projectID uuid.UUID, operation string, target string, sqlText string, opErr error) {
+	entry := &models.DDLAudit{
+		UserID:    &userID,
+		ProjectID: projectID,
+		Operation: operation,
+		Target:    target,
+		SQLText:   sqlText,
+		Success:   opErr == nil,
+	}
+	if opErr != nil {
+		msg := opErr.Error()
+		entry.ErrorMessage = &msg
+	}
+
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Warning: failed to record DDL audit entry for %s on %s: %v", operation, target, err)
+	}
+}
+
+// GetProjectAudit returns the DDL audit entries of a project owned by the user
+func (s *DDLAuditService) GetProjectAudit(userID uuid.UUID, projectID uuid.UUID, limit int) ([]models.DDLAudit, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("project not found or not accessible")
+	}
+
+	return s.auditRepo.GetByProjectID(projectID, limit)
+}
+
+// GetAllAudit returns the most recent DDL audit entries across all projects (admin only)
+func (s *DDLAuditService) GetAllAudit(limit int) ([]models.DDLAudit, error) {
+	return s.auditRepo.GetAll(limit)
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/mount"
+	"github.com/moby/moby/client"
+)
+
+// maxExecErrorOutput bounds how much of a command's stderr is kept for its error message
+const maxExecErrorOutput = 64 * 1024
+
+// execPollInterval is how often an exec that closed its output is checked for its exit code
+const execPollInterval = 100 * time.Millisecond
+
+// dockerContainers runs the container operations the orchestrator library does not
+// offer directly against the Docker daemon it manages. The daemon is found the same way
+// the Docker CLI finds it, from DOCKER_HOST or the default socket.
+type dockerContainers struct {
+	client *client.Client
+}
+
+func newDockerContainers() (*dockerContainers, error) {
+	cli, err := client.New(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return &dockerContainers{client: cli}, nil
+}
+
+// Logs returns the last tail lines of a container's stdout and stderr, interleaved as
+// they were written
+func (d *dockerContainers) Logs(ctx context.Context, containerID string, tail int) (string, error) {
+	stream, err := d.client.ContainerLogs(ctx, containerID, client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	// Database containers run without a TTY, so both streams arrive multiplexed
+	var logs bytes.Buffer
+	if _, err := stdcopy.StdCopy(&logs, &logs, stream); err != nil {
+		return "", fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return logs.String(), nil
+}
+
+// Pause freezes every process of a container
+func (d *dockerContainers) Pause(ctx context.Context, containerID string) error {
+	_, err := d.client.ContainerPause(ctx, containerID, client.ContainerPauseOptions{})
+	return err
+}
+
+// Unpause resumes a container frozen by Pause
+func (d *dockerContainers) Unpause(ctx context.Context, containerID string) error {
+	_, err := d.client.ContainerUnpause(ctx, containerID, client.ContainerUnpauseOptions{})
+	return err
+}
+
+// Restart stops a container, giving its processes timeout to exit cleanly, and starts it
+// again. The container keeps its volumes, so no data is lost.
+func (d *dockerContainers) Restart(ctx context.Context, containerID string, timeout time.Duration) error {
+	seconds := int(timeout.Seconds())
+	_, err := d.client.ContainerRestart(ctx, containerID, client.ContainerRestartOptions{Timeout: &seconds})
+	return err
+}
+
+// Stats samples the CPU, memory, disk and network usage of a container. CPU usage is
+// measured over the second Docker waits between its two samples. Disk usage is the size
+// of the container's mounts, where database containers keep their data.
+func (d *dockerContainers) Stats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	result, err := d.client.ContainerStats(ctx, containerID, client.ContainerStatsOptions{IncludePreviousSample: true})
+	if err != nil {
+		return nil, err
+	}
+	defer result.Body.Close()
+
+	var sample container.StatsResponse
+	if err := json.NewDecoder(result.Body).Decode(&sample); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	storage, err := d.mountUsage(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &ContainerStats{
+		CPUPercent:       cpuPercent(sample),
+		RAMPercent:       memoryPercent(sample.MemoryStats),
+		StorageUsedBytes: storage,
+	}
+	for _, network := range sample.Networks {
+		stats.NetworkInBytes += network.RxBytes
+		stats.NetworkOutBytes += network.TxBytes
+	}
+	return stats, nil
+}
+
+// cpuPercent is the CPU usage between the two samples of a stats response, where 100%
+// is one fully used CPU
+func cpuPercent(sample container.StatsResponse) float64 {
+	cpuDelta := float64(sample.CPUStats.CPUUsage.TotalUsage) - float64(sample.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(sample.CPUStats.SystemUsage) - float64(sample.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	cpus := float64(sample.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(sample.CPUStats.CPUUsage.PercpuUsage))
+	}
+	return cpuDelta / systemDelta * cpus * 100
+}
+
+// memoryPercent is the memory used by a container against its limit. Like docker stats,
+// it leaves out the page cache the kernel can reclaim.
+func memoryPercent(memory container.MemoryStats) float64 {
+	if memory.Limit == 0 {
+		return 0
+	}
+
+	used := memory.Usage
+	// cgroup v2 reports inactive_file, cgroup v1 total_inactive_file
+	inactive, ok := memory.Stats["inactive_file"]
+	if !ok {
+		inactive = memory.Stats["total_inactive_file"]
+	}
+	if inactive < used {
+		used -= inactive
+	}
+	return float64(used) / float64(memory.Limit) * 100
+}
+
+// mountUsage returns the bytes used by a container's volume and bind mounts, measured
+// with du inside the container
+func (d *dockerContainers) mountUsage(ctx context.Context, containerID string) (int64, error) {
+	inspect, err := d.client.ContainerInspect(ctx, containerID, client.ContainerInspectOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := []string{"du", "-s", "-k"}
+	for _, m := range inspect.Container.Mounts {
+		if m.Type == mount.TypeVolume || m.Type == mount.TypeBind {
+			cmd = append(cmd, m.Destination)
+		}
+	}
+	if len(cmd) == 3 {
+		return 0, nil
+	}
+
+	stream, err := d.Exec(ctx, containerID, cmd)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	output, err := io.ReadAll(stream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure disk usage: %w", err)
+	}
+	return parseDiskUsage(string(output))
+}
+
+// parseDiskUsage sums the sizes in the output of du -s -k, one "<KiB>\t<path>" line per
+// path, in bytes
+func parseDiskUsage(output string) (int64, error) {
+	var total int64
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		kib, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unexpected du output %q", scanner.Text())
+		}
+		total += kib * 1024
+	}
+	return total, scanner.Err()
+}
+
+// Exec runs cmd inside a container and streams its stdout. A non-zero exit code is
+// reported as the stream's error, carrying the command's stderr. Cancelling ctx or
+// closing the stream disconnects from the command.
+func (d *dockerContainers) Exec(ctx context.Context, containerID string, cmd []string) (io.ReadCloser, error) {
+	execID, attach, err := d.startExec(ctx, containerID, cmd, false)
+	if err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, attach.Close)
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer stop()
+		defer attach.Close()
+
+		stderr := &cappedBuffer{max: maxExecErrorOutput}
+		_, err := stdcopy.StdCopy(writer, stderr, attach.Reader)
+		if err == nil {
+			err = d.execError(ctx, execID, cmd[0], stderr.String())
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return &execStream{PipeReader: reader, attach: &attach}, nil
+}
+
+// ExecInput runs cmd inside a container with input as its stdin and returns its combined
+// stdout and stderr. The command's stdin is closed once input is exhausted. A non-zero
+// exit code is reported as an error. Cancelling ctx disconnects from the command, which
+// then sees its stdin end early.
+func (d *dockerContainers) ExecInput(ctx context.Context, containerID string, cmd []string, input io.Reader) ([]byte, error) {
+	execID, attach, err := d.startExec(ctx, containerID, cmd, true)
+	if err != nil {
+		return nil, err
+	}
+	defer attach.Close()
+	stop := context.AfterFunc(ctx, attach.Close)
+	defer stop()
+
+	go func() {
+		// Without a CloseWrite the command keeps waiting for more input until ctx ends
+		if _, err := io.Copy(attach.Conn, input); err == nil {
+			attach.CloseWrite()
+		}
+	}()
+
+	output := &cappedBuffer{max: maxExecErrorOutput}
+	if _, err := stdcopy.StdCopy(output, output, attach.Reader); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return []byte(output.String()), fmt.Errorf("%s did not complete: %w", cmd[0], err)
+	}
+	return []byte(output.String()), d.execError(ctx, execID, cmd[0], output.String())
+}
+
+// startExec creates an exec of cmd in a container and attaches to its output, and to its
+// input when withStdin is set
+func (d *dockerContainers) startExec(ctx context.Context, containerID string, cmd []string, withStdin bool) (string, client.ExecAttachResult, error) {
+	created, err := d.client.ExecCreate(ctx, containerID, client.ExecCreateOptions{
+		Cmd:          cmd,
+		AttachStdin:  withStdin,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", client.ExecAttachResult{}, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := d.client.ExecAttach(ctx, created.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return "", client.ExecAttachResult{}, fmt.Errorf("failed to start exec: %w", err)
+	}
+	return created.ID, attach, nil
+}
+
+// execError waits for an exec whose output has ended to exit and returns an error
+// describing a non-zero exit code
+func (d *dockerContainers) execError(ctx context.Context, execID string, command string, stderr string) error {
+	for {
+		inspect, err := d.client.ExecInspect(ctx, execID, client.ExecInspectOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get the exit code of %s: %w", command, err)
+		}
+		if !inspect.Running {
+			if inspect.ExitCode == 0 {
+				return nil
+			}
+			if message := strings.TrimSpace(stderr); message != "" {
+				return fmt.Errorf("%s exited with code %d: %s", command, inspect.ExitCode, message)
+			}
+			return fmt.Errorf("%s exited with code %d", command, inspect.ExitCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(execPollInterval):
+		}
+	}
+}
+
+// execStream is the stdout of an exec. Closing it disconnects from the exec.
+type execStream struct {
+	*io.PipeReader
+	attach *client.ExecAttachResult
+}
+
+func (s *execStream) Close() error {
+	s.attach.Close()
+	return s.PipeReader.Close()
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest
+type cappedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+func (d *dockerContainers) Close() error {
+	return d.client.Close()
+}
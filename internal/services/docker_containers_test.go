@@ -0,0 +1,79 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+func cpuSample(total, preTotal, system, preSystem uint64, onlineCPUs uint32, perCPU int) container.StatsResponse {
+	var sample container.StatsResponse
+	sample.CPUStats.CPUUsage.TotalUsage = total
+	sample.CPUStats.SystemUsage = system
+	sample.CPUStats.OnlineCPUs = onlineCPUs
+	sample.CPUStats.CPUUsage.PercpuUsage = make([]uint64, perCPU)
+	sample.PreCPUStats.CPUUsage.TotalUsage = preTotal
+	sample.PreCPUStats.SystemUsage = preSystem
+	return sample
+}
+
+func TestCPUPercent(t *testing.T) {
+	cases := []struct {
+		name   string
+		sample container.StatsResponse
+		want   float64
+	}{
+		{"one of two CPUs busy", cpuSample(1_500, 1_000, 2_000, 1_000, 2, 0), 100},
+		{"quarter of one CPU", cpuSample(1_250, 1_000, 2_000, 1_000, 1, 0), 25},
+		{"CPUs counted from per-CPU usage", cpuSample(1_500, 1_000, 2_000, 1_000, 0, 4), 200},
+		{"idle", cpuSample(1_000, 1_000, 2_000, 1_000, 2, 0), 0},
+		{"no previous sample", cpuSample(1_000, 0, 0, 0, 2, 0), 0},
+		{"counter reset", cpuSample(500, 1_000, 2_000, 1_000, 2, 0), 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cpuPercent(tc.sample); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("cpuPercent = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemoryPercent(t *testing.T) {
+	cases := []struct {
+		name   string
+		memory container.MemoryStats
+		want   float64
+	}{
+		{"cgroup v2 page cache left out", container.MemoryStats{Usage: 600, Limit: 1_000, Stats: map[string]uint64{"inactive_file": 100}}, 50},
+		{"cgroup v1 page cache left out", container.MemoryStats{Usage: 600, Limit: 1_000, Stats: map[string]uint64{"total_inactive_file": 350}}, 25},
+		{"no page cache reported", container.MemoryStats{Usage: 300, Limit: 1_000}, 30},
+		{"cache larger than usage", container.MemoryStats{Usage: 300, Limit: 1_000, Stats: map[string]uint64{"inactive_file": 400}}, 30},
+		{"no limit", container.MemoryStats{Usage: 300}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := memoryPercent(tc.memory); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("memoryPercent = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDiskUsage(t *testing.T) {
+	got, err := parseDiskUsage("1024\t/var/lib/postgresql/data\n8\t/tmp/mount\n\n")
+	if err != nil {
+		t.Fatalf("parseDiskUsage: %v", err)
+	}
+	if want := int64(1032 * 1024); got != want {
+		t.Errorf("parseDiskUsage = %d, want %d", got, want)
+	}
+
+	if got, err := parseDiskUsage(""); err != nil || got != 0 {
+		t.Errorf("parseDiskUsage(\"\") = %d, %v, want 0", got, err)
+	}
+	if _, err := parseDiskUsage("du: cannot access '/data': No such file or directory\n"); err == nil {
+		t.Error("parseDiskUsage accepted an error message")
+	}
+}
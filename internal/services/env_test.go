@@ -0,0 +1,155 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"backend/internal/testdb"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testEnv wires the services the way the server does, on a fresh backend database and
+// with a fakeOrchestrator providing the project databases. Tests using it are skipped
+// unless TEST_DATABASE_URL is set.
+type testEnv struct {
+	pool         *pgxpool.Pool
+	orchestrator *fakeOrchestrator
+	connections  *ConnectionManager
+
+	users        *repositories.UserRepository
+	projectRepo  *repositories.ProjectRepository
+	instances    *repositories.DatabaseInstanceRepository
+	credentials  *repositories.DatabaseCredentialRepository
+	history      *repositories.QueryHistoryRepository
+	auditRepo    *repositories.DDLAuditRepository
+	backupRepo   *repositories.BackupRepository
+	userQuotas   *repositories.UserQuotaRepository
+	usageMetrics *repositories.UsageMetricsRepository
+
+	audit    *DDLAuditService
+	quotas   *QuotaService
+	projects *ProjectService
+	queries  *QueryService
+	tables   *TableService
+	schemas  *SchemaService
+	backups  *BackupService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	pool := testdb.Pool(t)
+	t.Setenv("DB_CRED_ENCRYPTION_KEY", "test-encryption-key-0123456789abcdef")
+
+	e := &testEnv{
+		pool:         pool,
+		orchestrator: newFakeOrchestrator(t),
+		connections:  NewConnectionManager(),
+		users:        repositories.NewUserRepository(pool),
+		projectRepo:  repositories.NewProjectRepository(pool),
+		instances:    repositories.NewDatabaseInstanceRepository(pool),
+		credentials:  repositories.NewDatabaseCredentialRepository(pool),
+		history:      repositories.NewQueryHistoryRepository(pool),
+		auditRepo:    repositories.NewDDLAuditRepository(pool),
+		backupRepo:   repositories.NewBackupRepository(pool),
+		userQuotas:   repositories.NewUserQuotaRepository(pool),
+		usageMetrics: repositories.NewUsageMetricsRepository(pool),
+	}
+	// Close the cached pools to the project databases
+	t.Cleanup(e.closeConnections)
+
+	e.audit = NewDDLAuditService(e.auditRepo, e.projectRepo)
+	e.quotas = NewQuotaService(e.userQuotas, e.users, e.projectRepo, e.instances, config.DefaultTierInstanceLimits)
+	e.projects = NewProjectService(e.projectRepo, e.orchestrator, e.instances, e.credentials, e.audit, e.quotas, e.connections, e.usageMetrics)
+	e.queries = NewQueryService(e.projectRepo, e.instances, e.credentials, e.history,
+		repositories.NewQueryPlanSnapshotRepository(pool), repositories.NewSavedQueryRepository(pool),
+		e.orchestrator, 0, e.connections)
+	e.tables = NewTableService(e.projectRepo, e.instances, e.credentials, e.history, repositories.NewTableRepository(pool),
+		e.orchestrator, e.audit, config.TableLimits{MaxColumns: config.DefaultMaxTableColumns, MaxForeignKeys: config.DefaultMaxTableForeignKeys},
+		e.connections)
+	e.schemas = NewSchemaService(e.projectRepo, e.instances, e.credentials, e.orchestrator, e.audit, e.connections)
+	e.backups = NewBackupService(e.projectRepo, e.instances, e.credentials, e.backupRepo, e.orchestrator, t.TempDir())
+	return e
+}
+
+func (e *testEnv) closeConnections() {
+	rows, err := e.pool.Query(context.Background(), `SELECT id FROM database_instances`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		if rows.Scan(&id) == nil {
+			e.connections.Invalidate(id)
+		}
+	}
+}
+
+// createUser stores an active user
+func (e *testEnv) createUser(t *testing.T) *models.User {
+	t.Helper()
+
+	user := &models.User{Email: uuid.NewString() + "@example.com", PasswordHash: "hash"}
+	if err := e.users.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// createProject creates a basic-tier project of dbType with a running database instance
+func (e *testEnv) createProject(t *testing.T, user *models.User, dbType string) *models.Project {
+	t.Helper()
+
+	project, err := e.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{
+		Name:         "project-" + uuid.NewString()[:8],
+		DBType:       dbType,
+		ResourceTier: "basic",
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	return project
+}
+
+// instance returns the database instance of a project
+func (e *testEnv) instance(t *testing.T, project *models.Project) *models.DatabaseInstance {
+	t.Helper()
+
+	inst, err := e.instances.GetByProjectID(project.ID)
+	if err != nil || inst == nil {
+		t.Fatalf("failed to get database instance: %v, %v", inst, err)
+	}
+	return inst
+}
+
+// projectDB returns the pooled connection the services use for a project's database
+func (e *testEnv) projectDB(t *testing.T, user *models.User, project *models.Project) *sql.DB {
+	t.Helper()
+
+	db, err := e.projects.getDBConnection(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("failed to connect to project database: %v", err)
+	}
+	return db
+}
+
+// exec runs statements in a project's database, typically to set up a schema
+func (e *testEnv) exec(t *testing.T, user *models.User, project *models.Project, statements ...string) {
+	t.Helper()
+
+	db := e.projectDB(t, user, project)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("failed to run %q: %v", statement, err)
+		}
+	}
+}
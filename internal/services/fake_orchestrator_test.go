@@ -0,0 +1,227 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/testdb"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// fakeOrchestrator stands in for the container backend in service tests. Each container it
+// creates is a fresh database on the TEST_DATABASE_URL server owned by its own role, so
+// services connect to and query it like a real project database.
+type fakeOrchestrator struct {
+	t testing.TB
+
+	mu          sync.Mutex
+	containers  map[string]fakeDatabase
+	createCalls int
+	deleted     []string
+	restarted   []string
+	paused      []string
+	unpaused    []string
+
+	// forgetIPs makes GetContainerIP miss, as after a backend restart, so IPs come from Redis
+	forgetIPs bool
+	// redisErr fails every Redis lookup
+	redisErr error
+	// createErr, when set, is consulted on each create, numbered from 1
+	createErr func(call int) error
+	// created, when set, runs once a container exists
+	created func(containerID string)
+	// restart, when set, runs in place of a restart
+	restart func(containerID string) error
+	// stats is returned for every container
+	stats *ContainerStats
+	// backup and restore, when set, run in place of pg_dump and psql
+	backup  func(containerID string, dbName string) (io.ReadCloser, error)
+	restore func(containerID string, dbName string, dump io.Reader) error
+}
+
+type fakeDatabase struct {
+	server testdb.Server
+	name   string
+	dbType string
+}
+
+var _ Orchestrator = (*fakeOrchestrator)(nil)
+
+func newFakeOrchestrator(t testing.TB) *fakeOrchestrator {
+	return &fakeOrchestrator{t: t, containers: map[string]fakeDatabase{}}
+}
+
+func (f *fakeOrchestrator) CreateContainer(ctx context.Context, req CreateContainerRequest) (*CreateContainerResponse, error) {
+	f.mu.Lock()
+	f.createCalls++
+	call, createErr := f.createCalls, f.createErr
+	f.mu.Unlock()
+
+	if createErr != nil {
+		if err := createErr(call); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("container creation cancelled: %w", err)
+	}
+	port, ok := defaultPort(req.DatabaseType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type: %s", req.DatabaseType)
+	}
+
+	server, name := testdb.CreateOwned(f.t)
+	// Only postgres containers are backed by the test server
+	if req.DatabaseType == "postgresql" {
+		port = server.Port
+	}
+	id := "container-" + name
+
+	f.mu.Lock()
+	f.containers[id] = fakeDatabase{server: server, name: name, dbType: req.DatabaseType}
+	created := f.created
+	f.mu.Unlock()
+
+	if created != nil {
+		created(id)
+	}
+
+	resp := &CreateContainerResponse{
+		ID:            id,
+		SessionName:   req.SessionName,
+		Status:        "running",
+		ContainerID:   id,
+		ContainerName: name,
+	}
+	resp.ConnectionInfo.Host = server.Host
+	resp.ConnectionInfo.Port = port
+	resp.ConnectionInfo.User = server.User
+	resp.ConnectionInfo.Password = server.Password
+	resp.ConnectionInfo.Database = name
+	return resp, nil
+}
+
+func (f *fakeOrchestrator) GetContainerStatus(instance *models.DatabaseInstance) (*CreateContainerResponse, error) {
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, errors.New("database instance container ID not configured")
+	}
+	ip, err := f.ResolveContainerIP(*instance.ContainerID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &CreateContainerResponse{ID: *instance.ContainerID, ContainerID: *instance.ContainerID, Status: "running"}
+	resp.ConnectionInfo.Host = ip
+	resp.ConnectionInfo.Port = instance.Port
+	resp.ConnectionInfo.Database = instance.DatabaseName()
+	return resp, nil
+}
+
+func (f *fakeOrchestrator) DeleteContainer(containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.containers[containerID]; !ok {
+		return fmt.Errorf("no such container: %s", containerID)
+	}
+	delete(f.containers, containerID)
+	f.deleted = append(f.deleted, containerID)
+	return nil
+}
+
+func (f *fakeOrchestrator) RestartContainer(containerID string) error {
+	f.mu.Lock()
+	f.restarted = append(f.restarted, containerID)
+	restart := f.restart
+	f.mu.Unlock()
+
+	if restart != nil {
+		return restart(containerID)
+	}
+	return nil
+}
+
+func (f *fakeOrchestrator) GetContainerLogs(containerID string, tail int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	db, ok := f.containers[containerID]
+	if !ok {
+		return "", fmt.Errorf("no such container: %s", containerID)
+	}
+	return fmt.Sprintf("last %d lines of %s\n", tail, db.name), nil
+}
+
+func (f *fakeOrchestrator) PauseContainer(containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paused = append(f.paused, containerID)
+	return nil
+}
+
+func (f *fakeOrchestrator) UnpauseContainer(containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unpaused = append(f.unpaused, containerID)
+	return nil
+}
+
+func (f *fakeOrchestrator) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	stats := *f.stats
+	return &stats, nil
+}
+
+func (f *fakeOrchestrator) BackupDatabase(containerID string, dbName string) (io.ReadCloser, error) {
+	if f.backup == nil {
+		return nil, errors.New("backups are not supported by the fake orchestrator")
+	}
+	return f.backup(containerID, dbName)
+}
+
+func (f *fakeOrchestrator) RestoreDatabase(containerID string, dbName string, dump io.Reader) error {
+	if f.restore == nil {
+		return errors.New("restores are not supported by the fake orchestrator")
+	}
+	return f.restore(containerID, dbName, dump)
+}
+
+func (f *fakeOrchestrator) GetContainerIP(containerID string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	db, ok := f.containers[containerID]
+	if !ok || f.forgetIPs {
+		return "", false
+	}
+	return db.server.Host, true
+}
+
+func (f *fakeOrchestrator) GetContainerIPFromRedis(ctx context.Context, containerID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.redisErr != nil {
+		return "", f.redisErr
+	}
+	db, ok := f.containers[containerID]
+	if !ok {
+		return "", errors.New("redis: nil")
+	}
+	return db.server.Host, nil
+}
+
+func (f *fakeOrchestrator) ResolveContainerIP(containerID string) (string, error) {
+	if ip, ok := f.GetContainerIP(containerID); ok {
+		return ip, nil
+	}
+	ip, err := f.GetContainerIPFromRedis(context.Background(), containerID)
+	if err != nil {
+		return "", fmt.Errorf("%w: container %s is not registered with the orchestrator (%v)", ErrContainerUnreachable, containerID, err)
+	}
+	return ip, nil
+}
+
+func (f *fakeOrchestrator) running() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.containers)
+}
@@ -3,9 +3,9 @@ package services
 import (
 	"backend/internal/models"
 	"backend/internal/repositories"
-	"backend/internal/utils"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -18,19 +18,24 @@ const (
 	oauthGoogleUrlAPI = "https://www.googleapis.com/oauth2/v2/userinfo?access_token="
 )
 
+// ErrAccountDeleted is returned when a Google account's email belongs to a deleted user
+var ErrAccountDeleted = errors.New("this email belongs to a deleted account")
+
 type GoogleAuthService struct {
-	userRepo *repositories.UserRepository
-	// sessionRepo *repositories.SessionRepository
+	userRepo    *repositories.UserRepository
+	authService *AuthService
 }
 
-func NewGoogleAuthService(userRepo *repositories.UserRepository) *GoogleAuthService {
+func NewGoogleAuthService(userRepo *repositories.UserRepository, authService *AuthService) *GoogleAuthService {
 	return &GoogleAuthService{
-		userRepo: userRepo,
-		// sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		authService: authService,
 	}
 }
 
-func (s *GoogleAuthService) Callback(ctx context.Context, token *oauth2.Token) (string, error) {
+// Callback logs in the Google user of token, creating the user on their first login, and
+// returns an access and refresh token pair for a new session, like a password login.
+func (s *GoogleAuthService) Callback(ctx context.Context, token *oauth2.Token, client ClientInfo) (string, string, error) {
 	// Create OAuth2 HTTP client with the token
 	oauthClient := &http.Client{
 		Timeout: 10 * time.Second,
@@ -38,14 +43,14 @@ func (s *GoogleAuthService) Callback(ctx context.Context, token *oauth2.Token) (
 
 	req, err := http.NewRequestWithContext(ctx, "GET", "https://www.googleapis.com/oauth2/v2/userinfo", nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create userinfo request: %w", err)
+		return "", "", fmt.Errorf("failed to create userinfo request: %w", err)
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.AccessToken))
 
 	// Fetch user info from Google
 	response, err := oauthClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user info: %w", err)
+		return "", "", fmt.Errorf("failed to get user info: %w", err)
 	}
 	defer response.Body.Close()
 
@@ -59,35 +64,49 @@ func (s *GoogleAuthService) Callback(ctx context.Context, token *oauth2.Token) (
 
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %s", err.Error())
+		return "", "", fmt.Errorf("failed to read response: %s", err.Error())
 	}
 
 	if err := json.Unmarshal(body, &googleUser); err != nil {
-		return "", fmt.Errorf("failed to parse user info: %w", err)
+		return "", "", fmt.Errorf("failed to parse user info: %w", err)
 	}
 
 	if !googleUser.VerifiedEmail {
-		return "", fmt.Errorf("email is not verified by Google")
+		return "", "", fmt.Errorf("email is not verified by Google")
 	}
 
 	user, err := s.userRepo.FindUserByEmail(googleUser.Email)
-	if err != nil || user == nil {
-		// User doesn't exist, create new one
-		newUser := &models.User{
-			Email: googleUser.Email,
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up user: %w", err)
+	}
+	if user == nil {
+		if user, err = s.createUser(googleUser.Email); err != nil {
+			return "", "", err
 		}
+	}
 
-		if err := s.userRepo.Create(newUser); err != nil {
-			return "", fmt.Errorf("failed to create user: %w", err)
-		}
+	return s.authService.startSession(user.ID, client)
+}
 
-		user = newUser
+// createUser creates the user of a Google account logging in for the first time
+func (s *GoogleAuthService) createUser(email string) (*models.User, error) {
+	deleted, err := s.userRepo.ExistsDeletedByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if deleted {
+		return nil, ErrAccountDeleted
 	}
 
-	accessToken, err := utils.GenerateJWT(user.ID, 15*time.Minute, utils.AccessTokenSecret)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate access token: %w", err)
+	user := &models.User{
+		Email: email,
+	}
+	if err := assignInitialRole(s.userRepo, user); err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	return accessToken, nil
+	return user, nil
 }
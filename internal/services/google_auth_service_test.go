@@ -0,0 +1,32 @@
+package services
+
+import (
+	"backend/internal/repositories"
+	"backend/internal/testdb"
+	"errors"
+	"testing"
+)
+
+func TestGoogleUsersGetRolesAndDeletedEmailsAreRefused(t *testing.T) {
+	users := repositories.NewUserRepository(testdb.Pool(t))
+	google := NewGoogleAuthService(users, nil)
+
+	first, err := google.createUser("first-google@example.com")
+	if err != nil {
+		t.Fatalf("createUser: %v", err)
+	}
+	second, err := google.createUser("second-google@example.com")
+	if err != nil {
+		t.Fatalf("createUser: %v", err)
+	}
+	if first.Role != "admin" || second.Role != "user" {
+		t.Errorf("roles = %q and %q, want the first user admin and the next a user", first.Role, second.Role)
+	}
+
+	if err := users.Delete(second.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := google.createUser(second.Email); !errors.Is(err, ErrAccountDeleted) {
+		t.Errorf("createUser with a deleted user's email = %v, want %v", err, ErrAccountDeleted)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"context"
+	"fmt"
+	"log"
+)
+
+// bytesPerGB converts the byte counts reported by the orchestrator to the GB stored in usage_metrics
+const bytesPerGB = 1 << 30
+
+// MetricsCollector samples the resource usage of every running database container into
+// usage_metrics
+type MetricsCollector struct {
+	instanceRepo *repositories.DatabaseInstanceRepository
+	metricsRepo  *repositories.UsageMetricsRepository
+	orchestrator Orchestrator
+}
+
+func NewMetricsCollector(
+	instanceRepo *repositories.DatabaseInstanceRepository,
+	metricsRepo *repositories.UsageMetricsRepository,
+	orchestrator Orchestrator,
+) *MetricsCollector {
+	return &MetricsCollector{
+		instanceRepo: instanceRepo,
+		metricsRepo:  metricsRepo,
+		orchestrator: orchestrator,
+	}
+}
+
+// Collect stores one sample per running instance. A container that cannot be sampled
+// is logged and skipped so it does not hold back the others; collection stops early
+// when ctx is cancelled.
+func (c *MetricsCollector) Collect(ctx context.Context) error {
+	instances, err := c.instanceRepo.ListRunning()
+	if err != nil {
+		return fmt.Errorf("failed to list running database instances: %w", err)
+	}
+
+	for _, inst := range instances {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		stats, err := c.orchestrator.GetContainerStats(ctx, *inst.ContainerID)
+		if err != nil {
+			log.Printf("Failed to get stats of container %s: %v", *inst.ContainerID, err)
+			continue
+		}
+
+		metric := &models.UsageMetric{
+			DBInstanceID:   inst.ID,
+			CPUPercent:     float32Ptr(stats.CPUPercent),
+			RAMPercent:     float32Ptr(stats.RAMPercent),
+			StorageUsedGB:  float32Ptr(float64(stats.StorageUsedBytes) / bytesPerGB),
+			BandwidthInGB:  float32Ptr(float64(stats.NetworkInBytes) / bytesPerGB),
+			BandwidthOutGB: float32Ptr(float64(stats.NetworkOutBytes) / bytesPerGB),
+		}
+		if err := c.metricsRepo.Create(metric); err != nil {
+			log.Printf("Failed to store usage metrics of instance %s: %v", inst.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func float32Ptr(v float64) *float32 {
+	f := float32(v)
+	return &f
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCollectStoresSampleOfRunningInstances(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	inst := env.instance(t, env.createProject(t, user, "postgres"))
+
+	env.orchestrator.stats = &ContainerStats{
+		CPUPercent:       12.5,
+		RAMPercent:       40,
+		StorageUsedBytes: 2 * bytesPerGB,
+		NetworkInBytes:   bytesPerGB / 2,
+		NetworkOutBytes:  bytesPerGB / 4,
+	}
+	collector := NewMetricsCollector(env.instances, env.usageMetrics, env.orchestrator)
+	if err := collector.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	metrics, err := env.usageMetrics.GetByInstanceID(inst.ID, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil || len(metrics) != 1 {
+		t.Fatalf("GetByInstanceID = %v, %v, want one sample", metrics, err)
+	}
+	m := metrics[0]
+	if *m.CPUPercent != 12.5 || *m.RAMPercent != 40 || *m.StorageUsedGB != 2 || *m.BandwidthInGB != 0.5 || *m.BandwidthOutGB != 0.25 {
+		t.Errorf("sample = cpu %v ram %v storage %v in %v out %v", *m.CPUPercent, *m.RAMPercent, *m.StorageUsedGB, *m.BandwidthInGB, *m.BandwidthOutGB)
+	}
+}
@@ -1,21 +1,96 @@
 package services
 
 import (
+	"backend/internal/apperr"
+	"backend/internal/config"
+	"backend/internal/models"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	orchestrator "github.com/KilluaDB/Orchestrator"
 	"github.com/google/uuid"
 )
 
+// maxContainerNameAttempts bounds how often creation is retried after a container name collision
+const maxContainerNameAttempts = 3
+
+// containerDBUser is the superuser created in every database container
+const containerDBUser = "admin"
+
+// containerBackupTimeout bounds a pg_dump streamed out of a container
+const containerBackupTimeout = 30 * time.Minute
+
+// containerRestoreTimeout bounds a SQL dump piped into psql inside a container
+const containerRestoreTimeout = 30 * time.Minute
+
+// containerStopTimeout is how long a restarted container's database gets to shut down
+// cleanly before it is killed
+const containerStopTimeout = 30 * time.Second
+
+// maxContainerNameLength keeps container names usable as the container's hostname
+const maxContainerNameLength = 63
+
+// Orchestrator manages the database containers backing projects.
+// Services depend on this interface so the container backend can be substituted.
+type Orchestrator interface {
+	CreateContainer(ctx context.Context, req CreateContainerRequest) (*CreateContainerResponse, error)
+	GetContainerStatus(instance *models.DatabaseInstance) (*CreateContainerResponse, error)
+	DeleteContainer(containerID string) error
+	RestartContainer(containerID string) error
+	GetContainerLogs(containerID string, tail int) (string, error)
+	PauseContainer(containerID string) error
+	UnpauseContainer(containerID string) error
+	GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error)
+	BackupDatabase(containerID string, dbName string) (io.ReadCloser, error)
+	RestoreDatabase(containerID string, dbName string, dump io.Reader) error
+	GetContainerIP(containerID string) (string, bool)
+	GetContainerIPFromRedis(ctx context.Context, containerID string) (string, error)
+	ResolveContainerIP(containerID string) (string, error)
+}
+
+var _ Orchestrator = (*OrchestratorService)(nil)
+
+// ErrContainerUnreachable is returned when a project's container cannot be reached on the
+// orchestrator network, instead of letting the connection attempt time out
+var ErrContainerUnreachable = errors.New("database container is not reachable")
+
+// containerRuntime is the part of the orchestrator library the service relies on, so
+// tests can stand in for Docker and Redis
+type containerRuntime interface {
+	CreateContainer(ctx context.Context, opts orchestrator.ContainerOptions) (string, error)
+	GetContainerIP(containerID string) (string, bool)
+	GetContainerIPFromRedis(ctx context.Context, containerID string) (string, error)
+	StopContainer(ctx context.Context, containerID string) error
+	Close() error
+}
+
+var _ containerRuntime = (*orchestrator.Orchestrator)(nil)
+
 type OrchestratorService struct {
-	orchestrator *orchestrator.Orchestrator
+	orchestrator containerRuntime
 	ctx          context.Context
+
+	// docker runs the container operations the orchestrator has no API for
+	docker *dockerContainers
+
+	// networkName and subnet describe the network containers are expected to be on
+	networkName string
+	subnet      *net.IPNet
+
+	// environment prefixes container names, when set
+	environment string
+
+	// createMu serializes container creation so concurrent creates cannot be
+	// handed the same IP from the subnet by the orchestrator
+	createMu sync.Mutex
 }
 
 type CreateContainerRequest struct {
@@ -59,19 +134,24 @@ func NewOrchestratorService() (*OrchestratorService, error) {
 	if subnetCIDR == "" {
 		return nil, fmt.Errorf("ORCHESTRATOR_SUBNET_CIDR environment variable is required")
 	}
+	_, subnet, err := net.ParseCIDR(subnetCIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ORCHESTRATOR_SUBNET_CIDR: %w", err)
+	}
 
 	gateway := os.Getenv("ORCHESTRATOR_GATEWAY")
 	if gateway == "" {
 		return nil, fmt.Errorf("ORCHESTRATOR_GATEWAY environment variable is required")
 	}
 
-	monitorIntervalStr := os.Getenv("ORCHESTRATOR_MONITOR_INTERVAL")
-	if monitorIntervalStr == "" {
-		return nil, fmt.Errorf("ORCHESTRATOR_MONITOR_INTERVAL environment variable is required")
+	monitorInterval, err := config.MonitorInterval()
+	if err != nil {
+		return nil, err
 	}
-	monitorInterval, err := strconv.Atoi(monitorIntervalStr)
+
+	environment, err := config.EnvironmentName()
 	if err != nil {
-		return nil, fmt.Errorf("ORCHESTRATOR_MONITOR_INTERVAL must be a valid integer: %w", err)
+		return nil, err
 	}
 
 	// Create orchestrator config
@@ -105,24 +185,33 @@ func NewOrchestratorService() (*OrchestratorService, error) {
 
 	log.Println("Orchestrator initialized successfully")
 
+	docker, err := newDockerContainers()
+	if err != nil {
+		orch.Close()
+		return nil, err
+	}
+
 	return &OrchestratorService{
 		orchestrator: orch,
 		ctx:          ctx,
+		docker:       docker,
+		networkName:  networkName,
+		subnet:       subnet,
+		environment:  environment,
 	}, nil
 }
 
-func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*CreateContainerResponse, error) {
+// CreateContainer creates and starts a database container. If ctx is cancelled or times
+// out once the container exists, the container is stopped again so nothing is orphaned.
+func (s *OrchestratorService) CreateContainer(ctx context.Context, req CreateContainerRequest) (*CreateContainerResponse, error) {
 	// Get database image based on type
 	image := s.getDatabaseImage(req.DatabaseType)
 	if image == "" {
 		return nil, fmt.Errorf("unsupported database type: %s", req.DatabaseType)
 	}
 
-	// Generate container name
-	containerName := fmt.Sprintf("%s-%s", req.DatabaseType, uuid.New().String()[:8])
-
 	// Generate credentials
-	user := "admin"
+	user := containerDBUser
 	password := uuid.New().String()[:16]
 	database := req.SessionName
 
@@ -156,7 +245,7 @@ func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*Crea
 	}
 
 	// Get default port
-	port := s.getDefaultPort(req.DatabaseType)
+	port, _ := defaultPort(req.DatabaseType)
 
 	// Set resource limits from configuration if provided
 	resourceLimits := orchestrator.ResourceLimits{
@@ -178,7 +267,6 @@ func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*Crea
 
 	// Create container options
 	opts := orchestrator.ContainerOptions{
-		Name:            containerName,
 		Image:           image,
 		Env:             env,
 		ResourceLimits:  resourceLimits,
@@ -186,8 +274,7 @@ func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*Crea
 	}
 
 	// Create and start container
-	log.Printf("Creating container with name: %s, image: %s", containerName, image)
-	containerID, err := s.orchestrator.CreateContainer(s.ctx, opts)
+	containerID, containerName, err := s.createUniqueContainer(ctx, req.DatabaseType, opts)
 	if err != nil {
 		log.Printf("ERROR: Orchestrator CreateContainer failed: %v", err)
 		return nil, fmt.Errorf("failed to create container: %w", err)
@@ -199,14 +286,21 @@ func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*Crea
 	if !ok {
 		log.Printf("Container IP not found in memory, trying Redis for container: %s", containerID)
 		// Try to get from Redis
-		ip, err = s.orchestrator.GetContainerIPFromRedis(s.ctx, containerID)
+		ip, err = s.orchestrator.GetContainerIPFromRedis(ctx, containerID)
 		if err != nil {
 			log.Printf("ERROR: Failed to get container IP from Redis: %v", err)
+			s.cleanupContainer(containerID)
 			return nil, fmt.Errorf("failed to get container IP: %w", err)
 		}
 	}
 	log.Printf("Container IP retrieved: %s", ip)
 
+	// The caller gave up while the container was starting
+	if err := ctx.Err(); err != nil {
+		s.cleanupContainer(containerID)
+		return nil, fmt.Errorf("container creation cancelled: %w", err)
+	}
+
 	response := &CreateContainerResponse{
 		ID:            containerID,
 		SessionName:   req.SessionName,
@@ -231,7 +325,69 @@ func (s *OrchestratorService) CreateContainer(req CreateContainerRequest) (*Crea
 	return response, nil
 }
 
-func (s *OrchestratorService) GetContainerStatus(containerID string) (*CreateContainerResponse, error) {
+// createUniqueContainer creates a container under a freshly generated name, retrying with a
+// new name if the generated one is already taken. Creation is serialized so the orchestrator
+// allocates IPs from the subnet one container at a time.
+func (s *OrchestratorService) createUniqueContainer(ctx context.Context, dbType string, opts orchestrator.ContainerOptions) (string, string, error) {
+	s.createMu.Lock()
+	defer s.createMu.Unlock()
+
+	var lastErr error
+	for attempt := 0; attempt < maxContainerNameAttempts; attempt++ {
+		// Don't start a container nobody is waiting for anymore
+		if err := ctx.Err(); err != nil {
+			return "", "", fmt.Errorf("container creation cancelled: %w", err)
+		}
+
+		opts.Name = s.containerName(dbType)
+
+		log.Printf("Creating container with name: %s, image: %s", opts.Name, opts.Image)
+		containerID, err := s.orchestrator.CreateContainer(ctx, opts)
+		if err == nil {
+			return containerID, opts.Name, nil
+		}
+		if !isNameConflict(err) {
+			return "", "", err
+		}
+
+		log.Printf("Container name %s already in use, retrying with a new name", opts.Name)
+		lastErr = err
+	}
+
+	return "", "", fmt.Errorf("could not find a free container name after %d attempts: %w", maxContainerNameAttempts, lastErr)
+}
+
+// containerName generates a container name, "<environment>-<dbtype>-<random>" or
+// "<dbtype>-<random>" without an environment. The environment is shortened if the name
+// would not fit in maxContainerNameLength.
+func (s *OrchestratorService) containerName(dbType string) string {
+	name := fmt.Sprintf("%s-%s", dbType, uuid.New().String()[:8])
+	if s.environment == "" {
+		return name
+	}
+
+	environment := s.environment
+	if room := maxContainerNameLength - len(name) - 1; len(environment) > room {
+		environment = environment[:room]
+	}
+	return environment + "-" + name
+}
+
+// isNameConflict reports whether a create error was caused by the container name already being in use
+func isNameConflict(err error) bool {
+	errMsg := strings.ToLower(err.Error())
+	return strings.Contains(errMsg, "already in use") || strings.Contains(errMsg, "conflict")
+}
+
+// GetContainerStatus reports the container of a database instance. The IP is looked up
+// live since it can change; the port and database are the ones stored when the
+// container was created.
+func (s *OrchestratorService) GetContainerStatus(instance *models.DatabaseInstance) (*CreateContainerResponse, error) {
+	if instance.ContainerID == nil || *instance.ContainerID == "" {
+		return nil, errors.New("database instance container ID not configured")
+	}
+	containerID := *instance.ContainerID
+
 	// Get container IP
 	ip, ok := s.orchestrator.GetContainerIP(containerID)
 	if !ok {
@@ -255,8 +411,9 @@ func (s *OrchestratorService) GetContainerStatus(containerID string) (*CreateCon
 			Password string `json:"password"`
 			Database string `json:"database"`
 		}{
-			Host: ip,
-			Port: 5432, // Default, should be stored/retrieved
+			Host:     ip,
+			Port:     instance.Port,
+			Database: instance.DatabaseName(),
 		},
 	}
 
@@ -269,6 +426,130 @@ func (s *OrchestratorService) DeleteContainer(containerID string) error {
 	return s.orchestrator.StopContainer(ctx, containerID)
 }
 
+// RestartContainer stops a container and starts it again, keeping its volume. The
+// database gets containerStopTimeout to shut down cleanly.
+func (s *OrchestratorService) RestartContainer(containerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerStopTimeout+30*time.Second)
+	defer cancel()
+	return s.docker.Restart(ctx, containerID, containerStopTimeout)
+}
+
+// GetContainerLogs returns the last tail lines of a container's stdout and stderr
+func (s *OrchestratorService) GetContainerLogs(containerID string, tail int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.docker.Logs(ctx, containerID, tail)
+}
+
+// ContainerStats is the resource usage of a container at one point in time
+type ContainerStats struct {
+	CPUPercent       float64
+	RAMPercent       float64
+	StorageUsedBytes int64
+	NetworkInBytes   uint64 // Received since the container started
+	NetworkOutBytes  uint64 // Sent since the container started
+}
+
+// GetContainerStats samples the CPU, memory, disk and network usage of a container
+func (s *OrchestratorService) GetContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return s.docker.Stats(ctx, containerID)
+}
+
+// PauseContainer freezes every process of a container, keeping its memory and data
+func (s *OrchestratorService) PauseContainer(containerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.docker.Pause(ctx, containerID)
+}
+
+// UnpauseContainer resumes a container frozen by PauseContainer
+func (s *OrchestratorService) UnpauseContainer(containerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.docker.Unpause(ctx, containerID)
+}
+
+// BackupDatabase runs pg_dump inside a postgres container and streams the plain SQL dump
+// of dbName. Closing the stream stops the dump if it is still running.
+func (s *OrchestratorService) BackupDatabase(containerID string, dbName string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerBackupTimeout)
+	stream, err := s.docker.Exec(ctx, containerID, []string{
+		"pg_dump", "--username", containerDBUser, "--dbname", dbName, "--no-owner",
+	})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start pg_dump: %w", err)
+	}
+	return &cancelOnClose{ReadCloser: stream, cancel: cancel}, nil
+}
+
+// RestoreDatabase pipes a plain SQL dump into psql inside a postgres container. The dump
+// runs in a single transaction and stops at the first error, so a failed restore leaves
+// dbName as it was. The transaction is wrapped around the dump here rather than with
+// psql's --single-transaction, which commits whatever it read once its input ends: the
+// COMMIT is only sent after the whole dump was read, and an error reading the dump
+// disconnects psql, which then exits with the transaction still open.
+func (s *OrchestratorService) RestoreDatabase(containerID string, dbName string, dump io.Reader) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerRestoreTimeout)
+	defer cancel()
+
+	script := io.MultiReader(
+		strings.NewReader("BEGIN;\n"),
+		&cancelOnError{r: dump, cancel: cancel},
+		strings.NewReader("\nCOMMIT;\n"),
+	)
+	output, err := s.docker.ExecInput(ctx, containerID, []string{
+		"psql", "--username", containerDBUser, "--dbname", dbName,
+		"--set", "ON_ERROR_STOP=1", "--quiet",
+	}, script)
+	if err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return apperr.Invalid(apperr.CodeRestoreFailed, "restore failed: "+message)
+		}
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	return nil
+}
+
+// cancelOnError cancels a context when reading from r fails, rather than letting the
+// reader's consumer see a truncated stream as a complete one
+type cancelOnError struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnError) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && err != io.EOF {
+		c.cancel()
+	}
+	return n, err
+}
+
+// cancelOnClose releases the context of a stream once the stream is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// cleanupContainer stops a container created for a request that did not complete
+func (s *OrchestratorService) cleanupContainer(containerID string) {
+	if err := s.DeleteContainer(containerID); err != nil {
+		log.Printf("ERROR: Failed to clean up container %s: %v", containerID, err)
+		return
+	}
+	log.Printf("Cleaned up container %s", containerID)
+}
+
 // GetContainerIP gets the container IP address from the orchestrator
 // Returns the IP and true if found, or empty string and false if not found
 func (s *OrchestratorService) GetContainerIP(containerID string) (string, bool) {
@@ -281,6 +562,40 @@ func (s *OrchestratorService) GetContainerIPFromRedis(ctx context.Context, conta
 	return s.orchestrator.GetContainerIPFromRedis(ctx, containerID)
 }
 
+// ResolveContainerIP gets the container IP from memory, falling back to Redis, and checks
+// the container is still on the orchestrator network before anyone connects to it. A
+// container that is unknown or whose IP lies outside the configured subnet yields an
+// ErrContainerUnreachable describing why, rather than a connection timeout later on.
+func (s *OrchestratorService) ResolveContainerIP(containerID string) (string, error) {
+	ip, ok := s.orchestrator.GetContainerIP(containerID)
+	if !ok {
+		var err error
+		ip, err = s.orchestrator.GetContainerIPFromRedis(s.ctx, containerID)
+		if err != nil {
+			return "", fmt.Errorf("%w: container %s is not registered with the orchestrator, it may have stopped or been removed (%v)",
+				ErrContainerUnreachable, containerID, err)
+		}
+	}
+
+	if err := s.checkContainerNetwork(containerID, ip); err != nil {
+		return "", err
+	}
+	return ip, nil
+}
+
+// checkContainerNetwork verifies a container's IP belongs to the orchestrator network's subnet
+func (s *OrchestratorService) checkContainerNetwork(containerID string, ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return fmt.Errorf("%w: container %s has an invalid IP address %q", ErrContainerUnreachable, containerID, ip)
+	}
+	if s.subnet != nil && !s.subnet.Contains(parsed) {
+		return fmt.Errorf("%w: container %s has IP %s, outside the subnet %s of network %s; it may be attached to another network or the subnet changed",
+			ErrContainerUnreachable, containerID, ip, s.subnet, s.networkName)
+	}
+	return nil
+}
+
 // Helper functions
 
 func (s *OrchestratorService) getDatabaseImage(databaseType string) string {
@@ -298,19 +613,27 @@ func (s *OrchestratorService) getDatabaseImage(databaseType string) string {
 	return ""
 }
 
-func (s *OrchestratorService) getDefaultPort(databaseType string) int {
-	ports := map[string]int{
-		"postgresql": 5432,
-		"mysql":      3306,
-		"mongodb":    27017,
-		"redis":      6379,
-	}
+// defaultPorts are the ports each orchestrator database type listens on
+var defaultPorts = map[string]int{
+	"postgresql": 5432,
+	"mysql":      3306,
+	"mongodb":    27017,
+	"redis":      6379,
+}
 
-	if port, ok := ports[databaseType]; ok {
-		return port
+// orchestratorDBType maps a project's db_type to the orchestrator's database type
+func orchestratorDBType(dbType string) string {
+	if dbType == "postgres" {
+		return "postgresql"
 	}
+	return dbType
+}
 
-	return 5432
+// defaultPort returns the port an orchestrator database type listens on, or false for an
+// unknown type
+func defaultPort(databaseType string) (int, bool) {
+	port, ok := defaultPorts[databaseType]
+	return port, ok
 }
 
 func (s *OrchestratorService) getVolumeMountPath(databaseType string) string {
@@ -329,8 +652,11 @@ func (s *OrchestratorService) getVolumeMountPath(databaseType string) string {
 	return "/var/lib/postgresql/data"
 }
 
-// Close closes the orchestrator
+// Close closes the orchestrator and the Docker client
 func (s *OrchestratorService) Close() error {
+	if s.docker != nil {
+		s.docker.Close()
+	}
 	if s.orchestrator != nil {
 		return s.orchestrator.Close()
 	}
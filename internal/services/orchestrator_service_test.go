@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	orchestrator "github.com/KilluaDB/Orchestrator"
+)
+
+// fakeRuntime stands in for the orchestrator library, handing out IPs from 10.10.0.0/16
+type fakeRuntime struct {
+	mu         sync.Mutex
+	containers map[string]fakeContainer
+	created    []orchestrator.ContainerOptions
+	stopped    []string
+	nextIP     int
+
+	// forgetIPs makes GetContainerIP miss, as after a backend restart, so IPs come from Redis
+	forgetIPs bool
+	// redisErr fails every Redis lookup
+	redisErr error
+	// createErr, when set, is consulted before each container is created
+	createErr func(opts orchestrator.ContainerOptions) error
+	// onCreate, when set, runs once a container exists
+	onCreate func(containerID string)
+}
+
+type fakeContainer struct {
+	name string
+	ip   string
+}
+
+func newFakeRuntime() *fakeRuntime {
+	return &fakeRuntime{containers: map[string]fakeContainer{}}
+}
+
+func (f *fakeRuntime) CreateContainer(ctx context.Context, opts orchestrator.ContainerOptions) (string, error) {
+	f.mu.Lock()
+	if f.createErr != nil {
+		if err := f.createErr(opts); err != nil {
+			f.mu.Unlock()
+			return "", err
+		}
+	}
+	for _, c := range f.containers {
+		if c.name == opts.Name {
+			f.mu.Unlock()
+			return "", fmt.Errorf("Conflict. The container name %q is already in use", opts.Name)
+		}
+	}
+	// Like the orchestrator, the next free IP is picked before the container starts and
+	// only marked as used once it runs, so overlapping creates are handed the same IP
+	n := f.nextIP + 1
+	f.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	f.mu.Lock()
+	f.nextIP = n
+	id := fmt.Sprintf("container-%d", len(f.created)+1)
+	f.containers[id] = fakeContainer{name: opts.Name, ip: fmt.Sprintf("10.10.%d.%d", n/250, n%250+2)}
+	f.created = append(f.created, opts)
+	onCreate := f.onCreate
+	f.mu.Unlock()
+
+	if onCreate != nil {
+		onCreate(id)
+	}
+	return id, nil
+}
+
+func (f *fakeRuntime) GetContainerIP(containerID string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.containers[containerID]
+	if !ok || f.forgetIPs {
+		return "", false
+	}
+	return c.ip, true
+}
+
+func (f *fakeRuntime) GetContainerIPFromRedis(ctx context.Context, containerID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.redisErr != nil {
+		return "", f.redisErr
+	}
+	c, ok := f.containers[containerID]
+	if !ok {
+		return "", errors.New("redis: nil")
+	}
+	return c.ip, nil
+}
+
+func (f *fakeRuntime) StopContainer(ctx context.Context, containerID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.containers[containerID]; !ok {
+		return fmt.Errorf("no such container: %s", containerID)
+	}
+	delete(f.containers, containerID)
+	f.stopped = append(f.stopped, containerID)
+	return nil
+}
+
+func (f *fakeRuntime) Close() error {
+	return nil
+}
+
+func (f *fakeRuntime) running() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.containers)
+}
+
+func newTestOrchestratorService(runtime *fakeRuntime) *OrchestratorService {
+	_, subnet, _ := net.ParseCIDR("10.10.0.0/16")
+	return &OrchestratorService{
+		orchestrator: runtime,
+		ctx:          context.Background(),
+		networkName:  "test-network",
+		subnet:       subnet,
+	}
+}
+
+func TestCreateContainer(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+
+	if len(runtime.created) != 1 {
+		t.Fatalf("created %d containers, want 1", len(runtime.created))
+	}
+	opts := runtime.created[0]
+	if opts.Image != "postgres:16-alpine" || opts.VolumeMountPath != "/var/lib/postgresql/data" {
+		t.Errorf("container options = %+v, want the postgres image and data directory", opts)
+	}
+	if opts.Env["POSTGRES_DB"] != "shop" || opts.Env["POSTGRES_USER"] != "admin" {
+		t.Errorf("container env = %v, want database shop owned by admin", opts.Env)
+	}
+
+	info := resp.ConnectionInfo
+	if resp.ContainerID != "container-1" || resp.ContainerName != opts.Name {
+		t.Errorf("response container = %s %s, want container-1 %s", resp.ContainerID, resp.ContainerName, opts.Name)
+	}
+	if info.Host != "10.10.0.3" || info.Port != 5432 || info.User != "admin" || info.Database != "shop" {
+		t.Errorf("connection info = %+v", info)
+	}
+	if info.Password == "" || info.Password != opts.Env["POSTGRES_PASSWORD"] {
+		t.Errorf("connection password %q does not match the container's", info.Password)
+	}
+}
+
+func TestCreateContainerRejectsUnknownDatabaseType(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	if _, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "oracle"}); err == nil {
+		t.Fatal("CreateContainer succeeded for an unsupported database type")
+	}
+	if len(runtime.created) != 0 {
+		t.Errorf("created %d containers, want none", len(runtime.created))
+	}
+}
+
+func TestCreateContainerFallsBackToRedisForIP(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.forgetIPs = true
+	s := newTestOrchestratorService(runtime)
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+	if resp.ConnectionInfo.Host != "10.10.0.3" {
+		t.Errorf("host = %q, want the IP stored in Redis", resp.ConnectionInfo.Host)
+	}
+}
+
+func TestCreateContainerStopsContainerWithoutIP(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.forgetIPs = true
+	runtime.redisErr = errors.New("redis: connection refused")
+	s := newTestOrchestratorService(runtime)
+
+	if _, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"}); err == nil {
+		t.Fatal("CreateContainer succeeded without a container IP")
+	}
+	if runtime.running() != 0 || len(runtime.stopped) != 1 {
+		t.Errorf("%d containers left running, %d stopped, want the created one stopped", runtime.running(), len(runtime.stopped))
+	}
+}
+
+func TestDeleteContainer(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+	if err := s.DeleteContainer(resp.ContainerID); err != nil {
+		t.Fatalf("DeleteContainer: %v", err)
+	}
+	if runtime.running() != 0 {
+		t.Errorf("%d containers still running", runtime.running())
+	}
+	if err := s.DeleteContainer(resp.ContainerID); err == nil {
+		t.Error("deleting a removed container succeeded")
+	}
+}
+
+func TestResolveContainerIPFallsBackToRedis(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+
+	runtime.forgetIPs = true
+	ip, err := s.ResolveContainerIP(resp.ContainerID)
+	if err != nil || ip != resp.ConnectionInfo.Host {
+		t.Fatalf("ResolveContainerIP = %q, %v, want %q", ip, err, resp.ConnectionInfo.Host)
+	}
+}
+
+func TestConcurrentCreatesGetUniqueNamesAndIPs(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	const creates = 50
+	responses := make([]*CreateContainerResponse, creates)
+	errs := make([]error, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = s.CreateContainer(context.Background(), CreateContainerRequest{
+				SessionName:  fmt.Sprintf("project-%d", i),
+				DatabaseType: "postgresql",
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	names := map[string]bool{}
+	ips := map[string]bool{}
+	for i, resp := range responses {
+		if errs[i] != nil {
+			t.Fatalf("create %d: %v", i, errs[i])
+		}
+		if names[resp.ContainerName] {
+			t.Errorf("container name %s handed out twice", resp.ContainerName)
+		}
+		if ips[resp.ConnectionInfo.Host] {
+			t.Errorf("IP %s handed out twice", resp.ConnectionInfo.Host)
+		}
+		names[resp.ContainerName] = true
+		ips[resp.ConnectionInfo.Host] = true
+	}
+	if runtime.running() != creates {
+		t.Errorf("%d containers running, want %d", runtime.running(), creates)
+	}
+}
+
+func TestCreateContainerRetriesTakenName(t *testing.T) {
+	runtime := newFakeRuntime()
+	var attempted []string
+	runtime.createErr = func(opts orchestrator.ContainerOptions) error {
+		attempted = append(attempted, opts.Name)
+		if len(attempted) == 1 {
+			return fmt.Errorf("Conflict. The container name %q is already in use", opts.Name)
+		}
+		return nil
+	}
+	s := newTestOrchestratorService(runtime)
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+	if len(attempted) != 2 || attempted[0] == attempted[1] || resp.ContainerName != attempted[1] {
+		t.Errorf("attempted names %v, created %s, want a second, different name", attempted, resp.ContainerName)
+	}
+}
+
+func TestCreateContainerGivesUpOnRepeatedNameConflicts(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.createErr = func(opts orchestrator.ContainerOptions) error {
+		return fmt.Errorf("Conflict. The container name %q is already in use", opts.Name)
+	}
+	s := newTestOrchestratorService(runtime)
+
+	if _, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"}); err == nil {
+		t.Fatal("CreateContainer succeeded although every name was taken")
+	}
+}
+
+func TestCreateContainerDoesNotRetryOtherErrors(t *testing.T) {
+	runtime := newFakeRuntime()
+	calls := 0
+	runtime.createErr = func(orchestrator.ContainerOptions) error {
+		calls++
+		return errors.New("no space left on device")
+	}
+	s := newTestOrchestratorService(runtime)
+
+	if _, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"}); err == nil {
+		t.Fatal("CreateContainer succeeded")
+	}
+	if calls != 1 {
+		t.Errorf("create attempted %d times, want 1", calls)
+	}
+}
+
+func TestCancelledCreateStartsNoContainer(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.CreateContainer(ctx, CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateContainer error = %v, want context.Canceled", err)
+	}
+	if len(runtime.created) != 0 {
+		t.Errorf("created %d containers, want none", len(runtime.created))
+	}
+}
+
+func TestCreateCancelledWhileStartingLeavesNoOrphan(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runtime.onCreate = func(string) { cancel() }
+	if _, err := s.CreateContainer(ctx, CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateContainer error = %v, want context.Canceled", err)
+	}
+	if len(runtime.created) != 1 || runtime.running() != 0 {
+		t.Errorf("created %d containers and left %d running, want the one created stopped", len(runtime.created), runtime.running())
+	}
+}
+
+func TestResolveContainerIPChecksNetwork(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.containers["on-network"] = fakeContainer{name: "on-network", ip: "10.10.4.2"}
+	runtime.containers["other-network"] = fakeContainer{name: "other-network", ip: "172.17.0.5"}
+	runtime.containers["bad-ip"] = fakeContainer{name: "bad-ip", ip: "not-an-ip"}
+	s := newTestOrchestratorService(runtime)
+
+	if ip, err := s.ResolveContainerIP("on-network"); err != nil || ip != "10.10.4.2" {
+		t.Errorf("ResolveContainerIP(on-network) = %q, %v, want 10.10.4.2", ip, err)
+	}
+
+	cases := map[string]string{
+		"other-network": "outside the subnet 10.10.0.0/16 of network test-network",
+		"bad-ip":        "invalid IP address",
+		"missing":       "not registered with the orchestrator",
+	}
+	for containerID, reason := range cases {
+		_, err := s.ResolveContainerIP(containerID)
+		if !errors.Is(err, ErrContainerUnreachable) {
+			t.Errorf("ResolveContainerIP(%s) error = %v, want ErrContainerUnreachable", containerID, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), reason) {
+			t.Errorf("ResolveContainerIP(%s) error = %q, want it to mention %q", containerID, err, reason)
+		}
+	}
+}
+
+func TestContainerNamePrefix(t *testing.T) {
+	runtime := newFakeRuntime()
+	s := newTestOrchestratorService(runtime)
+	s.environment = "staging"
+
+	resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: "postgresql"})
+	if err != nil {
+		t.Fatalf("CreateContainer: %v", err)
+	}
+	name := runtime.created[0].Name
+	if !strings.HasPrefix(name, "staging-postgresql-") || len(name) != len("staging-postgresql-")+8 {
+		t.Errorf("container name = %q, want staging-postgresql-<8 characters>", name)
+	}
+	if resp.ContainerName != name {
+		t.Errorf("response container name = %q, want %q", resp.ContainerName, name)
+	}
+}
+
+func TestContainerNameWithoutEnvironment(t *testing.T) {
+	s := newTestOrchestratorService(newFakeRuntime())
+
+	if name := s.containerName("mysql"); !strings.HasPrefix(name, "mysql-") || len(name) != len("mysql-")+8 {
+		t.Errorf("container name = %q, want mysql-<8 characters>", name)
+	}
+}
+
+func TestContainerNameShortensLongEnvironment(t *testing.T) {
+	s := newTestOrchestratorService(newFakeRuntime())
+	s.environment = strings.Repeat("e", 60)
+
+	name := s.containerName("postgresql")
+	if len(name) != maxContainerNameLength {
+		t.Errorf("container name %q has %d characters, want %d", name, len(name), maxContainerNameLength)
+	}
+	if !strings.HasPrefix(name, "eee") || !strings.Contains(name, "-postgresql-") {
+		t.Errorf("container name = %q, want the shortened environment then the database type", name)
+	}
+}
+
+func TestCreateContainerReportsPortOfDatabaseType(t *testing.T) {
+	ports := map[string]int{"postgresql": 5432, "mysql": 3306, "mongodb": 27017, "redis": 6379}
+	for dbType, want := range ports {
+		s := newTestOrchestratorService(newFakeRuntime())
+
+		resp, err := s.CreateContainer(context.Background(), CreateContainerRequest{SessionName: "shop", DatabaseType: dbType})
+		if err != nil {
+			t.Fatalf("CreateContainer(%s): %v", dbType, err)
+		}
+		if resp.ConnectionInfo.Port != want {
+			t.Errorf("%s port = %d, want %d", dbType, resp.ConnectionInfo.Port, want)
+		}
+	}
+}
+
+func TestProjectDBTypesHavePorts(t *testing.T) {
+	for dbType, want := range map[string]int{"postgres": 5432, "mongodb": 27017} {
+		if port, ok := defaultPort(orchestratorDBType(dbType)); !ok || port != want {
+			t.Errorf("default port of %s = %d, %v, want %d", dbType, port, ok, want)
+		}
+	}
+	if _, ok := defaultPort("oracle"); ok {
+		t.Error("oracle has a default port")
+	}
+}
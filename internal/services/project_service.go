@@ -1,41 +1,88 @@
 package services
 
 import (
+	"backend/internal/apperr"
 	"backend/internal/models"
+	"backend/internal/pagination"
 	"backend/internal/repositories"
 	"backend/internal/utils"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 )
 
+// credentialsToKeep is how many of an instance's most recent credentials are retained
+const credentialsToKeep = 3
+
+// containerCreateTimeout bounds how long a project creation waits for its container
+const containerCreateTimeout = 3 * time.Minute
+
+// projectStorageGB is the storage allocated to each project's database, the same for all tiers
+const projectStorageGB = 10
+
+// readinessCacheTTL is how long a connectivity probe result is reused
+const readinessCacheTTL = 10 * time.Second
+
+type readinessEntry struct {
+	ready     bool
+	checkedAt time.Time
+}
+
 type ProjectService struct {
 	projectRepo      *repositories.ProjectRepository
-	orchestrator     *OrchestratorService
+	orchestrator     Orchestrator
 	dbInstanceRepo   *repositories.DatabaseInstanceRepository
 	dbCredentialRepo *repositories.DatabaseCredentialRepository
+	ddlAudit         *DDLAuditService
+	quotaService     *QuotaService
+	connections      *ConnectionManager
+	usageMetricsRepo *repositories.UsageMetricsRepository
+
+	readinessMu    sync.Mutex
+	readinessCache map[uuid.UUID]readinessEntry
+
+	// creating holds the cancel functions of container creations in progress, by project ID
+	creatingMu sync.Mutex
+	creating   map[uuid.UUID]context.CancelFunc
 }
 
 func NewProjectService(
 	projectRepo *repositories.ProjectRepository,
-	orchestrator *OrchestratorService,
+	orchestrator Orchestrator,
 	dbInstanceRepo *repositories.DatabaseInstanceRepository,
 	dbCredentialRepo *repositories.DatabaseCredentialRepository,
+	ddlAudit *DDLAuditService,
+	quotaService *QuotaService,
+	connections *ConnectionManager,
+	usageMetricsRepo *repositories.UsageMetricsRepository,
 ) *ProjectService {
 	return &ProjectService{
 		projectRepo:      projectRepo,
 		orchestrator:     orchestrator,
 		dbInstanceRepo:   dbInstanceRepo,
 		dbCredentialRepo: dbCredentialRepo,
+		ddlAudit:         ddlAudit,
+		quotaService:     quotaService,
+		connections:      connections,
+		usageMetricsRepo: usageMetricsRepo,
+		readinessCache:   make(map[uuid.UUID]readinessEntry),
+		creating:         make(map[uuid.UUID]context.CancelFunc),
 	}
 }
 
@@ -46,7 +93,10 @@ type CreateProjectRequest struct {
 	ResourceTier string  `json:"resource_tier" binding:"required"` // 'free', 'basic', or 'premium'
 }
 
-func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest) (*models.Project, error) {
+// CreateProject creates a project and its database container. Cancelling ctx (e.g. the
+// client disconnecting) or deleting the project while its container is being created
+// aborts the creation and removes the partially created container.
+func (s *ProjectService) CreateProject(ctx context.Context, userID string, req CreateProjectRequest) (*models.Project, error) {
 	// Parse user ID
 	userUUID, err := utils.ParseUUID(userID)
 	if err != nil {
@@ -63,24 +113,25 @@ func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest)
 		return nil, fmt.Errorf("invalid resource_tier: must be 'free', 'basic', or 'premium'")
 	}
 
+	// Enforce the user's project, storage and tier quotas
+	if err := s.quotaService.CheckProjectQuota(userUUID, projectStorageGB); err != nil {
+		return nil, err
+	}
+	if err := s.quotaService.CheckTierQuota(userUUID, req.ResourceTier); err != nil {
+		return nil, err
+	}
+
 	// Create project record
 	project := &models.Project{
 		UserID:       userUUID,
 		Name:         req.Name,
-		Description:  req.Description,
+		Description:  normalizeDescription(req.Description),
 		DBType:       req.DBType,
 		ResourceTier: req.ResourceTier,
 	}
 
-	if err := s.projectRepo.Create(project); err != nil {
-		return nil, fmt.Errorf("failed to save project to database: %w", err)
-	}
-
 	// Map DB type for orchestrator (postgres -> postgresql)
-	dbTypeForOrchestrator := req.DBType
-	if req.DBType == "postgres" {
-		dbTypeForOrchestrator = "postgresql"
-	}
+	dbTypeForOrchestrator := orchestratorDBType(req.DBType)
 
 	// Map resource tier to resource limits
 	resourceConfig := s.getResourceConfigForTier(req.ResourceTier)
@@ -88,35 +139,55 @@ func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest)
 	// Get CPU and RAM values for database instance
 	cpuCores := int(resourceConfig["cpu"].(float64))
 	ramMB := int(resourceConfig["memory_mb"].(float64))
-	// Storage can be set based on tier as well, defaulting to 10GB for all tiers
-	storageGB := 10
-
-	// Get default port for database type
-	var port int
-	if req.DBType == "postgres" {
-		port = 5432
-	} else if req.DBType == "mongodb" {
-		port = 27017
-	} else {
-		port = 5432 // Default to postgres port
+	storageGB := projectStorageGB
+
+	// The orchestrator reports the actual port once the container exists
+	port, ok := defaultPort(dbTypeForOrchestrator)
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type: %s", req.DBType)
 	}
 
 	// Create database instance record (status: creating) with resource information
 	dbInstance := &models.DatabaseInstance{
-		ProjectID: project.ID,
 		Status:    "creating",
 		CPUCores:  &cpuCores,
 		RAMMB:     &ramMB,
 		StorageGB: &storageGB,
-		Port:      &port,
+		Port:      port,
+	}
+
+	// The project and its instance rows are committed together, so a failure never
+	// leaves a project without an instance behind
+	err = s.projectRepo.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.projectRepo.CreateTx(ctx, tx, project); err != nil {
+			return fmt.Errorf("failed to save project to database: %w", err)
+		}
+		dbInstance.ProjectID = project.ID
+		if err := s.dbInstanceRepo.CreateTx(ctx, tx, dbInstance); err != nil {
+			return fmt.Errorf("failed to create database instance: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.dbInstanceRepo.Create(dbInstance); err != nil {
-		// If instance creation fails, delete the project (rollback)
-		s.projectRepo.Delete(project.ID)
-		return nil, fmt.Errorf("failed to create database instance: %w", err)
+	if err := s.provisionInstance(ctx, project, dbInstance); err != nil {
+		return nil, err
 	}
 
+	s.attachReadiness(project)
+
+	return project, nil
+}
+
+// provisionInstance creates the container of a project's database instance and stores its
+// connection details and credentials, marking the instance running. When the container
+// cannot be created the instance is marked failed.
+func (s *ProjectService) provisionInstance(ctx context.Context, project *models.Project, dbInstance *models.DatabaseInstance) error {
+	dbTypeForOrchestrator := orchestratorDBType(project.DBType)
+	resourceConfig := s.getResourceConfigForTier(project.ResourceTier)
+
 	// Create container via orchestrator
 	orchestratorReq := CreateContainerRequest{
 		SessionName:   project.ID.String(), // Use project ID as session name
@@ -124,14 +195,19 @@ func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest)
 		Configuration: resourceConfig,
 	}
 
-	fmt.Printf("Creating container for project %s with database type %s and tier %s (CPU: %d, RAM: %dMB)\n",
-		project.ID.String(), dbTypeForOrchestrator, req.ResourceTier, cpuCores, ramMB)
-	orchestratorResp, err := s.orchestrator.CreateContainer(orchestratorReq)
+	createCtx, cancel := context.WithTimeout(ctx, containerCreateTimeout)
+	defer cancel()
+	s.trackCreation(project.ID, cancel)
+	defer s.untrackCreation(project.ID)
+
+	fmt.Printf("Creating container for project %s with database type %s and tier %s (CPU: %v, RAM: %vMB)\n",
+		project.ID.String(), dbTypeForOrchestrator, project.ResourceTier, resourceConfig["cpu"], resourceConfig["memory_mb"])
+	orchestratorResp, err := s.orchestrator.CreateContainer(createCtx, orchestratorReq)
 	if err != nil {
 		// Update instance status to failed
 		s.dbInstanceRepo.UpdateStatus(dbInstance.ID, "failed")
 		fmt.Printf("ERROR: Failed to create container: %v\n", err)
-		return nil, fmt.Errorf("failed to create container: %w", err)
+		return fmt.Errorf("failed to create container: %w", err)
 	}
 	fmt.Printf("Container created successfully: %s\n", orchestratorResp.ContainerID)
 
@@ -140,12 +216,28 @@ func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest)
 
 	// Store container ID (IP will be retrieved from orchestrator when needed)
 	if err := s.dbInstanceRepo.UpdateContainerID(dbInstance.ID, containerID); err != nil {
-		return nil, fmt.Errorf("failed to update database instance container ID: %w", err)
+		return fmt.Errorf("failed to update database instance container ID: %w", err)
+	}
+	connInfo := orchestratorResp.ConnectionInfo
+	if err := s.dbInstanceRepo.UpdateConnectionInfo(dbInstance.ID, connInfo.Host, connInfo.Port, connInfo.Database); err != nil {
+		return fmt.Errorf("failed to update database instance connection info: %w", err)
+	}
+
+	// If the project was deleted before the container ID was stored, nothing else will stop it
+	current, err := s.dbInstanceRepo.GetByProjectID(project.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+	if current == nil {
+		if err := s.orchestrator.DeleteContainer(containerID); err != nil {
+			fmt.Printf("Warning: Failed to stop container %s of deleted project %s: %v\n", containerID, project.ID, err)
+		}
+		return errors.New("project was deleted while its container was being created")
 	}
 
 	// Update status to running
 	if err := s.dbInstanceRepo.UpdateStatus(dbInstance.ID, "running"); err != nil {
-		return nil, fmt.Errorf("failed to update database instance status: %w", err)
+		return fmt.Errorf("failed to update database instance status: %w", err)
 	}
 
 	// Store database credentials: encrypt the password returned by the orchestrator
@@ -163,10 +255,54 @@ func (s *ProjectService) CreateProject(userID string, req CreateProjectRequest)
 		if err := s.dbCredentialRepo.Create(credential); err != nil {
 			// Log error but don't fail - credentials can be recreated by recreating the instance
 			fmt.Printf("Warning: failed to save database credentials: %v\n", err)
+		} else {
+			s.pruneCredentials(dbInstance.ID)
 		}
 	}
 
-	return project, nil
+	return nil
+}
+
+// trackCreation registers the cancel function of a project's in-progress container creation
+func (s *ProjectService) trackCreation(projectID uuid.UUID, cancel context.CancelFunc) {
+	s.creatingMu.Lock()
+	defer s.creatingMu.Unlock()
+	s.creating[projectID] = cancel
+}
+
+func (s *ProjectService) untrackCreation(projectID uuid.UUID) {
+	s.creatingMu.Lock()
+	defer s.creatingMu.Unlock()
+	delete(s.creating, projectID)
+}
+
+// cancelCreation aborts a project's in-progress container creation on this instance, if any
+func (s *ProjectService) cancelCreation(projectID uuid.UUID) bool {
+	s.creatingMu.Lock()
+	defer s.creatingMu.Unlock()
+
+	cancel, ok := s.creating[projectID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// pruneCredentials removes superseded credentials of an instance once a new one is stored.
+// The instance's cached pools are closed first, so no connection opened with an older
+// credential outlives it; later requests connect with the latest one. A few older rows
+// are kept for audit.
+func (s *ProjectService) pruneCredentials(instanceID uuid.UUID) {
+	s.connections.Invalidate(instanceID)
+
+	deleted, err := s.dbCredentialRepo.DeleteAllExceptLatest(instanceID, credentialsToKeep)
+	if err != nil {
+		fmt.Printf("Warning: failed to prune old database credentials: %v\n", err)
+		return
+	}
+	if deleted > 0 {
+		fmt.Printf("Pruned %d old database credentials for instance %s\n", deleted, instanceID)
+	}
 }
 
 func (s *ProjectService) GetProjectByID(projectID string) (*models.Project, error) {
@@ -198,9 +334,56 @@ func (s *ProjectService) GetProjectByIDAndUserID(projectID string, userID string
 		return nil, fmt.Errorf("project not found or access denied")
 	}
 
+	s.attachReadiness(project)
+
 	return project, nil
 }
 
+// attachReadiness fills in the instance status and whether the project's database is connectable
+func (s *ProjectService) attachReadiness(project *models.Project) {
+	ready := false
+	project.Ready = &ready
+
+	inst, err := s.dbInstanceRepo.GetByProjectID(project.ID)
+	if err != nil || inst == nil {
+		return
+	}
+	project.InstanceStatus = inst.Status
+
+	if inst.Status != "running" {
+		return
+	}
+	ready = s.probeInstance(inst)
+}
+
+// probeInstance checks that the instance accepts TCP connections, caching the result briefly
+func (s *ProjectService) probeInstance(inst *models.DatabaseInstance) bool {
+	s.readinessMu.Lock()
+	entry, ok := s.readinessCache[inst.ID]
+	s.readinessMu.Unlock()
+	if ok && time.Since(entry.checkedAt) < readinessCacheTTL {
+		return entry.ready
+	}
+
+	ready := false
+	if inst.ContainerID != nil && *inst.ContainerID != "" {
+		ip, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+		if err == nil {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, strconv.Itoa(inst.Port)), 2*time.Second)
+			if err == nil {
+				conn.Close()
+				ready = true
+			}
+		}
+	}
+
+	s.readinessMu.Lock()
+	s.readinessCache[inst.ID] = readinessEntry{ready: ready, checkedAt: time.Now()}
+	s.readinessMu.Unlock()
+
+	return ready
+}
+
 func (s *ProjectService) GetProjectsByUserID(userID string) ([]models.Project, error) {
 	userUUID, err := utils.ParseUUID(userID)
 	if err != nil {
@@ -210,6 +393,37 @@ func (s *ProjectService) GetProjectsByUserID(userID string) ([]models.Project, e
 	return s.projectRepo.GetByUserID(userUUID)
 }
 
+// GetProjectsPageByUserID returns a page of a user's projects along with
+// the cursor of the next page ("" when there are no more projects)
+func (s *ProjectService) GetProjectsPageByUserID(userID string, limit int, cursor string) ([]models.Project, string, error) {
+	userUUID, err := utils.ParseUUID(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra project to know whether another page exists
+	projects, err := s.projectRepo.GetPageByUserID(userUUID, limit+1, after)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(projects) <= limit {
+		return projects, "", nil
+	}
+
+	projects = projects[:limit]
+	last := projects[len(projects)-1]
+	next, err := pagination.Encode(pagination.Cursor{Time: last.CreatedAt, ID: last.ID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode pagination cursor: %w", err)
+	}
+	return projects, next, nil
+}
+
 func (s *ProjectService) DeleteProject(projectID string) error {
 	projectUUID, err := utils.ParseUUID(projectID)
 	if err != nil {
@@ -227,6 +441,9 @@ func (s *ProjectService) DeleteProject(projectID string) error {
 
 	// Note: Container deletion should be handled via database_instances table
 	// For now, just delete the project (CASCADE will handle related records)
+	if dbInstance, err := s.dbInstanceRepo.GetByProjectID(projectUUID); err == nil && dbInstance != nil {
+		s.connections.Invalidate(dbInstance.ID)
+	}
 
 	// Delete project from database
 	return s.projectRepo.Delete(projectUUID)
@@ -258,6 +475,15 @@ func (s *ProjectService) DeleteProjectByIDAndUserID(projectID string, userID str
 		return fmt.Errorf("failed to get database instance: %w", err)
 	}
 
+	// A container still being created is stopped by CreateProject once it is cancelled
+	if s.cancelCreation(projectUUID) {
+		fmt.Printf("Cancelled in-progress container creation for project %s\n", projectID)
+	}
+
+	if dbInstance != nil {
+		s.connections.Invalidate(dbInstance.ID)
+	}
+
 	// If database instance exists and has a container ID, stop the container
 	if dbInstance != nil && dbInstance.ContainerID != nil && *dbInstance.ContainerID != "" {
 		// Try to stop container via orchestrator (best effort, don't fail if it fails)
@@ -278,6 +504,141 @@ func (s *ProjectService) DeleteProjectByIDAndUserID(projectID string, userID str
 	return nil
 }
 
+// maxBulkDeleteProjects caps how many projects can be deleted in a single request
+const maxBulkDeleteProjects = 50
+
+// DeleteProjectsRequest represents the request body for deleting several projects at once
+type DeleteProjectsRequest struct {
+	ProjectIDs []uuid.UUID `json:"project_ids" binding:"required,min=1"`
+}
+
+// DeleteProjectResult reports the outcome of deleting a single project in a bulk request
+type DeleteProjectResult struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DeleteProjects deletes several projects owned by the user, allowing partial success.
+// Each project is verified and deleted independently; results are keyed by project ID.
+func (s *ProjectService) DeleteProjects(userID uuid.UUID, projectIDs []uuid.UUID) (map[string]DeleteProjectResult, error) {
+	if len(projectIDs) == 0 {
+		return nil, errors.New("at least one project ID is required")
+	}
+	if len(projectIDs) > maxBulkDeleteProjects {
+		return nil, fmt.Errorf("cannot delete more than %d projects at once", maxBulkDeleteProjects)
+	}
+
+	results := make(map[string]DeleteProjectResult, len(projectIDs))
+	for _, projectID := range projectIDs {
+		key := projectID.String()
+		if _, seen := results[key]; seen {
+			continue // Skip duplicate IDs
+		}
+
+		if err := s.DeleteProjectByIDAndUserID(key, userID.String()); err != nil {
+			results[key] = DeleteProjectResult{Success: false, Error: err.Error()}
+			continue
+		}
+		results[key] = DeleteProjectResult{Success: true}
+	}
+
+	return results, nil
+}
+
+// maxBlockedKeywords caps the size of a project's blocked keyword list
+const maxBlockedKeywords = 20
+
+// UpdateProjectRequest represents the request body for updating a project.
+// Only fields that are present are changed.
+type UpdateProjectRequest struct {
+	Name            *string   `json:"name"`
+	Description     *string   `json:"description"`
+	ReadOnly        *bool     `json:"read_only"`
+	BlockedKeywords *[]string `json:"blocked_keywords"`
+	RedactQueryText *bool     `json:"redact_query_text"`
+}
+
+// UpdateProject updates a project's details and query policy
+func (s *ProjectService) UpdateProject(userID uuid.UUID, projectID uuid.UUID, req UpdateProjectRequest) (*models.Project, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("project not found or access denied")
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("project name cannot be empty")
+		}
+		project.Name = name
+	}
+	if req.Description != nil {
+		// An empty description clears it
+		project.Description = normalizeDescription(req.Description)
+	}
+	if req.ReadOnly != nil {
+		project.ReadOnly = *req.ReadOnly
+	}
+	if req.BlockedKeywords != nil {
+		keywords, err := normalizeBlockedKeywords(*req.BlockedKeywords)
+		if err != nil {
+			return nil, err
+		}
+		project.BlockedKeywords = keywords
+	}
+	if req.RedactQueryText != nil {
+		project.RedactQueryText = *req.RedactQueryText
+	}
+
+	if err := s.projectRepo.Update(project); err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	s.attachReadiness(project)
+
+	return project, nil
+}
+
+// normalizeDescription trims a project description, mapping a blank one to nil so the
+// column holds NULL rather than an empty string
+func normalizeDescription(description *string) *string {
+	if description == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*description)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+// normalizeBlockedKeywords validates, upper-cases and de-duplicates blocked keywords
+func normalizeBlockedKeywords(keywords []string) ([]string, error) {
+	if len(keywords) > maxBlockedKeywords {
+		return nil, fmt.Errorf("cannot block more than %d keywords", maxBlockedKeywords)
+	}
+
+	validPattern := regexp.MustCompile(`^[A-Z]+( [A-Z]+)*$`)
+	seen := make(map[string]bool, len(keywords))
+	normalized := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		keyword = strings.ToUpper(strings.Join(strings.Fields(keyword), " "))
+		if !validPattern.MatchString(keyword) {
+			return nil, fmt.Errorf("invalid blocked keyword '%s': must contain only letters and spaces", keyword)
+		}
+		if seen[keyword] {
+			continue
+		}
+		seen[keyword] = true
+		normalized = append(normalized, keyword)
+	}
+
+	return normalized, nil
+}
+
 // getResourceConfigForTier maps resource tiers to resource configurations
 // Returns a map with cpu (in cores) and memory_mb (in MB) for the orchestrator
 func (s *ProjectService) getResourceConfigForTier(tier string) map[string]interface{} {
@@ -305,7 +666,7 @@ func (s *ProjectService) getResourceConfigForTier(tier string) map[string]interf
 	return config
 }
 
-// getDBConnection gets a database connection for a project's database instance
+// getDBConnection gets the pooled connection to a project's database instance. It is shared, so callers must not close it.
 func (s *ProjectService) getDBConnection(userID uuid.UUID, projectID uuid.UUID) (*sql.DB, error) {
 	// Validate project ownership
 	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
@@ -315,6 +676,9 @@ func (s *ProjectService) getDBConnection(userID uuid.UUID, projectID uuid.UUID)
 	if project == nil {
 		return nil, errors.New("project not found or not accessible")
 	}
+	if err := requirePostgres(project); err != nil {
+		return nil, err
+	}
 
 	// Find running DB instance for this project
 	inst, err := s.dbInstanceRepo.GetRunningByProjectID(projectID)
@@ -338,19 +702,11 @@ func (s *ProjectService) getDBConnection(userID uuid.UUID, projectID uuid.UUID)
 	if inst.ContainerID == nil || *inst.ContainerID == "" {
 		return nil, errors.New("database instance container ID not configured")
 	}
-	if inst.Port == nil {
-		return nil, errors.New("database instance port not configured")
-	}
 
 	// Get container IP from orchestrator
-	containerIP, ok := s.orchestrator.GetContainerIP(*inst.ContainerID)
-	if !ok {
-		// Try to get from Redis as fallback
-		var err error
-		containerIP, err = s.orchestrator.GetContainerIPFromRedis(context.Background(), *inst.ContainerID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get container IP: %w", err)
-		}
+	containerIP, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container IP: %w", err)
 	}
 
 	// Decrypt password before building DSN
@@ -360,14 +716,20 @@ func (s *ProjectService) getDBConnection(userID uuid.UUID, projectID uuid.UUID)
 	}
 
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		containerIP, *inst.Port, cred.Username, dbPassword, "postgres")
+		containerIP, inst.Port, cred.Username, dbPassword, inst.DatabaseName())
 
-	sqlDB, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
+	return s.connections.Get(inst.ID, dsn)
+}
+
+// ErrPostgresOnly is returned when a SQL-only operation targets a non-postgres project
+var ErrPostgresOnly = apperr.Invalid(apperr.CodeUnsupportedDBType, "this operation is only supported for postgres projects")
 
-	return sqlDB, nil
+// requirePostgres rejects projects whose database type cannot serve SQL operations
+func requirePostgres(project *models.Project) error {
+	if project.DBType != "postgres" {
+		return ErrPostgresOnly
+	}
+	return nil
 }
 
 // validateIdentifier validates SQL identifiers (table names, column names) to prevent SQL injection
@@ -421,7 +783,6 @@ func (s *ProjectService) InsertRow(userID uuid.UUID, projectID uuid.UUID, req In
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
 
 	// Check if the table has an 'id' column before attempting RETURNING id
 	// PostgreSQL stores identifiers in lowercase in information_schema unless quoted
@@ -546,7 +907,6 @@ func (s *ProjectService) DeleteRow(
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
 	rowIDInt, err := strconv.ParseInt(rowID, 10, 64)
 	if err != nil {
@@ -575,130 +935,1033 @@ func (s *ProjectService) DeleteRow(
 	return nil
 }
 
-// AddColumnRequest represents the request body for adding a column
-type AddColumnRequest struct {
-	TableName string      `json:"table_name" binding:"required"`
-	Name      string      `json:"name" binding:"required"`
-	Type      string      `json:"type" binding:"required"`
-	Default   interface{} `json:"default,omitempty"`
+// UpdateRowsRequest represents the request body for updating rows matching a filter.
+// Where conditions are combined with AND; a null value matches NULL.
+type UpdateRowsRequest struct {
+	Set   map[string]interface{} `json:"set" binding:"required"`
+	Where map[string]interface{} `json:"where" binding:"required"`
 }
 
-// AddColumnResponse represents the response for adding a column
-type AddColumnResponse struct {
-	ColumnID int64 `json:"column_id"`
+// UpdateRowsResponse represents the response for updating rows
+type UpdateRowsResponse struct {
+	RowsAffected int64 `json:"rows_affected"`
 }
 
-// AddColumn adds a column to a table
-func (s *ProjectService) AddColumn(userID uuid.UUID, projectID uuid.UUID, req AddColumnRequest) (*AddColumnResponse, error) {
+// UpdateRows updates every row of a table matching the filter. Unconditional updates are refused.
+func (s *ProjectService) UpdateRows(userID uuid.UUID, projectID uuid.UUID, table string, req UpdateRowsRequest) (*UpdateRowsResponse, error) {
 	// Validate table name
-	if err := validateIdentifier(req.TableName); err != nil {
+	if err := validateIdentifier(table); err != nil {
 		return nil, fmt.Errorf("invalid table name: %w", err)
 	}
+	if len(req.Set) == 0 {
+		return nil, errors.New("set cannot be empty")
+	}
+	if len(req.Where) == 0 {
+		return nil, errors.New("where cannot be empty: unconditional updates are not allowed")
+	}
 
-	// Validate column name
-	if err := validateIdentifier(req.Name); err != nil {
-		return nil, fmt.Errorf("invalid column name: %w", err)
+	// Sort column names so the generated statement is deterministic
+	setColumns := make([]string, 0, len(req.Set))
+	for col := range req.Set {
+		if err := validateIdentifier(col); err != nil {
+			return nil, fmt.Errorf("invalid column name '%s': %w", col, err)
+		}
+		setColumns = append(setColumns, col)
 	}
+	sort.Strings(setColumns)
 
-	// Validate type is not empty
-	if req.Type == "" {
-		return nil, errors.New("column type cannot be empty")
+	whereColumns := make([]string, 0, len(req.Where))
+	for col := range req.Where {
+		if err := validateIdentifier(col); err != nil {
+			return nil, fmt.Errorf("invalid column name '%s': %w", col, err)
+		}
+		whereColumns = append(whereColumns, col)
 	}
+	sort.Strings(whereColumns)
 
-	// Get database connection
-	db, err := s.getDBConnection(userID, projectID)
-	if err != nil {
-		return nil, err
+	// Build the parameterized UPDATE statement
+	values := make([]interface{}, 0, len(setColumns)+len(whereColumns))
+	assignments := make([]string, 0, len(setColumns))
+	for _, col := range setColumns {
+		values = append(values, req.Set[col])
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(col), len(values)))
 	}
-	defer db.Close()
 
-	// Build ALTER TABLE query
-	tableNameQuoted := pq.QuoteIdentifier(req.TableName)
-	columnNameQuoted := pq.QuoteIdentifier(req.Name)
+	conditions := make([]string, 0, len(whereColumns))
+	for _, col := range whereColumns {
+		if req.Where[col] == nil {
+			conditions = append(conditions, fmt.Sprintf("%s IS NULL", pq.QuoteIdentifier(col)))
+			continue
+		}
+		values = append(values, req.Where[col])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(col), len(values)))
+	}
 
-	// Build the ALTER TABLE statement
-	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableNameQuoted, columnNameQuoted, req.Type)
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		pq.QuoteIdentifier(table), strings.Join(assignments, ", "), strings.Join(conditions, " AND "))
 
-	// Add DEFAULT clause if provided
-	// Since default is omitempty, if it's nil, the field might not be in the JSON
-	// We'll only add DEFAULT if it's explicitly provided (handled by binding:"omitempty")
-	// For now, we'll use the value as-is in the SQL, but this is not ideal for security
-	// A better approach would be to validate and quote properly based on type
-	if req.Default != nil {
-		// Format default value based on type
-		switch v := req.Default.(type) {
-		case string:
-			// Escape single quotes in strings
-			escaped := strings.ReplaceAll(v, "'", "''")
-			query += fmt.Sprintf(" DEFAULT '%s'", escaped)
-		case bool:
-			if v {
-				query += " DEFAULT TRUE"
-			} else {
-				query += " DEFAULT FALSE"
-			}
-		default:
-			// For numbers and other types, use as-is (they should be safe)
-			query += fmt.Sprintf(" DEFAULT %v", v)
-		}
+	// Get database connection
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Execute query
-	_, err = db.Exec(query)
+	result, err := db.Exec(query, values...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add column: %w", err)
+		return nil, fmt.Errorf("failed to update rows in table %s: %w", table, err)
 	}
 
-	// Get the column's ordinal position as column_id
-	// PostgreSQL stores column information in information_schema.columns
-	var columnID int64
-	err = db.QueryRow(`
-		SELECT ordinal_position 
-		FROM information_schema.columns 
-		WHERE table_name = $1 AND column_name = $2
-	`, req.TableName, req.Name).Scan(&columnID)
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		// If we can't get the column_id, return 0
-		columnID = 0
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
-	return &AddColumnResponse{ColumnID: columnID}, nil
+	return &UpdateRowsResponse{RowsAffected: rowsAffected}, nil
 }
 
-// DeleteColumnRequest represents the request body for deleting a column
-type DeleteColumnRequest struct {
-	TableName string `json:"table_name" binding:"required"`
+// maxBulkInsertParams is the most values one bulk insert may bind, PostgreSQL's limit of
+// parameters per statement
+const maxBulkInsertParams = 65535
+
+// InsertRowsRequest represents the request body for inserting several rows at once.
+// Table is taken from the :table path parameter.
+type InsertRowsRequest struct {
+	Table string                   `json:"-"`
+	Rows  []map[string]interface{} `json:"rows" binding:"required"`
 }
 
-// DeleteColumn deletes a column from a table
-func (s *ProjectService) DeleteColumn(userID uuid.UUID, projectID uuid.UUID, req DeleteColumnRequest, columnName string) error {
+// InsertRowsResponse represents the response for inserting several rows
+type InsertRowsResponse struct {
+	RowsInserted int64         `json:"rows_inserted"`
+	IDs          []interface{} `json:"ids,omitempty"` // Generated ids in row order, when the table has an id column
+}
+
+// InsertRows inserts rows into a table with a single INSERT statement, so either all of
+// them or none are inserted. Every row must set the same columns.
+func (s *ProjectService) InsertRows(userID uuid.UUID, projectID uuid.UUID, req InsertRowsRequest) (*InsertRowsResponse, error) {
 	// Validate table name
-	if err := validateIdentifier(req.TableName); err != nil {
-		return fmt.Errorf("invalid table name: %w", err)
+	if err := validateIdentifier(req.Table); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+	if len(req.Rows) == 0 {
+		return nil, errors.New("rows cannot be empty")
+	}
+	if len(req.Rows[0]) == 0 {
+		return nil, errors.New("values cannot be empty")
 	}
 
-	// Validate column name
-	if err := validateIdentifier(columnName); err != nil {
-		return fmt.Errorf("invalid column name: %w", err)
+	// The first row sets the columns, sorted so the generated statement is deterministic
+	columns := make([]string, 0, len(req.Rows[0]))
+	for col := range req.Rows[0] {
+		if err := validateIdentifier(col); err != nil {
+			return nil, fmt.Errorf("invalid column name '%s': %w", col, err)
+		}
+		columns = append(columns, col)
 	}
+	sort.Strings(columns)
 
-	// Get database connection
-	db, err := s.getDBConnection(userID, projectID)
-	if err != nil {
-		return err
+	if len(req.Rows)*len(columns) > maxBulkInsertParams {
+		return nil, fmt.Errorf("too many values: at most %d values can be inserted at once", maxBulkInsertParams)
 	}
-	defer db.Close()
 
-	// Build ALTER TABLE DROP COLUMN query
-	tableNameQuoted := pq.QuoteIdentifier(req.TableName)
-	columnNameQuoted := pq.QuoteIdentifier(columnName)
-	query := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableNameQuoted, columnNameQuoted)
+	// Build the parameterized multi-row INSERT statement
+	values := make([]interface{}, 0, len(req.Rows)*len(columns))
+	tuples := make([]string, 0, len(req.Rows))
+	for i, row := range req.Rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("inconsistent row %d: every row must set the same columns as the first one", i+1)
+		}
+		placeholders := make([]string, 0, len(columns))
+		for _, col := range columns {
+			val, ok := row[col]
+			if !ok {
+				return nil, fmt.Errorf("inconsistent row %d: missing column '%s'", i+1, col)
+			}
+			values = append(values, val)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)))
+		}
+		tuples = append(tuples, "("+strings.Join(placeholders, ", ")+")")
+	}
 
-	// Execute query
-	_, err = db.Exec(query)
+	quotedColumns := make([]string, len(columns))
+	for i, col := range columns {
+		quotedColumns[i] = pq.QuoteIdentifier(col)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		pq.QuoteIdentifier(req.Table), strings.Join(quotedColumns, ", "), strings.Join(tuples, ", "))
+
+	// Get database connection
+	db, err := s.getDBConnection(userID, projectID)
 	if err != nil {
-		return fmt.Errorf("failed to delete column: %w", err)
+		return nil, err
 	}
 
-	return nil
+	// Same check as InsertRow: only ask for the ids when the table has an id column
+	var hasIDColumn bool
+	err = db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM information_schema.columns
+			WHERE table_schema = 'public'
+			AND LOWER(table_name) = LOWER($1)
+			AND column_name = 'id'
+		)
+	`, req.Table).Scan(&hasIDColumn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect table %s: %w", req.Table, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	resp := &InsertRowsResponse{}
+	if hasIDColumn {
+		rows, err := tx.Query(query+" RETURNING id", values...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert rows into table %s: %w", req.Table, err)
+		}
+		defer rows.Close()
+
+		resp.IDs = make([]interface{}, 0, len(req.Rows))
+		for rows.Next() {
+			var id interface{}
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("failed to read inserted id: %w", err)
+			}
+			// Text-like ids such as uuids are scanned as bytes
+			if b, ok := id.([]byte); ok {
+				id = string(b)
+			}
+			resp.IDs = append(resp.IDs, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to insert rows into table %s: %w", req.Table, err)
+		}
+		resp.RowsInserted = int64(len(resp.IDs))
+	} else {
+		result, err := tx.Exec(query, values...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert rows into table %s: %w", req.Table, err)
+		}
+		resp.RowsInserted, err = result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return resp, nil
+}
+
+// UpdateRowRequest represents the request body for updating a single row by its primary key.
+// PrimaryKeyValue is taken from the :row_id path parameter.
+type UpdateRowRequest struct {
+	Table            string                 `json:"table" binding:"required"`
+	PrimaryKeyColumn string                 `json:"primary_key_column"` // Defaults to "id"
+	PrimaryKeyValue  interface{}            `json:"-"`
+	Values           map[string]interface{} `json:"values" binding:"required"`
+}
+
+// UpdateRowResponse represents the response for updating a row
+type UpdateRowResponse struct {
+	RowsAffected int64 `json:"rows_affected"`
+}
+
+// UpdateRow updates the columns in Values of the row whose primary key matches.
+// It returns "row not found" when no row matches.
+func (s *ProjectService) UpdateRow(userID uuid.UUID, projectID uuid.UUID, req UpdateRowRequest) (*UpdateRowResponse, error) {
+	if err := validateIdentifier(req.Table); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+	if req.PrimaryKeyColumn == "" {
+		req.PrimaryKeyColumn = "id"
+	}
+	if err := validateIdentifier(req.PrimaryKeyColumn); err != nil {
+		return nil, fmt.Errorf("invalid primary key column: %w", err)
+	}
+	if req.PrimaryKeyValue == nil {
+		return nil, errors.New("invalid primary key value: cannot be null")
+	}
+	if len(req.Values) == 0 {
+		return nil, errors.New("values cannot be empty")
+	}
+
+	// Sort column names so the generated statement is deterministic
+	columns := make([]string, 0, len(req.Values))
+	for col := range req.Values {
+		if err := validateIdentifier(col); err != nil {
+			return nil, fmt.Errorf("invalid column name '%s': %w", col, err)
+		}
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	// Build the parameterized UPDATE statement, with the primary key as the last parameter
+	values := make([]interface{}, 0, len(columns)+1)
+	assignments := make([]string, 0, len(columns))
+	for _, col := range columns {
+		values = append(values, req.Values[col])
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(col), len(values)))
+	}
+	values = append(values, req.PrimaryKeyValue)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d",
+		pq.QuoteIdentifier(req.Table), strings.Join(assignments, ", "), pq.QuoteIdentifier(req.PrimaryKeyColumn), len(values))
+
+	// Get database connection
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.Exec(query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update row in table %s: %w", req.Table, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, errors.New("row not found")
+	}
+
+	return &UpdateRowResponse{RowsAffected: rowsAffected}, nil
+}
+
+// AddColumnRequest represents the request body for adding a column
+type AddColumnRequest struct {
+	TableName string      `json:"table_name" binding:"required"`
+	Name      string      `json:"name" binding:"required"`
+	Type      string      `json:"type" binding:"required"`
+	Default   interface{} `json:"default,omitempty"`
+}
+
+// AddColumnResponse represents the response for adding a column
+type AddColumnResponse struct {
+	ColumnID int64 `json:"column_id"`
+}
+
+// AddColumn adds a column to a table
+func (s *ProjectService) AddColumn(userID uuid.UUID, projectID uuid.UUID, req AddColumnRequest) (*AddColumnResponse, error) {
+	query, err := buildAddColumnQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get database connection
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute query
+	_, err = db.Exec(query)
+	s.ddlAudit.Record(userID, projectID, models.DDLOperationAddColumn, req.TableName+"."+req.Name, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add column: %w", err)
+	}
+
+	// Get the column's ordinal position as column_id
+	// PostgreSQL stores column information in information_schema.columns
+	var columnID int64
+	err = db.QueryRow(`
+		SELECT ordinal_position 
+		FROM information_schema.columns 
+		WHERE table_name = $1 AND column_name = $2
+	`, req.TableName, req.Name).Scan(&columnID)
+	if err != nil {
+		// If we can't get the column_id, return 0
+		columnID = 0
+	}
+
+	return &AddColumnResponse{ColumnID: columnID}, nil
+}
+
+// PreviewAddColumn returns the ALTER TABLE statement AddColumn would run, without connecting to the instance
+func (s *ProjectService) PreviewAddColumn(req AddColumnRequest) (string, error) {
+	return buildAddColumnQuery(req)
+}
+
+// buildAddColumnQuery validates an add column request and builds its ALTER TABLE statement
+func buildAddColumnQuery(req AddColumnRequest) (string, error) {
+	// Validate table name
+	if err := validateIdentifier(req.TableName); err != nil {
+		return "", fmt.Errorf("invalid table name: %w", err)
+	}
+
+	// Validate column name
+	if err := validateIdentifier(req.Name); err != nil {
+		return "", fmt.Errorf("invalid column name: %w", err)
+	}
+
+	// Validate type is not empty
+	if req.Type == "" {
+		return "", errors.New("column type cannot be empty")
+	}
+
+	// Build ALTER TABLE query
+	tableNameQuoted := pq.QuoteIdentifier(req.TableName)
+	columnNameQuoted := pq.QuoteIdentifier(req.Name)
+
+	// Build the ALTER TABLE statement
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableNameQuoted, columnNameQuoted, req.Type)
+
+	// Add DEFAULT clause if provided, formatted as a literal of the column's type
+	if req.Default != nil {
+		literal, err := coerceLiteral(req.Default, req.Type)
+		if err != nil {
+			return "", fmt.Errorf("invalid default: %w", err)
+		}
+		query += " DEFAULT " + literal
+	}
+
+	return query, nil
+}
+
+// DeleteColumnRequest represents the request body for deleting a column
+type DeleteColumnRequest struct {
+	TableName string `json:"table_name" binding:"required"`
+}
+
+// DeleteColumn deletes a column from a table
+func (s *ProjectService) DeleteColumn(userID uuid.UUID, projectID uuid.UUID, req DeleteColumnRequest, columnName string) error {
+	// Validate table name
+	if err := validateIdentifier(req.TableName); err != nil {
+		return fmt.Errorf("invalid table name: %w", err)
+	}
+
+	// Validate column name
+	if err := validateIdentifier(columnName); err != nil {
+		return fmt.Errorf("invalid column name: %w", err)
+	}
+
+	// Get database connection
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return err
+	}
+
+	// Build ALTER TABLE DROP COLUMN query
+	tableNameQuoted := pq.QuoteIdentifier(req.TableName)
+	columnNameQuoted := pq.QuoteIdentifier(columnName)
+	query := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableNameQuoted, columnNameQuoted)
+
+	// Execute query
+	_, err = db.Exec(query)
+	s.ddlAudit.Record(userID, projectID, models.DDLOperationDropColumn, req.TableName+"."+columnName, query, err)
+	if err != nil {
+		return fmt.Errorf("failed to delete column: %w", err)
+	}
+
+	return nil
+}
+
+// CellValue represents the complete, untruncated value of a single cell
+type CellValue struct {
+	Table    string      `json:"table"`
+	Column   string      `json:"column"`
+	DataType string      `json:"data_type"`
+	Value    interface{} `json:"value"`
+	Encoding string      `json:"encoding,omitempty"` // "base64" for bytea values
+	Size     int         `json:"size"`
+	Raw      []byte      `json:"-"` // Raw bytes for bytea values, used for binary downloads
+}
+
+// GetCellValue returns the full value of one cell identified by its primary key
+func (s *ProjectService) GetCellValue(
+	userID uuid.UUID,
+	projectID uuid.UUID,
+	table string,
+	pkColumn string,
+	pkValue string,
+	column string,
+) (*CellValue, error) {
+	if err := validateIdentifier(table); err != nil {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "invalid table name: "+err.Error())
+	}
+	if err := validateIdentifier(pkColumn); err != nil {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "invalid primary key column name: "+err.Error())
+	}
+	if err := validateIdentifier(column); err != nil {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "invalid column name: "+err.Error())
+	}
+	if pkValue == "" {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "row id cannot be empty")
+	}
+
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = $1 LIMIT 1",
+		pq.QuoteIdentifier(column),
+		pq.QuoteIdentifier(table),
+		pq.QuoteIdentifier(pkColumn),
+	)
+
+	rows, err := db.Query(query, pkValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cell value: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column type: %w", err)
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to fetch cell value: %w", err)
+		}
+		return nil, apperr.NotFound(apperr.CodeRowNotFound, "row not found")
+	}
+
+	var value interface{}
+	if err := rows.Scan(&value); err != nil {
+		return nil, fmt.Errorf("failed to scan cell value: %w", err)
+	}
+
+	cell := &CellValue{
+		Table:    table,
+		Column:   column,
+		DataType: strings.ToLower(columnTypes[0].DatabaseTypeName()),
+	}
+
+	switch v := value.(type) {
+	case nil:
+		cell.Value = nil
+	case []byte:
+		if cell.DataType == "bytea" {
+			cell.Raw = v
+			cell.Value = base64.StdEncoding.EncodeToString(v)
+			cell.Encoding = "base64"
+		} else {
+			cell.Value = string(v)
+		}
+		cell.Size = len(v)
+	case string:
+		cell.Value = v
+		cell.Size = len(v)
+	case time.Time:
+		cell.Value = formatTimeValue(v, columnTypes[0].DatabaseTypeName())
+	default:
+		cell.Value = v
+	}
+
+	return cell, nil
+}
+
+// DatabaseRole describes a role inside a project's database instance
+type DatabaseRole struct {
+	Name            string     `json:"name"`
+	CanLogin        bool       `json:"can_login"`
+	Superuser       bool       `json:"superuser"`
+	CreateDB        bool       `json:"create_db"`
+	CreateRole      bool       `json:"create_role"`
+	ConnectionLimit int        `json:"connection_limit"`
+	ValidUntil      *time.Time `json:"valid_until,omitempty"`
+}
+
+// CreateDatabaseRoleRequest represents the request body for creating a database role.
+// Privileges are granted on all tables in the public schema.
+type CreateDatabaseRoleRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Password   string   `json:"password" binding:"required,min=8"`
+	Privileges []string `json:"privileges" binding:"omitempty,dive,oneof=SELECT INSERT UPDATE DELETE"`
+}
+
+// ListDatabaseRoles lists the non-system roles of the project's database instance
+func (s *ProjectService) ListDatabaseRoles(userID uuid.UUID, projectID uuid.UUID) ([]DatabaseRole, error) {
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT rolname, rolcanlogin, rolsuper, rolcreatedb, rolcreaterole, rolconnlimit, rolvaliduntil
+		FROM pg_roles
+		WHERE rolname NOT LIKE 'pg\_%'
+		ORDER BY rolname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []DatabaseRole{}
+	for rows.Next() {
+		var role DatabaseRole
+		if err := rows.Scan(&role.Name, &role.CanLogin, &role.Superuser, &role.CreateDB,
+			&role.CreateRole, &role.ConnectionLimit, &role.ValidUntil); err != nil {
+			return nil, fmt.Errorf("failed to list roles: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// BlockedQuery is a backend waiting on a lock held by other backends
+type BlockedQuery struct {
+	PID            int               `json:"pid"`
+	User           *string           `json:"user"`
+	Query          *string           `json:"query"`
+	WaitingSeconds float64           `json:"waiting_seconds"`
+	LockType       string            `json:"lock_type"` // e.g. "relation", "transactionid", "tuple"
+	LockMode       string            `json:"lock_mode"` // The mode being requested, e.g. "AccessExclusiveLock"
+	Relation       *string           `json:"relation"`  // Locked table, for relation-level locks
+	BlockedBy      []BlockingBackend `json:"blocked_by"`
+}
+
+// BlockingBackend is a backend holding a lock another backend is waiting for
+type BlockingBackend struct {
+	PID   int     `json:"pid"`
+	User  *string `json:"user"`
+	Query *string `json:"query"`
+	State *string `json:"state"` // "idle in transaction" usually means a forgotten open transaction
+}
+
+// lockWait is one (waiting backend, blocking backend) pair
+type lockWait struct {
+	waiter  BlockedQuery
+	blocker BlockingBackend
+}
+
+// restartReadyTimeout bounds how long RestartInstance waits for the database to accept connections again
+const restartReadyTimeout = 30 * time.Second
+
+// RestartInstanceResult reports the outcome of an instance restart
+type RestartInstanceResult struct {
+	Ready bool `json:"ready"`
+}
+
+// RestartInstance restarts a project's database container: the orchestrator stops it,
+// letting the database shut down cleanly, and starts it again with the same volume, so
+// no data is lost. It then waits for the database to accept connections again. While
+// restarting, the instance is marked "creating" so no new queries are started.
+func (s *ProjectService) RestartInstance(userID uuid.UUID, projectID uuid.UUID) (*RestartInstanceResult, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("project not found or not accessible")
+	}
+
+	inst, err := s.dbInstanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	if inst == nil {
+		return nil, errors.New("no running database instance for this project")
+	}
+	if inst.ContainerID == nil || *inst.ContainerID == "" {
+		return nil, errors.New("database instance container ID not configured")
+	}
+
+	if err := s.dbInstanceRepo.UpdateStatus(inst.ID, "creating"); err != nil {
+		return nil, fmt.Errorf("failed to update database instance status: %w", err)
+	}
+	s.invalidateReadiness(inst.ID)
+	// Pooled connections do not survive the restart
+	s.connections.Invalidate(inst.ID)
+
+	result := &RestartInstanceResult{}
+	restartErr := s.orchestrator.RestartContainer(*inst.ContainerID)
+	if restartErr == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), restartReadyTimeout)
+		defer cancel()
+		for {
+			s.invalidateReadiness(inst.ID)
+			if result.Ready = s.probeInstance(inst); result.Ready || ctx.Err() != nil {
+				break
+			}
+			time.Sleep(time.Second)
+		}
+	}
+
+	// A failed restart leaves the container as Docker left it; the instance is handed back
+	// either way rather than marked failed, which would have it provisioned anew
+	if err := s.dbInstanceRepo.UpdateStatus(inst.ID, "running"); err != nil {
+		return nil, fmt.Errorf("failed to update database instance status: %w", err)
+	}
+	if restartErr != nil {
+		return nil, fmt.Errorf("failed to restart container: %w", restartErr)
+	}
+
+	return result, nil
+}
+
+// Bounds of the number of log lines returned by GetInstanceLogs
+const (
+	defaultInstanceLogLines = 100
+	maxInstanceLogLines     = 5000
+)
+
+// GetInstanceLogs returns the last lines of the output of the project's database container.
+// Failed instances are included, since their logs usually explain the failure. A tail of 0
+// selects the default number of lines.
+func (s *ProjectService) GetInstanceLogs(userID uuid.UUID, projectID uuid.UUID, tail int) (string, error) {
+	if tail < 0 || tail > maxInstanceLogLines {
+		return "", apperr.Invalid(apperr.CodeInvalidInput, fmt.Sprintf("invalid tail: must be between 1 and %d", maxInstanceLogLines))
+	}
+	if tail == 0 {
+		tail = defaultInstanceLogLines
+	}
+
+	inst, err := s.ownedInstance(userID, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	logs, err := s.orchestrator.GetContainerLogs(*inst.ContainerID, tail)
+	if err != nil {
+		return "", apperr.Internal(err, "failed to get container logs")
+	}
+	return logs, nil
+}
+
+// ownedInstance returns the latest database instance of a project owned by the user,
+// whatever its status, as long as it has a container
+func (s *ProjectService) ownedInstance(userID uuid.UUID, projectID uuid.UUID) (*models.DatabaseInstance, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get project")
+	}
+	if project == nil {
+		return nil, apperr.NotFound(apperr.CodeProjectNotFound, "project not found or not accessible")
+	}
+
+	inst, err := s.dbInstanceRepo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get database instance")
+	}
+	if inst == nil || inst.ContainerID == nil || *inst.ContainerID == "" {
+		return nil, apperr.Conflict(apperr.CodeInstanceNoContainer, "database instance has no container")
+	}
+	return inst, nil
+}
+
+// ErrInstancePaused is returned when queries are run against a paused database instance
+var ErrInstancePaused = apperr.Conflict(apperr.CodeInstancePaused, "the project's database is paused; resume it to run queries")
+
+// InstanceStateResult is the status of an instance after a pause or resume
+type InstanceStateResult struct {
+	Status  string `json:"status"`
+	Changed bool   `json:"changed"` // False when the instance already was in the requested state
+}
+
+// PauseInstance freezes the container of a project's running database, keeping its data.
+// Pausing a paused instance does nothing, so it is safe to repeat.
+func (s *ProjectService) PauseInstance(userID uuid.UUID, projectID uuid.UUID) (*InstanceStateResult, error) {
+	inst, err := s.ownedInstance(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pauseInstance(inst)
+}
+
+// ResumeInstance unfreezes the container of a paused project database. Resuming a running
+// instance does nothing, so it is safe to repeat.
+func (s *ProjectService) ResumeInstance(userID uuid.UUID, projectID uuid.UUID) (*InstanceStateResult, error) {
+	inst, err := s.ownedInstance(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.resumeInstance(inst)
+}
+
+// pauseInstance moves an instance from running to paused. It does not check ownership, so
+// it can also be used to pause idle instances automatically.
+func (s *ProjectService) pauseInstance(inst *models.DatabaseInstance) (*InstanceStateResult, error) {
+	switch inst.Status {
+	case "paused":
+		return &InstanceStateResult{Status: inst.Status}, nil
+	case "running":
+	default:
+		return nil, apperr.Conflict(apperr.CodeInstanceState, fmt.Sprintf("cannot pause a database instance that is %s", inst.Status))
+	}
+
+	// Connections to a frozen container would hang, so pooled ones are dropped first
+	s.connections.Invalidate(inst.ID)
+
+	if err := s.orchestrator.PauseContainer(*inst.ContainerID); err != nil {
+		return nil, apperr.Internal(err, "failed to pause container")
+	}
+	if err := s.dbInstanceRepo.UpdateStatus(inst.ID, "paused"); err != nil {
+		return nil, apperr.Internal(err, "failed to update database instance status")
+	}
+	s.invalidateReadiness(inst.ID)
+
+	return &InstanceStateResult{Status: "paused", Changed: true}, nil
+}
+
+// resumeInstance moves an instance from paused back to running
+func (s *ProjectService) resumeInstance(inst *models.DatabaseInstance) (*InstanceStateResult, error) {
+	switch inst.Status {
+	case "running":
+		return &InstanceStateResult{Status: inst.Status}, nil
+	case "paused":
+	default:
+		return nil, apperr.Conflict(apperr.CodeInstanceState, fmt.Sprintf("cannot resume a database instance that is %s", inst.Status))
+	}
+
+	if err := s.orchestrator.UnpauseContainer(*inst.ContainerID); err != nil {
+		return nil, apperr.Internal(err, "failed to resume container")
+	}
+	if err := s.dbInstanceRepo.UpdateStatus(inst.ID, "running"); err != nil {
+		return nil, apperr.Internal(err, "failed to update database instance status")
+	}
+	s.invalidateReadiness(inst.ID)
+
+	return &InstanceStateResult{Status: "running", Changed: true}, nil
+}
+
+// invalidateReadiness drops the cached connectivity probe of an instance
+func (s *ProjectService) invalidateReadiness(instanceID uuid.UUID) {
+	s.readinessMu.Lock()
+	defer s.readinessMu.Unlock()
+	delete(s.readinessCache, instanceID)
+}
+
+// connectionWarningRatio is the share of max_connections above which an instance is
+// reported as close to its connection limit
+const connectionWarningRatio = 0.8
+
+// ConnectionStats compares the connections open on a project's database server with
+// its max_connections setting
+type ConnectionStats struct {
+	Current      int       `json:"current"` // Client connections, from every user and application
+	Max          int       `json:"max"`     // The server's max_connections
+	UsagePercent float64   `json:"usage_percent"`
+	Pool         PoolStats `json:"pool"` // Connections held by this backend, included in current
+	Warning      string    `json:"warning,omitempty"`
+}
+
+// GetConnectionStats reports how close a project's database server is to its connection limit
+func (s *ProjectService) GetConnectionStats(userID uuid.UUID, projectID uuid.UUID) (*ConnectionStats, error) {
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, err := s.dbInstanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database instance: %w", err)
+	}
+	if inst == nil {
+		return nil, errors.New("no running database instance for this project")
+	}
+
+	stats := &ConnectionStats{}
+	if err := db.QueryRow(
+		`SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend'`,
+	).Scan(&stats.Current); err != nil {
+		return nil, fmt.Errorf("failed to count connections: %w", err)
+	}
+
+	var maxConnections string
+	if err := db.QueryRow(`SHOW max_connections`).Scan(&maxConnections); err != nil {
+		return nil, fmt.Errorf("failed to read max_connections: %w", err)
+	}
+	if stats.Max, err = strconv.Atoi(maxConnections); err != nil {
+		return nil, fmt.Errorf("failed to parse max_connections %q: %w", maxConnections, err)
+	}
+
+	stats.Pool = s.connections.Stats(inst.ID)
+	stats.UsagePercent, stats.Warning = connectionUsage(stats.Current, stats.Max)
+
+	return stats, nil
+}
+
+// connectionUsage returns the share of max connections in use, in percent, and a
+// warning once it reaches connectionWarningRatio
+func connectionUsage(current int, max int) (float64, string) {
+	if max <= 0 {
+		return 0, ""
+	}
+
+	usage := float64(current) / float64(max)
+	percent := math.Round(usage*1000) / 10
+	if usage >= connectionWarningRatio {
+		return percent, fmt.Sprintf("%d of %d connections are in use; new connections will be refused once the limit is reached", current, max)
+	}
+	return percent, ""
+}
+
+// GetBlockedQueries reports the backends of the project's database that are waiting on
+// locks, together with the backends blocking them
+func (s *ProjectService) GetBlockedQueries(userID uuid.UUID, projectID uuid.UUID) ([]BlockedQuery, error) {
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			w.pid, w.usename, w.query,
+			COALESCE(EXTRACT(EPOCH FROM now() - w.state_change), 0)::float8,
+			l.locktype, l.mode, l.relation::regclass::text,
+			b.pid, b.usename, b.query, b.state
+		FROM pg_stat_activity w
+		JOIN pg_locks l ON l.pid = w.pid AND NOT l.granted
+		CROSS JOIN LATERAL unnest(pg_blocking_pids(w.pid)) AS blocking(pid)
+		JOIN pg_stat_activity b ON b.pid = blocking.pid
+		WHERE w.datname = current_database()
+		ORDER BY w.pid, b.pid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked queries: %w", err)
+	}
+	defer rows.Close()
+
+	var waits []lockWait
+	for rows.Next() {
+		var wait lockWait
+		if err := rows.Scan(
+			&wait.waiter.PID, &wait.waiter.User, &wait.waiter.Query, &wait.waiter.WaitingSeconds,
+			&wait.waiter.LockType, &wait.waiter.LockMode, &wait.waiter.Relation,
+			&wait.blocker.PID, &wait.blocker.User, &wait.blocker.Query, &wait.blocker.State,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked query: %w", err)
+		}
+		waits = append(waits, wait)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query blocked queries: %w", err)
+	}
+
+	return groupLockWaits(waits), nil
+}
+
+// groupLockWaits folds (waiter, blocker) pairs into one entry per waiting backend,
+// keeping the order in which waiters first appear
+func groupLockWaits(waits []lockWait) []BlockedQuery {
+	blocked := []BlockedQuery{}
+	byPID := make(map[int]int)
+
+	for _, wait := range waits {
+		i, ok := byPID[wait.waiter.PID]
+		if !ok {
+			entry := wait.waiter
+			entry.BlockedBy = []BlockingBackend{}
+			blocked = append(blocked, entry)
+			i = len(blocked) - 1
+			byPID[wait.waiter.PID] = i
+		}
+
+		duplicate := false
+		for _, existing := range blocked[i].BlockedBy {
+			if existing.PID == wait.blocker.PID {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			blocked[i].BlockedBy = append(blocked[i].BlockedBy, wait.blocker)
+		}
+	}
+
+	return blocked
+}
+
+// CreateDatabaseRole creates a login role limited to the requested table privileges.
+// The role can never be a superuser or create databases/roles, so it cannot escalate
+// beyond the project's own instance.
+func (s *ProjectService) CreateDatabaseRole(userID uuid.UUID, projectID uuid.UUID, req CreateDatabaseRoleRequest) (*DatabaseRole, error) {
+	if err := validateIdentifier(req.Name); err != nil {
+		return nil, fmt.Errorf("invalid role name: %w", err)
+	}
+	if strings.HasPrefix(strings.ToLower(req.Name), "pg_") {
+		return nil, errors.New("invalid role name: the pg_ prefix is reserved")
+	}
+
+	privileges := make([]string, 0, len(req.Privileges))
+	seen := make(map[string]bool, len(req.Privileges))
+	for _, privilege := range req.Privileges {
+		privilege = strings.ToUpper(privilege)
+		if !seen[privilege] {
+			seen[privilege] = true
+			privileges = append(privileges, privilege)
+		}
+	}
+
+	db, err := s.getDBConnection(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := s.dbInstanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		return nil, errors.New("no running database instance for this project")
+	}
+
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)`, req.Name).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check role: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("role '%s' already exists", req.Name)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	roleQuoted := pq.QuoteIdentifier(req.Name)
+
+	// Passwords cannot be bound as parameters in utility statements, so quote them as literals
+	createRole := "CREATE ROLE %s WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOREPLICATION NOBYPASSRLS NOINHERIT PASSWORD %s"
+	statements := []string{
+		fmt.Sprintf(createRole, roleQuoted, pq.QuoteLiteral(req.Password)),
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", pq.QuoteIdentifier(inst.DatabaseName()), roleQuoted),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA public TO %s", roleQuoted),
+	}
+	if len(privileges) > 0 {
+		privilegeList := strings.Join(privileges, ", ")
+		statements = append(statements,
+			fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA public TO %s", privilegeList, roleQuoted),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT %s ON TABLES TO %s", privilegeList, roleQuoted),
+		)
+	}
+
+	// Never write the password to the audit log
+	auditStatements := append([]string{fmt.Sprintf(createRole, roleQuoted, "'********'")}, statements[1:]...)
+	auditSQL := strings.Join(auditStatements, ";\n")
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			s.ddlAudit.Record(userID, projectID, models.DDLOperationCreateRole, req.Name, auditSQL, err)
+			return nil, fmt.Errorf("failed to create role: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userID, projectID, models.DDLOperationCreateRole, req.Name, auditSQL, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &DatabaseRole{
+		Name:            req.Name,
+		CanLogin:        true,
+		ConnectionLimit: -1,
+	}, nil
 }
@@ -0,0 +1,852 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/testdb"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateAndDeleteProject(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	project := env.createProject(t, user, "postgres")
+
+	inst := env.instance(t, project)
+	if inst.Status != "running" || inst.ContainerID == nil || inst.DBName == nil {
+		t.Fatalf("instance = %+v, want a running instance with its container and database", inst)
+	}
+	credential, err := env.credentials.GetLatestByInstanceID(inst.ID)
+	if err != nil || credential == nil {
+		t.Fatalf("GetLatestByInstanceID = %v, %v, want the container's credential", credential, err)
+	}
+
+	// The stored connection details reach the project's database
+	var database string
+	if err := env.projectDB(t, user, project).QueryRowContext(context.Background(), "SELECT current_database()").Scan(&database); err != nil {
+		t.Fatalf("query project database: %v", err)
+	}
+	if database != *inst.DBName {
+		t.Errorf("connected to %q, want %q", database, *inst.DBName)
+	}
+
+	if err := env.projects.DeleteProjectByIDAndUserID(project.ID.String(), user.ID.String()); err != nil {
+		t.Fatalf("DeleteProjectByIDAndUserID: %v", err)
+	}
+	if len(env.orchestrator.deleted) != 1 || env.orchestrator.deleted[0] != *inst.ContainerID {
+		t.Errorf("deleted containers = %v, want %s", env.orchestrator.deleted, *inst.ContainerID)
+	}
+	if got, err := env.projectRepo.GetByID(project.ID); err != nil || got != nil {
+		t.Errorf("GetByID after delete = %v, %v, want nil", got, err)
+	}
+}
+
+func TestCreateProjectMarksInstanceFailedWhenContainerFails(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	env.orchestrator.createErr = func(int) error { return context.DeadlineExceeded }
+
+	_, err := env.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{
+		Name:         "broken",
+		DBType:       "postgres",
+		ResourceTier: "basic",
+	})
+	if err == nil {
+		t.Fatal("CreateProject succeeded without a container")
+	}
+
+	projects, err := env.projectRepo.GetByUserID(user.ID)
+	if err != nil || len(projects) != 1 {
+		t.Fatalf("GetByUserID = %v, %v, want the project", projects, err)
+	}
+	if inst := env.instance(t, &projects[0]); inst.Status != "failed" {
+		t.Errorf("instance status = %q, want failed", inst.Status)
+	}
+}
+
+func TestGetDBConnectionFallsBackToRedisForIP(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	// The backend restarted and only Redis knows the container's IP
+	env.orchestrator.forgetIPs = true
+	if _, err := env.projects.getDBConnection(user.ID, project.ID); err != nil {
+		t.Fatalf("getDBConnection: %v", err)
+	}
+
+	env.orchestrator.redisErr = errors.New("redis: connection refused")
+	if _, err := env.projects.getDBConnection(user.ID, project.ID); err == nil || !strings.Contains(err.Error(), "failed to get container IP") {
+		t.Errorf("getDBConnection without an IP = %v, want it to report the missing IP", err)
+	}
+}
+
+func TestDeletingProjectDuringCreationLeavesNoContainer(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	// The project is deleted while its container is being created
+	env.orchestrator.createErr = func(int) error {
+		projects, err := env.projectRepo.GetByUserID(user.ID)
+		if err != nil || len(projects) != 1 {
+			t.Errorf("GetByUserID = %v, %v", projects, err)
+			return nil
+		}
+		if err := env.projects.DeleteProjectByIDAndUserID(projects[0].ID.String(), user.ID.String()); err != nil {
+			t.Errorf("DeleteProjectByIDAndUserID: %v", err)
+		}
+		return nil
+	}
+
+	if _, err := env.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{
+		Name:         "cancelled",
+		DBType:       "postgres",
+		ResourceTier: "basic",
+	}); err == nil {
+		t.Fatal("CreateProject succeeded for a deleted project")
+	}
+	if env.orchestrator.running() != 0 {
+		t.Errorf("%d containers left running", env.orchestrator.running())
+	}
+}
+
+func TestProjectDeletedAfterContainerStartedLeavesNoContainer(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	// The project is deleted once its container runs but before the container is recorded
+	env.orchestrator.created = func(string) {
+		projects, err := env.projectRepo.GetByUserID(user.ID)
+		if err != nil || len(projects) != 1 {
+			t.Errorf("GetByUserID = %v, %v", projects, err)
+			return
+		}
+		if err := env.projectRepo.Delete(projects[0].ID); err != nil {
+			t.Errorf("Delete: %v", err)
+		}
+	}
+
+	if _, err := env.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{
+		Name:         "raced",
+		DBType:       "postgres",
+		ResourceTier: "basic",
+	}); err == nil {
+		t.Fatal("CreateProject succeeded for a deleted project")
+	}
+	if env.orchestrator.running() != 0 || len(env.orchestrator.deleted) != 1 {
+		t.Errorf("%d containers left running, %d deleted, want the created one deleted", env.orchestrator.running(), len(env.orchestrator.deleted))
+	}
+}
+
+func TestGetCellValueReturnsLargeValuesWhole(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE documents (id integer PRIMARY KEY, body text, blob bytea)`,
+		`INSERT INTO documents VALUES (1, repeat('abcdefghij', 100000), decode(repeat('00ff', 50000), 'hex'))`,
+	)
+
+	text, err := env.projects.GetCellValue(user.ID, project.ID, "documents", "id", "1", "body")
+	if err != nil {
+		t.Fatalf("GetCellValue(body): %v", err)
+	}
+	if text.DataType != "text" || text.Size != 1000000 || text.Value != strings.Repeat("abcdefghij", 100000) || text.Encoding != "" {
+		t.Errorf("body = %s of %d bytes encoded %q, want the whole text", text.DataType, text.Size, text.Encoding)
+	}
+
+	blob, err := env.projects.GetCellValue(user.ID, project.ID, "documents", "id", "1", "blob")
+	if err != nil {
+		t.Fatalf("GetCellValue(blob): %v", err)
+	}
+	want := bytes.Repeat([]byte{0x00, 0xff}, 50000)
+	if blob.DataType != "bytea" || blob.Encoding != "base64" || blob.Size != len(want) || !bytes.Equal(blob.Raw, want) {
+		t.Fatalf("blob = %s of %d bytes encoded %q, want %d raw bytes", blob.DataType, blob.Size, blob.Encoding, len(want))
+	}
+	if blob.Value != base64.StdEncoding.EncodeToString(want) {
+		t.Error("blob value is not the base64 of its bytes")
+	}
+
+	if _, err := env.projects.GetCellValue(user.ID, project.ID, "documents", "id", "2", "body"); apperr.From(err).Status != http.StatusNotFound || apperr.From(err).Code != apperr.CodeRowNotFound {
+		t.Errorf("GetCellValue of a missing row = %v, want 404 %s", err, apperr.CodeRowNotFound)
+	}
+	if _, err := env.projects.GetCellValue(user.ID, project.ID, "documents; DROP TABLE documents", "id", "1", "body"); apperr.From(err).Status != http.StatusBadRequest {
+		t.Errorf("GetCellValue with an invalid table name = %v, want a 400", err)
+	}
+}
+
+func TestDeleteProjectsOnlyDeletesOwnedProjects(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	other := env.createUser(t)
+
+	owned := env.createProject(t, user, "postgres")
+	notOwned := env.createProject(t, other, "postgres")
+	missing := uuid.New()
+
+	results, err := env.projects.DeleteProjects(user.ID, []uuid.UUID{owned.ID, notOwned.ID, missing, owned.ID})
+	if err != nil {
+		t.Fatalf("DeleteProjects: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v, want one per distinct project", results)
+	}
+	if !results[owned.ID.String()].Success {
+		t.Errorf("owned project result = %+v, want success", results[owned.ID.String()])
+	}
+	for _, id := range []uuid.UUID{notOwned.ID, missing} {
+		if result := results[id.String()]; result.Success || result.Error == "" {
+			t.Errorf("result for %s = %+v, want a failure", id, result)
+		}
+	}
+
+	if got, err := env.projectRepo.GetByID(owned.ID); err != nil || got != nil {
+		t.Errorf("owned project after delete = %v, %v, want nil", got, err)
+	}
+	if got, err := env.projectRepo.GetByID(notOwned.ID); err != nil || got == nil {
+		t.Errorf("other user's project after delete = %v, %v, want it kept", got, err)
+	}
+}
+
+func TestDeleteProjectsCapsBatchSize(t *testing.T) {
+	projects := &ProjectService{}
+
+	if _, err := projects.DeleteProjects(uuid.New(), nil); err == nil {
+		t.Error("DeleteProjects accepted an empty batch")
+	}
+	ids := make([]uuid.UUID, maxBulkDeleteProjects+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	if _, err := projects.DeleteProjects(uuid.New(), ids); err == nil {
+		t.Errorf("DeleteProjects accepted %d projects", len(ids))
+	}
+}
+
+func TestProjectReadiness(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+
+	// An instance whose container is still being created is not ready
+	if err := env.instances.UpdateStatus(inst.ID, "creating"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	creating, err := env.projects.GetProjectByIDAndUserID(project.ID.String(), user.ID.String())
+	if err != nil {
+		t.Fatalf("GetProjectByIDAndUserID: %v", err)
+	}
+	if creating.Ready == nil || *creating.Ready || creating.InstanceStatus != "creating" {
+		t.Errorf("project while creating = %+v, want a creating instance that is not ready", creating)
+	}
+
+	if err := env.instances.UpdateStatus(inst.ID, "running"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	// Forget a probe made before the database accepted connections
+	env.projects.readinessMu.Lock()
+	delete(env.projects.readinessCache, inst.ID)
+	env.projects.readinessMu.Unlock()
+
+	got, err := env.projects.GetProjectByIDAndUserID(project.ID.String(), user.ID.String())
+	if err != nil {
+		t.Fatalf("GetProjectByIDAndUserID: %v", err)
+	}
+	if got.Ready == nil || !*got.Ready || got.InstanceStatus != "running" {
+		t.Errorf("project = %+v, want a running instance that is ready", got)
+	}
+}
+
+func TestCreateAndListDatabaseRoles(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE notes (id integer PRIMARY KEY, body text)`,
+		`INSERT INTO notes VALUES (1, 'hello')`,
+	)
+
+	name := "reader_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	testdb.DropRole(t, name)
+	role, err := env.projects.CreateDatabaseRole(user.ID, project.ID, CreateDatabaseRoleRequest{
+		Name:       name,
+		Password:   "reader-password",
+		Privileges: []string{"select"},
+	})
+	if err != nil {
+		t.Fatalf("CreateDatabaseRole: %v", err)
+	}
+	if role.Name != name || !role.CanLogin || role.Superuser || role.CreateRole {
+		t.Errorf("role = %+v, want a plain login role", role)
+	}
+
+	roles, err := env.projects.ListDatabaseRoles(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("ListDatabaseRoles: %v", err)
+	}
+	var listed *DatabaseRole
+	for i := range roles {
+		if strings.HasPrefix(roles[i].Name, "pg_") {
+			t.Errorf("system role %s is listed", roles[i].Name)
+		}
+		if roles[i].Name == name {
+			listed = &roles[i]
+		}
+	}
+	if listed == nil || listed.Superuser || listed.CreateDB || listed.CreateRole || !listed.CanLogin {
+		t.Errorf("listed role = %+v, want a login role without elevated privileges", listed)
+	}
+
+	// CONNECT is granted on the project's own database, not only through PUBLIC
+	inst := env.instance(t, project)
+	var granted bool
+	err = env.projectDB(t, user, project).QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM pg_database d, aclexplode(d.datacl) acl
+			WHERE d.datname = $1 AND acl.grantee = $2::regrole AND acl.privilege_type = 'CONNECT'
+		)`, *inst.DBName, name).Scan(&granted)
+	if err != nil || !granted {
+		t.Errorf("CONNECT on %s granted to %s = %v, %v", *inst.DBName, name, granted, err)
+	}
+
+	// The role can read but not write
+	server := testdb.Lookup(t)
+	server.User, server.Password = name, "reader-password"
+	db, err := sql.Open("postgres", server.URL(*inst.DBName))
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+
+	var body string
+	if err := db.QueryRow(`SELECT body FROM notes WHERE id = 1`).Scan(&body); err != nil || body != "hello" {
+		t.Errorf("select as %s = %q, %v", name, body, err)
+	}
+	if _, err := db.Exec(`INSERT INTO notes VALUES (2, 'nope')`); err == nil {
+		t.Errorf("insert as %s succeeded without the privilege", name)
+	}
+	if _, err := db.Exec(`CREATE ROLE escalated`); err == nil {
+		testdb.DropRole(t, "escalated")
+		t.Errorf("%s could create a role", name)
+	}
+}
+
+func TestCreateDatabaseRoleRejectsInvalidNames(t *testing.T) {
+	projects := &ProjectService{}
+
+	for _, name := range []string{"pg_monitor", "PG_reader", "reader; DROP ROLE postgres", "", "1reader"} {
+		if _, err := projects.CreateDatabaseRole(uuid.New(), uuid.New(), CreateDatabaseRoleRequest{Name: name, Password: "password"}); err == nil {
+			t.Errorf("CreateDatabaseRole(%q) succeeded", name)
+		}
+	}
+}
+
+func TestUpdateRowsWithMultiColumnSetAndFilter(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE tasks (id integer PRIMARY KEY, team text, status text, priority integer, note text)`,
+		`INSERT INTO tasks VALUES
+			(1, 'core', 'open', 1, NULL),
+			(2, 'core', 'open', 2, NULL),
+			(3, 'core', 'done', 1, NULL),
+			(4, 'web', 'open', 1, NULL),
+			(5, 'core', 'open', 3, 'keep')`,
+	)
+
+	// Values arrive decoded from JSON
+	resp, err := env.projects.UpdateRows(user.ID, project.ID, "tasks", UpdateRowsRequest{
+		Set:   map[string]interface{}{"status": "closed", "priority": float64(0)},
+		Where: map[string]interface{}{"team": "core", "status": "open", "note": nil},
+	})
+	if err != nil {
+		t.Fatalf("UpdateRows: %v", err)
+	}
+	if resp.RowsAffected != 2 {
+		t.Errorf("rows affected = %d, want 2", resp.RowsAffected)
+	}
+
+	rows, err := env.projectDB(t, user, project).Query(`SELECT id FROM tasks WHERE status = 'closed' AND priority = 0 ORDER BY id`)
+	if err != nil {
+		t.Fatalf("query tasks: %v", err)
+	}
+	defer rows.Close()
+	var updated []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		updated = append(updated, id)
+	}
+	if fmt.Sprint(updated) != "[1 2]" {
+		t.Errorf("updated rows = %v, want [1 2]", updated)
+	}
+}
+
+func TestUpdateRowsRejectsUnsafeRequests(t *testing.T) {
+	projects := &ProjectService{}
+
+	cases := map[string]struct {
+		table string
+		req   UpdateRowsRequest
+	}{
+		"empty filter":       {"tasks", UpdateRowsRequest{Set: map[string]interface{}{"status": "closed"}, Where: map[string]interface{}{}}},
+		"missing filter":     {"tasks", UpdateRowsRequest{Set: map[string]interface{}{"status": "closed"}}},
+		"empty set":          {"tasks", UpdateRowsRequest{Where: map[string]interface{}{"id": 1}}},
+		"invalid table":      {"tasks; DROP TABLE tasks", UpdateRowsRequest{Set: map[string]interface{}{"status": "closed"}, Where: map[string]interface{}{"id": 1}}},
+		"invalid set column": {"tasks", UpdateRowsRequest{Set: map[string]interface{}{"status = 'x', id": 1}, Where: map[string]interface{}{"id": 1}}},
+		"invalid filter":     {"tasks", UpdateRowsRequest{Set: map[string]interface{}{"status": "closed"}, Where: map[string]interface{}{"1 = 1 OR id": 1}}},
+	}
+	for name, c := range cases {
+		if _, err := projects.UpdateRows(uuid.New(), uuid.New(), c.table, c.req); err == nil {
+			t.Errorf("%s: UpdateRows succeeded", name)
+		}
+	}
+}
+
+func TestGroupLockWaits(t *testing.T) {
+	text := func(s string) *string { return &s }
+	waiter := func(pid int, mode string) BlockedQuery {
+		return BlockedQuery{PID: pid, Query: text("ALTER TABLE accounts ADD COLUMN note text"), LockType: "relation", LockMode: mode, Relation: text("accounts")}
+	}
+	blocker := func(pid int, state string) BlockingBackend {
+		return BlockingBackend{PID: pid, Query: text("UPDATE accounts SET balance = 0"), State: text(state)}
+	}
+
+	// Rows as the pg_locks/pg_stat_activity query returns them, ordered by waiter then blocker
+	waits := []lockWait{
+		{waiter: waiter(20, "AccessExclusiveLock"), blocker: blocker(10, "idle in transaction")},
+		{waiter: waiter(20, "AccessExclusiveLock"), blocker: blocker(11, "active")},
+		{waiter: waiter(20, "AccessExclusiveLock"), blocker: blocker(11, "active")}, // A second lock of the same pair
+		{waiter: waiter(30, "RowExclusiveLock"), blocker: blocker(20, "active")},
+	}
+
+	blocked := groupLockWaits(waits)
+	if len(blocked) != 2 {
+		t.Fatalf("groupLockWaits = %+v, want two waiting backends", blocked)
+	}
+	if blocked[0].PID != 20 || blocked[0].LockMode != "AccessExclusiveLock" || len(blocked[0].BlockedBy) != 2 ||
+		blocked[0].BlockedBy[0].PID != 10 || blocked[0].BlockedBy[1].PID != 11 {
+		t.Errorf("first waiter = %+v, want 20 blocked by 10 and 11", blocked[0])
+	}
+	if *blocked[0].BlockedBy[0].State != "idle in transaction" {
+		t.Errorf("blocker state = %q, want idle in transaction", *blocked[0].BlockedBy[0].State)
+	}
+	if blocked[1].PID != 30 || len(blocked[1].BlockedBy) != 1 || blocked[1].BlockedBy[0].PID != 20 {
+		t.Errorf("second waiter = %+v, want 30 blocked by 20", blocked[1])
+	}
+
+	if blocked := groupLockWaits(nil); blocked == nil || len(blocked) != 0 {
+		t.Errorf("groupLockWaits(nil) = %#v, want an empty list", blocked)
+	}
+}
+
+func TestGetBlockedQueriesReportsBlocker(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE accounts (id integer PRIMARY KEY)`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	db := env.projectDB(t, user, project)
+
+	holder, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer holder.Close()
+	var holderPID int
+	if err := holder.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&holderPID); err != nil {
+		t.Fatalf("holder pid: %v", err)
+	}
+	if _, err := holder.ExecContext(ctx, `BEGIN; LOCK TABLE accounts IN ACCESS EXCLUSIVE MODE`); err != nil {
+		t.Fatalf("lock accounts: %v", err)
+	}
+
+	waiter, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer waiter.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := waiter.ExecContext(ctx, `SELECT count(*) FROM accounts`)
+		done <- err
+	}()
+
+	var blocked []BlockedQuery
+	deadline := time.Now().Add(10 * time.Second)
+	for len(blocked) == 0 && time.Now().Before(deadline) {
+		if blocked, err = env.projects.GetBlockedQueries(user.ID, project.ID); err != nil {
+			t.Fatalf("GetBlockedQueries: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if len(blocked) != 1 || len(blocked[0].BlockedBy) != 1 || blocked[0].BlockedBy[0].PID != holderPID {
+		t.Fatalf("blocked queries = %+v, want the select blocked by %d", blocked, holderPID)
+	}
+	if blocked[0].LockType != "relation" || blocked[0].LockMode != "AccessShareLock" || blocked[0].Relation == nil || *blocked[0].Relation != "accounts" {
+		t.Errorf("blocked query = %+v, want an AccessShareLock on accounts", blocked[0])
+	}
+
+	if _, err := holder.ExecContext(ctx, `ROLLBACK`); err != nil {
+		t.Fatalf("release lock: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("blocked select failed once released: %v", err)
+	}
+}
+
+func TestUpdateProjectMovesUpdatedAtOnly(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	stored, err := env.projectRepo.GetByID(project.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("GetByID = %v, %v", stored, err)
+	}
+	if !stored.UpdatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("new project updated_at = %v, want its created_at %v", stored.UpdatedAt, stored.CreatedAt)
+	}
+
+	// NOW() has microsecond resolution; make sure the update lands on a later timestamp
+	time.Sleep(10 * time.Millisecond)
+	name := "renamed"
+	updated, err := env.projects.UpdateProject(user.ID, project.ID, UpdateProjectRequest{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	if !updated.UpdatedAt.After(stored.UpdatedAt) {
+		t.Errorf("returned updated_at = %v, want later than %v", updated.UpdatedAt, stored.UpdatedAt)
+	}
+
+	reread, err := env.projectRepo.GetByID(project.ID)
+	if err != nil || reread == nil {
+		t.Fatalf("GetByID = %v, %v", reread, err)
+	}
+	if !reread.CreatedAt.Equal(stored.CreatedAt) {
+		t.Errorf("created_at = %v after an update, want it to stay %v", reread.CreatedAt, stored.CreatedAt)
+	}
+	if !reread.UpdatedAt.Equal(updated.UpdatedAt) {
+		t.Errorf("stored updated_at = %v, want the returned %v", reread.UpdatedAt, updated.UpdatedAt)
+	}
+}
+
+func TestPreviewAddColumnSQL(t *testing.T) {
+	// A zero ProjectService has no database to connect to, so the preview cannot touch one
+	var projects ProjectService
+
+	tests := []struct {
+		req  AddColumnRequest
+		want string
+	}{
+		{AddColumnRequest{TableName: "orders", Name: "note", Type: "TEXT"}, `ALTER TABLE "orders" ADD COLUMN "note" TEXT`},
+		{AddColumnRequest{TableName: "orders", Name: "status", Type: "TEXT", Default: "it's new"}, `ALTER TABLE "orders" ADD COLUMN "status" TEXT DEFAULT 'it''s new'`},
+		{AddColumnRequest{TableName: "orders", Name: "quantity", Type: "INTEGER", Default: float64(1)}, `ALTER TABLE "orders" ADD COLUMN "quantity" INTEGER DEFAULT 1`},
+	}
+	for _, tt := range tests {
+		if got, err := projects.PreviewAddColumn(tt.req); err != nil || got != tt.want {
+			t.Errorf("PreviewAddColumn(%+v) = %q, %v, want %q", tt.req, got, err, tt.want)
+		}
+	}
+
+	for _, req := range []AddColumnRequest{
+		{TableName: "orders; DROP TABLE x", Name: "note", Type: "TEXT"},
+		{TableName: "orders", Name: "note", Type: ""},
+		{TableName: "orders", Name: "quantity", Type: "INTEGER", Default: "many"},
+	} {
+		if got, err := projects.PreviewAddColumn(req); err == nil {
+			t.Errorf("PreviewAddColumn(%+v) = %q, want an error", req, got)
+		}
+	}
+}
+
+func TestNormalizeDescription(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	if got := normalizeDescription(nil); got != nil {
+		t.Errorf("normalizeDescription(nil) = %q, want nil", *got)
+	}
+	for _, blank := range []string{"", "   \n\t"} {
+		if got := normalizeDescription(text(blank)); got != nil {
+			t.Errorf("normalizeDescription(%q) = %q, want nil", blank, *got)
+		}
+	}
+	if got := normalizeDescription(text("  orders and invoices ")); got == nil || *got != "orders and invoices" {
+		t.Errorf("normalizeDescription = %v, want the trimmed description", got)
+	}
+}
+
+func TestRestartInstance(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+	env.exec(t, user, project, `CREATE TABLE notes (body text)`, `INSERT INTO notes VALUES ('kept')`)
+
+	// While the container restarts the instance is not running, so queries are refused
+	var during string
+	var refused bool
+	env.orchestrator.restart = func(containerID string) error {
+		if containerID != *inst.ContainerID {
+			t.Errorf("restarted %s, want %s", containerID, *inst.ContainerID)
+		}
+		during = env.instance(t, project).Status
+		result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT body FROM notes"}, project.ID)
+		refused = err != nil || result.Error != ""
+		return nil
+	}
+
+	result, err := env.projects.RestartInstance(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("RestartInstance: %v", err)
+	}
+	if !result.Ready {
+		t.Error("instance not ready after the restart")
+	}
+	if during != "creating" || !refused {
+		t.Errorf("during the restart: status %q, query refused %v, want creating and refused", during, refused)
+	}
+	if status := env.instance(t, project).Status; status != "running" {
+		t.Errorf("status after the restart = %s, want running", status)
+	}
+
+	// The data survives and queries work again
+	query, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT body FROM notes"}, project.ID)
+	if err != nil || query.Error != "" {
+		t.Fatalf("query after the restart = %+v, %v", query, err)
+	}
+	if len(query.Rows) != 1 || fmt.Sprint(query.Rows[0]["body"]) != "kept" {
+		t.Errorf("rows after the restart = %v, want the note kept", query.Rows)
+	}
+
+	// A failed restart hands the instance back rather than failing it
+	env.orchestrator.restart = func(string) error { return errors.New("docker unavailable") }
+	if _, err := env.projects.RestartInstance(user.ID, project.ID); err == nil {
+		t.Error("RestartInstance succeeded although the orchestrator failed")
+	}
+	if status := env.instance(t, project).Status; status != "running" {
+		t.Errorf("status after a failed restart = %s, want running", status)
+	}
+}
+
+func TestConnectionUsage(t *testing.T) {
+	tests := []struct {
+		current, max int
+		percent      float64
+		warn         bool
+	}{
+		{current: 0, max: 100, percent: 0},
+		{current: 1, max: 3, percent: 33.3},
+		{current: 79, max: 100, percent: 79},
+		{current: 80, max: 100, percent: 80, warn: true},
+		{current: 100, max: 100, percent: 100, warn: true},
+		{current: 5, max: 0, percent: 0},
+	}
+	for _, tt := range tests {
+		percent, warning := connectionUsage(tt.current, tt.max)
+		if percent != tt.percent || (warning != "") != tt.warn {
+			t.Errorf("connectionUsage(%d, %d) = %v, %q, want %v with warning %v", tt.current, tt.max, percent, warning, tt.percent, tt.warn)
+		}
+		if tt.warn && !strings.Contains(warning, fmt.Sprintf("%d of %d connections", tt.current, tt.max)) {
+			t.Errorf("warning %q does not say how many connections are in use", warning)
+		}
+	}
+}
+
+func TestGetConnectionStatsCountsOpenConnections(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	before, err := env.projects.GetConnectionStats(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("GetConnectionStats: %v", err)
+	}
+	if before.Max <= 0 || before.Current < 1 || before.UsagePercent <= 0 {
+		t.Fatalf("stats = %+v, want at least this backend's connection against a positive limit", before)
+	}
+
+	// Holding more connections open shows up in the count
+	db := env.projectDB(t, user, project)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("connect: %v", err)
+		}
+		defer conn.Close()
+		if err := conn.PingContext(ctx); err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+	}
+
+	after, err := env.projects.GetConnectionStats(user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("GetConnectionStats: %v", err)
+	}
+	if after.Current < 3 || after.Current < before.Current || after.Pool.Open < 3 {
+		t.Errorf("stats with three connections held = %+v, want them counted", after)
+	}
+}
+
+func TestGetInstanceLogs(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	other := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	inst := env.instance(t, project)
+
+	logs, err := env.projects.GetInstanceLogs(user.ID, project.ID, 0)
+	if err != nil {
+		t.Fatalf("GetInstanceLogs: %v", err)
+	}
+	if want := fmt.Sprintf("last %d lines of %s\n", defaultInstanceLogLines, *inst.DBName); logs != want {
+		t.Errorf("logs = %q, want %q", logs, want)
+	}
+
+	for _, tail := range []int{-1, maxInstanceLogLines + 1} {
+		if _, err := env.projects.GetInstanceLogs(user.ID, project.ID, tail); err == nil {
+			t.Errorf("GetInstanceLogs with tail %d succeeded, want an error", tail)
+		}
+	}
+	if _, err := env.projects.GetInstanceLogs(other.ID, project.ID, 10); err == nil {
+		t.Error("GetInstanceLogs succeeded for a user who does not own the project")
+	}
+}
+
+func TestPauseAndResumeInstance(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	containerID := *env.instance(t, project).ContainerID
+	query := &ExecuteQueryRequest{Query: "SELECT 1"}
+
+	for i, wantChanged := range []bool{true, false} {
+		result, err := env.projects.PauseInstance(user.ID, project.ID)
+		if err != nil || result.Status != "paused" || result.Changed != wantChanged {
+			t.Fatalf("PauseInstance #%d = %+v, %v, want paused with changed %v", i+1, result, err, wantChanged)
+		}
+	}
+	if len(env.orchestrator.paused) != 1 || env.orchestrator.paused[0] != containerID {
+		t.Errorf("paused containers = %v, want only %s", env.orchestrator.paused, containerID)
+	}
+	if _, _, err := env.queries.ExecuteQuery(user.ID, query, project.ID); !errors.Is(err, ErrInstancePaused) {
+		t.Errorf("ExecuteQuery on a paused instance = %v, want ErrInstancePaused", err)
+	}
+
+	for i, wantChanged := range []bool{true, false} {
+		result, err := env.projects.ResumeInstance(user.ID, project.ID)
+		if err != nil || result.Status != "running" || result.Changed != wantChanged {
+			t.Fatalf("ResumeInstance #%d = %+v, %v, want running with changed %v", i+1, result, err, wantChanged)
+		}
+	}
+	if len(env.orchestrator.unpaused) != 1 || env.orchestrator.unpaused[0] != containerID {
+		t.Errorf("unpaused containers = %v, want only %s", env.orchestrator.unpaused, containerID)
+	}
+	if _, _, err := env.queries.ExecuteQuery(user.ID, query, project.ID); err != nil {
+		t.Errorf("ExecuteQuery after resuming: %v", err)
+	}
+}
+
+func TestInstancesStoreThePortOfTheirDatabaseType(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	mongo := env.instance(t, env.createProject(t, user, "mongodb"))
+	if mongo.Port != 27017 {
+		t.Errorf("mongodb instance port = %d, want 27017", mongo.Port)
+	}
+
+	// Postgres containers of the fake listen on the test server's port, which queries must use
+	project := env.createProject(t, user, "postgres")
+	postgres := env.instance(t, project)
+	if want := testdb.Lookup(t).Port; postgres.Port != want {
+		t.Errorf("postgres instance port = %d, want the reported %d", postgres.Port, want)
+	}
+	result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1"}, project.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("ExecuteQuery = %+v, %v", result, err)
+	}
+}
+
+func TestInsertRowsReturnsGeneratedIDs(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE tasks (id serial PRIMARY KEY, title text NOT NULL, done boolean)`,
+		`CREATE TABLE tags (name text PRIMARY KEY)`,
+	)
+
+	// Values arrive decoded from JSON
+	resp, err := env.projects.InsertRows(user.ID, project.ID, InsertRowsRequest{Table: "tasks", Rows: []map[string]interface{}{
+		{"title": "write", "done": true},
+		{"title": "review", "done": false},
+		{"title": "ship", "done": nil},
+	}})
+	if err != nil {
+		t.Fatalf("InsertRows: %v", err)
+	}
+	if resp.RowsInserted != 3 || fmt.Sprint(resp.IDs) != "[1 2 3]" {
+		t.Errorf("InsertRows = %+v, want 3 rows with ids [1 2 3]", resp)
+	}
+
+	// Without an id column only the count is returned
+	resp, err = env.projects.InsertRows(user.ID, project.ID, InsertRowsRequest{Table: "tags", Rows: []map[string]interface{}{
+		{"name": "urgent"}, {"name": "later"},
+	}})
+	if err != nil {
+		t.Fatalf("InsertRows into tags: %v", err)
+	}
+	if resp.RowsInserted != 2 || resp.IDs != nil {
+		t.Errorf("InsertRows into tags = %+v, want 2 rows and no ids", resp)
+	}
+
+	// A failing row leaves the whole batch out
+	_, err = env.projects.InsertRows(user.ID, project.ID, InsertRowsRequest{Table: "tasks", Rows: []map[string]interface{}{
+		{"title": "kept out", "done": true},
+		{"title": nil, "done": true},
+	}})
+	if err == nil {
+		t.Fatal("InsertRows with a NULL title succeeded")
+	}
+	var count int
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT count(*) FROM tasks`).Scan(&count); err != nil || count != 3 {
+		t.Errorf("tasks = %d, %v, want the 3 rows of the first batch", count, err)
+	}
+}
+
+func TestInsertRowsRejectsInconsistentRows(t *testing.T) {
+	projects := &ProjectService{}
+
+	cases := map[string]InsertRowsRequest{
+		"no rows":           {Table: "tasks"},
+		"empty row":         {Table: "tasks", Rows: []map[string]interface{}{{}}},
+		"invalid table":     {Table: "tasks; DROP TABLE tasks", Rows: []map[string]interface{}{{"title": "a"}}},
+		"invalid column":    {Table: "tasks", Rows: []map[string]interface{}{{"title) VALUES ('a'); --": "a"}}},
+		"extra column":      {Table: "tasks", Rows: []map[string]interface{}{{"title": "a"}, {"title": "b", "done": true}}},
+		"different columns": {Table: "tasks", Rows: []map[string]interface{}{{"title": "a"}, {"done": true}}},
+	}
+	for name, req := range cases {
+		if _, err := projects.InsertRows(uuid.New(), uuid.New(), req); err == nil {
+			t.Errorf("%s: InsertRows succeeded", name)
+		}
+	}
+}
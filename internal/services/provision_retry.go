@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// provisionRetryWindow is how long after failing an instance is still retried
+const provisionRetryWindow = 24 * time.Hour
+
+// provisionRetryBackoff is the wait before the first retry of a failed instance. It
+// doubles with every attempt.
+const provisionRetryBackoff = time.Minute
+
+// RetryFailedProvisions retries creating the containers of instances that recently failed
+// to provision, up to maxAttempts times each with exponential backoff. An instance is
+// marked running when its container is created and left failed after its last attempt.
+// It runs as a leader-only background job.
+func (s *ProjectService) RetryFailedProvisions(ctx context.Context, maxAttempts int) error {
+	instances, err := s.dbInstanceRepo.ListRetryableFailed(time.Now().Add(-provisionRetryWindow), maxAttempts, provisionRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to get failed database instances: %w", err)
+	}
+
+	for i := range instances {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		inst := &instances[i]
+
+		project, err := s.projectRepo.GetByID(inst.ProjectID)
+		if err != nil {
+			log.Printf("Failed to get project %s to retry its database instance: %v", inst.ProjectID, err)
+			continue
+		}
+		if project == nil {
+			continue
+		}
+
+		// Claim the instance first, so it is not retried twice if leadership moves mid-attempt
+		claimed, err := s.dbInstanceRepo.StartProvisionAttempt(inst.ID)
+		if err != nil {
+			log.Printf("Failed to record provisioning attempt of instance %s: %v", inst.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.provisionInstance(ctx, project, inst); err != nil {
+			log.Printf("Retrying provisioning of instance %s of project %s failed: %v", inst.ID, project.ID, err)
+			continue
+		}
+		log.Printf("Provisioned instance %s of project %s on retry", inst.ID, project.ID)
+	}
+
+	return nil
+}
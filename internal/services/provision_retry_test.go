@@ -0,0 +1,141 @@
+package services
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// failingProject creates a project whose container creation fails and returns it
+func failingProject(t *testing.T, env *testEnv, user *models.User, name string) *models.Project {
+	t.Helper()
+
+	_, err := env.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{Name: name, DBType: "postgres", ResourceTier: "basic"})
+	if err == nil {
+		t.Fatal("CreateProject succeeded, want the container creation to fail")
+	}
+	projects, err := env.projectRepo.GetByUserID(user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	for i := range projects {
+		if projects[i].Name == name {
+			return &projects[i]
+		}
+	}
+	t.Fatalf("project %s was not stored", name)
+	return nil
+}
+
+// backdate moves the last status change of a project's instance into the past
+func backdate(t *testing.T, env *testEnv, project *models.Project, age time.Duration) {
+	t.Helper()
+
+	_, err := env.pool.Exec(context.Background(),
+		`UPDATE database_instances SET updated_at = NOW() - make_interval(secs => $2) WHERE project_id = $1`,
+		project.ID, age.Seconds())
+	if err != nil {
+		t.Fatalf("backdate instance: %v", err)
+	}
+}
+
+func provisionAttempts(t *testing.T, env *testEnv, project *models.Project) int {
+	t.Helper()
+
+	var attempts int
+	err := env.pool.QueryRow(context.Background(), `SELECT provision_attempts FROM database_instances WHERE project_id = $1`, project.ID).Scan(&attempts)
+	if err != nil {
+		t.Fatalf("get provision attempts: %v", err)
+	}
+	return attempts
+}
+
+func TestRetryFailedProvisionsWithBackoff(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	// The orchestrator is down for the first two creations
+	env.orchestrator.createErr = func(call int) error {
+		if call <= 2 {
+			return errors.New("orchestrator unavailable")
+		}
+		return nil
+	}
+	project := failingProject(t, env, user, "flaky")
+	if inst := env.instance(t, project); inst.Status != "failed" {
+		t.Fatalf("instance status = %s, want failed", inst.Status)
+	}
+
+	// Nothing is retried before the first backoff has passed
+	if err := env.projects.RetryFailedProvisions(context.Background(), 3); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	if env.orchestrator.createCalls != 1 {
+		t.Fatalf("%d container creations, want no retry yet", env.orchestrator.createCalls)
+	}
+
+	backdate(t, env, project, provisionRetryBackoff+time.Second)
+	if err := env.projects.RetryFailedProvisions(context.Background(), 3); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	if inst := env.instance(t, project); inst.Status != "failed" || provisionAttempts(t, env, project) != 1 {
+		t.Fatalf("after a failed retry: status %s, %d attempts, want failed after 1", inst.Status, provisionAttempts(t, env, project))
+	}
+
+	// The second retry waits twice as long
+	backdate(t, env, project, provisionRetryBackoff+time.Second)
+	if err := env.projects.RetryFailedProvisions(context.Background(), 3); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	if env.orchestrator.createCalls != 2 {
+		t.Fatalf("%d container creations, want the backoff to have doubled", env.orchestrator.createCalls)
+	}
+
+	backdate(t, env, project, 2*provisionRetryBackoff+time.Second)
+	if err := env.projects.RetryFailedProvisions(context.Background(), 3); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	inst := env.instance(t, project)
+	if inst.Status != "running" || inst.ContainerID == nil || provisionAttempts(t, env, project) != 2 {
+		t.Fatalf("after a successful retry: %+v with %d attempts, want running after 2", inst, provisionAttempts(t, env, project))
+	}
+	env.exec(t, user, project, `CREATE TABLE provisioned (id integer)`)
+}
+
+func TestRetryFailedProvisionsGivesUp(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	env.orchestrator.createErr = func(call int) error { return errors.New("orchestrator unavailable") }
+
+	exhausted := failingProject(t, env, user, "exhausted")
+	stale := failingProject(t, env, user, "stale")
+
+	// One attempt is allowed; the stale instance failed too long ago to be retried at all
+	backdate(t, env, exhausted, provisionRetryBackoff+time.Second)
+	backdate(t, env, stale, provisionRetryWindow+time.Minute)
+	if err := env.projects.RetryFailedProvisions(context.Background(), 1); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	if got := provisionAttempts(t, env, exhausted); got != 1 {
+		t.Errorf("exhausted instance was attempted %d times, want 1", got)
+	}
+	if got := provisionAttempts(t, env, stale); got != 0 {
+		t.Errorf("stale instance was attempted %d times, want 0", got)
+	}
+
+	backdate(t, env, exhausted, time.Hour)
+	if err := env.projects.RetryFailedProvisions(context.Background(), 1); err != nil {
+		t.Fatalf("RetryFailedProvisions: %v", err)
+	}
+	if got := provisionAttempts(t, env, exhausted); got != 1 {
+		t.Errorf("exhausted instance was attempted %d times, want no attempt past the maximum", got)
+	}
+	if inst := env.instance(t, exhausted); inst.Status != "failed" {
+		t.Errorf("exhausted instance status = %s, want failed", inst.Status)
+	}
+	if calls := env.orchestrator.createCalls; calls != 3 {
+		t.Errorf("%d container creations, want the two creations and one retry", calls)
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GetProjectQueryHistoryEntry returns a query history entry of the user that was run on
+// one of the project's database instances
+func (s *QueryService) GetProjectQueryHistoryEntry(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID) (*models.QueryHistory, error) {
+	if err := s.requireProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.execRepo.GetByIDAndProjectID(id, userID, projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get query history entry")
+	}
+	if entry == nil {
+		return nil, apperr.NotFound(apperr.CodeHistoryNotFound, "query history entry not found")
+	}
+	return entry, nil
+}
+
+// RerunQuery runs the query of a history entry again through ExecuteQuery, so it is
+// validated against the project's current policy and recorded as a new history entry.
+// Entries of projects that redact query text only hold a digest and cannot be re-run.
+func (s *QueryService) RerunQuery(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID) (*QueryResult, *models.QueryHistory, error) {
+	entry, err := s.GetProjectQueryHistoryEntry(userID, projectID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.HasPrefix(entry.QueryText, redactedQueryPrefix) {
+		return nil, nil, apperr.Invalid(apperr.CodeQueryRedacted, "the query text of this entry was redacted and cannot be re-run")
+	}
+	return s.ExecuteQuery(userID, &ExecuteQueryRequest{Query: entry.QueryText}, projectID)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"testing"
+)
+
+func TestRerunQuery(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	other := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE counters (n integer)`)
+
+	_, exec, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "INSERT INTO counters VALUES (1)"}, project.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+
+	entry, err := env.queries.GetProjectQueryHistoryEntry(user.ID, project.ID, exec.ID)
+	if err != nil || entry.QueryText != "INSERT INTO counters VALUES (1)" {
+		t.Fatalf("GetProjectQueryHistoryEntry = %+v, %v", entry, err)
+	}
+	// The entry belongs to one project only
+	if _, err := env.queries.GetProjectQueryHistoryEntry(user.ID, other.ID, exec.ID); apperr.From(err).Code != apperr.CodeHistoryNotFound {
+		t.Errorf("GetProjectQueryHistoryEntry of another project = %v, want %s", err, apperr.CodeHistoryNotFound)
+	}
+
+	result, rerun, err := env.queries.RerunQuery(user.ID, project.ID, exec.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("RerunQuery = %+v, %v", result, err)
+	}
+	if rerun.ID == exec.ID {
+		t.Error("the re-run was not recorded as a new history entry")
+	}
+	var count int
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT count(*) FROM counters`).Scan(&count); err != nil || count != 2 {
+		t.Errorf("counters = %d, %v, want 2 rows after the re-run", count, err)
+	}
+
+	// A redacted entry only holds a digest
+	redact := true
+	if _, err := env.projects.UpdateProject(user.ID, project.ID, UpdateProjectRequest{RedactQueryText: &redact}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	_, redacted, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1"}, project.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+	if _, _, err := env.queries.RerunQuery(user.ID, project.ID, redacted.ID); apperr.From(err).Code != apperr.CodeQueryRedacted {
+		t.Errorf("RerunQuery of a redacted entry = %v, want %s", err, apperr.CodeQueryRedacted)
+	}
+}
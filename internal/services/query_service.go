@@ -1,15 +1,22 @@
 package services
 
 import (
+	"backend/internal/apperr"
 	"backend/internal/models"
+	"backend/internal/pagination"
 	"backend/internal/repositories"
 	"backend/internal/utils"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,34 +28,127 @@ type QueryService struct {
 	instanceRepo *repositories.DatabaseInstanceRepository
 	credRepo     *repositories.DatabaseCredentialRepository
 	execRepo     *repositories.QueryHistoryRepository
-	orchestrator *OrchestratorService
+	planRepo     *repositories.QueryPlanSnapshotRepository
+	savedRepo    *repositories.SavedQueryRepository
+	orchestrator Orchestrator
+
+	// timeout replaces the per-tier query time budgets when set (QUERY_TIMEOUT_MS)
+	timeout time.Duration
+
+	connections *ConnectionManager
+
+	// running holds the queries currently executing, by execution ID, so they can be cancelled
+	runningMu sync.Mutex
+	running   map[uuid.UUID]*runningQuery
 }
 
-func NewQueryService(projectRepo *repositories.ProjectRepository, instanceRepo *repositories.DatabaseInstanceRepository, credRepo *repositories.DatabaseCredentialRepository, execRepo *repositories.QueryHistoryRepository, orchestrator *OrchestratorService) *QueryService {
+// runningQuery is an in-flight query that can be cancelled by its owner
+type runningQuery struct {
+	userID    uuid.UUID
+	projectID uuid.UUID
+	db        *sql.DB
+	pid       int // Backend PID serving the query, 0 until known
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+func NewQueryService(projectRepo *repositories.ProjectRepository, instanceRepo *repositories.DatabaseInstanceRepository, credRepo *repositories.DatabaseCredentialRepository, execRepo *repositories.QueryHistoryRepository, planRepo *repositories.QueryPlanSnapshotRepository, savedRepo *repositories.SavedQueryRepository, orchestrator Orchestrator, timeout time.Duration, connections *ConnectionManager) *QueryService {
 	return &QueryService{
 		projectRepo:  projectRepo,
 		instanceRepo: instanceRepo,
 		credRepo:     credRepo,
 		execRepo:     execRepo,
+		planRepo:     planRepo,
+		savedRepo:    savedRepo,
 		orchestrator: orchestrator,
+		timeout:      timeout,
+		connections:  connections,
+		running:      make(map[uuid.UUID]*runningQuery),
 	}
 }
 
 type QueryResult struct {
 	Columns       []string                 `json:"columns"`
+	ColumnTypes   []ResultColumnType       `json:"column_types,omitempty"`
 	Rows          []map[string]interface{} `json:"rows"`
 	RowCount      int                      `json:"row_count"`
 	RowsAffected  int64                    `json:"rows_affected,omitempty"`
 	ExecutionTime int64                    `json:"execution_time_ms"`
+	TimeoutMs     int64                    `json:"timeout_ms,omitempty"`
 	Error         string                   `json:"error,omitempty"`
 }
 
+// queryTimeouts is the time budget of a single query per resource tier
+var queryTimeouts = map[string]time.Duration{
+	"free":    10 * time.Second,
+	"basic":   30 * time.Second,
+	"premium": 2 * time.Minute,
+}
+
+// queryTimeoutGrace lets Postgres' statement_timeout fire before the client-side deadline,
+// which then only guards against a connection that stops responding
+const queryTimeoutGrace = 2 * time.Second
+
+// queryTimeout returns the time budget of a query on a project: the configured
+// QUERY_TIMEOUT_MS when set, otherwise the budget of the project's tier
+func (s *QueryService) queryTimeout(project *models.Project) time.Duration {
+	if s.timeout > 0 {
+		return s.timeout
+	}
+	return queryTimeoutForTier(project.ResourceTier)
+}
+
+// queryTimeoutForTier returns the query time budget of a tier, defaulting to the free tier
+func queryTimeoutForTier(tier string) time.Duration {
+	if timeout, ok := queryTimeouts[tier]; ok {
+		return timeout
+	}
+	return queryTimeouts["free"]
+}
+
+// ResultColumnType describes a result column's database type (e.g. "INT4", "TIMESTAMPTZ")
+type ResultColumnType struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Encoding string `json:"encoding,omitempty"` // "base64" for bytea values
+}
+
+// ExecuteQueryRequest represents the request body for executing a query. A client that
+// may want to cancel the query generates its execution ID up front and passes it here.
 type ExecuteQueryRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query       string     `json:"query" binding:"required"`
+	ExecutionID *uuid.UUID `json:"execution_id"`
+}
+
+// ErrQueryNotRunning is returned when cancelling a query that is not executing
+var ErrQueryNotRunning = errors.New("query not found or not running")
+
+// ErrInvalidExecutionID is returned for an execution ID that can never identify a query
+var ErrInvalidExecutionID = apperr.Invalid(apperr.CodeInvalidExecutionID, "invalid execution_id")
+
+// ErrExecutionIDInUse is returned for an execution ID of a query that is running or in
+// the query history, whoever ran it
+var ErrExecutionIDInUse = apperr.Conflict(apperr.CodeExecutionIDInUse, "execution_id is already used by another query")
+
+// sqlExecutor is satisfied by *sql.DB and *sql.Conn
+type sqlExecutor interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
-// ValidateSQLQuery validates SQL queries to prevent dangerous operations
-func (s *QueryService) ValidateSQLQuery(query string) error {
+// readOnlyStatements are the leading keywords allowed on read-only projects
+var readOnlyStatements = []string{"SELECT", "WITH", "SHOW", "EXPLAIN", "VALUES", "TABLE"}
+
+// writeKeywords are rejected anywhere in a query on read-only projects
+var writeKeywords = []string{
+	"INSERT", "UPDATE", "DELETE", "MERGE", "CREATE", "ALTER", "DROP",
+	"TRUNCATE", "GRANT", "REVOKE", "COPY", "ANALYZE", "VACUUM", "REINDEX",
+}
+
+// ValidateSQLQuery validates SQL queries to prevent dangerous operations.
+// When project is non-nil its query policy (read-only mode and blocked
+// keywords) is applied on top of the global rules.
+func (s *QueryService) ValidateSQLQuery(query string, project *models.Project) error {
 	// Trim + uppercase
 	normalized := strings.ToUpper(strings.TrimSpace(query))
 
@@ -101,13 +201,63 @@ func (s *QueryService) ValidateSQLQuery(query string) error {
 		}
 	}
 
+	if project != nil {
+		if err := validateProjectPolicy(normalized, project); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// validateProjectPolicy applies a project's query policy to a normalized query
+func validateProjectPolicy(normalized string, project *models.Project) error {
+	if project.ReadOnly {
+		fields := strings.Fields(normalized)
+		allowed := false
+		for _, stmt := range readOnlyStatements {
+			if len(fields) > 0 && strings.TrimRight(fields[0], "(;") == stmt {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errors.New("project is read-only: only read queries are allowed")
+		}
+		for _, keyword := range writeKeywords {
+			if containsKeyword(normalized, keyword) {
+				return fmt.Errorf("project is read-only: operation '%s' is not allowed", keyword)
+			}
+		}
+	}
+
+	for _, keyword := range project.BlockedKeywords {
+		if containsKeyword(normalized, strings.ToUpper(keyword)) {
+			return fmt.Errorf("operation '%s' is blocked for this project", keyword)
+		}
+	}
+
+	return nil
+}
+
+// containsKeyword reports whether keyword appears in query as a whole word
+func containsKeyword(query, keyword string) bool {
+	pattern := `\b` + strings.Join(strings.Fields(regexp.QuoteMeta(keyword)), `\s+`) + `\b`
+	return regexp.MustCompile(pattern).MatchString(query)
+}
+
 // ExecuteQuery executes a SQL query on the specified database connection
 func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest, projectId uuid.UUID) (*QueryResult, *models.QueryHistory, error) {
 	startTime := time.Now()
 
+	executionID := uuid.New()
+	if req.ExecutionID != nil {
+		if *req.ExecutionID == uuid.Nil {
+			return nil, nil, ErrInvalidExecutionID
+		}
+		executionID = *req.ExecutionID
+	}
+
 	// Validate project ownership
 	project, err := s.projectRepo.GetByIDAndUserID(projectId, userID)
 	if err != nil {
@@ -116,15 +266,29 @@ func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest,
 	if project == nil {
 		return nil, nil, errors.New("project not found or not accessible")
 	}
+	if err := requirePostgres(project); err != nil {
+		return nil, nil, err
+	}
+
+	// A client-chosen ID must not be one a finished query already recorded
+	if req.ExecutionID != nil {
+		taken, err := s.execRepo.Exists(executionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check execution_id: %w", err)
+		}
+		if taken {
+			return nil, nil, ErrExecutionIDInUse
+		}
+	}
+
+	historyText := historyQueryText(project, req.Query)
+	stmtType := statementType(req.Query)
 
 	// Find running DB instance for this project
-	inst, err := s.instanceRepo.GetRunningByProjectID(projectId)
+	inst, err := s.runningInstance(projectId)
 	if err != nil {
 		return nil, nil, err
 	}
-	if inst == nil {
-		return nil, nil, errors.New("no running database instance for this project")
-	}
 
 	// Fetch credentials for the instance
 	cred, err := s.credRepo.GetLatestByInstanceID(inst.ID)
@@ -136,19 +300,25 @@ func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest,
 	}
 
 	// Validate query
-	if err := s.ValidateSQLQuery(req.Query); err != nil {
+	if err := s.ValidateSQLQuery(req.Query, project); err != nil {
 		execTime := time.Since(startTime).Milliseconds()
 		success := false
 		exec := &models.QueryHistory{
+			ID:              executionID,
 			DBInstanceID:    inst.ID,
 			UserID:          userID,
-			QueryText:       req.Query,
+			QueryText:       historyText,
+			StatementType:   &stmtType,
 			ExecutedAt:      time.Now(),
 			Success:         &success,
 			ExecutionTimeMs: &[]int{int(execTime)}[0],
 		}
-		_ = s.execRepo.Create(exec)
-		return &QueryResult{Error: err.Error(), ExecutionTime: execTime}, exec, nil
+		result := &QueryResult{Error: err.Error(), ExecutionTime: execTime}
+		exec.ErrorMessage = &result.Error
+		if err := s.recordExecution(exec); err != nil {
+			return nil, nil, err
+		}
+		return result, exec, nil
 	}
 
 	// Validate container_id exists
@@ -156,53 +326,47 @@ func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest,
 		execTime := time.Since(startTime).Milliseconds()
 		success := false
 		exec := &models.QueryHistory{
+			ID:              executionID,
 			DBInstanceID:    inst.ID,
 			UserID:          userID,
-			QueryText:       req.Query,
+			QueryText:       historyText,
+			StatementType:   &stmtType,
 			ExecutedAt:      time.Now(),
 			Success:         &success,
 			ExecutionTimeMs: &[]int{int(execTime)}[0],
 		}
-		_ = s.execRepo.Create(exec)
-		return &QueryResult{Error: "database instance container ID not configured", ExecutionTime: execTime}, exec, nil
-	}
-
-	// Get current IP from orchestrator
-	ip, ok := s.orchestrator.GetContainerIP(*inst.ContainerID)
-	if !ok {
-		// Try Redis as fallback
-		var err error
-		ip, err = s.orchestrator.GetContainerIPFromRedis(context.Background(), *inst.ContainerID)
-		if err != nil {
-			execTime := time.Since(startTime).Milliseconds()
-			success := false
-			exec := &models.QueryHistory{
-				DBInstanceID:    inst.ID,
-				UserID:          userID,
-				QueryText:       req.Query,
-				ExecutedAt:      time.Now(),
-				Success:         &success,
-				ExecutionTimeMs: &[]int{int(execTime)}[0],
-			}
-			_ = s.execRepo.Create(exec)
-			return &QueryResult{Error: "failed to get container IP from orchestrator", ExecutionTime: execTime}, exec, nil
+		result := &QueryResult{Error: "database instance container ID not configured", ExecutionTime: execTime}
+		exec.ErrorMessage = &result.Error
+		if err := s.recordExecution(exec); err != nil {
+			return nil, nil, err
 		}
+		return result, exec, nil
 	}
 
-	// Validate port
-	if inst.Port == nil {
+	// Get current IP from orchestrator
+	ip, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+	if err != nil {
 		execTime := time.Since(startTime).Milliseconds()
 		success := false
 		exec := &models.QueryHistory{
+			ID:              executionID,
 			DBInstanceID:    inst.ID,
 			UserID:          userID,
-			QueryText:       req.Query,
+			QueryText:       historyText,
+			StatementType:   &stmtType,
 			ExecutedAt:      time.Now(),
 			Success:         &success,
 			ExecutionTimeMs: &[]int{int(execTime)}[0],
 		}
-		_ = s.execRepo.Create(exec)
-		return &QueryResult{Error: "database instance port not configured", ExecutionTime: execTime}, exec, nil
+		result := &QueryResult{Error: "failed to get container IP from orchestrator", ExecutionTime: execTime}
+		if errors.Is(err, ErrContainerUnreachable) {
+			result.Error = err.Error()
+		}
+		exec.ErrorMessage = &result.Error
+		if err := s.recordExecution(exec); err != nil {
+			return nil, nil, err
+		}
+		return result, exec, nil
 	}
 
 	// Decrypt password before building DSN
@@ -211,47 +375,84 @@ func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest,
 		execTime := time.Since(startTime).Milliseconds()
 		success := false
 		exec := &models.QueryHistory{
+			ID:              executionID,
 			DBInstanceID:    inst.ID,
 			UserID:          userID,
-			QueryText:       req.Query,
+			QueryText:       historyText,
+			StatementType:   &stmtType,
 			ExecutedAt:      time.Now(),
 			Success:         &success,
 			ExecutionTimeMs: &[]int{int(execTime)}[0],
 		}
-		_ = s.execRepo.Create(exec)
-		return &QueryResult{Error: "failed to decrypt database credentials", ExecutionTime: execTime}, exec, nil
+		result := &QueryResult{Error: "failed to decrypt database credentials", ExecutionTime: execTime}
+		exec.ErrorMessage = &result.Error
+		if err := s.recordExecution(exec); err != nil {
+			return nil, nil, err
+		}
+		return result, exec, nil
 	}
 
 	// Build connection string using IP from orchestrator
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		ip, *inst.Port, cred.Username, dbPassword, "postgres")
+	timeout := s.queryTimeout(project)
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable statement_timeout=%d",
+		ip, inst.Port, cred.Username, dbPassword, inst.DatabaseName(), timeout.Milliseconds())
+	if project.ReadOnly {
+		// Enforce read-only at the server as well as in the validator
+		dsn += " default_transaction_read_only=on"
+	}
+	// Arbitrary SQL can leave session state behind (SET, temp tables, open transactions),
+	// so user queries get a connection of their own rather than one from the shared pool
 	sqlDB, err := sql.Open("postgres", dsn)
 	if err != nil {
 		execTime := time.Since(startTime).Milliseconds()
 		success := false
 		exec := &models.QueryHistory{
+			ID:              executionID,
 			DBInstanceID:    inst.ID,
 			UserID:          userID,
-			QueryText:       req.Query,
+			QueryText:       historyText,
+			StatementType:   &stmtType,
 			ExecutedAt:      time.Now(),
 			Success:         &success,
 			ExecutionTimeMs: &[]int{int(execTime)}[0],
 		}
-		_ = s.execRepo.Create(exec)
-		return &QueryResult{Error: err.Error(), ExecutionTime: execTime}, exec, nil
+		result := &QueryResult{Error: err.Error(), ExecutionTime: execTime}
+		exec.ErrorMessage = &result.Error
+		if err := s.recordExecution(exec); err != nil {
+			return nil, nil, err
+		}
+		return result, exec, nil
 	}
 	defer sqlDB.Close()
 
-	result, err := s.executeSQLQuery(sqlDB, req.Query)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+queryTimeoutGrace)
+	defer cancel()
+
+	running := &runningQuery{userID: userID, projectID: projectId, db: sqlDB, cancel: cancel}
+	if !s.trackQuery(executionID, running) {
+		return nil, nil, ErrExecutionIDInUse
+	}
+	defer s.untrackQuery(executionID)
+
+	result, err := s.executeTrackedQuery(ctx, sqlDB, running, req.Query)
 	execTime := time.Since(startTime).Milliseconds()
 	result.ExecutionTime = execTime
+	result.TimeoutMs = timeout.Milliseconds()
+	if s.wasCancelled(running) && (err != nil || result.Error != "") {
+		err = nil
+		result.Error = "query cancelled by the client"
+	} else if isQueryTimeout(ctx, result.Error) {
+		result.Error = fmt.Sprintf("query cancelled: exceeded the %s time limit", timeout)
+	}
 
 	success := err == nil && result.Error == ""
 	execTimeInt := int(execTime)
 	exec := &models.QueryHistory{
+		ID:              executionID,
 		DBInstanceID:    inst.ID,
 		UserID:          userID,
-		QueryText:       req.Query,
+		QueryText:       historyText,
+		StatementType:   &stmtType,
 		ExecutedAt:      time.Now(),
 		Success:         &success,
 		ExecutionTimeMs: &execTimeInt,
@@ -261,40 +462,263 @@ func (s *QueryService) ExecuteQuery(userID uuid.UUID, req *ExecuteQueryRequest,
 		if err != nil {
 			result.Error = err.Error()
 		}
+		exec.ErrorMessage = &result.Error
+	} else {
+		exec.RowsAffected = &result.RowsAffected
+	}
+	if err := s.recordExecution(exec); err != nil {
+		return nil, nil, err
 	}
-	_ = s.execRepo.Create(exec)
 	return result, exec, nil
 }
 
-// executeSQLQuery executes a SQL query and returns results
-func (s *QueryService) executeSQLQuery(db *sql.DB, query string) (*QueryResult, error) {
-	// Check if it's a SELECT query or other query type
+// recordExecution stores a query history entry. An execution ID taken since it was
+// checked, by a concurrent query with the same ID, is reported like one taken before.
+func (s *QueryService) recordExecution(exec *models.QueryHistory) error {
+	if err := s.execRepo.Create(exec); err != nil {
+		if errors.Is(err, repositories.ErrDuplicateHistoryID) {
+			return ErrExecutionIDInUse
+		}
+		return fmt.Errorf("failed to record query history: %w", err)
+	}
+	return nil
+}
 
-	normalized := strings.ToUpper(strings.TrimSpace(query))
-	isSelect := strings.HasPrefix(normalized, "SELECT") || strings.HasPrefix(normalized, "EXPLAIN SELECT")
+// redactedQueryPrefix starts the history text of queries of projects that redact query text
+const redactedQueryPrefix = "[redacted] sha256:"
+
+// historyQueryText returns the text stored in query history for a query. Projects that
+// redact query text store only a SHA-256 digest, so identical queries can still be
+// matched without keeping literals that may hold personal data.
+func historyQueryText(project *models.Project, query string) string {
+	if !project.RedactQueryText {
+		return query
+	}
+	sum := sha256.Sum256([]byte(query))
+	return redactedQueryPrefix + hex.EncodeToString(sum[:])
+}
 
-	if isSelect {
-		return s.executeSelectQuery(db, query)
+// executeTrackedQuery runs the query on a dedicated connection whose backend PID is
+// recorded first, so a cancellation can also be sent to the server with pg_cancel_backend
+func (s *QueryService) executeTrackedQuery(ctx context.Context, db *sql.DB, running *runningQuery, query string) (*QueryResult, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return &QueryResult{Error: err.Error()}, nil
+	}
+	defer conn.Close()
+
+	var pid int
+	if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		return &QueryResult{Error: err.Error()}, nil
+	}
+
+	s.runningMu.Lock()
+	running.pid = pid
+	s.runningMu.Unlock()
+
+	return s.executeSQLQuery(ctx, conn, query)
+}
+
+func (s *QueryService) trackQuery(executionID uuid.UUID, running *runningQuery) bool {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	if _, exists := s.running[executionID]; exists {
+		return false
+	}
+	s.running[executionID] = running
+	return true
+}
+
+func (s *QueryService) untrackQuery(executionID uuid.UUID) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	delete(s.running, executionID)
+}
+
+func (s *QueryService) wasCancelled(running *runningQuery) bool {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	return running.cancelled
+}
+
+// CancelQuery cancels a running query of the user's project by its execution ID. The
+// server-side backend is signalled with pg_cancel_backend and the query's context is
+// cancelled, so the execute request returns promptly and is recorded as cancelled.
+func (s *QueryService) CancelQuery(userID uuid.UUID, projectID uuid.UUID, executionID uuid.UUID) error {
+	s.runningMu.Lock()
+	running, ok := s.running[executionID]
+	if !ok || running.userID != userID || running.projectID != projectID {
+		s.runningMu.Unlock()
+		return ErrQueryNotRunning
+	}
+	running.cancelled = true
+	pid := running.pid
+	s.runningMu.Unlock()
+
+	if pid != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		// Best effort: the context cancellation below stops the request either way
+		_, _ = running.db.ExecContext(ctx, "SELECT pg_cancel_backend($1)", pid)
+	}
+	running.cancel()
+
+	return nil
+}
+
+// isQueryTimeout reports whether a query failed because it ran out of time, either
+// cancelled by Postgres' statement_timeout or by the client-side deadline
+func isQueryTimeout(ctx context.Context, queryErr string) bool {
+	if queryErr == "" {
+		return false
+	}
+	return errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+		strings.Contains(queryErr, "canceling statement due to statement timeout")
+}
+
+// executeSQLQuery executes a SQL query and returns results
+func (s *QueryService) executeSQLQuery(ctx context.Context, db sqlExecutor, query string) (*QueryResult, error) {
+	// Statements that produce a result set are read row by row, everything else only reports rows affected
+	if returnsRows(query) {
+		return s.executeSelectQuery(ctx, db, query)
 	}
 
 	// For non-SELECT queries (INSERT, UPDATE, DELETE, etc.)
-	return s.executeNonSelectQuery(db, query)
+	return s.executeNonSelectQuery(ctx, db, query)
 }
 
-// executeSelectQuery executes a SELECT query
-func (s *QueryService) executeSelectQuery(db *sql.DB, query string) (*QueryResult, error) {
-	rows, err := db.Query(query)
+// sqlQuotedOrComment matches string literals, quoted identifiers and comments,
+// so keywords inside them are not mistaken for part of the statement
+var sqlQuotedOrComment = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"|--[^\n]*|/\*(?s:.*?)\*/`)
+
+// rowReturningDML are the data-modifying statements that produce rows when they carry a RETURNING clause
+var rowReturningDML = []string{"INSERT", "UPDATE", "DELETE", "MERGE"}
+
+// statementPrefix returns a query upper-cased without literals and comments, and its
+// leading keyword, which is empty for an empty query
+func statementPrefix(query string) (normalized string, first string) {
+	normalized = strings.ToUpper(sqlQuotedOrComment.ReplaceAllString(query, " "))
+	fields := strings.Fields(normalized)
+	if len(fields) == 0 {
+		return normalized, ""
+	}
+	return normalized, strings.TrimLeft(strings.TrimRight(fields[0], "(;"), "(")
+}
+
+// returnsRows reports whether a query produces a result set: read statements,
+// and INSERT/UPDATE/DELETE/MERGE with a RETURNING clause
+func returnsRows(query string) bool {
+	normalized, first := statementPrefix(query)
+	if first == "" {
+		return false
+	}
+
+	for _, stmt := range readOnlyStatements {
+		if first == stmt {
+			return true
+		}
+	}
+	for _, stmt := range rowReturningDML {
+		if first == stmt {
+			return containsKeyword(normalized, "RETURNING")
+		}
+	}
+	return false
+}
+
+// Statement types recorded in query history
+const (
+	StatementTypeSelect = "SELECT"
+	StatementTypeDML    = "DML"
+	StatementTypeDDL    = "DDL"
+	StatementTypeOther  = "OTHER"
+)
+
+// ddlStatements are the leading keywords of statements changing the schema
+var ddlStatements = []string{"CREATE", "ALTER", "DROP", "TRUNCATE", "COMMENT"}
+
+// statementType classifies a query by its leading keyword. A WITH query writing data in
+// one of its parts counts as DML.
+func statementType(query string) string {
+	normalized, first := statementPrefix(query)
+	if first == "WITH" {
+		for _, stmt := range rowReturningDML {
+			if containsKeyword(normalized, stmt) {
+				return StatementTypeDML
+			}
+		}
+		return StatementTypeSelect
+	}
+	for _, stmt := range readOnlyStatements {
+		if first == stmt {
+			return StatementTypeSelect
+		}
+	}
+	for _, stmt := range rowReturningDML {
+		if first == stmt {
+			return StatementTypeDML
+		}
+	}
+	for _, stmt := range ddlStatements {
+		if first == stmt {
+			return StatementTypeDDL
+		}
+	}
+	return StatementTypeOther
+}
+
+// executeSelectQuery executes a query that returns rows. For DML with RETURNING,
+// Postgres returns one row per affected row, so RowsAffected matches the returned rows
+func (s *QueryService) executeSelectQuery(ctx context.Context, db sqlExecutor, query string) (*QueryResult, error) {
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return &QueryResult{Error: err.Error()}, nil
 	}
 	defer rows.Close()
 
-	columns, err := rows.Columns()
+	result, err := readQueryResult(rows)
 	if err != nil {
 		return &QueryResult{Error: err.Error()}, nil
 	}
+	if isReturningDML(query) {
+		result.RowsAffected = int64(result.RowCount)
+	}
+	return result, nil
+}
+
+// isReturningDML reports whether a query is an INSERT/UPDATE/DELETE/MERGE, whose
+// returned rows are the rows it affected
+func isReturningDML(query string) bool {
+	_, first := statementPrefix(query)
+	for _, stmt := range rowReturningDML {
+		if first == stmt {
+			return true
+		}
+	}
+	return false
+}
+
+// readQueryResult reads every row of a result set into a QueryResult
+func readQueryResult(rows *sql.Rows) (*QueryResult, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
 
-	var resultRows []map[string]interface{}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes := make([]ResultColumnType, len(colTypes))
+	for i, ct := range colTypes {
+		columnTypes[i] = ResultColumnType{Name: ct.Name(), Type: ct.DatabaseTypeName()}
+		if columnTypes[i].Type == "BYTEA" {
+			columnTypes[i].Encoding = "base64"
+		}
+	}
+
+	resultRows := []map[string]interface{}{}
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -303,7 +727,7 @@ func (s *QueryService) executeSelectQuery(db *sql.DB, query string) (*QueryResul
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return &QueryResult{Error: err.Error()}, nil
+			return nil, err
 		}
 
 		rowMap := make(map[string]interface{})
@@ -312,9 +736,14 @@ func (s *QueryService) executeSelectQuery(db *sql.DB, query string) (*QueryResul
 			if val != nil {
 				switch v := val.(type) {
 				case []byte:
-					rowMap[col] = string(v)
+					// Text-like values arrive as bytes too; only bytea is binary
+					if columnTypes[i].Encoding == "base64" {
+						rowMap[col] = base64.StdEncoding.EncodeToString(v)
+					} else {
+						rowMap[col] = string(v)
+					}
 				case time.Time:
-					rowMap[col] = v.Format(time.RFC3339)
+					rowMap[col] = formatTimeValue(v, columnTypes[i].Type)
 				default:
 					rowMap[col] = v
 				}
@@ -326,20 +755,48 @@ func (s *QueryService) executeSelectQuery(db *sql.DB, query string) (*QueryResul
 	}
 
 	if err := rows.Err(); err != nil {
-		return &QueryResult{Error: err.Error()}, nil
+		return nil, err
 	}
 
 	return &QueryResult{
-		Columns:      columns,
-		Rows:         resultRows,
-		RowCount:     len(resultRows),
-		RowsAffected: int64(len(resultRows)),
+		Columns:     columns,
+		ColumnTypes: columnTypes,
+		Rows:        resultRows,
+		RowCount:    len(resultRows),
 	}, nil
 }
 
+// Result layouts for date/time columns. Fractional seconds are kept up to Postgres'
+// microsecond precision, with trailing zeros dropped.
+const (
+	timestampLayout   = "2006-01-02T15:04:05.999999"
+	timestampTZLayout = "2006-01-02T15:04:05.999999Z07:00"
+	dateLayout        = "2006-01-02"
+	timeLayout        = "15:04:05.999999"
+	timeTZLayout      = "15:04:05.999999Z07:00"
+)
+
+// formatTimeValue formats a date/time value according to its column type. timestamptz
+// values are normalized to UTC so the result does not depend on the session time zone;
+// timestamp (without time zone) values are wall-clock times and carry no offset.
+func formatTimeValue(t time.Time, dbType string) string {
+	switch dbType {
+	case "TIMESTAMP":
+		return t.Format(timestampLayout)
+	case "DATE":
+		return t.Format(dateLayout)
+	case "TIME":
+		return t.Format(timeLayout)
+	case "TIMETZ":
+		return t.Format(timeTZLayout)
+	default: // TIMESTAMPTZ
+		return t.UTC().Format(timestampTZLayout)
+	}
+}
+
 // executeNonSelectQuery executes non-SELECT queries (INSERT, UPDATE, DELETE, etc.)
-func (s *QueryService) executeNonSelectQuery(db *sql.DB, query string) (*QueryResult, error) {
-	result, err := db.Exec(query)
+func (s *QueryService) executeNonSelectQuery(ctx context.Context, db sqlExecutor, query string) (*QueryResult, error) {
+	result, err := db.ExecContext(ctx, query)
 	if err != nil {
 		return &QueryResult{Error: err.Error()}, nil
 	}
@@ -355,7 +812,442 @@ func (s *QueryService) executeNonSelectQuery(db *sql.DB, query string) (*QueryRe
 	}, nil
 }
 
-// GetQueryHistory returns query execution history for a user
-func (s *QueryService) GetQueryHistory(userID uuid.UUID, limit int) ([]models.QueryHistory, error) {
-	return s.execRepo.GetByUserID(userID, limit)
+// GetQueryHistory returns a page of query execution history for a user along with
+// the cursor of the next page ("" when there are no more entries)
+func (s *QueryService) GetQueryHistory(userID uuid.UUID, limit int, cursor string) ([]models.QueryHistory, string, error) {
+	after, err := pagination.Decode(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch one extra entry to know whether another page exists
+	history, err := s.execRepo.GetByUserID(userID, limit+1, after)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(history) <= limit {
+		return history, "", nil
+	}
+
+	history = history[:limit]
+	last := history[len(history)-1]
+	next, err := pagination.Encode(pagination.Cursor{Time: last.ExecutedAt, ID: last.ID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode pagination cursor: %w", err)
+	}
+	return history, next, nil
+}
+
+// GetQueryHistoryEntry returns a single query history entry owned by the user
+func (s *QueryService) GetQueryHistoryEntry(userID uuid.UUID, id uuid.UUID) (*models.QueryHistory, error) {
+	entry, err := s.execRepo.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errors.New("query history entry not found")
+	}
+	return entry, nil
+}
+
+// PlanSnapshotRequest represents the request body for storing a named query plan
+type PlanSnapshotRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Query string `json:"query" binding:"required"`
+}
+
+// ExplainQueryRequest represents the request body for explaining a query
+type ExplainQueryRequest struct {
+	Query   string `json:"query" binding:"required"`
+	Analyze bool   `json:"analyze"`
+}
+
+// ComparePlansRequest represents the request body for comparing query plans.
+// The baseline snapshot is compared against the target snapshot if given,
+// otherwise against the current plan of query (or the baseline's own query).
+type ComparePlansRequest struct {
+	Baseline string `json:"baseline" binding:"required"`
+	Target   string `json:"target"`
+	Query    string `json:"query"`
+}
+
+// PlanSummary describes one side of a plan comparison
+type PlanSummary struct {
+	Name       string    `json:"name"`
+	QueryText  string    `json:"query_text"`
+	TotalCost  float64   `json:"total_cost"`
+	PlanRows   float64   `json:"plan_rows"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// PlanNodeChange reports an estimate change for a plan node present in both plans
+type PlanNodeChange struct {
+	Node         string  `json:"node"`
+	BaselineCost float64 `json:"baseline_cost"`
+	TargetCost   float64 `json:"target_cost"`
+	CostDelta    float64 `json:"cost_delta"`
+	BaselineRows float64 `json:"baseline_rows"`
+	TargetRows   float64 `json:"target_rows"`
+}
+
+// PlanDiff is the structured difference between two query plans
+type PlanDiff struct {
+	Baseline          PlanSummary      `json:"baseline"`
+	Target            PlanSummary      `json:"target"`
+	CostDelta         float64          `json:"cost_delta"`
+	CostChangePercent float64          `json:"cost_change_percent"`
+	RowsDelta         float64          `json:"rows_delta"`
+	AddedNodes        []string         `json:"added_nodes"`
+	RemovedNodes      []string         `json:"removed_nodes"`
+	ChangedNodes      []PlanNodeChange `json:"changed_nodes"`
+}
+
+// planNode mirrors the fields of EXPLAIN (FORMAT JSON) output used for comparisons
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	IndexName    string     `json:"Index Name"`
+	TotalCost    float64    `json:"Total Cost"`
+	PlanRows     float64    `json:"Plan Rows"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// label identifies a plan node by its type and the relation/index it touches
+func (n planNode) label() string {
+	label := n.NodeType
+	if n.IndexName != "" {
+		label += " using " + n.IndexName
+	}
+	if n.RelationName != "" {
+		label += " on " + n.RelationName
+	}
+	return label
+}
+
+// flatten returns the node and all of its descendants
+func (n planNode) flatten() []planNode {
+	nodes := []planNode{n}
+	for _, child := range n.Plans {
+		nodes = append(nodes, child.flatten()...)
+	}
+	return nodes
+}
+
+// CapturePlanSnapshot captures the current EXPLAIN plan of a query and stores it under a name
+func (s *QueryService) CapturePlanSnapshot(userID uuid.UUID, projectID uuid.UUID, req PlanSnapshotRequest) (*models.QueryPlanSnapshot, error) {
+	raw, root, err := s.explainQuery(userID, projectID, req.Query, false)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.QueryPlanSnapshot{
+		ProjectID: projectID,
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		QueryText: req.Query,
+		Plan:      raw,
+		TotalCost: root.TotalCost,
+		PlanRows:  root.PlanRows,
+	}
+	if err := s.planRepo.Save(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to save plan snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
+
+// ListPlanSnapshots returns the stored plan snapshots of a project
+func (s *QueryService) ListPlanSnapshots(userID uuid.UUID, projectID uuid.UUID) ([]models.QueryPlanSnapshot, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("project not found or not accessible")
+	}
+
+	return s.planRepo.GetByProjectID(projectID)
+}
+
+// ComparePlans diffs a stored baseline plan against another snapshot or the query's current plan
+func (s *QueryService) ComparePlans(userID uuid.UUID, projectID uuid.UUID, req ComparePlansRequest) (*PlanDiff, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("project not found or not accessible")
+	}
+
+	baseline, err := s.getPlanSnapshot(projectID, req.Baseline)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.QueryPlanSnapshot
+	if req.Target != "" {
+		target, err = s.getPlanSnapshot(projectID, req.Target)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		query := req.Query
+		if strings.TrimSpace(query) == "" {
+			query = baseline.QueryText
+		}
+		raw, root, err := s.explainQuery(userID, projectID, query, false)
+		if err != nil {
+			return nil, err
+		}
+		target = &models.QueryPlanSnapshot{
+			Name:      "current",
+			QueryText: query,
+			Plan:      raw,
+			TotalCost: root.TotalCost,
+			PlanRows:  root.PlanRows,
+			CreatedAt: time.Now(),
+		}
+	}
+
+	return diffPlans(baseline, target)
+}
+
+// getPlanSnapshot loads a named snapshot of a project
+func (s *QueryService) getPlanSnapshot(projectID uuid.UUID, name string) (*models.QueryPlanSnapshot, error) {
+	snapshot, err := s.planRepo.GetByProjectIDAndName(projectID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan snapshot: %w", err)
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("plan snapshot '%s' not found", name)
+	}
+	return snapshot, nil
+}
+
+// explainableStatements are the statement types ExplainQuery accepts
+var explainableStatements = map[string]bool{"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true}
+
+// ExplainQuery returns the JSON plan of a single SELECT/INSERT/UPDATE/DELETE statement.
+// With analyze the statement is executed to collect actual timings, inside a transaction
+// that is always rolled back so writes leave no trace.
+func (s *QueryService) ExplainQuery(userID uuid.UUID, projectID uuid.UUID, query string, analyze bool) (json.RawMessage, error) {
+	statements, err := splitSQLStatements(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if len(statements) != 1 {
+		return nil, errors.New("invalid query: exactly one statement can be explained")
+	}
+	fields := strings.Fields(stripLeadingSQLComments(statements[0]))
+	if len(fields) == 0 || !explainableStatements[strings.ToUpper(strings.TrimLeft(fields[0], "("))] {
+		return nil, errors.New("invalid query: only SELECT, INSERT, UPDATE and DELETE statements can be explained")
+	}
+
+	raw, _, err := s.explainQuery(userID, projectID, statements[0], analyze)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(raw), nil
+}
+
+// explainQuery runs EXPLAIN (FORMAT JSON) for a query on the project's database. Without
+// analyze the query is only planned; with analyze it runs in a transaction that is rolled back.
+func (s *QueryService) explainQuery(userID uuid.UUID, projectID uuid.UUID, query string, analyze bool) ([]byte, *planNode, error) {
+	project, db, err := s.openProjectDB(userID, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.ValidateSQLQuery(query, project); err != nil {
+		return nil, nil, err
+	}
+
+	statement := strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	if fields := strings.Fields(statement); len(fields) > 0 && strings.EqualFold(fields[0], "EXPLAIN") {
+		return nil, nil, errors.New("query must not include EXPLAIN")
+	}
+
+	timeout := 30 * time.Second
+	explain := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		// The statement really runs, so it gets the same budget as executing it
+		timeout = s.queryTimeout(project)
+		explain = "EXPLAIN (ANALYZE, FORMAT JSON) "
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Read-only projects are enforced per transaction, as the connection is shared
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: project.ReadOnly})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer tx.Rollback()
+
+	var raw []byte
+	if err := tx.QueryRowContext(ctx, explain+statement).Scan(&raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	root, err := parsePlan(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return raw, root, nil
+}
+
+// runningInstance returns the running instance of a project. When the project's instance is
+// paused, ErrInstancePaused tells the user to resume it instead of a generic error.
+func (s *QueryService) runningInstance(projectID uuid.UUID) (*models.DatabaseInstance, error) {
+	inst, err := s.instanceRepo.GetRunningByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if inst != nil {
+		return inst, nil
+	}
+
+	latest, err := s.instanceRepo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Status == "paused" {
+		return nil, ErrInstancePaused
+	}
+	return nil, errors.New("no running database instance for this project")
+}
+
+// openProjectDB verifies project ownership and returns the pooled connection to the project's
+// running instance. The pool is shared, so callers must not close it.
+func (s *QueryService) openProjectDB(userID uuid.UUID, projectID uuid.UUID) (*models.Project, *sql.DB, error) {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if project == nil {
+		return nil, nil, errors.New("project not found or not accessible")
+	}
+	if err := requirePostgres(project); err != nil {
+		return nil, nil, err
+	}
+
+	inst, err := s.runningInstance(projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inst.ContainerID == nil || *inst.ContainerID == "" {
+		return nil, nil, errors.New("database instance container ID not configured")
+	}
+
+	cred, err := s.credRepo.GetLatestByInstanceID(inst.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cred == nil {
+		return nil, nil, errors.New("no credentials configured for this database instance")
+	}
+
+	ip, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get container IP from orchestrator: %w", err)
+	}
+
+	dbPassword, err := utils.DecryptString(cred.PasswordEncrypted)
+	if err != nil {
+		return nil, nil, errors.New("failed to decrypt database credentials")
+	}
+
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		ip, inst.Port, cred.Username, dbPassword, inst.DatabaseName())
+	db, err := s.connections.Get(inst.ID, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return project, db, nil
+}
+
+// diffPlans compares the estimated cost and nodes of two plans
+func diffPlans(baseline, target *models.QueryPlanSnapshot) (*PlanDiff, error) {
+	baselineRoot, err := parsePlan(baseline.Plan)
+	if err != nil {
+		return nil, err
+	}
+	targetRoot, err := parsePlan(target.Plan)
+	if err != nil {
+		return nil, err
+	}
+	baselineNodes := baselineRoot.flatten()
+	targetNodes := targetRoot.flatten()
+
+	diff := &PlanDiff{
+		Baseline:     summarizeSnapshot(baseline),
+		Target:       summarizeSnapshot(target),
+		CostDelta:    target.TotalCost - baseline.TotalCost,
+		RowsDelta:    target.PlanRows - baseline.PlanRows,
+		AddedNodes:   []string{},
+		RemovedNodes: []string{},
+		ChangedNodes: []PlanNodeChange{},
+	}
+	if baseline.TotalCost != 0 {
+		diff.CostChangePercent = diff.CostDelta / baseline.TotalCost * 100
+	}
+
+	// Match nodes by label in plan order; unmatched nodes were added or removed
+	remaining := make(map[string][]planNode)
+	for _, node := range baselineNodes {
+		remaining[node.label()] = append(remaining[node.label()], node)
+	}
+	for _, node := range targetNodes {
+		label := node.label()
+		matches := remaining[label]
+		if len(matches) == 0 {
+			diff.AddedNodes = append(diff.AddedNodes, label)
+			continue
+		}
+		before := matches[0]
+		remaining[label] = matches[1:]
+		if before.TotalCost != node.TotalCost || before.PlanRows != node.PlanRows {
+			diff.ChangedNodes = append(diff.ChangedNodes, PlanNodeChange{
+				Node:         label,
+				BaselineCost: before.TotalCost,
+				TargetCost:   node.TotalCost,
+				CostDelta:    node.TotalCost - before.TotalCost,
+				BaselineRows: before.PlanRows,
+				TargetRows:   node.PlanRows,
+			})
+		}
+	}
+	for _, node := range baselineNodes {
+		label := node.label()
+		if len(remaining[label]) > 0 {
+			diff.RemovedNodes = append(diff.RemovedNodes, label)
+			remaining[label] = remaining[label][1:]
+		}
+	}
+
+	return diff, nil
+}
+
+// parsePlan returns the root node of EXPLAIN (FORMAT JSON) output
+func parsePlan(raw []byte) (*planNode, error) {
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil || len(plans) == 0 {
+		return nil, errors.New("failed to parse query plan")
+	}
+	return &plans[0].Plan, nil
+}
+
+func summarizeSnapshot(snapshot *models.QueryPlanSnapshot) PlanSummary {
+	return PlanSummary{
+		Name:       snapshot.Name,
+		QueryText:  snapshot.QueryText,
+		TotalCost:  snapshot.TotalCost,
+		PlanRows:   snapshot.PlanRows,
+		CapturedAt: snapshot.CreatedAt,
+	}
 }
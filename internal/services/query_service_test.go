@@ -0,0 +1,599 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryOnUnreachableContainerReportsWhy(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	// The container left the orchestrator network
+	inst := env.instance(t, project)
+	if err := env.orchestrator.DeleteContainer(*inst.ContainerID); err != nil {
+		t.Fatalf("DeleteContainer: %v", err)
+	}
+
+	result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1"}, project.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+	if !strings.Contains(result.Error, ErrContainerUnreachable.Error()) {
+		t.Errorf("query error = %q, want it to explain the container is unreachable", result.Error)
+	}
+}
+
+func TestValidateSQLQueryAppliesProjectPolicy(t *testing.T) {
+	s := &QueryService{}
+	readOnly := &models.Project{ReadOnly: true}
+	blocked := &models.Project{BlockedKeywords: []string{"delete", "pg_sleep"}}
+
+	cases := []struct {
+		query   string
+		project *models.Project
+		allowed bool
+	}{
+		{"SELECT * FROM users", readOnly, true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", readOnly, true},
+		{"EXPLAIN SELECT 1", readOnly, true},
+		{"INSERT INTO users VALUES (1)", readOnly, false},
+		{"update users set name = 'x' where id = 1", readOnly, false},
+		{"WITH d AS (DELETE FROM users WHERE id = 1 RETURNING *) SELECT * FROM d", readOnly, false},
+		{"CREATE TABLE t (id int)", readOnly, false},
+		{"SELECT * FROM users WHERE note = 'updated'", readOnly, true},
+		{"DELETE FROM users WHERE id = 1", blocked, false},
+		{"SELECT pg_sleep(10)", blocked, false},
+		{"SELECT * FROM deleted_users", blocked, true},
+		{"INSERT INTO users VALUES (1)", blocked, true},
+		{"INSERT INTO users VALUES (1)", nil, true},
+	}
+	for _, c := range cases {
+		err := s.ValidateSQLQuery(c.query, c.project)
+		if (err == nil) != c.allowed {
+			t.Errorf("ValidateSQLQuery(%q) = %v, want allowed %v", c.query, err, c.allowed)
+		}
+	}
+}
+
+func TestProjectPolicyRejectsQueries(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE notes (id integer PRIMARY KEY, body text)`)
+
+	readOnly := true
+	if _, err := env.projects.UpdateProject(user.ID, project.ID, UpdateProjectRequest{ReadOnly: &readOnly}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "INSERT INTO notes VALUES (1, 'x')"}, project.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+	if !strings.Contains(result.Error, "read-only") {
+		t.Errorf("insert on a read-only project = %+v, want it rejected", result)
+	}
+	if result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT * FROM notes"}, project.ID); err != nil || result.Error != "" {
+		t.Errorf("select on a read-only project = %+v, %v, want it allowed", result, err)
+	}
+
+	readOnly = false
+	keywords := []string{"pg_sleep"}
+	if _, err := env.projects.UpdateProject(user.ID, project.ID, UpdateProjectRequest{ReadOnly: &readOnly, BlockedKeywords: &keywords}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	result, _, err = env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT pg_sleep(0)"}, project.ID)
+	if err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+	if !strings.Contains(result.Error, "blocked") {
+		t.Errorf("blocked keyword = %+v, want it rejected", result)
+	}
+	if result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "INSERT INTO notes VALUES (1, 'x')"}, project.ID); err != nil || result.Error != "" {
+		t.Errorf("insert once writable = %+v, %v, want it allowed", result, err)
+	}
+}
+
+func TestDiffPlans(t *testing.T) {
+	baseline := &models.QueryPlanSnapshot{
+		Name:      "before",
+		Plan:      []byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "events", "Total Cost": 200, "Plan Rows": 10}}]`),
+		TotalCost: 200,
+		PlanRows:  10,
+	}
+	target := &models.QueryPlanSnapshot{
+		Name: "after",
+		Plan: []byte(`[{"Plan": {"Node Type": "Bitmap Heap Scan", "Relation Name": "events", "Total Cost": 50, "Plan Rows": 10,
+			"Plans": [{"Node Type": "Bitmap Index Scan", "Index Name": "events_kind_idx", "Total Cost": 4, "Plan Rows": 10}]}}]`),
+		TotalCost: 50,
+		PlanRows:  10,
+	}
+
+	diff, err := diffPlans(baseline, target)
+	if err != nil {
+		t.Fatalf("diffPlans: %v", err)
+	}
+	if diff.CostDelta != -150 || diff.CostChangePercent != -75 || diff.RowsDelta != 0 {
+		t.Errorf("delta = %v (%v%%), rows %v, want -150 (-75%%), rows 0", diff.CostDelta, diff.CostChangePercent, diff.RowsDelta)
+	}
+	if fmt.Sprint(diff.RemovedNodes) != "[Seq Scan on events]" {
+		t.Errorf("removed nodes = %v", diff.RemovedNodes)
+	}
+	if fmt.Sprint(diff.AddedNodes) != "[Bitmap Heap Scan on events Bitmap Index Scan using events_kind_idx]" {
+		t.Errorf("added nodes = %v", diff.AddedNodes)
+	}
+
+	// The same plan at a different cost is a changed node
+	target.Plan = []byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "events", "Total Cost": 260, "Plan Rows": 12}}]`)
+	target.TotalCost, target.PlanRows = 260, 12
+	if diff, err = diffPlans(baseline, target); err != nil {
+		t.Fatalf("diffPlans: %v", err)
+	}
+	if len(diff.ChangedNodes) != 1 || diff.ChangedNodes[0].CostDelta != 60 || len(diff.AddedNodes) != 0 || len(diff.RemovedNodes) != 0 {
+		t.Errorf("diff = %+v, want one changed node costing 60 more", diff)
+	}
+}
+
+func TestPlanSnapshotsAreStoredAndCompared(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE events (id integer PRIMARY KEY, kind integer)`,
+		`INSERT INTO events SELECT i, i % 1000 FROM generate_series(1, 20000) AS i`,
+		`ANALYZE events`,
+	)
+	query := "SELECT * FROM events WHERE kind = 42"
+
+	before, err := env.queries.CapturePlanSnapshot(user.ID, project.ID, PlanSnapshotRequest{Name: "before", Query: query})
+	if err != nil {
+		t.Fatalf("CapturePlanSnapshot: %v", err)
+	}
+	if before.TotalCost <= 0 {
+		t.Errorf("snapshot cost = %v, want the plan's estimate", before.TotalCost)
+	}
+
+	env.exec(t, user, project, `CREATE INDEX events_kind_idx ON events (kind)`, `ANALYZE events`)
+	if _, err := env.queries.CapturePlanSnapshot(user.ID, project.ID, PlanSnapshotRequest{Name: "after", Query: query}); err != nil {
+		t.Fatalf("CapturePlanSnapshot: %v", err)
+	}
+
+	snapshots, err := env.queries.ListPlanSnapshots(user.ID, project.ID)
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("ListPlanSnapshots = %v, %v, want both snapshots", snapshots, err)
+	}
+
+	diff, err := env.queries.ComparePlans(user.ID, project.ID, ComparePlansRequest{Baseline: "before", Target: "after"})
+	if err != nil {
+		t.Fatalf("ComparePlans: %v", err)
+	}
+	if diff.CostDelta >= 0 || diff.CostDelta != diff.Target.TotalCost-diff.Baseline.TotalCost {
+		t.Errorf("cost delta = %v from %v to %v, want the index to lower the cost", diff.CostDelta, diff.Baseline.TotalCost, diff.Target.TotalCost)
+	}
+	if fmt.Sprint(diff.RemovedNodes) != "[Seq Scan on events]" {
+		t.Errorf("removed nodes = %v, want the sequential scan", diff.RemovedNodes)
+	}
+}
+
+func TestSQLOperationsOnMongoProjectReportCleanError(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "mongodb")
+
+	_, _, queryErr := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1"}, project.ID)
+	_, tablesErr := env.tables.DeleteTable(&DeleteTableRequest{Schema: "public", Table: "users"}, user.ID, project.ID)
+	_, schemaErr := env.schemas.VisualizeSchema(user.ID, project.ID, "public", false)
+	_, cellErr := env.projects.GetCellValue(user.ID, project.ID, "users", "id", "1", "name")
+
+	errs := map[string]error{"query": queryErr, "tables": tablesErr, "schema": schemaErr, "rows": cellErr}
+	for name, err := range errs {
+		if !errors.Is(err, ErrPostgresOnly) {
+			t.Errorf("%s on a mongodb project = %v, want %v", name, err, ErrPostgresOnly)
+			continue
+		}
+		if appErr := apperr.From(err); appErr.Status != http.StatusBadRequest || appErr.Code != apperr.CodeUnsupportedDBType {
+			t.Errorf("%s on a mongodb project = %d %s, want 400 %s", name, appErr.Status, appErr.Code, apperr.CodeUnsupportedDBType)
+		}
+	}
+}
+
+func TestQueryResultReportsColumnTypes(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: `
+		SELECT 1::int4 AS id, 'x'::text AS name, 1.5::numeric AS price, true AS active,
+			DATE '2024-01-02' AS day, TIMESTAMPTZ '2024-01-02 03:04:05+00' AS at,
+			'{"a": 1}'::jsonb AS doc, '\x00ff'::bytea AS blob`}, project.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("ExecuteQuery = %+v, %v", result, err)
+	}
+
+	want := []ResultColumnType{
+		{Name: "id", Type: "INT4"},
+		{Name: "name", Type: "TEXT"},
+		{Name: "price", Type: "NUMERIC"},
+		{Name: "active", Type: "BOOL"},
+		{Name: "day", Type: "DATE"},
+		{Name: "at", Type: "TIMESTAMPTZ"},
+		{Name: "doc", Type: "JSONB"},
+		{Name: "blob", Type: "BYTEA", Encoding: "base64"},
+	}
+	if fmt.Sprint(result.ColumnTypes) != fmt.Sprint(want) {
+		t.Errorf("column types = %v, want %v", result.ColumnTypes, want)
+	}
+	// bytea is base64 encoded, text is not
+	if blob, name := result.Rows[0]["blob"], result.Rows[0]["name"]; blob != "AP8=" || name != "x" {
+		t.Errorf("blob, name = %v, %v, want AP8=, x", blob, name)
+	}
+}
+
+func TestQueryTimeoutDependsOnTier(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+
+	defaults := queryTimeouts
+	queryTimeouts = map[string]time.Duration{"free": 500 * time.Millisecond, "premium": 10 * time.Second}
+	t.Cleanup(func() { queryTimeouts = defaults })
+
+	sleep := &ExecuteQueryRequest{Query: "SELECT pg_sleep(2)"}
+	for tier, cutOff := range map[string]bool{"free": true, "premium": false} {
+		project, err := env.projects.CreateProject(context.Background(), user.ID.String(), CreateProjectRequest{
+			Name:         tier,
+			DBType:       "postgres",
+			ResourceTier: tier,
+		})
+		if err != nil {
+			t.Fatalf("CreateProject(%s): %v", tier, err)
+		}
+
+		result, _, err := env.queries.ExecuteQuery(user.ID, sleep, project.ID)
+		if err != nil {
+			t.Fatalf("ExecuteQuery(%s): %v", tier, err)
+		}
+		if want := queryTimeouts[tier].Milliseconds(); result.TimeoutMs != want {
+			t.Errorf("%s timeout = %dms, want %dms", tier, result.TimeoutMs, want)
+		}
+		if got := strings.Contains(result.Error, "time limit"); got != cutOff {
+			t.Errorf("%s query after %dms: error %q, want cut off %v", tier, result.ExecutionTime, result.Error, cutOff)
+		}
+	}
+}
+
+func TestQueryTimeoutForTier(t *testing.T) {
+	if free, premium := queryTimeoutForTier("free"), queryTimeoutForTier("premium"); free >= premium {
+		t.Errorf("free timeout %s is not shorter than premium %s", free, premium)
+	}
+	if got := queryTimeoutForTier("unknown"); got != queryTimeoutForTier("free") {
+		t.Errorf("unknown tier timeout = %s, want the free tier's", got)
+	}
+
+	// QUERY_TIMEOUT_MS overrides the tiers
+	s := &QueryService{timeout: time.Second}
+	if got := s.queryTimeout(&models.Project{ResourceTier: "premium"}); got != time.Second {
+		t.Errorf("configured timeout = %s, want 1s", got)
+	}
+}
+
+func TestFormatTimeValue(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	at := time.Date(2024, 3, 10, 12, 34, 56, 500000000, berlin)
+
+	cases := map[string]string{
+		"TIMESTAMPTZ": "2024-03-10T10:34:56.5Z",
+		"TIMESTAMP":   "2024-03-10T12:34:56.5",
+		"DATE":        "2024-03-10",
+		"TIME":        "12:34:56.5",
+		"TIMETZ":      "12:34:56.5+02:00",
+	}
+	for dbType, want := range cases {
+		if got := formatTimeValue(at, dbType); got != want {
+			t.Errorf("formatTimeValue(%s) = %q, want %q", dbType, got, want)
+		}
+	}
+
+	// The same instant formats identically whatever zone the driver returns it in
+	if a, b := formatTimeValue(at, "TIMESTAMPTZ"), formatTimeValue(at.In(time.UTC), "TIMESTAMPTZ"); a != b {
+		t.Errorf("timestamptz formatting depends on the zone: %q and %q", a, b)
+	}
+	if got := formatTimeValue(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), "TIMESTAMP"); got != "2024-03-10T12:00:00" {
+		t.Errorf("whole-second timestamp = %q", got)
+	}
+}
+
+func TestQueryFormatsTimestampColumns(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE events (id integer PRIMARY KEY, local_at timestamp, at timestamptz)`,
+		`INSERT INTO events VALUES (1, '2024-03-10 12:34:56.123456', '2024-03-10 12:34:56.5+02'), (2, '2024-03-10 00:00:00', '2024-03-10 00:00:00-05')`,
+	)
+
+	result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT local_at, at FROM events ORDER BY id"}, project.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("ExecuteQuery = %+v, %v", result, err)
+	}
+
+	want := []map[string]interface{}{
+		{"local_at": "2024-03-10T12:34:56.123456", "at": "2024-03-10T10:34:56.5Z"},
+		{"local_at": "2024-03-10T00:00:00", "at": "2024-03-10T05:00:00Z"},
+	}
+	if fmt.Sprint(result.Rows) != fmt.Sprint(want) {
+		t.Errorf("rows = %v, want %v", result.Rows, want)
+	}
+}
+
+func TestCancelRunningQuery(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	executionID := uuid.New()
+	type outcome struct {
+		result *QueryResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT pg_sleep(60)", ExecutionID: &executionID}, project.ID)
+		done <- outcome{result, err}
+	}()
+
+	// Wait until the query is registered and has its backend PID
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		env.queries.runningMu.Lock()
+		running := env.queries.running[executionID]
+		started := running != nil && running.pid != 0
+		env.queries.runningMu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("query never started")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Only the owner of the project can cancel it
+	other := env.createUser(t)
+	if err := env.queries.CancelQuery(other.ID, project.ID, executionID); !errors.Is(err, ErrQueryNotRunning) {
+		t.Errorf("CancelQuery by another user = %v, want ErrQueryNotRunning", err)
+	}
+	if err := env.queries.CancelQuery(user.ID, project.ID, executionID); err != nil {
+		t.Fatalf("CancelQuery: %v", err)
+	}
+
+	var got outcome
+	select {
+	case got = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("cancelled query did not return")
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("cancelled query returned after %s", elapsed)
+	}
+	if got.err != nil {
+		t.Fatalf("ExecuteQuery: %v", got.err)
+	}
+	if got.result.Error != "query cancelled by the client" {
+		t.Errorf("result error = %q, want the query reported as cancelled", got.result.Error)
+	}
+
+	stored, err := env.history.GetByID(executionID, user.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("history GetByID = %v, %v", stored, err)
+	}
+	if stored.Success == nil || *stored.Success || stored.ErrorMessage == nil || *stored.ErrorMessage != "query cancelled by the client" {
+		t.Errorf("history = %+v, want a failed execution recorded as cancelled", stored)
+	}
+
+	// The registry entry is gone once the query returned
+	if err := env.queries.CancelQuery(user.ID, project.ID, executionID); !errors.Is(err, ErrQueryNotRunning) {
+		t.Errorf("CancelQuery after completion = %v, want ErrQueryNotRunning", err)
+	}
+}
+
+func TestExecutionIDCannotBeReused(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	executionID := uuid.New()
+	if _, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1", ExecutionID: &executionID}, project.ID); err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+
+	// The first query has finished, but its history entry still owns the ID
+	_, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 2", ExecutionID: &executionID}, project.ID)
+	if !errors.Is(err, ErrExecutionIDInUse) {
+		t.Fatalf("reusing a finished execution ID = %v, want ErrExecutionIDInUse", err)
+	}
+	if appErr := apperr.From(err); appErr.Status != http.StatusConflict || appErr.Code != apperr.CodeExecutionIDInUse {
+		t.Errorf("reusing a finished execution ID = %d %s, want 409 %s", appErr.Status, appErr.Code, apperr.CodeExecutionIDInUse)
+	}
+	stored, err := env.history.GetByID(executionID, user.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("history GetByID = %v, %v", stored, err)
+	}
+	if stored.QueryText != "SELECT 1" {
+		t.Errorf("history entry = %+v, want the first query kept", stored)
+	}
+
+	nilID := uuid.Nil
+	_, _, err = env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 1", ExecutionID: &nilID}, project.ID)
+	if !errors.Is(err, ErrInvalidExecutionID) {
+		t.Fatalf("nil execution ID = %v, want ErrInvalidExecutionID", err)
+	}
+	if appErr := apperr.From(err); appErr.Status != http.StatusBadRequest || appErr.Code != apperr.CodeInvalidExecutionID {
+		t.Errorf("nil execution ID = %d %s, want 400 %s", appErr.Status, appErr.Code, apperr.CodeInvalidExecutionID)
+	}
+}
+
+func TestHistoryQueryText(t *testing.T) {
+	query := "SELECT * FROM patients WHERE ssn = '123-45-6789'"
+
+	if got := historyQueryText(&models.Project{}, query); got != query {
+		t.Errorf("historyQueryText = %q, want the query as is", got)
+	}
+
+	project := &models.Project{RedactQueryText: true}
+	redacted := historyQueryText(project, query)
+	if !strings.HasPrefix(redacted, redactedQueryPrefix) || strings.Contains(redacted, "123-45-6789") {
+		t.Errorf("historyQueryText = %q, want a redacted digest", redacted)
+	}
+	if again := historyQueryText(project, query); again != redacted {
+		t.Errorf("historyQueryText is not stable: %q then %q", redacted, again)
+	}
+	if other := historyQueryText(project, query+" "); other == redacted {
+		t.Errorf("different queries share the redacted text %q", redacted)
+	}
+}
+
+func TestRedactedProjectStoresNoQueryText(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE patients (id integer, ssn text)`)
+
+	redact := true
+	if _, err := env.projects.UpdateProject(user.ID, project.ID, UpdateProjectRequest{RedactQueryText: &redact}); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+
+	query := "INSERT INTO patients VALUES (1, '123-45-6789'), (2, '987-65-4321')"
+	result, exec, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: query}, project.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("ExecuteQuery = %+v, %v", result, err)
+	}
+
+	stored, err := env.history.GetByID(exec.ID, user.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("history GetByID = %v, %v", stored, err)
+	}
+	if stored.QueryText != historyQueryText(&models.Project{RedactQueryText: true}, query) || strings.Contains(stored.QueryText, "123-45") {
+		t.Errorf("stored query text = %q, want only its digest", stored.QueryText)
+	}
+	if stored.Success == nil || !*stored.Success || stored.RowsAffected == nil || *stored.RowsAffected != 2 ||
+		stored.ExecutionTimeMs == nil || stored.StatementType == nil || *stored.StatementType != "DML" {
+		t.Errorf("history = %+v, want the execution metadata of a successful DML statement of 2 rows", stored)
+	}
+
+	// Failed queries are redacted too
+	failing := "SELECT ssn FROM patients WHERE ssn = '123-45-6789' AND nope"
+	if _, exec, err = env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: failing}, project.ID); err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+	if stored, err = env.history.GetByID(exec.ID, user.ID); err != nil || stored == nil {
+		t.Fatalf("history GetByID = %v, %v", stored, err)
+	}
+	if strings.Contains(stored.QueryText, "123-45") || stored.Success == nil || *stored.Success || stored.ErrorMessage == nil {
+		t.Errorf("history = %+v, want a redacted failed execution", stored)
+	}
+}
+
+func TestStatementType(t *testing.T) {
+	cases := map[string]string{
+		"SELECT 1":                             StatementTypeSelect,
+		"  explain select * from t":            StatementTypeSelect,
+		"(SELECT 1)":                           StatementTypeSelect,
+		"WITH x AS (SELECT 1) SELECT * FROM x": StatementTypeSelect,
+		"WITH d AS (DELETE FROM t RETURNING *) TABLE d": StatementTypeDML,
+		"insert into t values (1)":                      StatementTypeDML,
+		"-- note\nUPDATE t SET a = 1":                   StatementTypeDML,
+		"CREATE TABLE t (id int)":                       StatementTypeDDL,
+		"truncate t":                                    StatementTypeDDL,
+		"SELECT 'drop table t'":                         StatementTypeSelect,
+		"VACUUM t":                                      StatementTypeOther,
+		"":                                              StatementTypeOther,
+	}
+	for query, want := range cases {
+		if got := statementType(query); got != want {
+			t.Errorf("statementType(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT 1":                                       true,
+		"  select * from t":                              true,
+		"WITH x AS (SELECT 1) SELECT * FROM x":           true,
+		"INSERT INTO t VALUES (1) RETURNING id":          true,
+		"update t set a = 1 returning *":                 true,
+		"DELETE FROM t WHERE id = 1 RETURNING id, name":  true,
+		"INSERT INTO t VALUES (1)":                       false,
+		"INSERT INTO t (note) VALUES ('returning soon')": false,
+		"UPDATE t SET a = 1 -- returning\nWHERE id = 2":  false,
+		"CREATE TABLE returning_rows (id int)":           false,
+		"":                                               false,
+	}
+	for query, want := range cases {
+		if got := returnsRows(query); got != want {
+			t.Errorf("returnsRows(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestIsReturningDML(t *testing.T) {
+	for _, query := range []string{"INSERT INTO t VALUES (1) RETURNING id", "(UPDATE t SET a = 1 RETURNING a)", "delete from t returning *"} {
+		if !isReturningDML(query) {
+			t.Errorf("isReturningDML(%q) = false", query)
+		}
+	}
+	for _, query := range []string{"SELECT 1", "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", "EXPLAIN SELECT 1"} {
+		if isReturningDML(query) {
+			t.Errorf("isReturningDML(%q) = true", query)
+		}
+	}
+}
+
+func TestInsertReturningReportsRowsAndAffectedCount(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, "CREATE TABLE items (id serial PRIMARY KEY, name text NOT NULL)")
+
+	run := func(query string) *QueryResult {
+		t.Helper()
+		result, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: query}, project.ID)
+		if err != nil || result.Error != "" {
+			t.Fatalf("ExecuteQuery(%q) = %+v, %v", query, result, err)
+		}
+		return result
+	}
+
+	inserted := run("INSERT INTO items (name) VALUES ('a'), ('b'), ('c') RETURNING id, name")
+	if inserted.RowCount != 3 || len(inserted.Rows) != 3 || inserted.RowsAffected != 3 {
+		t.Fatalf("INSERT RETURNING = %d rows (%d listed), %d affected, want 3 of each", inserted.RowCount, len(inserted.Rows), inserted.RowsAffected)
+	}
+	if len(inserted.Columns) != 2 || inserted.Columns[0] != "id" || inserted.Columns[1] != "name" {
+		t.Errorf("columns = %v, want [id name]", inserted.Columns)
+	}
+	if got := fmt.Sprint(inserted.Rows[1]["name"]); got != "b" {
+		t.Errorf("second returned name = %q, want b", got)
+	}
+
+	if deleted := run("DELETE FROM items WHERE name <> 'a' RETURNING id"); deleted.RowCount != 2 || deleted.RowsAffected != 2 {
+		t.Errorf("DELETE RETURNING = %d rows, %d affected, want 2 of each", deleted.RowCount, deleted.RowsAffected)
+	}
+	if updated := run("UPDATE items SET name = 'z'"); updated.RowsAffected != 1 || len(updated.Rows) != 0 {
+		t.Errorf("UPDATE = %d rows, %d affected, want no rows and 1 affected", len(updated.Rows), updated.RowsAffected)
+	}
+	if selected := run("SELECT * FROM items"); selected.RowCount != 1 || selected.RowsAffected != 0 {
+		t.Errorf("SELECT = %d rows, %d affected, want 1 row and no affected count", selected.RowCount, selected.RowsAffected)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Default quotas for users without an admin override
+const (
+	defaultMaxProjects  = 10
+	defaultMaxStorageGB = 100
+)
+
+// maxQuotaFlags caps how many custom flags a quota override can carry
+const maxQuotaFlags = 20
+
+var quotaFlagPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ErrQuotaExceeded is returned when an operation would take a user over their quota
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ErrTierLimitReached is returned when a user already has as many active instances on a
+// resource tier as the tier allows. It wraps ErrQuotaExceeded.
+var ErrTierLimitReached = fmt.Errorf("%w: resource tier limit reached", ErrQuotaExceeded)
+
+type QuotaService struct {
+	quotaRepo    *repositories.UserQuotaRepository
+	userRepo     *repositories.UserRepository
+	projectRepo  *repositories.ProjectRepository
+	instanceRepo *repositories.DatabaseInstanceRepository
+
+	// tierLimits caps the active database instances of a user per resource tier
+	tierLimits map[string]int
+}
+
+func NewQuotaService(
+	quotaRepo *repositories.UserQuotaRepository,
+	userRepo *repositories.UserRepository,
+	projectRepo *repositories.ProjectRepository,
+	instanceRepo *repositories.DatabaseInstanceRepository,
+	tierLimits map[string]int,
+) *QuotaService {
+	return &QuotaService{
+		quotaRepo:    quotaRepo,
+		userRepo:     userRepo,
+		projectRepo:  projectRepo,
+		instanceRepo: instanceRepo,
+		tierLimits:   tierLimits,
+	}
+}
+
+// SetUserQuotaRequest replaces a user's quota override. A null limit falls back to the default.
+type SetUserQuotaRequest struct {
+	MaxProjects  *int     `json:"max_projects" binding:"omitempty,min=0"`
+	MaxStorageGB *int     `json:"max_storage_gb" binding:"omitempty,min=0"`
+	Flags        []string `json:"flags"`
+}
+
+// GetEffectiveQuota returns the quota that applies to a user, preferring an admin override over the defaults
+func (s *QuotaService) GetEffectiveQuota(userID uuid.UUID) (*models.EffectiveQuota, error) {
+	quota := &models.EffectiveQuota{
+		MaxProjects:  defaultMaxProjects,
+		MaxStorageGB: defaultMaxStorageGB,
+		Flags:        []string{},
+	}
+
+	override, err := s.quotaRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user quota: %w", err)
+	}
+	if override == nil {
+		return quota, nil
+	}
+
+	quota.Custom = true
+	if override.MaxProjects != nil {
+		quota.MaxProjects = *override.MaxProjects
+	}
+	if override.MaxStorageGB != nil {
+		quota.MaxStorageGB = *override.MaxStorageGB
+	}
+	if override.Flags != nil {
+		quota.Flags = override.Flags
+	}
+
+	return quota, nil
+}
+
+// SetUserQuota stores an admin override of a user's quotas and returns the resulting effective quota
+func (s *QuotaService) SetUserQuota(userID uuid.UUID, adminID uuid.UUID, req SetUserQuotaRequest) (*models.EffectiveQuota, error) {
+	user, err := s.userRepo.FindUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	flags, err := normalizeQuotaFlags(req.Flags)
+	if err != nil {
+		return nil, err
+	}
+
+	quota := &models.UserQuota{
+		UserID:       userID,
+		MaxProjects:  req.MaxProjects,
+		MaxStorageGB: req.MaxStorageGB,
+		Flags:        flags,
+		UpdatedBy:    &adminID,
+	}
+	if err := s.quotaRepo.Upsert(quota); err != nil {
+		return nil, fmt.Errorf("failed to save user quota: %w", err)
+	}
+
+	return s.GetEffectiveQuota(userID)
+}
+
+// GetUsage returns a user's current project and storage usage along with their effective quota
+func (s *QuotaService) GetUsage(userID uuid.UUID) (*models.UserUsage, error) {
+	quota, err := s.GetEffectiveQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepo.CountByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count projects: %w", err)
+	}
+
+	storageGB, err := s.instanceRepo.SumStorageByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum storage: %w", err)
+	}
+
+	return &models.UserUsage{
+		Projects:  projects,
+		StorageGB: storageGB,
+		Quota:     *quota,
+	}, nil
+}
+
+// CheckProjectQuota verifies the user can create another project needing storageGB of storage
+func (s *QuotaService) CheckProjectQuota(userID uuid.UUID, storageGB int) error {
+	usage, err := s.GetUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	if usage.Projects+1 > usage.Quota.MaxProjects {
+		return fmt.Errorf("%w: project limit of %d reached", ErrQuotaExceeded, usage.Quota.MaxProjects)
+	}
+	if usage.StorageGB+storageGB > usage.Quota.MaxStorageGB {
+		return fmt.Errorf("%w: storage limit of %d GB would be exceeded", ErrQuotaExceeded, usage.Quota.MaxStorageGB)
+	}
+
+	return nil
+}
+
+// CheckTierQuota verifies the user can create another database instance on a resource tier
+func (s *QuotaService) CheckTierQuota(userID uuid.UUID, tier string) error {
+	limit, ok := s.tierLimits[tier]
+	if !ok {
+		return fmt.Errorf("invalid resource_tier: %s", tier)
+	}
+
+	active, err := s.instanceRepo.CountActiveByUserIDAndTier(userID, tier)
+	if err != nil {
+		return fmt.Errorf("failed to count database instances: %w", err)
+	}
+	if active >= limit {
+		return fmt.Errorf("%w: the %s tier allows %d active database instances", ErrTierLimitReached, tier, limit)
+	}
+
+	return nil
+}
+
+// normalizeQuotaFlags validates custom quota flags, lower-casing and de-duplicating them
+func normalizeQuotaFlags(flags []string) ([]string, error) {
+	if len(flags) > maxQuotaFlags {
+		return nil, fmt.Errorf("invalid flags: at most %d flags are allowed", maxQuotaFlags)
+	}
+
+	normalized := make([]string, 0, len(flags))
+	seen := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		flag = strings.ToLower(strings.TrimSpace(flag))
+		if !quotaFlagPattern.MatchString(flag) {
+			return nil, fmt.Errorf("invalid flag %q: use lowercase letters, digits and underscores", flag)
+		}
+		if seen[flag] {
+			continue
+		}
+		seen[flag] = true
+		normalized = append(normalized, flag)
+	}
+
+	return normalized, nil
+}
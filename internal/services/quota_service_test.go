@@ -0,0 +1,74 @@
+package services
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestQuotaOverrideRaisesProjectCap(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	admin := env.createUser(t)
+
+	for i := 0; i < defaultMaxProjects; i++ {
+		project := &models.Project{UserID: user.ID, Name: fmt.Sprintf("project-%d", i), DBType: "postgres", ResourceTier: "basic"}
+		if err := env.projectRepo.Create(project); err != nil {
+			t.Fatalf("Create project: %v", err)
+		}
+	}
+	if err := env.quotas.CheckProjectQuota(user.ID, 0); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("CheckProjectQuota at the default cap = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	maxProjects := defaultMaxProjects + 2
+	quota, err := env.quotas.SetUserQuota(user.ID, admin.ID, SetUserQuotaRequest{MaxProjects: &maxProjects, Flags: []string{"Beta", "beta"}})
+	if err != nil {
+		t.Fatalf("SetUserQuota: %v", err)
+	}
+	if !quota.Custom || quota.MaxProjects != maxProjects || quota.MaxStorageGB != defaultMaxStorageGB || fmt.Sprint(quota.Flags) != "[beta]" {
+		t.Errorf("effective quota = %+v, want %d projects over the default storage", quota, maxProjects)
+	}
+	if err := env.quotas.CheckProjectQuota(user.ID, 0); err != nil {
+		t.Errorf("CheckProjectQuota with the override = %v, want nil", err)
+	}
+
+	usage, err := env.quotas.GetUsage(user.ID)
+	if err != nil {
+		t.Fatalf("GetUsage: %v", err)
+	}
+	if usage.Projects != defaultMaxProjects || usage.Quota.MaxProjects != maxProjects {
+		t.Errorf("usage = %+v, want %d of %d projects", usage, defaultMaxProjects, maxProjects)
+	}
+
+	// Other users keep the default
+	if quota, err := env.quotas.GetEffectiveQuota(admin.ID); err != nil || quota.Custom || quota.MaxProjects != defaultMaxProjects {
+		t.Errorf("quota of another user = %+v, %v, want the default", quota, err)
+	}
+}
+
+func TestCheckTierQuotaCountsActiveInstances(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	inst := env.instance(t, env.createProject(t, user, "postgres"))
+	quotas := NewQuotaService(env.userQuotas, env.users, env.projectRepo, env.instances, map[string]int{"free": 1, "basic": 1})
+
+	if err := quotas.CheckTierQuota(user.ID, "basic"); !errors.Is(err, ErrTierLimitReached) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckTierQuota with a running basic instance = %v, want %v", err, ErrTierLimitReached)
+	}
+	if err := quotas.CheckTierQuota(user.ID, "free"); err != nil {
+		t.Errorf("CheckTierQuota on another tier = %v, want nil", err)
+	}
+	if err := quotas.CheckTierQuota(user.ID, "premium"); err == nil || errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("CheckTierQuota on a tier without a limit = %v, want an invalid tier error", err)
+	}
+
+	// Failed instances no longer count
+	if err := env.instances.UpdateStatus(inst.ID, "failed"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if err := quotas.CheckTierQuota(user.ID, "basic"); err != nil {
+		t.Errorf("CheckTierQuota after the instance failed = %v, want nil", err)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SaveQueryRequest represents the request body for saving a query under a name
+type SaveQueryRequest struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Query string `json:"query" binding:"required"`
+}
+
+// RenameSavedQueryRequest represents the request body for renaming a saved query
+type RenameSavedQueryRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// SaveQuery saves a query of the user's project under a name that is unique among the
+// user's saved queries of the project
+func (s *QueryService) SaveQuery(userID uuid.UUID, projectID uuid.UUID, req SaveQueryRequest) (*models.SavedQuery, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "name cannot be empty")
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "query cannot be empty")
+	}
+	if err := s.requireProject(userID, projectID); err != nil {
+		return nil, err
+	}
+	if err := s.requireFreeSavedQueryName(userID, projectID, name); err != nil {
+		return nil, err
+	}
+
+	savedQuery := &models.SavedQuery{
+		UserID:    userID,
+		ProjectID: projectID,
+		Name:      name,
+		QueryText: req.Query,
+	}
+	if err := s.savedRepo.Create(savedQuery); err != nil {
+		return nil, apperr.Internal(err, "failed to save query")
+	}
+	return savedQuery, nil
+}
+
+// ListSavedQueries returns the user's saved queries of a project, sorted by name
+func (s *QueryService) ListSavedQueries(userID uuid.UUID, projectID uuid.UUID) ([]models.SavedQuery, error) {
+	if err := s.requireProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	savedQueries, err := s.savedRepo.GetByUserAndProject(userID, projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get saved queries")
+	}
+	return savedQueries, nil
+}
+
+// RenameSavedQuery gives one of the user's saved queries of a project a new name
+func (s *QueryService) RenameSavedQuery(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID, req RenameSavedQueryRequest) (*models.SavedQuery, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "name cannot be empty")
+	}
+	savedQuery, err := s.getSavedQuery(userID, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if savedQuery.Name == name {
+		return savedQuery, nil
+	}
+	if err := s.requireFreeSavedQueryName(userID, projectID, name); err != nil {
+		return nil, err
+	}
+
+	renamed, err := s.savedRepo.Rename(id, userID, projectID, name)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to rename saved query")
+	}
+	if !renamed {
+		// Deleted since it was read
+		return nil, apperr.NotFound(apperr.CodeSavedQueryNotFound, "saved query not found")
+	}
+	savedQuery.Name = name
+	return savedQuery, nil
+}
+
+// DeleteSavedQuery deletes one of the user's saved queries of a project
+func (s *QueryService) DeleteSavedQuery(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID) error {
+	if err := s.requireProject(userID, projectID); err != nil {
+		return err
+	}
+
+	deleted, err := s.savedRepo.Delete(id, userID, projectID)
+	if err != nil {
+		return apperr.Internal(err, "failed to delete saved query")
+	}
+	if !deleted {
+		return apperr.NotFound(apperr.CodeSavedQueryNotFound, "saved query not found")
+	}
+	return nil
+}
+
+// ExecuteSavedQuery runs one of the user's saved queries of a project through
+// ExecuteQuery, so it is validated and recorded in the history like any other query
+func (s *QueryService) ExecuteSavedQuery(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID) (*QueryResult, *models.QueryHistory, error) {
+	savedQuery, err := s.getSavedQuery(userID, projectID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.ExecuteQuery(userID, &ExecuteQueryRequest{Query: savedQuery.QueryText}, projectID)
+}
+
+// getSavedQuery loads one of the user's saved queries of a project
+func (s *QueryService) getSavedQuery(userID uuid.UUID, projectID uuid.UUID, id uuid.UUID) (*models.SavedQuery, error) {
+	if err := s.requireProject(userID, projectID); err != nil {
+		return nil, err
+	}
+
+	savedQuery, err := s.savedRepo.GetByID(id, userID, projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get saved query")
+	}
+	if savedQuery == nil {
+		return nil, apperr.NotFound(apperr.CodeSavedQueryNotFound, "saved query not found")
+	}
+	return savedQuery, nil
+}
+
+// requireFreeSavedQueryName rejects a name the user already gave a query of the project
+func (s *QueryService) requireFreeSavedQueryName(userID uuid.UUID, projectID uuid.UUID, name string) error {
+	exists, err := s.savedRepo.NameExists(userID, projectID, name)
+	if err != nil {
+		return apperr.Internal(err, "failed to check saved query name")
+	}
+	if exists {
+		return apperr.Conflict(apperr.CodeSavedQueryExists, "a saved query with this name already exists")
+	}
+	return nil
+}
+
+// requireProject verifies a project exists and belongs to the user
+func (s *QueryService) requireProject(userID uuid.UUID, projectID uuid.UUID) error {
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return apperr.Internal(err, "failed to get project")
+	}
+	if project == nil {
+		return apperr.NotFound(apperr.CodeProjectNotFound, "project not found or not accessible")
+	}
+	return nil
+}
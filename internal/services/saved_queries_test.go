@@ -0,0 +1,58 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"fmt"
+	"testing"
+)
+
+func TestSavedQueryLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE tasks (id integer PRIMARY KEY, done boolean)`,
+		`INSERT INTO tasks VALUES (1, false), (2, true)`,
+	)
+
+	saved, err := env.queries.SaveQuery(user.ID, project.ID, SaveQueryRequest{Name: " open tasks ", Query: "SELECT id FROM tasks WHERE NOT done"})
+	if err != nil {
+		t.Fatalf("SaveQuery: %v", err)
+	}
+	if saved.Name != "open tasks" {
+		t.Errorf("saved name = %q, want it trimmed", saved.Name)
+	}
+	if _, err := env.queries.SaveQuery(user.ID, project.ID, SaveQueryRequest{Name: "open tasks", Query: "SELECT 1"}); apperr.From(err).Code != apperr.CodeSavedQueryExists {
+		t.Errorf("SaveQuery with a taken name = %v, want %s", err, apperr.CodeSavedQueryExists)
+	}
+
+	result, history, err := env.queries.ExecuteSavedQuery(user.ID, project.ID, saved.ID)
+	if err != nil || result.Error != "" {
+		t.Fatalf("ExecuteSavedQuery = %+v, %v", result, err)
+	}
+	if fmt.Sprint(result.Rows) != "[map[id:1]]" || history == nil || history.QueryText != saved.QueryText {
+		t.Errorf("ExecuteSavedQuery = %v, history %+v, want the open task and a history entry", result.Rows, history)
+	}
+
+	renamed, err := env.queries.RenameSavedQuery(user.ID, project.ID, saved.ID, RenameSavedQueryRequest{Name: "todo"})
+	if err != nil || renamed.Name != "todo" {
+		t.Fatalf("RenameSavedQuery = %+v, %v", renamed, err)
+	}
+	listed, err := env.queries.ListSavedQueries(user.ID, project.ID)
+	if err != nil || len(listed) != 1 || listed[0].Name != "todo" {
+		t.Errorf("ListSavedQueries = %+v, %v, want the renamed query", listed, err)
+	}
+
+	// Another user sees neither the project nor its saved queries
+	other := env.createUser(t)
+	if _, _, err := env.queries.ExecuteSavedQuery(other.ID, project.ID, saved.ID); apperr.From(err).Code != apperr.CodeProjectNotFound {
+		t.Errorf("ExecuteSavedQuery by another user = %v, want %s", err, apperr.CodeProjectNotFound)
+	}
+
+	if err := env.queries.DeleteSavedQuery(user.ID, project.ID, saved.ID); err != nil {
+		t.Fatalf("DeleteSavedQuery: %v", err)
+	}
+	if err := env.queries.DeleteSavedQuery(user.ID, project.ID, saved.ID); apperr.From(err).Code != apperr.CodeSavedQueryNotFound {
+		t.Errorf("second DeleteSavedQuery = %v, want %s", err, apperr.CodeSavedQueryNotFound)
+	}
+}
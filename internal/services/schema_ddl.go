@@ -0,0 +1,274 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// serialDefault matches the default of a serial column, which draws from its own sequence
+var serialDefault = regexp.MustCompile(`^nextval\('[^']+'::regclass\)$`)
+
+// serialTypes maps integer types to the serial type creating the same column and sequence
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+// GetTableDDL reconstructs the CREATE TABLE statement of a table from the catalog: its
+// columns, primary key, unique, check and foreign key constraints, followed by the
+// CREATE INDEX statements of the indexes that do not back a constraint.
+func (s *SchemaService) GetTableDDL(userID uuid.UUID, projectID uuid.UUID, schema string, table string) (string, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return "", fmt.Errorf("invalid schema name: %w", err)
+	}
+	if err := validateIdentifier(table); err != nil {
+		return "", fmt.Errorf("invalid table name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exists, err := schemaRepo.TableExists(ctx, schema, table)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.New("table not found")
+	}
+
+	introspection, err := introspectSchema(ctx, schemaRepo, schema)
+	if err != nil {
+		return "", err
+	}
+	for i := range introspection.Tables {
+		if t := &introspection.Tables[i]; t.Name == table {
+			return tableDDL(schema, t), nil
+		}
+	}
+
+	// Dropped between the two queries
+	return "", errors.New("table not found")
+}
+
+// ExportSchemaSQL builds a script recreating every table of a schema. Tables are created
+// after the tables their foreign keys reference. When foreign keys form a cycle, the
+// first remaining table in alphabetical order is created without the foreign keys to
+// tables that do not exist yet, and those are added by ALTER TABLE at the end.
+func (s *SchemaService) ExportSchemaSQL(userID uuid.UUID, projectID uuid.UUID, schema string) (string, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return "", fmt.Errorf("invalid schema name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return "", err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	introspection, err := introspectSchema(ctx, schemaRepo, schema)
+	if err != nil {
+		return "", err
+	}
+	return schemaScript(schema, introspection.Tables), nil
+}
+
+// schemaScript orders the tables of a schema by their foreign keys and concatenates their
+// statements, followed by the foreign keys deferred to break cycles
+func schemaScript(schema string, tables []models.IntrospectedTable) string {
+	pending := make([]*models.IntrospectedTable, len(tables))
+	for i := range tables {
+		pending[i] = &tables[i]
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Name < pending[j].Name })
+
+	statements := []string{fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", pq.QuoteIdentifier(schema))}
+	var deferred []string
+	created := make(map[string]bool, len(tables))
+	for len(pending) > 0 {
+		// Without a table whose references all exist, the first one breaks the cycle
+		next := 0
+		for i, table := range pending {
+			if len(missingReferences(schema, table, created)) == 0 {
+				next = i
+				break
+			}
+		}
+		table := pending[next]
+		pending = append(pending[:next], pending[next+1:]...)
+
+		missing := missingReferences(schema, table, created)
+		var inline []models.IntrospectedForeignKey
+		for _, fk := range table.ForeignKeys {
+			if missing[fk.Name] {
+				deferred = append(deferred, fmt.Sprintf("ALTER TABLE %s.%s ADD %s;",
+					pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table.Name), foreignKeyConstraint(fk)))
+				continue
+			}
+			inline = append(inline, fk)
+		}
+
+		statements = append(statements, createTableStatement(schema, table, inline))
+		statements = append(statements, indexStatements(table)...)
+		created[table.Name] = true
+	}
+
+	statements = append(statements, deferred...)
+	return strings.Join(statements, "\n\n") + "\n"
+}
+
+// missingReferences returns the names of a table's foreign keys referencing another table
+// of the schema that is not created yet. References to other schemas are assumed to exist.
+func missingReferences(schema string, table *models.IntrospectedTable, created map[string]bool) map[string]bool {
+	missing := make(map[string]bool)
+	for _, fk := range table.ForeignKeys {
+		if fk.ReferencedSchema == schema && fk.ReferencedTable != table.Name && !created[fk.ReferencedTable] {
+			missing[fk.Name] = true
+		}
+	}
+	return missing
+}
+
+// tableDDL builds the CREATE TABLE statement of a table, with its foreign keys inline,
+// and the CREATE INDEX statements of its other indexes
+func tableDDL(schema string, table *models.IntrospectedTable) string {
+	statements := []string{createTableStatement(schema, table, table.ForeignKeys)}
+	statements = append(statements, indexStatements(table)...)
+	return strings.Join(statements, "\n\n") + "\n"
+}
+
+// createTableStatement builds the CREATE TABLE statement of a table with the given foreign keys
+func createTableStatement(schema string, table *models.IntrospectedTable, foreignKeys []models.IntrospectedForeignKey) string {
+	var definitions []string
+	for _, col := range table.Columns {
+		definitions = append(definitions, "    "+columnDefinition(col))
+	}
+
+	if len(table.PrimaryKey) > 0 {
+		definitions = append(definitions, fmt.Sprintf("    CONSTRAINT %s PRIMARY KEY (%s)",
+			pq.QuoteIdentifier(primaryKeyName(table)), quoteIdentifiers(table.PrimaryKey)))
+	}
+	for _, unique := range table.UniqueConstraints {
+		definitions = append(definitions, fmt.Sprintf("    CONSTRAINT %s UNIQUE (%s)",
+			pq.QuoteIdentifier(unique.Name), quoteIdentifiers(unique.Columns)))
+	}
+	for _, check := range table.Checks {
+		definitions = append(definitions, fmt.Sprintf("    CONSTRAINT %s %s", pq.QuoteIdentifier(check.Name), check.Definition))
+	}
+	for _, fk := range foreignKeys {
+		definitions = append(definitions, "    "+foreignKeyConstraint(fk))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s.%s (\n%s\n);",
+		pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table.Name), strings.Join(definitions, ",\n"))
+}
+
+// columnDefinition builds the definition of a column. Serial columns are written as
+// serial types, so the statement creates their sequence rather than referencing it.
+func columnDefinition(col models.IntrospectedColumn) string {
+	dataType := col.DataType
+	var defaultExpr *string
+	if col.Default != nil {
+		if serial, ok := serialTypes[dataType]; ok && serialDefault.MatchString(*col.Default) {
+			dataType = serial
+		} else {
+			defaultExpr = col.Default
+		}
+	}
+
+	def := pq.QuoteIdentifier(col.Name) + " " + dataType
+	if col.Identity != nil {
+		def += " GENERATED " + *col.Identity + " AS IDENTITY"
+	}
+	if defaultExpr != nil {
+		def += " DEFAULT " + *defaultExpr
+	}
+	if !col.Nullable {
+		def += " NOT NULL"
+	}
+	return def
+}
+
+// foreignKeyConstraint builds the named constraint definition of a foreign key.
+// NO ACTION is the default and left out.
+func foreignKeyConstraint(fk models.IntrospectedForeignKey) string {
+	def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s.%s (%s)",
+		pq.QuoteIdentifier(fk.Name),
+		quoteIdentifiers(fk.Columns),
+		pq.QuoteIdentifier(fk.ReferencedSchema),
+		pq.QuoteIdentifier(fk.ReferencedTable),
+		quoteIdentifiers(fk.ReferencedColumns),
+	)
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		def += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		def += " ON DELETE " + fk.OnDelete
+	}
+	return def
+}
+
+// indexStatements returns the CREATE INDEX statements of a table's indexes, leaving out
+// those created by its primary key and unique constraints
+func indexStatements(table *models.IntrospectedTable) []string {
+	constraintIndexes := make(map[string]bool)
+	for _, unique := range table.UniqueConstraints {
+		constraintIndexes[unique.Name] = true
+	}
+
+	var statements []string
+	for _, idx := range table.Indexes {
+		if idx.Primary || constraintIndexes[idx.Name] {
+			continue
+		}
+		statements = append(statements, idx.Definition+";")
+	}
+	return statements
+}
+
+// primaryKeyName returns the name of the index backing a table's primary key, which is
+// also the name of the constraint, or PostgreSQL's default name
+func primaryKeyName(table *models.IntrospectedTable) string {
+	for _, idx := range table.Indexes {
+		if idx.Primary {
+			return idx.Name
+		}
+	}
+	return table.Name + "_pkey"
+}
+
+// quoteIdentifiers quotes and joins a list of column names
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = pq.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"backend/internal/models"
+	"backend/internal/repositories"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DiffSchemas compares a schema of two projects of the user and returns the changes that
+// turn projectA's schema into projectB's: tables and columns only found in projectB are
+// added, those only found in projectA removed. Types are compared as reported by
+// information_schema, so a change of length or precision alone is not detected.
+func (s *SchemaService) DiffSchemas(userID uuid.UUID, projectA uuid.UUID, projectB uuid.UUID, schema string) (*models.SchemaDiff, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %w", err)
+	}
+
+	tablesA, err := s.diffSideTables(userID, projectA, schema)
+	if err != nil {
+		return nil, err
+	}
+	tablesB, err := s.diffSideTables(userID, projectB, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffTables(tablesA, tablesB)
+	diff.Schema = schema
+	diff.Summary = diffSummary(diff)
+	return diff, nil
+}
+
+// diffSideTables parses the tables of one side of a diff
+func (s *SchemaService) diffSideTables(userID uuid.UUID, projectID uuid.UUID, schema string) ([]models.Table, error) {
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tables, err := parseTables(ctx, schemaRepo, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tables: %w", err)
+	}
+	return tables, nil
+}
+
+// diffTables compares two lists of tables. Tables and columns are reported in the order
+// of the side they are found on, so the output is stable.
+func diffTables(tablesA []models.Table, tablesB []models.Table) *models.SchemaDiff {
+	// Slices are never nil so every key is present in the JSON output
+	diff := &models.SchemaDiff{
+		AddedTables:    []string{},
+		RemovedTables:  []string{},
+		AddedColumns:   []models.ColumnDiff{},
+		RemovedColumns: []models.ColumnDiff{},
+		TypeChanges:    []models.ColumnTypeChange{},
+	}
+
+	byNameB := make(map[string]*models.Table, len(tablesB))
+	for i := range tablesB {
+		byNameB[tablesB[i].Name] = &tablesB[i]
+	}
+	byNameA := make(map[string]bool, len(tablesA))
+
+	for _, tableA := range tablesA {
+		byNameA[tableA.Name] = true
+		tableB, ok := byNameB[tableA.Name]
+		if !ok {
+			diff.RemovedTables = append(diff.RemovedTables, tableA.Name)
+			continue
+		}
+
+		columnsB := make(map[string]models.Column, len(tableB.Columns))
+		for _, col := range tableB.Columns {
+			columnsB[col.Name] = col
+		}
+		columnsA := make(map[string]bool, len(tableA.Columns))
+		for _, colA := range tableA.Columns {
+			columnsA[colA.Name] = true
+			colB, ok := columnsB[colA.Name]
+			if !ok {
+				diff.RemovedColumns = append(diff.RemovedColumns, models.ColumnDiff{
+					Table: tableA.Name, Column: colA.Name, DataType: colA.DataType,
+				})
+				continue
+			}
+			if colA.DataType != colB.DataType {
+				diff.TypeChanges = append(diff.TypeChanges, models.ColumnTypeChange{
+					Table: tableA.Name, Column: colA.Name, From: colA.DataType, To: colB.DataType,
+				})
+			}
+		}
+		for _, colB := range tableB.Columns {
+			if !columnsA[colB.Name] {
+				diff.AddedColumns = append(diff.AddedColumns, models.ColumnDiff{
+					Table: tableB.Name, Column: colB.Name, DataType: colB.DataType,
+				})
+			}
+		}
+	}
+
+	for _, tableB := range tablesB {
+		if !byNameA[tableB.Name] {
+			diff.AddedTables = append(diff.AddedTables, tableB.Name)
+		}
+	}
+
+	return diff
+}
+
+// diffSummary describes a diff in one line per change
+func diffSummary(diff *models.SchemaDiff) string {
+	var lines []string
+	for _, table := range diff.AddedTables {
+		lines = append(lines, fmt.Sprintf("+ table %s", table))
+	}
+	for _, table := range diff.RemovedTables {
+		lines = append(lines, fmt.Sprintf("- table %s", table))
+	}
+	for _, col := range diff.AddedColumns {
+		lines = append(lines, fmt.Sprintf("+ column %s.%s %s", col.Table, col.Column, col.DataType))
+	}
+	for _, col := range diff.RemovedColumns {
+		lines = append(lines, fmt.Sprintf("- column %s.%s %s", col.Table, col.Column, col.DataType))
+	}
+	for _, change := range diff.TypeChanges {
+		lines = append(lines, fmt.Sprintf("~ column %s.%s %s -> %s", change.Table, change.Column, change.From, change.To))
+	}
+
+	if len(lines) == 0 {
+		return "No differences"
+	}
+	return strings.Join(lines, "\n")
+}
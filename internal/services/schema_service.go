@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/lib/pq"
 )
 
 const (
@@ -20,11 +22,46 @@ const (
 	minJunctionTableFKs     = 2
 )
 
+// Thresholds for table health suggestions
+const (
+	staleAnalyzeRatio     = 0.1   // Rows modified since ANALYZE relative to live rows
+	minStaleAnalyzeMods   = 50    // Ignore tiny tables with a handful of changes
+	highDeadTupleRatio    = 0.2   // Dead rows relative to all rows
+	minDeadTuples         = 1000  // Ignore small amounts of bloat
+	highSeqScanRatio      = 0.9   // Share of scans that are sequential
+	minSeqScans           = 100   // Ignore tables that are rarely scanned
+	minRowsForIndexAdvice = 10000 // Sequential scans are fine on small tables
+)
+
+// Limits on scripts passed to ApplyDDL
+const (
+	maxDDLScriptBytes     = 1 << 20
+	maxDDLScriptStatement = 500
+	applyDDLTimeout       = 2 * time.Minute
+)
+
+// ddlForbiddenObjects are object kinds a script may not create or alter, as they reach
+// beyond the project's own schema objects
+var ddlForbiddenObjects = map[string]bool{
+	"DATABASE": true, "SYSTEM": true, "ROLE": true, "USER": true, "GROUP": true,
+	"TABLESPACE": true, "SUBSCRIPTION": true, "PUBLICATION": true, "SERVER": true,
+	"EXTENSION": true, "LANGUAGE": true, "EVENT": true,
+}
+
+// ddlModifiers are words that may sit between CREATE/ALTER and the object kind
+var ddlModifiers = map[string]bool{
+	"OR": true, "REPLACE": true, "UNIQUE": true, "TEMP": true, "TEMPORARY": true,
+	"UNLOGGED": true, "GLOBAL": true, "LOCAL": true, "MATERIALIZED": true,
+	"RECURSIVE": true, "CONSTRAINT": true, "FOREIGN": true,
+}
+
 type SchemaService struct {
 	projectRepo  *repositories.ProjectRepository
 	instanceRepo *repositories.DatabaseInstanceRepository
 	credRepo     *repositories.DatabaseCredentialRepository
-	orchestrator *OrchestratorService
+	orchestrator Orchestrator
+	ddlAudit     *DDLAuditService
+	connections  *ConnectionManager
 }
 
 // NewSchemaService creates a new SchemaService
@@ -32,91 +69,656 @@ func NewSchemaService(
 	projectRepo *repositories.ProjectRepository,
 	instanceRepo *repositories.DatabaseInstanceRepository,
 	credRepo *repositories.DatabaseCredentialRepository,
-	orchestrator *OrchestratorService,
+	orchestrator Orchestrator,
+	ddlAudit *DDLAuditService,
+	connections *ConnectionManager,
 ) *SchemaService {
 	return &SchemaService{
 		projectRepo:  projectRepo,
 		instanceRepo: instanceRepo,
 		credRepo:     credRepo,
 		orchestrator: orchestrator,
+		ddlAudit:     ddlAudit,
+		connections:  connections,
+	}
+}
+
+// VisualizeSchema parses the tables and relationships of a project's database schema, to
+// be rendered with RenderMermaid or returned as is. With withStats each table is annotated
+// with its row count, at the cost of a count per table.
+func (s *SchemaService) VisualizeSchema(userID uuid.UUID, projectID uuid.UUID, schema string, withStats bool) (*models.SchemaVisualization, error) {
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema == "" {
+		schema = "public"
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel2()
+
+	visualization, err := GenerateSchemaVisualization(ctx2, schemaRepo, schema, withStats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema visualization: %w", err)
+	}
+	return visualization, nil
+}
+
+// GetTableDependents returns the foreign keys, views and functions that reference a table,
+// so callers can warn before the table is dropped
+func (s *SchemaService) GetTableDependents(userID uuid.UUID, projectID uuid.UUID, schema string, table string) (*models.TableDependents, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %w", err)
+	}
+	if err := validateIdentifier(table); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dependents := &models.TableDependents{Schema: schema, Table: table}
+
+	dependents.ForeignKeys, err = schemaRepo.GetReferencingForeignKeys(ctx, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referencing foreign keys: %w", err)
+	}
+
+	dependents.Views, err = schemaRepo.GetDependentViews(ctx, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependent views: %w", err)
+	}
+
+	dependents.Functions, err = schemaRepo.GetDependentFunctions(ctx, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependent functions: %w", err)
+	}
+
+	return dependents, nil
+}
+
+// ListIndexes returns the indexes of a table, including partial index predicates
+func (s *SchemaService) ListIndexes(userID uuid.UUID, projectID uuid.UUID, schema string, table string) ([]models.IntrospectedIndex, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %w", err)
+	}
+	if err := validateIdentifier(table); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exists, err := schemaRepo.TableExists(ctx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("table not found")
+	}
+
+	indexes, err := schemaRepo.GetTableIndexes(ctx, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// GetTableStats returns per-table statistics for a schema with suggestions for stale
+// statistics, bloat and missing indexes
+func (s *SchemaService) GetTableStats(userID uuid.UUID, projectID uuid.UUID, schema string) ([]models.TableStats, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stats, err := schemaRepo.GetTableStats(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table statistics: %w", err)
+	}
+
+	for i := range stats {
+		summarizeTableHealth(&stats[i])
+	}
+
+	return stats, nil
+}
+
+// summarizeTableHealth fills in the derived ratios and suggestions of a table's statistics
+func summarizeTableHealth(stat *models.TableStats) {
+	stat.Suggestions = []string{}
+
+	if total := stat.LiveTuples + stat.DeadTuples; total > 0 {
+		stat.DeadTupleRatio = float64(stat.DeadTuples) / float64(total)
+	}
+	if scans := stat.SeqScans + stat.IndexScans; scans > 0 {
+		stat.SeqScanRatio = float64(stat.SeqScans) / float64(scans)
+	}
+
+	neverAnalyzed := stat.LastAnalyze == nil && stat.LastAutoanalyze == nil
+	if neverAnalyzed && stat.LiveTuples > 0 {
+		stat.Suggestions = append(stat.Suggestions, "Table has never been analyzed; consider ANALYZE")
+	} else if stat.ModsSinceAnalyze >= minStaleAnalyzeMods &&
+		float64(stat.ModsSinceAnalyze) > staleAnalyzeRatio*float64(stat.LiveTuples) {
+		stat.Suggestions = append(stat.Suggestions, "Statistics are stale after many changes; consider ANALYZE")
+	}
+
+	if stat.DeadTuples >= minDeadTuples && stat.DeadTupleRatio > highDeadTupleRatio {
+		stat.Suggestions = append(stat.Suggestions, "High share of dead rows; consider VACUUM")
+	}
+
+	if stat.SeqScans >= minSeqScans && stat.LiveTuples >= minRowsForIndexAdvice && stat.SeqScanRatio > highSeqScanRatio {
+		stat.Suggestions = append(stat.Suggestions, "Mostly sequential scans on a large table; consider adding an index")
+	}
+}
+
+// RenameSchemaRequest represents the request body for renaming a schema
+type RenameSchemaRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// isProtectedSchema reports whether a schema is public or a system schema that must not be renamed
+func isProtectedSchema(schema string) bool {
+	lower := strings.ToLower(schema)
+	return lower == "public" || lower == "information_schema" || strings.HasPrefix(lower, "pg_")
+}
+
+// RenameSchema renames a schema in the project's database
+func (s *SchemaService) RenameSchema(userID uuid.UUID, projectID uuid.UUID, oldName string, newName string) error {
+	if err := validateIdentifier(oldName); err != nil {
+		return fmt.Errorf("invalid schema name: %w", err)
+	}
+	if err := validateIdentifier(newName); err != nil {
+		return fmt.Errorf("invalid new schema name: %w", err)
+	}
+	if isProtectedSchema(oldName) {
+		return fmt.Errorf("invalid schema name: schema '%s' cannot be renamed", oldName)
+	}
+	if isProtectedSchema(newName) {
+		return fmt.Errorf("invalid new schema name: '%s' is reserved", newName)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var oldExists, newExists bool
+	err = pool.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1),
+			EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $2)
+	`, oldName, newName).Scan(&oldExists, &newExists)
+	if err != nil {
+		return fmt.Errorf("failed to check schemas: %w", err)
+	}
+	if !oldExists {
+		return fmt.Errorf("schema '%s' not found", oldName)
+	}
+	if newExists {
+		return fmt.Errorf("schema '%s' already exists", newName)
+	}
+
+	query := fmt.Sprintf("ALTER SCHEMA %s RENAME TO %s", pq.QuoteIdentifier(oldName), pq.QuoteIdentifier(newName))
+	_, err = pool.Exec(ctx, query)
+	s.ddlAudit.Record(userID, projectID, models.DDLOperationRenameSchema, oldName, query, err)
+	if err != nil {
+		return fmt.Errorf("failed to rename schema: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyDDLRequest represents the request body for applying a DDL script
+type ApplyDDLRequest struct {
+	Script string `json:"script" binding:"required"`
+}
+
+// ApplyDDLResult reports a successfully applied script
+type ApplyDDLResult struct {
+	Statements int `json:"statements"`
+}
+
+// DDLStatementError is returned when a statement of a script fails. Nothing of the
+// script has been applied.
+type DDLStatementError struct {
+	Index     int // 1-based position of the statement in the script
+	Statement string
+	Err       error
+}
+
+func (e *DDLStatementError) Error() string {
+	return fmt.Sprintf("statement %d failed: %v", e.Index, e.Err)
+}
+
+func (e *DDLStatementError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyDDL runs a script of CREATE/ALTER statements in a single transaction, so either
+// the whole script is applied or nothing is
+func (s *SchemaService) ApplyDDL(userID uuid.UUID, projectID uuid.UUID, script string) (*ApplyDDLResult, error) {
+	if len(script) > maxDDLScriptBytes {
+		return nil, fmt.Errorf("invalid script: larger than %d bytes", maxDDLScriptBytes)
+	}
+
+	statements, err := splitSQLStatements(script)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script: %w", err)
+	}
+	if len(statements) == 0 {
+		return nil, errors.New("invalid script: no statements found")
+	}
+	if len(statements) > maxDDLScriptStatement {
+		return nil, fmt.Errorf("invalid script: more than %d statements", maxDDLScriptStatement)
+	}
+	for i, statement := range statements {
+		if err := validateDDLStatement(statement); err != nil {
+			return nil, fmt.Errorf("invalid statement %d: %w", i+1, err)
+		}
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), applyDDLTimeout)
+	defer cancel()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	target := fmt.Sprintf("%d statements", len(statements))
+	for i, statement := range statements {
+		if _, err := tx.Exec(ctx, statement); err != nil {
+			stmtErr := &DDLStatementError{Index: i + 1, Statement: statement, Err: err}
+			s.ddlAudit.Record(userID, projectID, models.DDLOperationApplyScript, target, script, stmtErr)
+			return nil, stmtErr
+		}
+	}
+
+	err = tx.Commit(ctx)
+	s.ddlAudit.Record(userID, projectID, models.DDLOperationApplyScript, target, script, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &ApplyDDLResult{Statements: len(statements)}, nil
+}
+
+// validateDDLStatement allows only CREATE and ALTER statements on schema objects
+func validateDDLStatement(statement string) error {
+	words := strings.Fields(strings.ToUpper(stripLeadingSQLComments(statement)))
+	if len(words) == 0 {
+		return errors.New("empty statement")
+	}
+	if words[0] != "CREATE" && words[0] != "ALTER" {
+		return fmt.Errorf("only CREATE and ALTER statements are allowed, got %s", words[0])
+	}
+
+	for _, word := range words[1:] {
+		word = strings.TrimRight(word, "(")
+		if ddlModifiers[word] {
+			continue
+		}
+		if ddlForbiddenObjects[word] {
+			return fmt.Errorf("%s %s is not allowed", words[0], word)
+		}
+		break
 	}
+
+	return nil
+}
+
+// stripLeadingSQLComments removes comments and whitespace before the first keyword
+func stripLeadingSQLComments(statement string) string {
+	for {
+		statement = strings.TrimSpace(statement)
+		switch {
+		case strings.HasPrefix(statement, "--"):
+			end := strings.IndexByte(statement, '\n')
+			if end < 0 {
+				return ""
+			}
+			statement = statement[end+1:]
+		case strings.HasPrefix(statement, "/*"):
+			end := blockCommentEnd(statement)
+			if end < 0 {
+				return ""
+			}
+			statement = statement[end:]
+		default:
+			return statement
+		}
+	}
+}
+
+// splitSQLStatements splits a script on semicolons, ignoring those inside string
+// literals, quoted identifiers, dollar-quoted bodies and comments. Empty statements
+// are dropped.
+func splitSQLStatements(script string) ([]string, error) {
+	var statements []string
+	start := 0
+
+	flush := func(end int) {
+		statement := strings.TrimSpace(script[start:end])
+		if stripLeadingSQLComments(statement) != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them, which this loop handles as two literals.
+			// E'...' strings may also escape them with a backslash.
+			escapes := c == '\'' && i > 0 && (script[i-1] == 'E' || script[i-1] == 'e')
+			j := i + 1
+			for j < len(script) && script[j] != c {
+				if escapes && script[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(script) {
+				return nil, errors.New("unterminated quoted string")
+			}
+			i = j + 1
+
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 1
+			}
+
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := blockCommentEnd(script[i:])
+			if end < 0 {
+				return nil, errors.New("unterminated block comment")
+			}
+			i += end
+
+		case c == '$':
+			tag := dollarQuoteTag(script[i:])
+			if tag == "" {
+				i++
+				continue
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string %s", tag)
+			}
+			i += len(tag) + end + len(tag)
+
+		case c == ';':
+			flush(i)
+			i++
+			start = i
+
+		default:
+			i++
+		}
+	}
+	flush(len(script))
+
+	return statements, nil
+}
+
+// blockCommentEnd returns the offset just past the block comment starting s, or -1 if it
+// is unterminated. Block comments nest in PostgreSQL.
+func blockCommentEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// dollarQuoteTag returns the opening $tag$ at the start of s, or "" if there is none
+// (e.g. a $1 parameter)
+func dollarQuoteTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1]
+		}
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !(isDigit && j > 1) {
+			return ""
+		}
+	}
+	return ""
+}
+
+// IntrospectSchema returns a complete description of a schema's tables, columns, keys,
+// unique constraints and indexes, for ORMs generating models. Each kind of object is
+// loaded for the whole schema in a single query.
+func (s *SchemaService) IntrospectSchema(userID uuid.UUID, projectID uuid.UUID, schema string) (*models.SchemaIntrospection, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, fmt.Errorf("invalid schema name: %w", err)
+	}
+
+	pool, err := s.connectProjectDatabase(userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaRepo := repositories.NewSchemaRepository(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return introspectSchema(ctx, schemaRepo, schema)
 }
 
-// VisualizeSchema generates a Mermaid ER diagram for a project's database schema
-func (s *SchemaService) VisualizeSchema(userID uuid.UUID, projectID uuid.UUID, schema string) (string, error) {
+// introspectSchema describes every table of a schema with its columns, keys, checks and indexes
+func introspectSchema(ctx context.Context, schemaRepo *repositories.SchemaRepository, schema string) (*models.SchemaIntrospection, error) {
+	tableNames, err := schemaRepo.GetTables(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	columns, err := schemaRepo.GetSchemaColumns(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	constraints, err := schemaRepo.GetSchemaConstraints(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get constraints: %w", err)
+	}
+	indexes, err := schemaRepo.GetSchemaIndexes(ctx, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexes: %w", err)
+	}
+
+	// Slices are never nil so every key is present in the JSON output
+	tables := make(map[string]*models.IntrospectedTable, len(tableNames))
+	result := &models.SchemaIntrospection{Schema: schema, Tables: make([]models.IntrospectedTable, 0, len(tableNames))}
+	for _, name := range tableNames {
+		table := &models.IntrospectedTable{
+			Name:              name,
+			Columns:           columns[name],
+			PrimaryKey:        []string{},
+			ForeignKeys:       []models.IntrospectedForeignKey{},
+			UniqueConstraints: []models.UniqueConstraint{},
+			Checks:            []models.CheckConstraint{},
+			Indexes:           indexes[name],
+		}
+		if table.Columns == nil {
+			table.Columns = []models.IntrospectedColumn{}
+		}
+		if table.Indexes == nil {
+			table.Indexes = []models.IntrospectedIndex{}
+		}
+		tables[name] = table
+	}
+
+	for _, con := range constraints {
+		table, ok := tables[con.Table]
+		if !ok {
+			continue
+		}
+		switch con.Type {
+		case "PRIMARY KEY":
+			table.PrimaryKey = con.Columns
+		case "UNIQUE":
+			table.UniqueConstraints = append(table.UniqueConstraints, models.UniqueConstraint{
+				Name:    con.Name,
+				Columns: con.Columns,
+			})
+		case "FOREIGN KEY":
+			fk := models.IntrospectedForeignKey{
+				Name:              con.Name,
+				Columns:           con.Columns,
+				ReferencedColumns: con.ReferencedColumns,
+			}
+			if con.ReferencedSchema != nil {
+				fk.ReferencedSchema = *con.ReferencedSchema
+			}
+			if con.ReferencedTable != nil {
+				fk.ReferencedTable = *con.ReferencedTable
+			}
+			if con.OnUpdate != nil {
+				fk.OnUpdate = *con.OnUpdate
+			}
+			if con.OnDelete != nil {
+				fk.OnDelete = *con.OnDelete
+			}
+			table.ForeignKeys = append(table.ForeignKeys, fk)
+		case "CHECK":
+			check := models.CheckConstraint{Name: con.Name}
+			if con.Definition != nil {
+				check.Definition = *con.Definition
+			}
+			table.Checks = append(table.Checks, check)
+		}
+	}
+
+	for _, name := range tableNames {
+		result.Tables = append(result.Tables, *tables[name])
+	}
+
+	return result, nil
+}
+
+// connectProjectDatabase verifies project ownership and returns the pooled connection to the
+// project's running instance. The pool is shared, so callers must not close it.
+func (s *SchemaService) connectProjectDatabase(userID uuid.UUID, projectID uuid.UUID) (*pgxpool.Pool, error) {
 	// Validate project ownership
 	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if project == nil {
-		return "", errors.New("project not found or not accessible")
+		return nil, errors.New("project not found or not accessible")
+	}
+	if err := requirePostgres(project); err != nil {
+		return nil, err
 	}
 
 	inst, err := s.instanceRepo.GetRunningByProjectID(projectID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if inst == nil {
-		return "", errors.New("no running database instance for this project")
+		return nil, errors.New("no running database instance for this project")
 	}
 
 	// Fetch credentials for the instance
 	cred, err := s.credRepo.GetLatestByInstanceID(inst.ID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if cred == nil {
-		return "", errors.New("no credentials configured for this database instance")
+		return nil, errors.New("no credentials configured for this database instance")
 	}
 
 	// Validate container_id
 	if inst.ContainerID == nil || *inst.ContainerID == "" {
-		return "", errors.New("database instance container ID not configured")
+		return nil, errors.New("database instance container ID not configured")
 	}
 
 	// Get current IP from orchestrator
-	ip, ok := s.orchestrator.GetContainerIP(*inst.ContainerID)
-	if !ok {
-		var err error
-		ip, err = s.orchestrator.GetContainerIPFromRedis(context.Background(), *inst.ContainerID)
-		if err != nil {
-			return "", fmt.Errorf("failed to get container IP from orchestrator: %w", err)
-		}
-	}
-
-	// Validate port
-	if inst.Port == nil {
-		return "", errors.New("database instance port not configured")
+	ip, err := s.orchestrator.ResolveContainerIP(*inst.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container IP from orchestrator: %w", err)
 	}
 
 	// Decrypt password
 	dbPassword, err := utils.DecryptString(cred.PasswordEncrypted)
 	if err != nil {
-		return "", fmt.Errorf("failed to decrypt database credentials: %w", err)
+		return nil, fmt.Errorf("failed to decrypt database credentials: %w", err)
 	}
 
 	// Connect to the project database using IP from orchestrator
-	pool, err := database.ConnectToProjectDatabase(ip, *inst.Port, cred.Username, dbPassword, "postgres")
+	pool, err := s.connections.GetPool(inst.ID, database.ProjectDatabaseURL(ip, inst.Port, cred.Username, dbPassword, inst.DatabaseName()))
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to project database: %w", err)
-	}
-	defer pool.Close()
-
-	if schema == "" {
-		schema = "public"
+		return nil, fmt.Errorf("failed to connect to project database: %w", err)
 	}
 
-	schemaRepo := repositories.NewSchemaRepository(pool)
-
-	ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel2()
-
-	mermaidDiagram, err := GenerateSchemaVisualization(ctx2, schemaRepo, schema)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate schema visualization: %w", err)
-	}
-	return mermaidDiagram, nil
+	return pool, nil
 }
 
 func parseTables(ctx context.Context, schemaRepo *repositories.SchemaRepository, schema string) ([]models.Table, error) {
@@ -151,27 +753,37 @@ func parseTables(ctx context.Context, schemaRepo *repositories.SchemaRepository,
 		}
 		table.ForeignKeys = fks
 
+		// Slices are never nil so every key is present in the JSON output
+		if table.Columns == nil {
+			table.Columns = []models.Column{}
+		}
+		if table.PrimaryKeys == nil {
+			table.PrimaryKeys = []string{}
+		}
+		if table.ForeignKeys == nil {
+			table.ForeignKeys = []models.ForeignKey{}
+		}
+
 		tables = append(tables, table)
 	}
 
 	return tables, nil
 }
 func buildRelationshipsWithDetection(ctx context.Context, schemaRepo *repositories.SchemaRepository, schema string, tables []models.Table) ([]models.Relationship, error) {
-	var relationships []models.Relationship
+	relationships := []models.Relationship{}
 	junctionTables := detectJunctionTables(tables)
 
-	// Collect all table-column pairs that need unique constraint checking
+	// Collect all table-column pairs: foreign key columns decide the relationship
+	// cardinality, and every unique column is annotated in the diagram
 	var tableColumns []repositories.TableColumn
 
-	// First pass: collect all foreign keys that need checking (excluding junction tables)
+	// First pass: collect all columns that need checking
 	for _, table := range tables {
-		if !junctionTables[table.Name] {
-			for _, fk := range table.ForeignKeys {
-				tableColumns = append(tableColumns, repositories.TableColumn{
-					Table:  table.Name,
-					Column: fk.FromColumn,
-				})
-			}
+		for _, col := range table.Columns {
+			tableColumns = append(tableColumns, repositories.TableColumn{
+				Table:  table.Name,
+				Column: col.Name,
+			})
 		}
 	}
 
@@ -180,6 +792,11 @@ func buildRelationshipsWithDetection(ctx context.Context, schemaRepo *repositori
 	if err != nil {
 		return nil, fmt.Errorf("failed to get unique constraints: %w", err)
 	}
+	for _, table := range tables {
+		for i := range table.Columns {
+			table.Columns[i].Unique = uniqueMap[fmt.Sprintf("%s:%s", table.Name, table.Columns[i].Name)]
+		}
+	}
 
 	// Second pass: build relationships
 	for _, table := range tables {
@@ -194,6 +811,7 @@ func buildRelationshipsWithDetection(ctx context.Context, schemaRepo *repositori
 							FromTable: table.ForeignKeys[i].ToTable,
 							ToTable:   table.ForeignKeys[j].ToTable,
 							Type:      "}o--o{",
+							Label:     "has_many",
 						}
 						relationships = append(relationships, rel)
 					}
@@ -207,7 +825,8 @@ func buildRelationshipsWithDetection(ctx context.Context, schemaRepo *repositori
 			key := fmt.Sprintf("%s:%s", table.Name, fk.FromColumn)
 			isUnique := uniqueMap[key]
 
-			relType := "||--o{" // Default: one-to-many
+			// The referencing table comes first, so the "many" side is on the left
+			relType := "}o--||" // Default: many-to-one
 			if isUnique {
 				relType = "||--||" // One-to-one
 			}
@@ -216,6 +835,7 @@ func buildRelationshipsWithDetection(ctx context.Context, schemaRepo *repositori
 				FromTable: table.Name,
 				ToTable:   fk.ToTable,
 				Type:      relType,
+				Label:     "belongs_to",
 			}
 			relationships = append(relationships, rel)
 		}
@@ -255,7 +875,11 @@ func detectJunctionTables(tables []models.Table) map[string]bool {
 	}
 	return junctionTables
 }
-func generateMermaid(tables []models.Table, relationships []models.Relationship) string {
+
+// RenderMermaid renders a schema visualization as a Mermaid ER diagram. Tables with a row
+// count are preceded by a comment with it.
+func RenderMermaid(visualization *models.SchemaVisualization) string {
+	tables, relationships := visualization.Tables, visualization.Relationships
 	var sb strings.Builder
 
 	sb.WriteString("erDiagram\n")
@@ -271,32 +895,46 @@ func generateMermaid(tables []models.Table, relationships []models.Relationship)
 			}
 			seen[key] = true
 
-			// Mermaid ER diagram syntax requires a label (even if empty)
-			// Use empty string as label to effectively hide it
-			sb.WriteString(fmt.Sprintf("    %s %s %s : \"\"\n",
+			// Mermaid ER diagram syntax requires a label
+			sb.WriteString(fmt.Sprintf("    %s %s %s : %q\n",
 				strings.ToUpper(rel.FromTable),
 				rel.Type,
-				strings.ToUpper(rel.ToTable)))
+				strings.ToUpper(rel.ToTable),
+				rel.Label))
 		}
 		sb.WriteString("\n")
 	}
 
 	// Write table definitions
 	for _, table := range tables {
+		if table.RowCount != nil {
+			sb.WriteString(fmt.Sprintf("    %%%% %s: %d rows\n", strings.ToUpper(table.Name), *table.RowCount))
+		}
 		sb.WriteString(fmt.Sprintf("    %s {\n", strings.ToUpper(table.Name)))
 
 		for _, col := range table.Columns {
 			dataType := simplifyDataType(col.DataType)
-			annotations := ""
+			var keys []string
 
 			// Add PK annotation
 			if utils.Contains(table.PrimaryKeys, col.Name) {
-				annotations = " PK"
+				keys = append(keys, "PK")
 			}
 
 			// Add FK annotation
 			if isForeignKey(table.ForeignKeys, col.Name) {
-				annotations += " FK"
+				keys = append(keys, "FK")
+			}
+
+			// Add UK annotation
+			if col.Unique {
+				keys = append(keys, "UK")
+			}
+
+			// Mermaid separates several keys of one attribute with commas
+			annotations := ""
+			if len(keys) > 0 {
+				annotations = " " + strings.Join(keys, ", ")
 			}
 
 			sb.WriteString(fmt.Sprintf("        %s %s%s\n",
@@ -366,19 +1004,35 @@ func isForeignKey(fks []models.ForeignKey, colName string) bool {
 	}
 	return false
 }
-func GenerateSchemaVisualization(ctx context.Context, schemaRepo *repositories.SchemaRepository, schema string) (string, error) {
+
+// GenerateSchemaVisualization parses the tables of a schema and the relationships between
+// them. With withStats each table gets its row count.
+func GenerateSchemaVisualization(ctx context.Context, schemaRepo *repositories.SchemaRepository, schema string, withStats bool) (*models.SchemaVisualization, error) {
 	// Parse tables
 	tables, err := parseTables(ctx, schemaRepo, schema)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse tables: %w", err)
+		return nil, fmt.Errorf("failed to parse tables: %w", err)
 	}
 
 	// Build relationships
 	relationships, err := buildRelationshipsWithDetection(ctx, schemaRepo, schema, tables)
 	if err != nil {
-		return "", fmt.Errorf("failed to build relationships: %w", err)
+		return nil, fmt.Errorf("failed to build relationships: %w", err)
+	}
+
+	if withStats {
+		for i := range tables {
+			count, err := schemaRepo.CountRows(ctx, schema, tables[i].Name)
+			if err != nil {
+				return nil, err
+			}
+			tables[i].RowCount = &count
+		}
 	}
 
-	mermaidDiagram := generateMermaid(tables, relationships)
-	return mermaidDiagram, nil
+	return &models.SchemaVisualization{
+		Schema:        schema,
+		Tables:        tables,
+		Relationships: relationships,
+	}, nil
 }
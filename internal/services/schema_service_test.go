@@ -0,0 +1,672 @@
+package services
+
+import (
+	"backend/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetTableDependentsReportsReferencingObjects(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE authors (id integer PRIMARY KEY, name text)`,
+		`CREATE TABLE books (id integer PRIMARY KEY, author_id integer CONSTRAINT books_author_fk REFERENCES authors (id))`,
+		`CREATE VIEW author_names AS SELECT name FROM authors`,
+	)
+
+	dependents, err := env.schemas.GetTableDependents(user.ID, project.ID, "public", "authors")
+	if err != nil {
+		t.Fatalf("GetTableDependents: %v", err)
+	}
+	if len(dependents.ForeignKeys) != 1 || dependents.ForeignKeys[0].Name != "books_author_fk" || dependents.ForeignKeys[0].Table != "books" {
+		t.Errorf("foreign keys = %+v, want books_author_fk on books", dependents.ForeignKeys)
+	}
+	if len(dependents.Views) != 1 || dependents.Views[0].Name != "author_names" {
+		t.Errorf("views = %+v, want author_names", dependents.Views)
+	}
+
+	// Nothing references books
+	dependents, err = env.schemas.GetTableDependents(user.ID, project.ID, "public", "books")
+	if err != nil {
+		t.Fatalf("GetTableDependents: %v", err)
+	}
+	if len(dependents.ForeignKeys) != 0 || len(dependents.Views) != 0 || len(dependents.Functions) != 0 {
+		t.Errorf("dependents of books = %+v, want none", dependents)
+	}
+}
+
+func TestRenameSchema(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE SCHEMA reporting`,
+		`CREATE TABLE reporting.daily (day date PRIMARY KEY)`,
+		`CREATE SCHEMA archive`,
+	)
+
+	if err := env.schemas.RenameSchema(user.ID, project.ID, "reporting", "analytics"); err != nil {
+		t.Fatalf("RenameSchema: %v", err)
+	}
+	var table string
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT 'analytics.daily'::regclass::text`).Scan(&table); err != nil {
+		t.Errorf("renamed schema's table: %v", err)
+	}
+
+	if err := env.schemas.RenameSchema(user.ID, project.ID, "analytics", "archive"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("RenameSchema onto an existing schema = %v, want it rejected", err)
+	}
+	if err := env.schemas.RenameSchema(user.ID, project.ID, "missing", "other"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RenameSchema of a missing schema = %v, want not found", err)
+	}
+}
+
+func TestRenameSchemaRejectsProtectedSchemas(t *testing.T) {
+	schemas := &SchemaService{}
+
+	cases := [][2]string{
+		{"public", "renamed"},
+		{"PUBLIC", "renamed"},
+		{"pg_catalog", "renamed"},
+		{"information_schema", "renamed"},
+		{"reporting", "public"},
+		{"reporting", "pg_reporting"},
+		{"reporting", "bad name"},
+	}
+	for _, c := range cases {
+		if err := schemas.RenameSchema(uuid.New(), uuid.New(), c[0], c[1]); err == nil {
+			t.Errorf("RenameSchema(%q, %q) succeeded", c[0], c[1])
+		}
+	}
+}
+
+func TestSummarizeTableHealth(t *testing.T) {
+	analyzed := time.Now().Add(-time.Hour)
+	const (
+		analyze = "Table has never been analyzed; consider ANALYZE"
+		stale   = "Statistics are stale after many changes; consider ANALYZE"
+		vacuum  = "High share of dead rows; consider VACUUM"
+		index   = "Mostly sequential scans on a large table; consider adding an index"
+	)
+
+	cases := []struct {
+		name string
+		stat models.TableStats
+		want []string
+	}{
+		{"healthy", models.TableStats{LiveTuples: 50000, DeadTuples: 100, SeqScans: 10, IndexScans: 5000, ModsSinceAnalyze: 10, LastAnalyze: &analyzed}, nil},
+		{"empty and never analyzed", models.TableStats{}, nil},
+		{"never analyzed", models.TableStats{LiveTuples: 1, LastAnalyze: nil}, []string{analyze}},
+		{"autoanalyzed", models.TableStats{LiveTuples: 1, LastAutoanalyze: &analyzed}, nil},
+		{"stale at threshold", models.TableStats{LiveTuples: 1000, ModsSinceAnalyze: 101, LastAnalyze: &analyzed}, []string{stale}},
+		{"not stale below ratio", models.TableStats{LiveTuples: 1000, ModsSinceAnalyze: 100, LastAnalyze: &analyzed}, nil},
+		{"few changes to a tiny table", models.TableStats{LiveTuples: 10, ModsSinceAnalyze: minStaleAnalyzeMods - 1, LastAnalyze: &analyzed}, nil},
+		{"bloated", models.TableStats{LiveTuples: 3000, DeadTuples: 1000, LastAnalyze: &analyzed}, []string{vacuum}},
+		{"bloat at ratio", models.TableStats{LiveTuples: 4000, DeadTuples: 1000, LastAnalyze: &analyzed}, nil},
+		{"little bloat", models.TableStats{LiveTuples: 10, DeadTuples: minDeadTuples - 1, LastAnalyze: &analyzed}, nil},
+		{"sequential scans", models.TableStats{LiveTuples: minRowsForIndexAdvice, SeqScans: 950, IndexScans: 50, LastAnalyze: &analyzed}, []string{index}},
+		{"sequential scans on a small table", models.TableStats{LiveTuples: minRowsForIndexAdvice - 1, SeqScans: 950, IndexScans: 50, LastAnalyze: &analyzed}, nil},
+		{"rarely scanned", models.TableStats{LiveTuples: minRowsForIndexAdvice, SeqScans: minSeqScans - 1, LastAnalyze: &analyzed}, nil},
+		{"everything", models.TableStats{LiveTuples: 20000, DeadTuples: 10000, SeqScans: 1000, ModsSinceAnalyze: 5000, LastAnalyze: &analyzed}, []string{stale, vacuum, index}},
+	}
+	for _, c := range cases {
+		stat := c.stat
+		summarizeTableHealth(&stat)
+		if fmt.Sprint(stat.Suggestions) != fmt.Sprint(c.want) {
+			t.Errorf("%s: suggestions = %q, want %q", c.name, stat.Suggestions, c.want)
+		}
+	}
+
+	stat := models.TableStats{LiveTuples: 3000, DeadTuples: 1000, SeqScans: 30, IndexScans: 10}
+	summarizeTableHealth(&stat)
+	if stat.DeadTupleRatio != 0.25 || stat.SeqScanRatio != 0.75 {
+		t.Errorf("ratios = %v dead, %v sequential, want 0.25 and 0.75", stat.DeadTupleRatio, stat.SeqScanRatio)
+	}
+}
+
+func TestIntrospectSchema(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE authors (
+			id integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			email varchar(100) NOT NULL UNIQUE
+		)`,
+		`CREATE TABLE books (
+			id integer PRIMARY KEY,
+			author_id integer NOT NULL CONSTRAINT books_author_fk REFERENCES authors (id) ON DELETE CASCADE,
+			price numeric(10,2) DEFAULT 0 CONSTRAINT books_price_check CHECK (price >= 0),
+			title text
+		)`,
+		`CREATE INDEX books_title_idx ON books (title) WHERE price > 0`,
+	)
+
+	introspection, err := env.schemas.IntrospectSchema(user.ID, project.ID, "public")
+	if err != nil {
+		t.Fatalf("IntrospectSchema: %v", err)
+	}
+
+	want := `{
+		"schema": "public",
+		"tables": [
+			{
+				"name": "authors",
+				"columns": [
+					{"name": "id", "data_type": "integer", "nullable": false, "default": null, "identity": "ALWAYS"},
+					{"name": "email", "data_type": "character varying(100)", "nullable": false, "default": null, "identity": null}
+				],
+				"primary_key": ["id"],
+				"foreign_keys": [],
+				"unique_constraints": [{"name": "authors_email_key", "columns": ["email"]}],
+				"checks": [],
+				"indexes": [
+					{"name": "authors_email_key", "columns": ["email"], "expressions": null, "predicate": null, "unique": true, "primary": false,
+						"definition": "CREATE UNIQUE INDEX authors_email_key ON public.authors USING btree (email)"},
+					{"name": "authors_pkey", "columns": ["id"], "expressions": null, "predicate": null, "unique": true, "primary": true,
+						"definition": "CREATE UNIQUE INDEX authors_pkey ON public.authors USING btree (id)"}
+				]
+			},
+			{
+				"name": "books",
+				"columns": [
+					{"name": "id", "data_type": "integer", "nullable": false, "default": null, "identity": null},
+					{"name": "author_id", "data_type": "integer", "nullable": false, "default": null, "identity": null},
+					{"name": "price", "data_type": "numeric(10,2)", "nullable": true, "default": "0", "identity": null},
+					{"name": "title", "data_type": "text", "nullable": true, "default": null, "identity": null}
+				],
+				"primary_key": ["id"],
+				"foreign_keys": [{"name": "books_author_fk", "columns": ["author_id"], "referenced_schema": "public", "referenced_table": "authors",
+					"referenced_columns": ["id"], "on_update": "NO ACTION", "on_delete": "CASCADE"}],
+				"unique_constraints": [],
+				"checks": [{"name": "books_price_check", "definition": "CHECK ((price >= (0)::numeric))"}],
+				"indexes": [
+					{"name": "books_pkey", "columns": ["id"], "expressions": null, "predicate": null, "unique": true, "primary": true,
+						"definition": "CREATE UNIQUE INDEX books_pkey ON public.books USING btree (id)"},
+					{"name": "books_title_idx", "columns": ["title"], "expressions": null, "predicate": "(price > (0)::numeric)", "unique": false, "primary": false,
+						"definition": "CREATE INDEX books_title_idx ON public.books USING btree (title) WHERE (price > (0)::numeric)"}
+				]
+			}
+		]
+	}`
+
+	got, err := json.Marshal(introspection)
+	if err != nil {
+		t.Fatalf("marshal introspection: %v", err)
+	}
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("unmarshal introspection: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal expected introspection: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("introspection = %s\nwant %s", got, want)
+	}
+}
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := `
+-- authors first; books reference them
+CREATE TABLE authors (id integer PRIMARY KEY, name text DEFAULT 'a;b');
+CREATE TABLE "odd;name" (id integer);
+/* a /* nested; */ comment */
+CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN NEW.note := E'it\'s; done'; RETURN NEW; END;
+$body$ LANGUAGE plpgsql;
+;
+ALTER TABLE authors ADD COLUMN note text`
+
+	statements, err := splitSQLStatements(script)
+	if err != nil {
+		t.Fatalf("splitSQLStatements: %v", err)
+	}
+	want := []string{
+		"-- authors first; books reference them\nCREATE TABLE authors (id integer PRIMARY KEY, name text DEFAULT 'a;b')",
+		`CREATE TABLE "odd;name" (id integer)`,
+		"/* a /* nested; */ comment */\nCREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN NEW.note := E'it\\'s; done'; RETURN NEW; END;\n$body$ LANGUAGE plpgsql",
+		"ALTER TABLE authors ADD COLUMN note text",
+	}
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("splitSQLStatements =\n%q\nwant\n%q", statements, want)
+	}
+
+	for _, script := range []string{
+		`CREATE TABLE t (name text DEFAULT 'open)`,
+		`CREATE FUNCTION f() RETURNS int AS $$ SELECT 1`,
+		`/* never closed CREATE TABLE t (id int)`,
+	} {
+		if _, err := splitSQLStatements(script); err == nil {
+			t.Errorf("splitSQLStatements(%q) succeeded, want an unterminated error", script)
+		}
+	}
+}
+
+func TestValidateDDLStatement(t *testing.T) {
+	for _, statement := range []string{
+		"CREATE TABLE t (id int)",
+		"create or replace view v as select 1",
+		"CREATE UNIQUE INDEX t_id ON t (id)",
+		"-- comment\nALTER TABLE t ADD COLUMN note text",
+		"CREATE MATERIALIZED VIEW mv AS SELECT 1",
+	} {
+		if err := validateDDLStatement(statement); err != nil {
+			t.Errorf("validateDDLStatement(%q) = %v, want nil", statement, err)
+		}
+	}
+	for _, statement := range []string{
+		"DROP TABLE t",
+		"DROP DATABASE postgres",
+		"INSERT INTO t VALUES (1)",
+		"CREATE DATABASE other",
+		"ALTER SYSTEM SET work_mem = '1GB'",
+		"CREATE ROLE admin SUPERUSER",
+		"create extension dblink",
+		"/* only a comment */",
+	} {
+		if err := validateDDLStatement(statement); err == nil {
+			t.Errorf("validateDDLStatement(%q) succeeded, want an error", statement)
+		}
+	}
+}
+
+func TestApplyDDLCreatesAllTables(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	result, err := env.schemas.ApplyDDL(user.ID, project.ID, `
+		CREATE TABLE authors (id integer PRIMARY KEY, name text NOT NULL);
+		CREATE TABLE books (id integer PRIMARY KEY, author_id integer REFERENCES authors (id), title text);
+		CREATE INDEX books_author_id ON books (author_id);
+		ALTER TABLE books ADD COLUMN published date;
+	`)
+	if err != nil {
+		t.Fatalf("ApplyDDL: %v", err)
+	}
+	if result.Statements != 4 {
+		t.Errorf("applied %d statements, want 4", result.Statements)
+	}
+	for _, table := range []string{"authors", "books"} {
+		if !tableExists(t, env, user, project, table) {
+			t.Errorf("table %s was not created", table)
+		}
+	}
+	env.exec(t, user, project, `INSERT INTO authors VALUES (1, 'Ann')`, `INSERT INTO books VALUES (1, 1, 'Notes', '2024-01-01')`)
+}
+
+func TestApplyDDLRollsBackOnFailure(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	_, err := env.schemas.ApplyDDL(user.ID, project.ID, `
+		CREATE TABLE authors (id integer PRIMARY KEY);
+		CREATE TABLE books (id integer PRIMARY KEY, author_id integer REFERENCES writers (id));
+		CREATE TABLE reviews (id integer PRIMARY KEY);
+	`)
+	var stmtErr *DDLStatementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("ApplyDDL error = %v, want a DDLStatementError", err)
+	}
+	if stmtErr.Index != 2 || !strings.HasPrefix(stmtErr.Statement, "CREATE TABLE books") {
+		t.Errorf("failed statement = %d %q, want the second one", stmtErr.Index, stmtErr.Statement)
+	}
+	for _, table := range []string{"authors", "books", "reviews"} {
+		if tableExists(t, env, user, project, table) {
+			t.Errorf("table %s exists after the script failed", table)
+		}
+	}
+
+	// Scripts with a disallowed statement are refused before anything runs
+	if _, err := env.schemas.ApplyDDL(user.ID, project.ID, `CREATE TABLE authors (id integer); DROP DATABASE postgres`); err == nil {
+		t.Fatal("ApplyDDL accepted a DROP DATABASE")
+	}
+	if tableExists(t, env, user, project, "authors") {
+		t.Error("table authors exists after the script was refused")
+	}
+}
+
+func TestVisualizeSchemaStatsOnlyWhenRequested(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE authors (id integer PRIMARY KEY, name text)`,
+		`CREATE TABLE books (id integer PRIMARY KEY, author_id integer REFERENCES authors (id))`,
+		`INSERT INTO authors VALUES (1, 'Ann'), (2, 'Bo')`,
+		`INSERT INTO books VALUES (1, 1), (2, 1), (3, 2)`,
+	)
+
+	plain, err := env.schemas.VisualizeSchema(user.ID, project.ID, "public", false)
+	if err != nil {
+		t.Fatalf("VisualizeSchema: %v", err)
+	}
+	withStats, err := env.schemas.VisualizeSchema(user.ID, project.ID, "public", true)
+	if err != nil {
+		t.Fatalf("VisualizeSchema withStats: %v", err)
+	}
+
+	wantRows := map[string]int64{"authors": 2, "books": 3}
+	for _, table := range plain.Tables {
+		if table.RowCount != nil {
+			t.Errorf("table %s has a row count without withStats", table.Name)
+		}
+	}
+	for i, table := range withStats.Tables {
+		if table.RowCount == nil || *table.RowCount != wantRows[table.Name] {
+			t.Errorf("table %s row count = %v, want %d", table.Name, table.RowCount, wantRows[table.Name])
+		}
+		withStats.Tables[i].RowCount = nil
+	}
+	// Apart from the counts the visualizations are identical
+	if !reflect.DeepEqual(plain, withStats) {
+		t.Errorf("visualization with stats differs beyond row counts:\n%+v\n%+v", plain, withStats)
+	}
+}
+
+func TestRenderMermaidRowCounts(t *testing.T) {
+	count := int64(42)
+	visualization := &models.SchemaVisualization{
+		Schema: "public",
+		Tables: []models.Table{
+			{Name: "authors", Columns: []models.Column{{Name: "id", DataType: "integer"}}, PrimaryKeys: []string{"id"}},
+		},
+	}
+
+	plain := RenderMermaid(visualization)
+	if strings.Contains(plain, "%%") {
+		t.Errorf("diagram without stats has comments:\n%s", plain)
+	}
+
+	visualization.Tables[0].RowCount = &count
+	annotated := RenderMermaid(visualization)
+	comment := "    %% AUTHORS: 42 rows\n"
+	if !strings.Contains(annotated, comment) {
+		t.Errorf("diagram with stats lacks %q:\n%s", comment, annotated)
+	}
+	if strings.Replace(annotated, comment, "", 1) != plain {
+		t.Errorf("diagram with stats differs beyond the comment:\n%s\nwant\n%s", annotated, plain)
+	}
+}
+
+func TestRenderMermaidKeysAndLabels(t *testing.T) {
+	visualization := &models.SchemaVisualization{
+		Schema: "public",
+		Tables: []models.Table{
+			{
+				Name: "profiles",
+				Columns: []models.Column{
+					{Name: "id", DataType: "integer", Unique: true},
+					{Name: "user_id", DataType: "integer", Unique: true},
+					{Name: "handle", DataType: "text", Unique: true},
+				},
+				PrimaryKeys: []string{"id"},
+				ForeignKeys: []models.ForeignKey{{ConstraintName: "profiles_user_id_fkey", FromColumn: "user_id", ToTable: "users", ToColumn: "id"}},
+			},
+		},
+		Relationships: []models.Relationship{
+			{FromTable: "profiles", ToTable: "users", Type: "}o--||", Label: "belongs_to"},
+		},
+	}
+
+	diagram := RenderMermaid(visualization)
+	for _, want := range []string{
+		"    PROFILES }o--|| USERS : \"belongs_to\"\n",
+		" id PK, UK\n",
+		" user_id FK, UK\n",
+		" handle UK\n",
+	} {
+		if !strings.Contains(diagram, want) {
+			t.Errorf("diagram lacks %q:\n%s", want, diagram)
+		}
+	}
+}
+
+func TestListIndexes(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE accounts (id integer PRIMARY KEY, tenant integer, email text, deleted_at timestamptz)`,
+		`CREATE UNIQUE INDEX accounts_tenant_email_idx ON accounts (tenant, email)`,
+		`CREATE UNIQUE INDEX accounts_active_email_idx ON accounts (email) WHERE deleted_at IS NULL`,
+		`CREATE INDEX accounts_lower_email_idx ON accounts (lower(email))`,
+		`CREATE TABLE audit (id integer PRIMARY KEY)`,
+	)
+
+	indexes, err := env.schemas.ListIndexes(user.ID, project.ID, "public", "accounts")
+	if err != nil {
+		t.Fatalf("ListIndexes: %v", err)
+	}
+	want := `[
+		{"name": "accounts_active_email_idx", "columns": ["email"], "expressions": null, "predicate": "(deleted_at IS NULL)", "unique": true, "primary": false,
+			"definition": "CREATE UNIQUE INDEX accounts_active_email_idx ON public.accounts USING btree (email) WHERE (deleted_at IS NULL)"},
+		{"name": "accounts_lower_email_idx", "columns": [], "expressions": "lower(email)", "predicate": null, "unique": false, "primary": false,
+			"definition": "CREATE INDEX accounts_lower_email_idx ON public.accounts USING btree (lower(email))"},
+		{"name": "accounts_pkey", "columns": ["id"], "expressions": null, "predicate": null, "unique": true, "primary": true,
+			"definition": "CREATE UNIQUE INDEX accounts_pkey ON public.accounts USING btree (id)"},
+		{"name": "accounts_tenant_email_idx", "columns": ["tenant", "email"], "expressions": null, "predicate": null, "unique": true, "primary": false,
+			"definition": "CREATE UNIQUE INDEX accounts_tenant_email_idx ON public.accounts USING btree (tenant, email)"}
+	]`
+
+	got, err := json.Marshal(indexes)
+	if err != nil {
+		t.Fatalf("marshal indexes: %v", err)
+	}
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("unmarshal indexes: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("unmarshal expected indexes: %v", err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("indexes = %s\nwant %s", got, want)
+	}
+
+	if _, err := env.schemas.ListIndexes(user.ID, project.ID, "public", "missing"); err == nil || err.Error() != "table not found" {
+		t.Errorf("ListIndexes of a missing table = %v, want table not found", err)
+	}
+	if _, err := env.schemas.ListIndexes(user.ID, project.ID, "public", `accounts"; --`); err == nil {
+		t.Error("ListIndexes accepted an invalid table name")
+	}
+}
+
+func TestTableDDL(t *testing.T) {
+	serial := "nextval('orders_id_seq'::regclass)"
+	status := "'new'::text"
+	table := &models.IntrospectedTable{
+		Name: "orders",
+		Columns: []models.IntrospectedColumn{
+			{Name: "id", DataType: "integer", Default: &serial},
+			{Name: "customer_id", DataType: "integer", Nullable: true},
+			{Name: "status", DataType: "text", Default: &status},
+		},
+		PrimaryKey: []string{"id"},
+		ForeignKeys: []models.IntrospectedForeignKey{{
+			Name: "orders_customer_id_fkey", Columns: []string{"customer_id"},
+			ReferencedSchema: "public", ReferencedTable: "customers", ReferencedColumns: []string{"id"},
+			OnUpdate: "NO ACTION", OnDelete: "CASCADE",
+		}},
+		UniqueConstraints: []models.UniqueConstraint{{Name: "orders_customer_status_key", Columns: []string{"customer_id", "status"}}},
+		Checks:            []models.CheckConstraint{{Name: "orders_status_check", Definition: "CHECK ((status <> ''::text))"}},
+		Indexes: []models.IntrospectedIndex{
+			{Name: "orders_pkey", Primary: true, Definition: "CREATE UNIQUE INDEX orders_pkey ON public.orders USING btree (id)"},
+			{Name: "orders_customer_status_key", Unique: true, Definition: "CREATE UNIQUE INDEX orders_customer_status_key ON public.orders USING btree (customer_id, status)"},
+			{Name: "orders_status_idx", Definition: "CREATE INDEX orders_status_idx ON public.orders USING btree (status)"},
+		},
+	}
+
+	want := `CREATE TABLE "public"."orders" (
+    "id" serial NOT NULL,
+    "customer_id" integer,
+    "status" text DEFAULT 'new'::text NOT NULL,
+    CONSTRAINT "orders_pkey" PRIMARY KEY ("id"),
+    CONSTRAINT "orders_customer_status_key" UNIQUE ("customer_id", "status"),
+    CONSTRAINT "orders_status_check" CHECK ((status <> ''::text)),
+    CONSTRAINT "orders_customer_id_fkey" FOREIGN KEY ("customer_id") REFERENCES "public"."customers" ("id") ON DELETE CASCADE
+);
+
+CREATE INDEX orders_status_idx ON public.orders USING btree (status);
+`
+	if got := tableDDL("public", table); got != want {
+		t.Errorf("tableDDL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGetTableDDLRecreatesTheTable(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE customers (id integer PRIMARY KEY)`,
+		`CREATE TABLE orders (
+			id serial PRIMARY KEY,
+			number integer GENERATED ALWAYS AS IDENTITY,
+			customer_id integer REFERENCES customers (id) ON DELETE CASCADE,
+			status varchar(20) NOT NULL DEFAULT 'new',
+			UNIQUE (customer_id, status),
+			CHECK (status <> '')
+		)`,
+		`CREATE INDEX orders_status_idx ON orders (lower(status))`,
+	)
+
+	ddl, err := env.schemas.GetTableDDL(user.ID, project.ID, "public", "orders")
+	if err != nil {
+		t.Fatalf("GetTableDDL: %v", err)
+	}
+
+	// Running the statement after dropping the table gives back the same table
+	env.exec(t, user, project, `DROP TABLE orders`, ddl)
+	recreated, err := env.schemas.GetTableDDL(user.ID, project.ID, "public", "orders")
+	if err != nil {
+		t.Fatalf("GetTableDDL of the recreated table: %v", err)
+	}
+	if recreated != ddl {
+		t.Errorf("recreated table DDL =\n%s\nwant\n%s", recreated, ddl)
+	}
+
+	if _, err := env.schemas.GetTableDDL(user.ID, project.ID, "public", "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetTableDDL of a missing table = %v, want not found", err)
+	}
+}
+
+// referencing returns a table with one integer column per referenced table, each with a
+// foreign key to that table
+func referencing(name string, referenced ...string) models.IntrospectedTable {
+	table := models.IntrospectedTable{Name: name, Columns: []models.IntrospectedColumn{{Name: "id", DataType: "integer"}}}
+	for _, other := range referenced {
+		column := other + "_id"
+		table.Columns = append(table.Columns, models.IntrospectedColumn{Name: column, DataType: "integer", Nullable: true})
+		table.ForeignKeys = append(table.ForeignKeys, models.IntrospectedForeignKey{
+			Name: name + "_" + column + "_fkey", Columns: []string{column},
+			ReferencedSchema: "public", ReferencedTable: other, ReferencedColumns: []string{"id"},
+		})
+	}
+	return table
+}
+
+func TestSchemaScriptOrdersTablesByForeignKeys(t *testing.T) {
+	tables := []models.IntrospectedTable{
+		referencing("a_orders", "customers", "products"),
+		referencing("customers"),
+		referencing("products", "suppliers"),
+		referencing("suppliers"),
+		referencing("b_employees", "b_employees", "c_teams"),
+		referencing("c_teams", "b_employees"),
+	}
+
+	script := schemaScript("public", tables)
+
+	// Referenced tables come first. Self references stay inline, and once only the cycle
+	// is left, its first table alphabetically is created without its reference into it.
+	order := []string{
+		`CREATE SCHEMA IF NOT EXISTS "public";`,
+		`CREATE TABLE "public"."customers"`,
+		`CREATE TABLE "public"."suppliers"`,
+		`CREATE TABLE "public"."products"`,
+		`CREATE TABLE "public"."a_orders"`,
+		`CREATE TABLE "public"."b_employees"`,
+		`CONSTRAINT "b_employees_b_employees_id_fkey"`,
+		`CREATE TABLE "public"."c_teams"`,
+		`ALTER TABLE "public"."b_employees" ADD CONSTRAINT "b_employees_c_teams_id_fkey"`,
+	}
+	rest := script
+	for _, want := range order {
+		i := strings.Index(rest, want)
+		if i < 0 {
+			t.Fatalf("script lacks %q after the previous statements:\n%s", want, script)
+		}
+		rest = rest[i+len(want):]
+	}
+	if strings.Count(script, "b_employees_c_teams_id_fkey") != 1 {
+		t.Errorf("deferred foreign key is not only added at the end:\n%s", script)
+	}
+}
+
+func TestDiffTables(t *testing.T) {
+	column := func(name, dataType string) models.Column { return models.Column{Name: name, DataType: dataType} }
+	production := []models.Table{
+		{Name: "users", Columns: []models.Column{column("id", "integer"), column("name", "text"), column("age", "integer")}},
+		{Name: "sessions", Columns: []models.Column{column("id", "uuid")}},
+	}
+	staging := []models.Table{
+		{Name: "users", Columns: []models.Column{column("id", "bigint"), column("name", "text"), column("email", "text")}},
+		{Name: "invites", Columns: []models.Column{column("code", "text")}},
+	}
+
+	diff := diffTables(production, staging)
+	want := &models.SchemaDiff{
+		AddedTables:    []string{"invites"},
+		RemovedTables:  []string{"sessions"},
+		AddedColumns:   []models.ColumnDiff{{Table: "users", Column: "email", DataType: "text"}},
+		RemovedColumns: []models.ColumnDiff{{Table: "users", Column: "age", DataType: "integer"}},
+		TypeChanges:    []models.ColumnTypeChange{{Table: "users", Column: "id", From: "integer", To: "bigint"}},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diffTables = %+v, want %+v", diff, want)
+	}
+
+	wantSummary := "+ table invites\n- table sessions\n+ column users.email text\n- column users.age integer\n~ column users.id integer -> bigint"
+	if summary := diffSummary(diff); summary != wantSummary {
+		t.Errorf("diffSummary =\n%s\nwant\n%s", summary, wantSummary)
+	}
+	if summary := diffSummary(diffTables(staging, staging)); summary != "No differences" {
+		t.Errorf("diffSummary of identical schemas = %q", summary)
+	}
+}
+
+func TestDiffSchemasRequiresBothProjects(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	production := env.createProject(t, user, "postgres")
+	staging := env.createProject(t, user, "postgres")
+	env.exec(t, user, production, `CREATE TABLE users (id integer PRIMARY KEY)`)
+	env.exec(t, user, staging, `CREATE TABLE users (id integer PRIMARY KEY, email text)`)
+
+	diff, err := env.schemas.DiffSchemas(user.ID, production.ID, staging.ID, "public")
+	if err != nil {
+		t.Fatalf("DiffSchemas: %v", err)
+	}
+	if len(diff.AddedColumns) != 1 || diff.AddedColumns[0].Column != "email" {
+		t.Errorf("added columns = %+v, want users.email", diff.AddedColumns)
+	}
+
+	// Another user's project cannot be compared against
+	other := env.createUser(t)
+	foreign := env.createProject(t, other, "postgres")
+	if _, err := env.schemas.DiffSchemas(user.ID, foreign.ID, staging.ID, "public"); err == nil {
+		t.Error("DiffSchemas against another user's project succeeded")
+	}
+}
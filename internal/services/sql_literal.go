@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// literalKind groups column types by how a value of that type is written as a SQL literal
+type literalKind int
+
+const (
+	literalInteger literalKind = iota
+	literalDecimal
+	literalBoolean
+	literalString
+	literalTemporal
+	literalUUID
+)
+
+// literalKinds maps base column types to their literal kind
+var literalKinds = map[string]literalKind{
+	"smallint": literalInteger, "int2": literalInteger, "integer": literalInteger, "int": literalInteger,
+	"int4": literalInteger, "bigint": literalInteger, "int8": literalInteger,
+	"serial": literalInteger, "bigserial": literalInteger, "smallserial": literalInteger,
+	"numeric": literalDecimal, "decimal": literalDecimal, "real": literalDecimal, "float4": literalDecimal,
+	"double precision": literalDecimal, "float8": literalDecimal, "float": literalDecimal,
+	"boolean": literalBoolean, "bool": literalBoolean,
+	"text": literalString, "varchar": literalString, "character varying": literalString,
+	"char": literalString, "character": literalString, "citext": literalString,
+	"json": literalString, "jsonb": literalString, "interval": literalString,
+	"date": literalTemporal, "time": literalTemporal, "timetz": literalTemporal,
+	"timestamp": literalTemporal, "timestamptz": literalTemporal,
+	"uuid": literalUUID,
+}
+
+// literalExpressions are the functions accepted in place of a literal, per kind. They are
+// the usual column defaults and are emitted in this canonical spelling.
+var literalExpressions = map[literalKind]map[string]string{
+	literalTemporal: {
+		"CURRENT_TIMESTAMP": "CURRENT_TIMESTAMP",
+		"CURRENT_DATE":      "CURRENT_DATE",
+		"CURRENT_TIME":      "CURRENT_TIME",
+		"LOCALTIMESTAMP":    "LOCALTIMESTAMP",
+		"LOCALTIME":         "LOCALTIME",
+		"NOW()":             "NOW()",
+	},
+	literalUUID: {
+		"GEN_RANDOM_UUID()": "gen_random_uuid()",
+	},
+}
+
+// columnTypePattern matches a column type with an optional (precision[, scale]) modifier and time zone suffix
+var columnTypePattern = regexp.MustCompile(`^([a-z][a-z0-9]*(?: [a-z]+)?)\s*(?:\(\s*\d+\s*(?:,\s*\d+\s*)?\))?(?:\s+with(?:out)?\s+time\s+zone)?$`)
+
+var (
+	integerLiteralPattern = regexp.MustCompile(`^[+-]?\d+$`)
+	decimalLiteralPattern = regexp.MustCompile(`^[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?$`)
+	uuidLiteralPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// coerceLiteral turns a value into a SQL literal for a column of declaredType, for use in
+// DEFAULT clauses and other generated DDL. Numbers must fit the type, booleans are TRUE or
+// FALSE, and strings are quoted with embedded quotes doubled. A nil value is NULL. Values
+// whose meaning depends on guessing (a number for a text column, an expression for a
+// number) are rejected rather than passed through.
+func coerceLiteral(value interface{}, declaredType string) (string, error) {
+	kind, err := literalKindOf(declaredType)
+	if err != nil {
+		return "", err
+	}
+	if value == nil {
+		return "NULL", nil
+	}
+
+	switch kind {
+	case literalInteger:
+		switch v := value.(type) {
+		case float64:
+			if v != math.Trunc(v) || math.IsInf(v, 0) || math.Abs(v) > 1<<53 {
+				return "", fmt.Errorf("%v is not a valid %s value", v, declaredType)
+			}
+			return strconv.FormatInt(int64(v), 10), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case string:
+			s := strings.TrimSpace(v)
+			if !integerLiteralPattern.MatchString(s) {
+				return "", fmt.Errorf("%q is not a valid %s value", v, declaredType)
+			}
+			if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+				return "", fmt.Errorf("%q is out of range for %s", v, declaredType)
+			}
+			return s, nil
+		}
+
+	case literalDecimal:
+		switch v := value.(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return "", fmt.Errorf("%v is not a valid %s value", v, declaredType)
+			}
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(v), nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case string:
+			s := strings.TrimSpace(v)
+			if !decimalLiteralPattern.MatchString(s) {
+				return "", fmt.Errorf("%q is not a valid %s value", v, declaredType)
+			}
+			return s, nil
+		}
+
+	case literalBoolean:
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return "TRUE", nil
+			}
+			return "FALSE", nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true":
+				return "TRUE", nil
+			case "false":
+				return "FALSE", nil
+			}
+			return "", fmt.Errorf("%q is not a valid boolean value, use true or false", v)
+		}
+
+	case literalString, literalTemporal, literalUUID:
+		s, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("a %s value must be given as a string", declaredType)
+		}
+		if expr, ok := literalExpressions[kind][strings.ToUpper(strings.TrimSpace(s))]; ok {
+			return expr, nil
+		}
+		if kind == literalUUID && !uuidLiteralPattern.MatchString(s) {
+			return "", fmt.Errorf("%q is not a valid uuid", s)
+		}
+		return quoteLiteral(s)
+	}
+
+	return "", fmt.Errorf("a %T value cannot be used for a %s column", value, declaredType)
+}
+
+// quoteLiteral quotes a string as a standard SQL string literal
+func quoteLiteral(s string) (string, error) {
+	if strings.ContainsRune(s, 0) {
+		return "", errors.New("string values cannot contain NUL characters")
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
+
+// literalKindOf resolves the literal kind of a declared column type such as VARCHAR(50) or
+// TIMESTAMP WITH TIME ZONE. Array and unknown types are not supported.
+func literalKindOf(declaredType string) (literalKind, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(declaredType)), " ")
+	match := columnTypePattern.FindStringSubmatch(normalized)
+	if match == nil {
+		return 0, fmt.Errorf("values are not supported for column type %q", declaredType)
+	}
+	kind, ok := literalKinds[match[1]]
+	if !ok {
+		return 0, fmt.Errorf("values are not supported for column type %q", declaredType)
+	}
+	return kind, nil
+}
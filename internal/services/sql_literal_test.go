@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestCoerceLiteral(t *testing.T) {
+	cases := []struct {
+		value        interface{}
+		declaredType string
+		want         string
+	}{
+		{nil, "integer", "NULL"},
+		{float64(42), "integer", "42"},
+		{-7, "bigint", "-7"},
+		{int64(9), "SMALLINT", "9"},
+		{" 12 ", "int4", "12"},
+		{float64(1.5), "numeric(10, 2)", "1.5"},
+		{"-0.25e3", "double precision", "-0.25e3"},
+		{3, "real", "3"},
+		{true, "boolean", "TRUE"},
+		{"FALSE", "bool", "FALSE"},
+		{"hello", "varchar(50)", "'hello'"},
+		{"it's", "text", "'it''s'"},
+		{`C:\path`, "text", `'C:\path'`},
+		{`{"a": 1}`, "jsonb", `'{"a": 1}'`},
+		{"1 day", "interval", "'1 day'"},
+		{"2024-05-17 10:00:00+02", "timestamp with time zone", "'2024-05-17 10:00:00+02'"},
+		{"now()", "timestamptz", "NOW()"},
+		{"current_timestamp", "TIMESTAMP WITHOUT TIME ZONE", "CURRENT_TIMESTAMP"},
+		{"current_date", "date", "CURRENT_DATE"},
+		{"gen_random_uuid()", "uuid", "gen_random_uuid()"},
+		{"6f1c1d0e-2b9a-4c8e-9a4b-1f2e3d4c5b6a", "uuid", "'6f1c1d0e-2b9a-4c8e-9a4b-1f2e3d4c5b6a'"},
+	}
+	for _, tc := range cases {
+		got, err := coerceLiteral(tc.value, tc.declaredType)
+		if err != nil || got != tc.want {
+			t.Errorf("coerceLiteral(%#v, %q) = %q, %v, want %q", tc.value, tc.declaredType, got, err, tc.want)
+		}
+	}
+}
+
+// Injection attempts for each kind either end up inside a quoted string or are rejected
+func TestCoerceLiteralInjection(t *testing.T) {
+	quoted := []struct {
+		value        string
+		declaredType string
+		want         string
+	}{
+		{"'; DROP TABLE users; --", "text", "'''; DROP TABLE users; --'"},
+		{"x' OR '1'='1", "varchar(20)", "'x'' OR ''1''=''1'"},
+		{"'')--", "char(5)", "''''')--'"},
+		{"{\"a\": \"'); DROP TABLE t; --\"}", "json", "'{\"a\": \"''); DROP TABLE t; --\"}'"},
+		{"2024-01-01'::date; DROP TABLE t; --", "date", "'2024-01-01''::date; DROP TABLE t; --'"},
+		{"1 day'; DELETE FROM t; --", "interval", "'1 day''; DELETE FROM t; --'"},
+		{"gen_random_uuid()", "text", "'gen_random_uuid()'"},
+	}
+	for _, tc := range quoted {
+		got, err := coerceLiteral(tc.value, tc.declaredType)
+		if err != nil || got != tc.want {
+			t.Errorf("coerceLiteral(%q, %q) = %q, %v, want %q", tc.value, tc.declaredType, got, err, tc.want)
+		}
+	}
+
+	rejected := []struct {
+		value        interface{}
+		declaredType string
+	}{
+		{"1; DROP TABLE users", "integer"},
+		{"1 OR 1=1", "bigint"},
+		{"0x10", "integer"},
+		{"99999999999999999999", "bigint"},
+		{float64(1.5), "integer"},
+		{float64(1 << 60), "bigint"},
+		{"1.0); DROP TABLE t; --", "numeric"},
+		{"NaN", "numeric"},
+		{"random()", "double precision"},
+		{"true; DROP TABLE t", "boolean"},
+		{"1", "boolean"},
+		{1, "boolean"},
+		{"now(); DROP TABLE t", "uuid"},
+		{"6f1c1d0e-2b9a-4c8e-9a4b-1f2e3d4c5b6a'; --", "uuid"},
+		{true, "text"},
+		{float64(5), "timestamp"},
+		{"a\x00b", "text"},
+		{"x", "text); DROP TABLE t; --"},
+		{"x", "integer[]"},
+		{"x", "bytea"},
+	}
+	for _, tc := range rejected {
+		if got, err := coerceLiteral(tc.value, tc.declaredType); err == nil {
+			t.Errorf("coerceLiteral(%#v, %q) = %q, want an error", tc.value, tc.declaredType, got)
+		}
+	}
+}
+
+// Quoted literals read back as the original value in a real DEFAULT clause
+func TestCoerceLiteralRoundTrips(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	values := []string{"plain", "it's", "'; DROP TABLE users; --", `back\slash`, "x' OR '1'='1", "$$dollar$$"}
+	for i, value := range values {
+		literal, err := coerceLiteral(value, "text")
+		if err != nil {
+			t.Fatalf("coerceLiteral(%q): %v", value, err)
+		}
+		table := fmt.Sprintf("defaults_%d", i)
+		env.exec(t, user, project,
+			fmt.Sprintf("CREATE TABLE %s (id int, v text DEFAULT %s)", table, literal),
+			fmt.Sprintf("INSERT INTO %s (id) VALUES (1)", table))
+
+		var got string
+		if err := env.projectDB(t, user, project).QueryRowContext(context.Background(), "SELECT v FROM "+table).Scan(&got); err != nil {
+			t.Fatalf("read default of %q: %v", value, err)
+		}
+		if got != value {
+			t.Errorf("default read back as %q, want %q", got, value)
+		}
+	}
+}
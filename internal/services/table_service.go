@@ -1,6 +1,8 @@
 package services
 
 import (
+	"backend/internal/config"
+	"backend/internal/models"
 	"backend/internal/repositories"
 	"backend/internal/utils"
 	"context"
@@ -10,8 +12,10 @@ import (
 	_ "log"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type TableService struct {
@@ -20,7 +24,10 @@ type TableService struct {
 	credentialsRepo *repositories.DatabaseCredentialRepository
 	executeRepo     *repositories.QueryHistoryRepository
 	tableRepo       *repositories.TableRepository
-	orchestrator    *OrchestratorService
+	orchestrator    Orchestrator
+	ddlAudit        *DDLAuditService
+	limits          config.TableLimits
+	connections     *ConnectionManager
 }
 
 func NewTableService(
@@ -29,7 +36,10 @@ func NewTableService(
 	credentialsRepo *repositories.DatabaseCredentialRepository,
 	executeRepo *repositories.QueryHistoryRepository,
 	tableRepo *repositories.TableRepository,
-	orchestrator *OrchestratorService,
+	orchestrator Orchestrator,
+	ddlAudit *DDLAuditService,
+	limits config.TableLimits,
+	connections *ConnectionManager,
 ) *TableService {
 	return &TableService{
 		projectRepo:     projectRepo,
@@ -38,6 +48,9 @@ func NewTableService(
 		executeRepo:     executeRepo,
 		tableRepo:       tableRepo,
 		orchestrator:    orchestrator,
+		ddlAudit:        ddlAudit,
+		limits:          limits,
+		connections:     connections,
 	}
 }
 
@@ -54,38 +67,587 @@ type Column struct {
 type ForeignKeyRef struct {
 	LocalColumn   string `json:"local_column" binding:"required"`
 	ForeignColumn string `json:"foreign_column" binding:"required"`
-	OnUpdate      string `json:"on_update" binding:"omitempty, oneof=CASCADE RESTRICT NO ACTION"`
-	OnDelete      string `json:"on_delete" binding:"omitempty, oneof=CASCADE RESTRICT NO ACTION SET NULL SET DEFAULT"`
+	OnUpdate      string `json:"on_update" binding:"omitempty,oneof=CASCADE RESTRICT 'NO ACTION' 'SET NULL' 'SET DEFAULT'"`
+	OnDelete      string `json:"on_delete" binding:"omitempty,oneof=CASCADE RESTRICT 'NO ACTION' 'SET NULL' 'SET DEFAULT'"`
 }
 
+// ForeignKey references one table. Each reference is its own constraint, unless Composite
+// is set: then all references form a single constraint over their columns, in order, and
+// must share their actions.
 type ForeignKey struct {
 	Schema     string          `json:"schema" binding:"required"`
 	Table      string          `json:"table" binding:"required"`
-	References []ForeignKeyRef `json:"references" binding:"required, min=1"`
+	References []ForeignKeyRef `json:"references" binding:"required,min=1,dive"`
+	Composite  bool            `json:"composite"`
 }
 
+// CreateTableRequest represents the request body for creating a table. Besides the keys of
+// its columns, a table may have CHECK constraints, each a boolean expression, and UNIQUE
+// constraints over several columns.
 type CreateTableRequest struct {
-	Schema      string      `json:"schema" binding:"required"`
-	Table       string      `json:"table" binding:"required"`
-	Columns     []Column    `json:"columns" binding:"required"`
+	Schema            string       `json:"schema" binding:"required"`
+	Table             string       `json:"table" binding:"required"`
+	Columns           []Column     `json:"columns" binding:"required"`
+	ForeignKeys       []ForeignKey `json:"foreign_keys" binding:"omitempty,dive"`
+	Checks            []string     `json:"checks"`
+	UniqueConstraints [][]string   `json:"unique_constraints"`
+}
+
+type UpdateTableRequest struct {
+	Schema      string      `json:"schema"`
+	Table       string      `json:"table"`
+	Columns     []Column    `json:"columns"`
 	ForeignKeys *ForeignKey `json:"foreign_keys"`
 }
 
-type UpdateTableRequest struct {
-	Schema      string      `json:"schema"`
-	Table       string      `json:"table"`
-	Columns     []Column    `json:"columns"`
-	ForeignKeys *ForeignKey `json:"foreign_keys"`
-}
+// DeleteTableRequest represents the request body for dropping a table. A table holding
+// rows is only dropped when ConfirmRowCount matches its current row count or Force is set.
+type DeleteTableRequest struct {
+	Schema          string `json:"schema" binding:"required"`
+	Table           string `json:"table" binding:"required"`
+	ConfirmRowCount *int64 `json:"confirm_row_count"`
+	Force           bool   `json:"force"`
+}
+
+// CreateIndexRequest represents the request body for creating an index. Either Columns or
+// Expression must be given; Where turns it into a partial index. Table may come from the URL.
+type CreateIndexRequest struct {
+	Schema     string   `json:"schema"`
+	Table      string   `json:"table"`
+	Name       string   `json:"name"` // Generated by PostgreSQL when empty
+	Columns    []string `json:"columns"`
+	Expression string   `json:"expression"`
+	Where      string   `json:"where"`
+	Unique     bool     `json:"unique"`
+}
+
+// RenameTableRequest represents the request body for renaming a table. Table is taken
+// from the URL.
+type RenameTableRequest struct {
+	Schema  string `json:"schema"`
+	Table   string `json:"-"`
+	NewName string `json:"name" binding:"required"`
+}
+
+// RenameColumnRequest represents the request body for renaming a column. Table and
+// Column are taken from the URL.
+type RenameColumnRequest struct {
+	Schema  string `json:"schema"`
+	Table   string `json:"-"`
+	Column  string `json:"-"`
+	NewName string `json:"name" binding:"required"`
+}
+
+// DropIndexRequest identifies an index to drop. Table and Name are taken from the URL.
+type DropIndexRequest struct {
+	Schema string
+	Table  string
+	Name   string
+}
+
+// AddForeignKeyRequest represents the request body for adding foreign keys to an existing
+// table. As when creating a table, each reference becomes its own constraint.
+type AddForeignKeyRequest struct {
+	Schema     string     `json:"schema"`
+	Table      string     `json:"table" binding:"required"`
+	ForeignKey ForeignKey `json:"foreign_key" binding:"required"`
+}
+
+// ForeignKeyViolation reports the rows of a table whose value has no matching referenced row
+type ForeignKeyViolation struct {
+	LocalColumn   string       `json:"local_column"`
+	ForeignColumn string       `json:"foreign_column"`
+	RowCount      int64        `json:"row_count"`
+	Sample        *QueryResult `json:"sample"` // The first offending rows, at most foreignKeySampleSize
+}
+
+// ForeignKeyValidation is the result of checking a table's rows against foreign keys
+// before adding them, together with the statement that adds them
+type ForeignKeyValidation struct {
+	Valid      bool                  `json:"valid"`
+	SQL        string                `json:"sql"`
+	Violations []ForeignKeyViolation `json:"violations"`
+}
+
+// ForeignKeyViolationError is returned when foreign keys are added to a table holding rows
+// that violate them
+type ForeignKeyViolationError struct {
+	Validation *ForeignKeyValidation
+}
+
+func (e *ForeignKeyViolationError) Error() string {
+	return fmt.Sprintf("existing rows violate %d of the foreign key references", len(e.Validation.Violations))
+}
+
+// RowCountConfirmationError is returned when a table holding data is dropped without
+// confirming its current row count
+type RowCountConfirmationError struct {
+	RowCount int64
+}
+
+func (e *RowCountConfirmationError) Error() string {
+	return fmt.Sprintf("table contains %d rows: confirm_row_count must match to drop it", e.RowCount)
+}
+
+func (s *TableService) CreateTable(req *CreateTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	// Validate request
+	if err := s.validateCreateTableRequest(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start transaction
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check the referenced table and columns up front so a typo yields a clear error
+	if err := s.validateForeignKeyTarget(tx, req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	query, err := s.parseCreateQuery(req)
+	if err != nil {
+		return nil, err
+	}
+
+	target := req.Schema + "." + req.Table
+	result, err := tx.Exec(query)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationCreateTable, target, query, err)
+		return nil, fmt.Errorf("failed to create table: %w", err)
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationCreateTable, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ListTables returns the tables of a schema with their column count and estimated row count
+func (s *TableService) ListTables(userId uuid.UUID, projectId uuid.UUID, schema string) ([]models.TableSummary, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if !isValidIdentifier(schema) {
+		return nil, errors.New("invalid schema name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return s.tableRepo.ListTables(tx, schema)
+}
+
+// Page sizes for browsing table rows
+const (
+	defaultTableRowsLimit = 100
+	maxTableRowsLimit     = 1000
+)
+
+// GetTableRows returns a page of a table's rows. orderBy must name a column of the table;
+// without it rows come back in no particular order.
+func (s *TableService) GetTableRows(userId uuid.UUID, projectId uuid.UUID, schema string, table string, limit int, offset int, orderBy string) (*QueryResult, error) {
+	if schema == "" {
+		schema = "public"
+	}
+	if !isValidIdentifier(schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(table) {
+		return nil, errors.New("invalid table name")
+	}
+	if orderBy != "" && !isValidIdentifier(orderBy) {
+		return nil, errors.New("invalid order_by column")
+	}
+	if limit == 0 {
+		limit = defaultTableRowsLimit
+	}
+	if limit < 0 || limit > maxTableRowsLimit {
+		return nil, fmt.Errorf("invalid limit: must be between 1 and %d", maxTableRowsLimit)
+	}
+	if offset < 0 {
+		return nil, errors.New("invalid offset: must not be negative")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exists, err := s.tableRepo.TableExists(tx, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("table not found")
+	}
+
+	query := fmt.Sprintf("SELECT * FROM \"%s\".\"%s\"", schema, table)
+	if orderBy != "" {
+		// Only an existing column may be used, so the name never reaches the query unchecked
+		columnExists, _, err := s.tableRepo.ColumnKeyInfo(tx, schema, table, orderBy)
+		if err != nil {
+			return nil, err
+		}
+		if !columnExists {
+			return nil, fmt.Errorf("invalid order_by column: %s does not exist", orderBy)
+		}
+		query += fmt.Sprintf(" ORDER BY \"%s\"", orderBy)
+	}
+	query += " LIMIT $1 OFFSET $2"
+
+	start := time.Now()
+	rows, err := tx.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := readQueryResult(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	result.RowsAffected = 0
+	result.ExecutionTime = time.Since(start).Milliseconds()
+
+	return result, nil
+}
+
+// PreviewCreateTable validates a create table request and returns the statement CreateTable
+// would run, without connecting to the instance. Foreign key targets are not checked, since
+// that needs the database.
+func (s *TableService) PreviewCreateTable(req *CreateTableRequest) (string, error) {
+	if err := s.validateCreateTableRequest(req); err != nil {
+		return "", fmt.Errorf("validation failed: %w", err)
+	}
+
+	return s.parseCreateQuery(req)
+}
+
+func (s *TableService) DeleteTable(req *DeleteTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	// Validate identifiers
+	if !isValidIdentifier(req.Schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(req.Table) {
+		return nil, errors.New("invalid table name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start transaction
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Make the caller acknowledge how much data is about to be lost
+	if !req.Force {
+		rowCount, err := s.tableRepo.CountRows(tx, req.Schema, req.Table)
+		if err != nil {
+			return nil, err
+		}
+		if rowCount > 0 && (req.ConfirmRowCount == nil || *req.ConfirmRowCount != rowCount) {
+			return nil, &RowCountConfirmationError{RowCount: rowCount}
+		}
+	}
+
+	target := req.Schema + "." + req.Table
+	query := repositories.DropTableQuery(req.Schema, req.Table)
+	result, err := s.tableRepo.Delete(tx, req.Schema, req.Table)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationDropTable, target, query, err)
+		return nil, fmt.Errorf("failed to delete table: %w", err)
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationDropTable, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// RenameTable renames a table within its schema. Views, foreign keys and indexes follow
+// the table, since PostgreSQL tracks them by OID.
+func (s *TableService) RenameTable(req *RenameTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+	if !isValidIdentifier(req.Schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(req.Table) {
+		return nil, errors.New("invalid table name")
+	}
+	if !isValidIdentifier(req.NewName) {
+		return nil, errors.New("invalid new table name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exists, err := s.tableRepo.TableExists(tx, req.Schema, req.Table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("table not found")
+	}
+	taken, err := s.tableRepo.TableExists(tx, req.Schema, req.NewName)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, fmt.Errorf("table %s.%s already exists", req.Schema, req.NewName)
+	}
+
+	target := req.Schema + "." + req.Table
+	query := repositories.RenameTableQuery(req.Schema, req.Table, req.NewName)
+	result, err := s.tableRepo.UpdateTableName(tx, req.Schema, req.Table, req.NewName)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationRenameTable, target, query, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationRenameTable, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// RenameColumn renames a column of a table. Indexes and constraints on the column follow it.
+func (s *TableService) RenameColumn(req *RenameColumnRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+	if !isValidIdentifier(req.Schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(req.Table) {
+		return nil, errors.New("invalid table name")
+	}
+	if !isValidIdentifier(req.Column) {
+		return nil, errors.New("invalid column name")
+	}
+	if !isValidIdentifier(req.NewName) {
+		return nil, errors.New("invalid new column name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exists, err := s.tableRepo.TableExists(tx, req.Schema, req.Table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("table not found")
+	}
+	columnExists, _, err := s.tableRepo.ColumnKeyInfo(tx, req.Schema, req.Table, req.Column)
+	if err != nil {
+		return nil, err
+	}
+	if !columnExists {
+		return nil, errors.New("column not found")
+	}
+	taken, _, err := s.tableRepo.ColumnKeyInfo(tx, req.Schema, req.Table, req.NewName)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, fmt.Errorf("column %s already exists", req.NewName)
+	}
+
+	target := req.Schema + "." + req.Table + "." + req.Column
+	query := repositories.RenameColumnQuery(req.Schema, req.Table, req.Column, req.NewName)
+	result, err := s.tableRepo.UpdateColumnName(tx, req.Schema, req.Table, req.Column, req.NewName)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationRenameColumn, target, query, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationRenameColumn, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CreateIndex creates a plain, expression or partial index on a table
+func (s *TableService) CreateIndex(req *CreateIndexRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	if err := validateCreateIndexRequest(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := parseCreateIndexQuery(req)
+	target := req.Schema + "." + req.Table
+	result, err := tx.Exec(query)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationCreateIndex, target, query, err)
+		// Index names share a namespace with tables, views and sequences of the schema
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P07" {
+			return nil, fmt.Errorf("index %s already exists: a table, view or index of schema %s already has this name", req.Name, req.Schema)
+		}
+		return nil, fmt.Errorf("failed to create index: %w", err)
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationCreateIndex, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// DropIndex drops an index of a table. Indexes backing a primary key, unique or exclusion
+// constraint are refused; the constraint has to be dropped instead.
+func (s *TableService) DropIndex(req *DropIndexRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+	if !isValidIdentifier(req.Schema) {
+		return nil, errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(req.Table) {
+		return nil, errors.New("invalid table name")
+	}
+	if !isValidIdentifier(req.Name) {
+		return nil, errors.New("invalid index name")
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exists, constraint, err := s.tableRepo.IndexInfo(tx, req.Schema, req.Table, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("index not found")
+	}
+	if constraint {
+		return nil, fmt.Errorf("index %s backs a constraint and cannot be dropped on its own", req.Name)
+	}
+
+	target := req.Schema + "." + req.Name
+	query := repositories.DropIndexQuery(req.Schema, req.Name)
+	result, err := s.tableRepo.DropIndex(tx, req.Schema, req.Name)
+	if err != nil {
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationDropIndex, target, query, err)
+		return nil, err
+	}
+
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationDropIndex, target, query, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &result, nil
+}
+
+// foreignKeySampleSize is the number of offending rows returned for each foreign key reference
+const foreignKeySampleSize = 10
 
-type DeleteTableRequest struct {
-	Schema string `json:"schema" binding:"required"`
-	Table  string `json:"table" binding:"required"`
+// ValidateForeignKey checks the rows of a table against foreign keys before they are added,
+// reporting for each reference how many rows have no matching referenced row and a sample
+// of them. Nothing is changed.
+func (s *TableService) ValidateForeignKey(userId uuid.UUID, projectId uuid.UUID, req *AddForeignKeyRequest) (*ForeignKeyValidation, error) {
+	if err := s.validateAddForeignKeyRequest(req); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	sqlDb, err := s.openDbConnection(userId, projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDb.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return s.checkForeignKeyData(tx, req)
 }
 
-func (s *TableService) CreateTable(req *CreateTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
-	// Validate request
-	if err := s.validateCreateTableRequest(req); err != nil {
+// AddForeignKey adds foreign keys to an existing table. The rows are checked first, so data
+// violating the constraints yields a ForeignKeyViolationError listing the offending rows
+// instead of a raw constraint error.
+func (s *TableService) AddForeignKey(userId uuid.UUID, projectId uuid.UUID, req *AddForeignKeyRequest) (*sql.Result, error) {
+	if err := s.validateAddForeignKeyRequest(req); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
@@ -93,64 +655,136 @@ func (s *TableService) CreateTable(req *CreateTableRequest, userId uuid.UUID, pr
 	if err != nil {
 		return nil, err
 	}
-	defer sqlDb.Close()
 
-	// Start transaction
 	tx, err := sqlDb.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("failed to start transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	query, err := s.parseCreateQuery(req)
+	validation, err := s.checkForeignKeyData(tx, req)
 	if err != nil {
 		return nil, err
 	}
+	if !validation.Valid {
+		return nil, &ForeignKeyViolationError{Validation: validation}
+	}
 
+	query := validation.SQL
+	target := req.Schema + "." + req.Table
 	result, err := tx.Exec(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
+		s.ddlAudit.Record(userId, projectId, models.DDLOperationAddForeignKey, target, query, err)
+		return nil, fmt.Errorf("failed to add foreign key: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
+	err = tx.Commit()
+	s.ddlAudit.Record(userId, projectId, models.DDLOperationAddForeignKey, target, query, err)
+	if err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return &result, nil
 }
 
-func (s *TableService) DeleteTable(req *DeleteTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
-	// Validate identifiers
+// validateAddForeignKeyRequest validates the add foreign key request
+func (s *TableService) validateAddForeignKeyRequest(req *AddForeignKeyRequest) error {
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+
 	if !isValidIdentifier(req.Schema) {
-		return nil, errors.New("invalid schema name")
+		return errors.New("invalid schema name")
 	}
 	if !isValidIdentifier(req.Table) {
-		return nil, errors.New("invalid table name")
+		return errors.New("invalid table name")
+	}
+	if len(req.ForeignKey.References) == 0 {
+		return errors.New("at least one foreign key reference is required")
+	}
+	// The rows are checked one column at a time, which cannot judge a composite key
+	if req.ForeignKey.Composite {
+		return errors.New("composite foreign keys can only be declared when creating a table")
 	}
 
-	sqlDb, err := s.openDbConnection(userId, projectId)
+	return s.validateForeignKey(&req.ForeignKey)
+}
+
+// checkForeignKeyData checks that the table, its local columns and the referenced columns
+// exist, then looks for rows whose non-null local value has no matching referenced row
+func (s *TableService) checkForeignKeyData(tx *sql.Tx, req *AddForeignKeyRequest) (*ForeignKeyValidation, error) {
+	exists, err := s.tableRepo.TableExists(tx, req.Schema, req.Table)
 	if err != nil {
 		return nil, err
 	}
-	defer sqlDb.Close()
+	if !exists {
+		return nil, errors.New("table not found")
+	}
 
-	// Start transaction
-	tx, err := sqlDb.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	fk := &req.ForeignKey
+	for _, ref := range fk.References {
+		columnExists, _, err := s.tableRepo.ColumnKeyInfo(tx, req.Schema, req.Table, ref.LocalColumn)
+		if err != nil {
+			return nil, err
+		}
+		if !columnExists {
+			return nil, fmt.Errorf("validation failed: column %s does not exist in %s.%s", ref.LocalColumn, req.Schema, req.Table)
+		}
+	}
+	if err := s.checkForeignKeyTarget(tx, fk); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
 	}
-	defer tx.Rollback()
 
-	result, err := s.tableRepo.Delete(tx, req.Schema, req.Table)
-	if err != nil {
-		return nil, fmt.Errorf("failed to delete table: %w", err)
+	validation := &ForeignKeyValidation{
+		Valid:      true,
+		SQL:        parseAddForeignKeyQuery(req),
+		Violations: []ForeignKeyViolation{},
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	for _, ref := range fk.References {
+		orphans := fmt.Sprintf(
+			"FROM \"%s\".\"%s\" l WHERE l.\"%s\" IS NOT NULL AND NOT EXISTS (SELECT 1 FROM \"%s\".\"%s\" f WHERE f.\"%s\" = l.\"%s\")",
+			req.Schema, req.Table, ref.LocalColumn, fk.Schema, fk.Table, ref.ForeignColumn, ref.LocalColumn,
+		)
+
+		var count int64
+		if err := tx.QueryRow("SELECT count(*) " + orphans).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to check rows against %s: %w", ref.LocalColumn, err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		rows, err := tx.Query("SELECT l.* "+orphans+" LIMIT $1", foreignKeySampleSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rows violating %s: %w", ref.LocalColumn, err)
+		}
+		sample, err := readQueryResult(rows)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rows violating %s: %w", ref.LocalColumn, err)
+		}
+		sample.RowsAffected = 0
+
+		validation.Valid = false
+		validation.Violations = append(validation.Violations, ForeignKeyViolation{
+			LocalColumn:   ref.LocalColumn,
+			ForeignColumn: ref.ForeignColumn,
+			RowCount:      count,
+			Sample:        sample,
+		})
 	}
 
-	return &result, nil
+	return validation, nil
+}
+
+// parseAddForeignKeyQuery builds the ALTER TABLE statement adding the foreign keys of a validated request
+func parseAddForeignKeyQuery(req *AddForeignKeyRequest) string {
+	clauses := foreignKeyClauses(&req.ForeignKey)
+	for i := range clauses {
+		clauses[i] = "ADD " + clauses[i]
+	}
+	return fmt.Sprintf("ALTER TABLE \"%s\".\"%s\" %s", req.Schema, req.Table, strings.Join(clauses, ", "))
 }
 
 // func (s *TableService) UpdateTable(req *UpdateTableRequest, userId uuid.UUID, projectId uuid.UUID) (*sql.Result, error) {
@@ -169,8 +803,8 @@ func (s *TableService) parseCreateQuery(req *CreateTableRequest) (string, error)
 	}
 
 	// Use quoted identifiers to prevent SQL injection
-	query := fmt.Sprintf("CREATE TABLE \"%s\".\"%s\" (\n", req.Schema, req.Table)
-	for i, col := range req.Columns {
+	var definitions []string
+	for _, col := range req.Columns {
 		columnDef := fmt.Sprintf("  \"%s\" %s", col.Name, col.Type)
 
 		if col.IsIdentity {
@@ -190,43 +824,35 @@ func (s *TableService) parseCreateQuery(req *CreateTableRequest) (string, error)
 		}
 
 		if col.Default != nil && *col.Default != "" {
-			columnDef += fmt.Sprintf(" DEFAULT %s", *col.Default)
-		}
-
-		// Add comma for all but last column, or if FK exists
-		if i < len(req.Columns)-1 || (req.ForeignKeys != nil && len(req.ForeignKeys.References) > 0) {
-			columnDef += ","
+			literal, err := coerceLiteral(*col.Default, col.Type)
+			if err != nil {
+				return "", fmt.Errorf("invalid default for column %s: %w", col.Name, err)
+			}
+			columnDef += " DEFAULT " + literal
 		}
 
-		query += columnDef + "\n"
+		definitions = append(definitions, columnDef)
 	}
 
-	if req.ForeignKeys != nil && len(req.ForeignKeys.References) > 0 {
-		for i, fk := range req.ForeignKeys.References {
-			fkDef := fmt.Sprintf("  FOREIGN KEY (\"%s\") REFERENCES \"%s\".\"%s\"(\"%s\")",
-				fk.LocalColumn,
-				req.ForeignKeys.Schema,
-				req.ForeignKeys.Table,
-				fk.ForeignColumn,
-			)
-
-			if fk.OnDelete != "" {
-				fkDef += " ON DELETE " + fk.OnDelete
-			}
-
-			if fk.OnUpdate != "" {
-				fkDef += " ON UPDATE " + fk.OnUpdate
-			}
+	for _, columns := range req.UniqueConstraints {
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = fmt.Sprintf("\"%s\"", col)
+		}
+		definitions = append(definitions, "  UNIQUE ("+strings.Join(quoted, ", ")+")")
+	}
 
-			// No comma on last FK
-			if i < len(req.ForeignKeys.References)-1 {
-				fkDef += ","
-			}
+	for _, check := range req.Checks {
+		definitions = append(definitions, "  CHECK ("+check+")")
+	}
 
-			query += fkDef + "\n"
+	for i := range req.ForeignKeys {
+		for _, clause := range foreignKeyClauses(&req.ForeignKeys[i]) {
+			definitions = append(definitions, "  "+clause)
 		}
 	}
-	query += ");\n"
+
+	query := fmt.Sprintf("CREATE TABLE \"%s\".\"%s\" (\n%s\n);\n", req.Schema, req.Table, strings.Join(definitions, ",\n"))
 
 	return query, nil
 
@@ -277,6 +903,48 @@ func (s *TableService) parseCreateQuery(req *CreateTableRequest) (string, error)
 	*/
 }
 
+// foreignKeyClauses builds the FOREIGN KEY constraints of a foreign key: one per reference,
+// or a single one over all columns for a composite key
+func foreignKeyClauses(fk *ForeignKey) []string {
+	if fk.Composite {
+		return []string{foreignKeyClause(fk, fk.References)}
+	}
+
+	clauses := make([]string, len(fk.References))
+	for i := range fk.References {
+		clauses[i] = foreignKeyClause(fk, fk.References[i:i+1])
+	}
+	return clauses
+}
+
+// foreignKeyClause builds the FOREIGN KEY constraint over the given references. The
+// actions are those of the first reference, which all references share.
+func foreignKeyClause(fk *ForeignKey, refs []ForeignKeyRef) string {
+	local := make([]string, len(refs))
+	foreign := make([]string, len(refs))
+	for i, ref := range refs {
+		local[i] = fmt.Sprintf("\"%s\"", ref.LocalColumn)
+		foreign[i] = fmt.Sprintf("\"%s\"", ref.ForeignColumn)
+	}
+
+	clause := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES \"%s\".\"%s\"(%s)",
+		strings.Join(local, ", "),
+		fk.Schema,
+		fk.Table,
+		strings.Join(foreign, ", "),
+	)
+
+	if refs[0].OnDelete != "" {
+		clause += " ON DELETE " + refs[0].OnDelete
+	}
+
+	if refs[0].OnUpdate != "" {
+		clause += " ON UPDATE " + refs[0].OnUpdate
+	}
+
+	return clause
+}
+
 // isValidIdentifier checks if a string is a valid PostgreSQL identifier
 func isValidIdentifier(name string) bool {
 	if name == "" || len(name) > 63 {
@@ -303,6 +971,9 @@ func (s *TableService) validateCreateTableRequest(req *CreateTableRequest) error
 	if len(req.Columns) == 0 {
 		return errors.New("at least one column is required")
 	}
+	if len(req.Columns) > s.limits.MaxColumns {
+		return fmt.Errorf("too many columns: %d given, at most %d are allowed", len(req.Columns), s.limits.MaxColumns)
+	}
 
 	// Validate column names and types
 	for i, col := range req.Columns {
@@ -316,26 +987,414 @@ func (s *TableService) validateCreateTableRequest(req *CreateTableRequest) error
 		if !isValidColumnType(col.Type) {
 			return fmt.Errorf("invalid column type for %s: %s", col.Name, col.Type)
 		}
+		if col.Default != nil && *col.Default != "" {
+			if _, err := coerceLiteral(*col.Default, col.Type); err != nil {
+				return fmt.Errorf("invalid default for column %s: %w", col.Name, err)
+			}
+		}
+	}
+
+	// Table constraints may only name the table's columns
+	for i, columns := range req.UniqueConstraints {
+		if len(columns) == 0 {
+			return fmt.Errorf("unique constraint at index %d has no columns", i)
+		}
+		for _, col := range columns {
+			if !isValidIdentifier(col) {
+				return fmt.Errorf("invalid column name in unique constraint at index %d: %s", i, col)
+			}
+			if findColumn(req.Columns, col) == nil {
+				return fmt.Errorf("unique constraint column %s is not one of the table's columns", col)
+			}
+		}
+	}
+	for i := range req.Checks {
+		req.Checks[i] = strings.TrimSpace(req.Checks[i])
+		if req.Checks[i] == "" {
+			return fmt.Errorf("check constraint at index %d is empty", i)
+		}
+		// Checks are embedded in the DDL, so they get the same scrutiny as index expressions
+		if err := validateSQLExpression(req.Checks[i]); err != nil {
+			return fmt.Errorf("invalid check constraint at index %d: %w", i, err)
+		}
+	}
+
+	// Validate foreign keys if present; the limit applies to their references together
+	references := 0
+	for i := range req.ForeignKeys {
+		references += len(req.ForeignKeys[i].References)
+	}
+	if references > s.limits.MaxForeignKeys {
+		return fmt.Errorf("too many foreign key references: %d given, at most %d are allowed", references, s.limits.MaxForeignKeys)
+	}
+	for i := range req.ForeignKeys {
+		fk := &req.ForeignKeys[i]
+		if len(fk.References) == 0 {
+			return fmt.Errorf("foreign key to %s.%s has no references", fk.Schema, fk.Table)
+		}
+		if err := s.validateForeignKey(fk); err != nil {
+			return err
+		}
+		for _, ref := range fk.References {
+			if findColumn(req.Columns, ref.LocalColumn) == nil {
+				return fmt.Errorf("foreign key column %s is not one of the table's columns", ref.LocalColumn)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateForeignKey checks the names, actions and number of references of a foreign key
+func (s *TableService) validateForeignKey(fk *ForeignKey) error {
+	if len(fk.References) > s.limits.MaxForeignKeys {
+		return fmt.Errorf("too many foreign key references: %d given, at most %d are allowed", len(fk.References), s.limits.MaxForeignKeys)
+	}
+	if !isValidIdentifier(fk.Schema) {
+		return errors.New("invalid foreign key schema name")
+	}
+	if !isValidIdentifier(fk.Table) {
+		return errors.New("invalid foreign key table name")
+	}
+	for _, ref := range fk.References {
+		if !isValidIdentifier(ref.LocalColumn) || !isValidIdentifier(ref.ForeignColumn) {
+			return errors.New("invalid foreign key column name")
+		}
+		// Actions are concatenated into the DDL, so only the known keywords are allowed
+		if ref.OnUpdate != "" && !isValidForeignKeyAction(ref.OnUpdate) {
+			return fmt.Errorf("invalid foreign key ON UPDATE action: %s", ref.OnUpdate)
+		}
+		if ref.OnDelete != "" && !isValidForeignKeyAction(ref.OnDelete) {
+			return fmt.Errorf("invalid foreign key ON DELETE action: %s", ref.OnDelete)
+		}
+		if fk.Composite && (ref.OnUpdate != fk.References[0].OnUpdate || ref.OnDelete != fk.References[0].OnDelete) {
+			return errors.New("the references of a composite foreign key must have the same actions")
+		}
+	}
+	return nil
+}
+
+// validateForeignKeyTarget checks, for every foreign key, that the table and columns it
+// references exist and that they are a primary key or unique. A table referencing itself
+// is checked against the columns being created.
+func (s *TableService) validateForeignKeyTarget(tx *sql.Tx, req *CreateTableRequest) error {
+	for i := range req.ForeignKeys {
+		fk := &req.ForeignKeys[i]
+		if len(fk.References) == 0 {
+			continue
+		}
+
+		if fk.Schema == req.Schema && fk.Table == req.Table {
+			if err := validateSelfReference(req, fk); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := s.checkForeignKeyTarget(tx, fk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSelfReference checks a foreign key of a table to itself against the columns
+// being created
+func validateSelfReference(req *CreateTableRequest, fk *ForeignKey) error {
+	target := fk.Schema + "." + fk.Table
+
+	for _, ref := range fk.References {
+		col := findColumn(req.Columns, ref.ForeignColumn)
+		if col == nil {
+			return fmt.Errorf("foreign key references column %s which does not exist in %s", ref.ForeignColumn, target)
+		}
+		// A composite key is checked as a whole by PostgreSQL, against the table's constraints
+		if !fk.Composite && !col.Primary && !col.IsUnique {
+			return fmt.Errorf("foreign key references column %s of %s which is not a primary key or unique", ref.ForeignColumn, target)
+		}
+	}
+	return nil
+}
+
+// checkForeignKeyTarget checks in the database that the referenced table exists and that
+// each referenced column exists and is a primary key or unique. The columns of a composite
+// key must together match a primary key or unique constraint.
+func (s *TableService) checkForeignKeyTarget(tx *sql.Tx, fk *ForeignKey) error {
+	target := fk.Schema + "." + fk.Table
+
+	exists, err := s.tableRepo.TableExists(tx, fk.Schema, fk.Table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("foreign key references table %s which does not exist", target)
+	}
+
+	columns := make([]string, len(fk.References))
+	for i, ref := range fk.References {
+		columns[i] = ref.ForeignColumn
+
+		exists, unique, err := s.tableRepo.ColumnKeyInfo(tx, fk.Schema, fk.Table, ref.ForeignColumn)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("foreign key references column %s which does not exist in %s", ref.ForeignColumn, target)
+		}
+		if !unique && !fk.Composite {
+			return fmt.Errorf("foreign key references column %s of %s which is not a primary key or unique", ref.ForeignColumn, target)
+		}
+	}
+
+	if fk.Composite {
+		unique, err := s.tableRepo.ColumnsUnique(tx, fk.Schema, fk.Table, columns)
+		if err != nil {
+			return err
+		}
+		if !unique {
+			return fmt.Errorf("foreign key references columns (%s) of %s which are not a primary key or unique together", strings.Join(columns, ", "), target)
+		}
+	}
+
+	return nil
+}
+
+// findColumn returns the column with the given name, or nil
+func findColumn(columns []Column, name string) *Column {
+	for i := range columns {
+		if columns[i].Name == name {
+			return &columns[i]
+		}
+	}
+	return nil
+}
+
+// validateCreateIndexRequest validates the create index request
+func validateCreateIndexRequest(req *CreateIndexRequest) error {
+	if req.Schema == "" {
+		req.Schema = "public"
+	}
+
+	if !isValidIdentifier(req.Schema) {
+		return errors.New("invalid schema name")
+	}
+	if !isValidIdentifier(req.Table) {
+		return errors.New("invalid table name")
+	}
+	if req.Name != "" && !isValidIdentifier(req.Name) {
+		return errors.New("invalid index name")
+	}
+
+	req.Expression = strings.TrimSpace(req.Expression)
+	req.Where = strings.TrimSpace(req.Where)
+
+	if (len(req.Columns) == 0) == (req.Expression == "") {
+		return errors.New("exactly one of columns or expression is required")
+	}
+	for _, col := range req.Columns {
+		if !isValidIdentifier(col) {
+			return fmt.Errorf("invalid column name: %s", col)
+		}
+	}
+	if req.Expression != "" {
+		if err := validateSQLExpression(req.Expression); err != nil {
+			return fmt.Errorf("invalid expression: %w", err)
+		}
+	}
+	if req.Where != "" {
+		if err := validateSQLExpression(req.Where); err != nil {
+			return fmt.Errorf("invalid where predicate: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseCreateIndexQuery builds the CREATE INDEX statement for a validated request
+func parseCreateIndexQuery(req *CreateIndexRequest) string {
+	query := "CREATE "
+	if req.Unique {
+		query += "UNIQUE "
+	}
+	query += "INDEX "
+	if req.Name != "" {
+		query += fmt.Sprintf("\"%s\" ", req.Name)
+	}
+	query += fmt.Sprintf("ON \"%s\".\"%s\" ", req.Schema, req.Table)
+
+	if req.Expression != "" {
+		// Expressions must be wrapped in their own parentheses
+		query += fmt.Sprintf("((%s))", req.Expression)
+	} else {
+		quoted := make([]string, len(req.Columns))
+		for i, col := range req.Columns {
+			quoted[i] = fmt.Sprintf("\"%s\"", col)
+		}
+		query += "(" + strings.Join(quoted, ", ") + ")"
+	}
+
+	if req.Where != "" {
+		query += " WHERE " + req.Where
+	}
+
+	return query
+}
+
+// Tokens allowed in index expressions and predicates
+var (
+	expressionOperators = []string{"<=", ">=", "<>", "!=", "||", "::", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ",", "."}
+
+	// Keywords that may appear in an expression; any other bare word is a column or type name
+	expressionKeywords = map[string]bool{
+		"and": true, "or": true, "not": true, "is": true, "null": true, "true": true, "false": true,
+		"in": true, "between": true, "like": true, "ilike": true, "distinct": true, "from": true,
+		"case": true, "when": true, "then": true, "else": true, "end": true,
+	}
+
+	// Words that would start another statement or a subquery
+	expressionForbiddenWords = map[string]bool{
+		"select": true, "insert": true, "update": true, "delete": true, "drop": true, "alter": true,
+		"create": true, "truncate": true, "grant": true, "revoke": true, "union": true, "with": true,
+		"into": true, "copy": true, "execute": true, "do": true,
 	}
 
-	// Validate foreign keys if present
-	if req.ForeignKeys != nil {
-		if !isValidIdentifier(req.ForeignKeys.Schema) {
-			return errors.New("invalid foreign key schema name")
+	// Immutable functions that may be called in an index expression or predicate
+	expressionFunctions = map[string]bool{
+		"lower": true, "upper": true, "btrim": true, "ltrim": true, "rtrim": true, "length": true,
+		"abs": true, "coalesce": true, "nullif": true, "left": true, "right": true, "substr": true,
+		"date_trunc": true, "md5": true,
+	}
+)
+
+// validateSQLExpression checks that an index expression or predicate consists only of
+// column names, literals, allowlisted keywords, functions and operators, so it can be
+// embedded in DDL without allowing another statement or a comment to be smuggled in
+func validateSQLExpression(expr string) error {
+	if strings.Contains(expr, ";") {
+		return errors.New("semicolons are not allowed")
+	}
+	if strings.Contains(expr, "--") || strings.Contains(expr, "/*") {
+		return errors.New("comments are not allowed")
+	}
+
+	depth := 0
+	prevWord := ""     // Name just before the current token, to recognise function calls
+	afterCast := false // Whether prevWord is a type name following ::
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+
+		case c == '\'':
+			// String literal, with '' as the only escape
+			j := i + 1
+			for {
+				if j >= len(expr) {
+					return errors.New("unterminated string literal")
+				}
+				if expr[j] == '\'' {
+					if j+1 < len(expr) && expr[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				if expr[j] == '\\' {
+					return errors.New("backslashes are not allowed in string literals")
+				}
+				j++
+			}
+			i = j + 1
+			prevWord = ""
+			afterCast = false
+			continue
+
+		case c == '"':
+			end := strings.IndexByte(expr[i+1:], '"')
+			if end < 0 {
+				return errors.New("unterminated quoted identifier")
+			}
+			if !isValidIdentifier(expr[i+1 : i+1+end]) {
+				return fmt.Errorf("invalid quoted identifier: %s", expr[i:i+2+end])
+			}
+			i += end + 2
+			prevWord = expr[i-end-2 : i] // Quoted names cannot be called as functions
+			afterCast = false
+			continue
+
+		case c >= '0' && c <= '9':
+			for i < len(expr) && ((expr[i] >= '0' && expr[i] <= '9') || expr[i] == '.') {
+				i++
+			}
+			prevWord = ""
+			afterCast = false
+			continue
+
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i
+			for j < len(expr) && (expr[j] == '_' || (expr[j] >= 'a' && expr[j] <= 'z') ||
+				(expr[j] >= 'A' && expr[j] <= 'Z') || (expr[j] >= '0' && expr[j] <= '9')) {
+				j++
+			}
+			word := strings.ToLower(expr[i:j])
+			if expressionForbiddenWords[word] {
+				return fmt.Errorf("keyword not allowed: %s", word)
+			}
+			afterCast = strings.HasSuffix(strings.TrimRight(expr[:i], " \t\n\r"), "::")
+			i = j
+			prevWord = word
+			continue
+		}
+
+		op := ""
+		for _, candidate := range expressionOperators {
+			if strings.HasPrefix(expr[i:], candidate) {
+				op = candidate
+				break
+			}
 		}
-		if !isValidIdentifier(req.ForeignKeys.Table) {
-			return errors.New("invalid foreign key table name")
+		if op == "" {
+			return fmt.Errorf("character not allowed: %q", c)
 		}
-		for _, ref := range req.ForeignKeys.References {
-			if !isValidIdentifier(ref.LocalColumn) || !isValidIdentifier(ref.ForeignColumn) {
-				return errors.New("invalid foreign key column name")
+
+		switch op {
+		case "(":
+			// A word directly followed by a parenthesis is a function call, unless it is
+			// a type modifier such as ::numeric(10, 2)
+			if prevWord != "" && !afterCast && !expressionKeywords[prevWord] && !expressionFunctions[prevWord] {
+				return fmt.Errorf("function not allowed: %s", prevWord)
+			}
+			depth++
+		case ")":
+			depth--
+			if depth < 0 {
+				return errors.New("unbalanced parentheses")
 			}
 		}
+		i += len(op)
+		prevWord = ""
+		afterCast = false
+	}
+
+	if depth != 0 {
+		return errors.New("unbalanced parentheses")
 	}
 
 	return nil
 }
 
+// isValidForeignKeyAction reports whether action is a referential action PostgreSQL accepts
+func isValidForeignKeyAction(action string) bool {
+	switch action {
+	case "CASCADE", "RESTRICT", "NO ACTION", "SET NULL", "SET DEFAULT":
+		return true
+	}
+	return false
+}
+
 // isValidColumnType validates PostgreSQL column types
 func isValidColumnType(colType string) bool {
 	// Convert to uppercase for comparison
@@ -358,6 +1417,7 @@ func isValidColumnType(colType string) bool {
 	return false
 }
 
+// openDbConnection returns the pooled connection to the project's running instance. It is shared, so callers must not close it.
 func (s *TableService) openDbConnection(userId uuid.UUID, projectId uuid.UUID) (*sql.DB, error) {
 	project, err := s.projectRepo.GetByIDAndUserID(projectId, userId)
 	if err != nil {
@@ -366,6 +1426,9 @@ func (s *TableService) openDbConnection(userId uuid.UUID, projectId uuid.UUID) (
 	if project == nil {
 		return nil, errors.New("project not found or not accessible")
 	}
+	if err := requirePostgres(project); err != nil {
+		return nil, err
+	}
 
 	dbInstance, err := s.instanceRepo.GetRunningByProjectID(projectId)
 	if err != nil {
@@ -386,19 +1449,11 @@ func (s *TableService) openDbConnection(userId uuid.UUID, projectId uuid.UUID) (
 	if dbInstance.ContainerID == nil || *dbInstance.ContainerID == "" {
 		return nil, errors.New("database instance container ID not configured")
 	}
-	if dbInstance.Port == nil {
-		return nil, errors.New("database instance port not configured")
-	}
 
 	// Get container IP from orchestrator
-	containerIP, ok := s.orchestrator.GetContainerIP(*dbInstance.ContainerID)
-	if !ok {
-		// Try to get from Redis as fallback
-		var err error
-		containerIP, err = s.orchestrator.GetContainerIPFromRedis(context.Background(), *dbInstance.ContainerID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get container IP: %w", err)
-		}
+	containerIP, err := s.orchestrator.ResolveContainerIP(*dbInstance.ContainerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container IP: %w", err)
 	}
 
 	dbPassword, err := utils.DecryptString(dbCred.PasswordEncrypted)
@@ -408,16 +1463,11 @@ func (s *TableService) openDbConnection(userId uuid.UUID, projectId uuid.UUID) (
 
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		containerIP,
-		*dbInstance.Port,
+		dbInstance.Port,
 		dbCred.Username,
 		dbPassword,
-		"postgres",
+		dbInstance.DatabaseName(),
 	)
 
-	sqlDb, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	return sqlDb, nil
+	return s.connections.Get(dbInstance.ID, dsn)
 }
@@ -0,0 +1,639 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestDDLOperationsAreAudited(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+
+	_, err := env.tables.CreateTable(&CreateTableRequest{
+		Schema: "public",
+		Table:  "notes",
+		Columns: []Column{
+			{Name: "id", Type: "INTEGER", Primary: true},
+			{Name: "body", Type: "TEXT", Nullable: true},
+		},
+	}, user.ID, project.ID)
+	if err != nil {
+		t.Fatalf("CreateTable: %v", err)
+	}
+	if err := env.projects.DeleteColumn(user.ID, project.ID, DeleteColumnRequest{TableName: "notes"}, "missing"); err == nil {
+		t.Fatal("DeleteColumn of a missing column succeeded")
+	}
+
+	entries, err := env.audit.GetProjectAudit(user.ID, project.ID, 10)
+	if err != nil {
+		t.Fatalf("GetProjectAudit: %v", err)
+	}
+	byOperation := map[string]models.DDLAudit{}
+	for _, entry := range entries {
+		byOperation[entry.Operation] = entry
+	}
+
+	created, ok := byOperation[models.DDLOperationCreateTable]
+	if !ok || !created.Success || created.ErrorMessage != nil || created.SQLText == "" {
+		t.Errorf("CreateTable audit = %+v, want a successful entry with its SQL", created)
+	}
+	if created.UserID == nil || *created.UserID != user.ID {
+		t.Errorf("CreateTable audit user = %v, want %s", created.UserID, user.ID)
+	}
+
+	dropped, ok := byOperation[models.DDLOperationDropColumn]
+	if !ok || dropped.Success || dropped.ErrorMessage == nil || dropped.Target != "notes.missing" {
+		t.Errorf("DeleteColumn audit = %+v, want a failed entry on notes.missing", dropped)
+	}
+}
+
+// newOfflineTableService returns a TableService with the default limits that can only
+// validate and preview statements
+func newOfflineTableService() *TableService {
+	return &TableService{limits: config.TableLimits{MaxColumns: config.DefaultMaxTableColumns, MaxForeignKeys: config.DefaultMaxTableForeignKeys}}
+}
+
+// ordersTable returns a request creating a table whose customer_id references customers
+func ordersTable(onUpdate, onDelete string) *CreateTableRequest {
+	return &CreateTableRequest{
+		Schema: "public",
+		Table:  "orders",
+		Columns: []Column{
+			{Name: "id", Type: "INTEGER", Primary: true},
+			{Name: "customer_id", Type: "INTEGER", Nullable: true},
+		},
+		ForeignKeys: []ForeignKey{{
+			Schema: "public",
+			Table:  "customers",
+			References: []ForeignKeyRef{{
+				LocalColumn:   "customer_id",
+				ForeignColumn: "id",
+				OnUpdate:      onUpdate,
+				OnDelete:      onDelete,
+			}},
+		}},
+	}
+}
+
+func TestForeignKeyActions(t *testing.T) {
+	tables := newOfflineTableService()
+
+	for _, action := range []string{"", "CASCADE", "RESTRICT", "NO ACTION", "SET NULL", "SET DEFAULT"} {
+		query, err := tables.PreviewCreateTable(ordersTable(action, action))
+		if err != nil {
+			t.Errorf("action %q: %v", action, err)
+			continue
+		}
+		if action != "" && !strings.Contains(query, "ON DELETE "+action) {
+			t.Errorf("action %q missing from %s", action, query)
+		}
+	}
+
+	injected := []string{
+		"CASCADE; DROP TABLE users; --",
+		"cascade",
+		"SET  NULL",
+		"NO ACTION DEFERRABLE",
+		"RESTRICT)",
+	}
+	for _, action := range injected {
+		if query, err := tables.PreviewCreateTable(ordersTable("", action)); err == nil {
+			t.Errorf("ON DELETE %q was accepted: %s", action, query)
+		}
+		if query, err := tables.PreviewCreateTable(ordersTable(action, "")); err == nil {
+			t.Errorf("ON UPDATE %q was accepted: %s", action, query)
+		}
+	}
+}
+
+func TestForeignKeyActionBinding(t *testing.T) {
+	valid := ForeignKeyRef{LocalColumn: "customer_id", ForeignColumn: "id", OnUpdate: "NO ACTION", OnDelete: "SET NULL"}
+	if err := binding.Validator.ValidateStruct(valid); err != nil {
+		t.Errorf("binding rejected %+v: %v", valid, err)
+	}
+
+	injected := ForeignKeyRef{LocalColumn: "customer_id", ForeignColumn: "id", OnDelete: "CASCADE; DROP TABLE users"}
+	if err := binding.Validator.ValidateStruct(injected); err == nil {
+		t.Errorf("binding accepted %+v", injected)
+	}
+}
+
+func TestDeleteTableRequiresRowCountConfirmation(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE notes (id integer PRIMARY KEY)`,
+		`INSERT INTO notes VALUES (1), (2), (3)`,
+		`CREATE TABLE drafts (id integer PRIMARY KEY)`,
+		`INSERT INTO drafts VALUES (1)`,
+		`CREATE TABLE empty (id integer PRIMARY KEY)`,
+	)
+	count := func(n int64) *int64 { return &n }
+
+	for _, confirm := range []*int64{nil, count(2), count(0)} {
+		_, err := env.tables.DeleteTable(&DeleteTableRequest{Schema: "public", Table: "notes", ConfirmRowCount: confirm}, user.ID, project.ID)
+		var confirmation *RowCountConfirmationError
+		if !errors.As(err, &confirmation) || confirmation.RowCount != 3 {
+			t.Errorf("DeleteTable confirming %v = %v, want a confirmation error for 3 rows", confirm, err)
+		}
+	}
+	if !tableExists(t, env, user, project, "notes") {
+		t.Fatal("notes was dropped without a matching confirmation")
+	}
+
+	cases := map[string]*DeleteTableRequest{
+		"notes":  {Schema: "public", Table: "notes", ConfirmRowCount: count(3)},
+		"drafts": {Schema: "public", Table: "drafts", Force: true},
+		"empty":  {Schema: "public", Table: "empty"},
+	}
+	for table, req := range cases {
+		if _, err := env.tables.DeleteTable(req, user.ID, project.ID); err != nil {
+			t.Errorf("DeleteTable(%s): %v", table, err)
+		}
+		if tableExists(t, env, user, project, table) {
+			t.Errorf("%s was not dropped", table)
+		}
+	}
+}
+
+func tableExists(t *testing.T, env *testEnv, user *models.User, project *models.Project, table string) bool {
+	t.Helper()
+
+	var exists bool
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT to_regclass($1) IS NOT NULL`, "public."+table).Scan(&exists); err != nil {
+		t.Fatalf("check table %s: %v", table, err)
+	}
+	return exists
+}
+
+func TestCreateTableChecksForeignKeyTarget(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE customers (id integer PRIMARY KEY, email text UNIQUE, name text)`)
+
+	invalid := map[string]func(*CreateTableRequest){
+		"does not exist in public.customers":        func(req *CreateTableRequest) { req.ForeignKeys[0].References[0].ForeignColumn = "uuid" },
+		"table public.clients which does not exist": func(req *CreateTableRequest) { req.ForeignKeys[0].Table = "clients" },
+		"not a primary key or unique":               func(req *CreateTableRequest) { req.ForeignKeys[0].References[0].ForeignColumn = "name" },
+	}
+	for want, change := range invalid {
+		req := ordersTable("", "")
+		change(req)
+		_, err := env.tables.CreateTable(req, user.ID, project.ID)
+		if err == nil || !strings.Contains(err.Error(), "validation failed") || !strings.Contains(err.Error(), want) {
+			t.Errorf("CreateTable = %v, want a validation error containing %q", err, want)
+		}
+	}
+	if tableExists(t, env, user, project, "orders") {
+		t.Fatal("orders was created with an invalid foreign key")
+	}
+
+	// A unique column is a valid target, like the primary key
+	req := ordersTable("", "CASCADE")
+	req.ForeignKeys[0].References[0].ForeignColumn = "email"
+	req.Columns[1].Type = "TEXT"
+	if _, err := env.tables.CreateTable(req, user.ID, project.ID); err != nil {
+		t.Fatalf("CreateTable referencing a unique column: %v", err)
+	}
+	env.exec(t, user, project, `DROP TABLE orders`)
+
+	if _, err := env.tables.CreateTable(ordersTable("", "CASCADE"), user.ID, project.ID); err != nil {
+		t.Fatalf("CreateTable referencing the primary key: %v", err)
+	}
+	if !tableExists(t, env, user, project, "orders") {
+		t.Error("orders was not created")
+	}
+}
+
+func TestValidateSQLExpression(t *testing.T) {
+	valid := []string{
+		"deleted_at IS NULL",
+		"status = 'active' AND priority > 2",
+		"lower(email)",
+		"price::numeric(10, 2) >= 0",
+		`"Status" IN ('a', 'b''c')`,
+		"coalesce(note, '') <> ''",
+	}
+	for _, expr := range valid {
+		if err := validateSQLExpression(expr); err != nil {
+			t.Errorf("validateSQLExpression(%q) = %v, want it accepted", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"true; DROP TABLE users",
+		"active -- comment",
+		"active /* comment */",
+		"id IN (SELECT id FROM admins)",
+		"pg_sleep(10) IS NULL",
+		"status = 'unterminated",
+		`status = 'a\'`,
+		"(active",
+		"active)",
+		"id = $1",
+	}
+	for _, expr := range invalid {
+		if err := validateSQLExpression(expr); err == nil {
+			t.Errorf("validateSQLExpression(%q) accepted", expr)
+		}
+	}
+}
+
+func TestCreatePartialAndExpressionIndexes(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE users (id integer PRIMARY KEY, email text, deleted_at timestamptz)`)
+
+	indexes := []*CreateIndexRequest{
+		{Table: "users", Name: "users_active_email_idx", Columns: []string{"email"}, Where: "deleted_at IS NULL", Unique: true},
+		{Table: "users", Name: "users_lower_email_idx", Expression: "lower(email)"},
+	}
+	for _, req := range indexes {
+		if _, err := env.tables.CreateIndex(req, user.ID, project.ID); err != nil {
+			t.Fatalf("CreateIndex(%s): %v", req.Name, err)
+		}
+	}
+
+	listed, err := env.schemas.ListIndexes(user.ID, project.ID, "public", "users")
+	if err != nil {
+		t.Fatalf("ListIndexes: %v", err)
+	}
+	found := map[string]models.IntrospectedIndex{}
+	for _, index := range listed {
+		found[index.Name] = index
+	}
+	partial := found["users_active_email_idx"]
+	if !partial.Unique || partial.Predicate == nil || *partial.Predicate != "(deleted_at IS NULL)" {
+		t.Errorf("partial index = %+v, want a unique index with its predicate", partial)
+	}
+	expression := found["users_lower_email_idx"]
+	if expression.Expressions == nil || *expression.Expressions != "lower(email)" {
+		t.Errorf("expression index = %+v, want its expression", expression)
+	}
+
+	for _, where := range []string{"deleted_at IS NULL; DROP TABLE users", "deleted_at IS NULL -- ignore the rest", "deleted_at IS NULL /* x */"} {
+		req := &CreateIndexRequest{Table: "users", Columns: []string{"email"}, Where: where}
+		if _, err := env.tables.CreateIndex(req, user.ID, project.ID); err == nil {
+			t.Errorf("CreateIndex with predicate %q succeeded", where)
+		}
+	}
+	if !tableExists(t, env, user, project, "users") {
+		t.Error("users was dropped")
+	}
+}
+
+// tableWithLimits returns a request for a table of INTEGER columns, the first references
+// of which each reference customers
+func tableWithLimits(columns, references int) *CreateTableRequest {
+	req := &CreateTableRequest{Schema: "public", Table: "wide"}
+	fk := ForeignKey{Schema: "public", Table: "customers"}
+	for i := 0; i < columns; i++ {
+		name := fmt.Sprintf("c%d", i)
+		req.Columns = append(req.Columns, Column{Name: name, Type: "INTEGER", Nullable: true})
+		if i < references {
+			fk.References = append(fk.References, ForeignKeyRef{LocalColumn: name, ForeignColumn: "id"})
+		}
+	}
+	if references > 0 {
+		req.ForeignKeys = []ForeignKey{fk}
+	}
+	return req
+}
+
+func TestCreateTableLimits(t *testing.T) {
+	tables := &TableService{limits: config.TableLimits{MaxColumns: 4, MaxForeignKeys: 2}}
+
+	tests := []struct {
+		columns, references int
+		wantErr             string
+	}{
+		{columns: 3, references: 1},
+		{columns: 4, references: 2},
+		{columns: 5, references: 0, wantErr: "too many columns: 5 given, at most 4 are allowed"},
+		{columns: 4, references: 3, wantErr: "too many foreign key references: 3 given, at most 2 are allowed"},
+	}
+	for _, tt := range tests {
+		err := tables.validateCreateTableRequest(tableWithLimits(tt.columns, tt.references))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%d columns, %d references: %v", tt.columns, tt.references, err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("%d columns, %d references: error = %v, want %q", tt.columns, tt.references, err, tt.wantErr)
+		}
+	}
+
+	// The references of several foreign keys count together
+	req := tableWithLimits(4, 2)
+	req.ForeignKeys = append(req.ForeignKeys, ForeignKey{Schema: "public", Table: "accounts", References: []ForeignKeyRef{{LocalColumn: "c3", ForeignColumn: "id"}}})
+	if err := tables.validateCreateTableRequest(req); err == nil {
+		t.Error("three references over two foreign keys were accepted")
+	}
+
+	// The defaults
+	tables = newOfflineTableService()
+	if err := tables.validateCreateTableRequest(tableWithLimits(config.DefaultMaxTableColumns, config.DefaultMaxTableForeignKeys)); err != nil {
+		t.Errorf("a table at the default limits was rejected: %v", err)
+	}
+	if err := tables.validateCreateTableRequest(tableWithLimits(config.DefaultMaxTableColumns+1, 0)); err == nil {
+		t.Error("a table above the default column limit was accepted")
+	}
+	if err := tables.validateCreateTableRequest(tableWithLimits(config.DefaultMaxTableForeignKeys+1, config.DefaultMaxTableForeignKeys+1)); err == nil {
+		t.Error("a table above the default foreign key limit was accepted")
+	}
+}
+
+func TestPreviewCreateTableSQL(t *testing.T) {
+	status := "it's new"
+	quantity := "1"
+	req := &CreateTableRequest{
+		Schema: "public",
+		Table:  "orders",
+		Columns: []Column{
+			{Name: "id", Type: "INTEGER", Primary: true, IsIdentity: true},
+			{Name: "customer_id", Type: "INTEGER"},
+			{Name: "product_id", Type: "INTEGER", Nullable: true},
+			{Name: "reference", Type: "VARCHAR(20)", IsUnique: true, Nullable: true},
+			{Name: "status", Type: "TEXT", Default: &status},
+			{Name: "quantity", Type: "INTEGER", Default: &quantity},
+		},
+		ForeignKeys: []ForeignKey{
+			{Schema: "public", Table: "customers", References: []ForeignKeyRef{{LocalColumn: "customer_id", ForeignColumn: "id", OnDelete: "CASCADE"}}},
+			{Schema: "shop", Table: "products", References: []ForeignKeyRef{{LocalColumn: "product_id", ForeignColumn: "id", OnDelete: "SET NULL", OnUpdate: "CASCADE"}}},
+		},
+		Checks: []string{"quantity > 0"},
+	}
+
+	// newOfflineTableService has no repositories or orchestrator, so this also shows the
+	// preview never connects to a database
+	query, err := newOfflineTableService().PreviewCreateTable(req)
+	if err != nil {
+		t.Fatalf("PreviewCreateTable: %v", err)
+	}
+	want := `CREATE TABLE "public"."orders" (
+  "id" INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY NOT NULL,
+  "customer_id" INTEGER NOT NULL,
+  "product_id" INTEGER,
+  "reference" VARCHAR(20) UNIQUE,
+  "status" TEXT NOT NULL DEFAULT 'it''s new',
+  "quantity" INTEGER NOT NULL DEFAULT 1,
+  CHECK (quantity > 0),
+  FOREIGN KEY ("customer_id") REFERENCES "public"."customers"("id") ON DELETE CASCADE,
+  FOREIGN KEY ("product_id") REFERENCES "shop"."products"("id") ON DELETE SET NULL ON UPDATE CASCADE
+);
+`
+	if query != want {
+		t.Errorf("PreviewCreateTable =\n%s\nwant\n%s", query, want)
+	}
+
+	// Requests are validated as usual
+	req.Columns[1].Name = `customer"id`
+	if _, err := newOfflineTableService().PreviewCreateTable(req); err == nil {
+		t.Error("PreviewCreateTable accepted an invalid column name")
+	}
+}
+
+func TestPreviewTableConstraints(t *testing.T) {
+	req := &CreateTableRequest{
+		Schema: "public",
+		Table:  "bookings",
+		Columns: []Column{
+			{Name: "room", Type: "INTEGER"},
+			{Name: "day", Type: "DATE"},
+			{Name: "nights", Type: "INTEGER"},
+		},
+		Checks:            []string{"  nights > 0  "},
+		UniqueConstraints: [][]string{{"room", "day"}},
+	}
+
+	query, err := newOfflineTableService().PreviewCreateTable(req)
+	if err != nil {
+		t.Fatalf("PreviewCreateTable: %v", err)
+	}
+	for _, want := range []string{`UNIQUE ("room", "day")`, `CHECK (nights > 0)`} {
+		if !strings.Contains(query, want) {
+			t.Errorf("PreviewCreateTable =\n%s\nwant it to contain %s", query, want)
+		}
+	}
+
+	invalid := map[string]func(*CreateTableRequest){
+		"an empty check":                 func(req *CreateTableRequest) { req.Checks = []string{" "} },
+		"a check with a statement":       func(req *CreateTableRequest) { req.Checks = []string{"nights > 0); DROP TABLE bookings; --"} },
+		"a check with a subquery":        func(req *CreateTableRequest) { req.Checks = []string{"nights > (SELECT 1)"} },
+		"a unique constraint of nothing": func(req *CreateTableRequest) { req.UniqueConstraints = [][]string{{}} },
+		"a unique constraint on a missing column": func(req *CreateTableRequest) {
+			req.UniqueConstraints = [][]string{{"room", "guest"}}
+		},
+	}
+	for name, change := range invalid {
+		req := *req
+		change(&req)
+		if _, err := newOfflineTableService().PreviewCreateTable(&req); err == nil {
+			t.Errorf("PreviewCreateTable accepted %s", name)
+		}
+	}
+}
+
+func TestPreviewCompositeForeignKey(t *testing.T) {
+	req := &CreateTableRequest{
+		Schema: "public",
+		Table:  "order_lines",
+		Columns: []Column{
+			{Name: "order_id", Type: "INTEGER"},
+			{Name: "line", Type: "INTEGER"},
+		},
+		ForeignKeys: []ForeignKey{{
+			Schema:    "public",
+			Table:     "lines",
+			Composite: true,
+			References: []ForeignKeyRef{
+				{LocalColumn: "order_id", ForeignColumn: "order_id", OnDelete: "CASCADE"},
+				{LocalColumn: "line", ForeignColumn: "number", OnDelete: "CASCADE"},
+			},
+		}},
+	}
+
+	query, err := newOfflineTableService().PreviewCreateTable(req)
+	if err != nil {
+		t.Fatalf("PreviewCreateTable: %v", err)
+	}
+	want := `FOREIGN KEY ("order_id", "line") REFERENCES "public"."lines"("order_id", "number") ON DELETE CASCADE`
+	if !strings.Contains(query, want) {
+		t.Errorf("PreviewCreateTable =\n%s\nwant it to contain\n%s", query, want)
+	}
+
+	// The references of a composite key share their actions
+	req.ForeignKeys[0].References[1].OnDelete = "SET NULL"
+	if _, err := newOfflineTableService().PreviewCreateTable(req); err == nil {
+		t.Error("PreviewCreateTable accepted a composite key with differing actions")
+	}
+
+	// Local columns must be among the columns being created
+	req.ForeignKeys[0].References[1].OnDelete = "CASCADE"
+	req.ForeignKeys[0].References[1].LocalColumn = "missing"
+	if _, err := newOfflineTableService().PreviewCreateTable(req); err == nil {
+		t.Error("PreviewCreateTable accepted a foreign key on a column that is not created")
+	}
+}
+
+func TestValidateForeignKeyReportsOrphanedRows(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE customers (id integer PRIMARY KEY)`,
+		`CREATE TABLE orders (id integer PRIMARY KEY, customer_id integer)`,
+		`INSERT INTO customers VALUES (1), (2)`,
+		`INSERT INTO orders VALUES (1, 1), (2, 3), (3, NULL), (4, 4), (5, 3)`,
+	)
+	req := &AddForeignKeyRequest{Schema: "public", Table: "orders", ForeignKey: ordersTable("", "CASCADE").ForeignKeys[0]}
+	wantSQL := `ALTER TABLE "public"."orders" ADD FOREIGN KEY ("customer_id") REFERENCES "public"."customers"("id") ON DELETE CASCADE`
+
+	validation, err := env.tables.ValidateForeignKey(user.ID, project.ID, req)
+	if err != nil {
+		t.Fatalf("ValidateForeignKey: %v", err)
+	}
+	if validation.Valid || validation.SQL != wantSQL || len(validation.Violations) != 1 {
+		t.Fatalf("validation = %+v, want one violation and the statement %q", validation, wantSQL)
+	}
+	violation := validation.Violations[0]
+	if violation.LocalColumn != "customer_id" || violation.ForeignColumn != "id" || violation.RowCount != 3 || len(violation.Sample.Rows) != 3 {
+		t.Fatalf("violation = %+v, want the three orders of missing customers", violation)
+	}
+	for _, row := range violation.Sample.Rows {
+		if customer := fmt.Sprint(row["customer_id"]); customer != "3" && customer != "4" {
+			t.Errorf("sample row %v does not violate the foreign key", row)
+		}
+	}
+
+	// Adding the foreign key is refused with the same report, and nothing changes
+	var violationErr *ForeignKeyViolationError
+	if _, err := env.tables.AddForeignKey(user.ID, project.ID, req); !errors.As(err, &violationErr) || violationErr.Validation.Violations[0].RowCount != 3 {
+		t.Fatalf("AddForeignKey = %v, want a ForeignKeyViolationError over 3 rows", err)
+	}
+	var constraints int
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT count(*) FROM pg_constraint WHERE conrelid = 'orders'::regclass AND contype = 'f'`).Scan(&constraints); err != nil {
+		t.Fatalf("count constraints: %v", err)
+	}
+	if constraints != 0 {
+		t.Errorf("%d foreign keys on orders after a refused AddForeignKey, want 0", constraints)
+	}
+
+	// Once the orphans are gone the preview is valid and the constraint is added
+	env.exec(t, user, project, `DELETE FROM orders WHERE customer_id > 2`)
+	if validation, err = env.tables.ValidateForeignKey(user.ID, project.ID, req); err != nil || !validation.Valid || len(validation.Violations) != 0 {
+		t.Fatalf("ValidateForeignKey after cleanup = %+v, %v, want it valid", validation, err)
+	}
+	if _, err := env.tables.AddForeignKey(user.ID, project.ID, req); err != nil {
+		t.Fatalf("AddForeignKey: %v", err)
+	}
+	if _, err := env.projectDB(t, user, project).Exec(`INSERT INTO orders VALUES (6, 9)`); err == nil {
+		t.Error("an order of a missing customer was inserted after the foreign key was added")
+	}
+}
+
+func TestRenameTableAndColumn(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project,
+		`CREATE TABLE notes (id integer PRIMARY KEY, body text)`,
+		`CREATE TABLE taken (id integer)`,
+		`INSERT INTO notes VALUES (1, 'kept')`,
+	)
+
+	if _, err := env.tables.RenameTable(&RenameTableRequest{Table: "notes", NewName: "taken"}, user.ID, project.ID); err == nil {
+		t.Error("RenameTable onto an existing table succeeded")
+	}
+	if _, err := env.tables.RenameTable(&RenameTableRequest{Table: "notes", NewName: "bad name"}, user.ID, project.ID); err == nil {
+		t.Error("RenameTable to an invalid name succeeded")
+	}
+	if _, err := env.tables.RenameTable(&RenameTableRequest{Table: "notes", NewName: "memos"}, user.ID, project.ID); err != nil {
+		t.Fatalf("RenameTable: %v", err)
+	}
+
+	if _, err := env.tables.RenameColumn(&RenameColumnRequest{Table: "memos", Column: "body", NewName: "id"}, user.ID, project.ID); err == nil {
+		t.Error("RenameColumn onto an existing column succeeded")
+	}
+	if _, err := env.tables.RenameColumn(&RenameColumnRequest{Table: "memos", Column: "missing", NewName: "text"}, user.ID, project.ID); err == nil {
+		t.Error("RenameColumn of a missing column succeeded")
+	}
+	if _, err := env.tables.RenameColumn(&RenameColumnRequest{Table: "memos", Column: "body", NewName: "text"}, user.ID, project.ID); err != nil {
+		t.Fatalf("RenameColumn: %v", err)
+	}
+
+	var text string
+	if err := env.projectDB(t, user, project).QueryRow(`SELECT text FROM memos WHERE id = 1`).Scan(&text); err != nil || text != "kept" {
+		t.Errorf("renamed row = %q, %v, want the data kept", text, err)
+	}
+
+	entries, err := env.audit.GetProjectAudit(user.ID, project.ID, 10)
+	if err != nil {
+		t.Fatalf("GetProjectAudit: %v", err)
+	}
+	audited := map[string]string{}
+	for _, entry := range entries {
+		if entry.Success {
+			audited[entry.Operation] = entry.Target
+		}
+	}
+	if audited[models.DDLOperationRenameTable] != "public.notes" || audited[models.DDLOperationRenameColumn] != "public.memos.body" {
+		t.Errorf("audited renames = %v, want the table and the column", audited)
+	}
+}
+
+func TestCreateAndDropTableIndex(t *testing.T) {
+	env := newTestEnv(t)
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	env.exec(t, user, project, `CREATE TABLE notes (id integer PRIMARY KEY, body text)`)
+
+	create := &CreateIndexRequest{Table: "notes", Name: "notes_body_idx", Columns: []string{"body"}}
+	if _, err := env.tables.CreateIndex(create, user.ID, project.ID); err != nil {
+		t.Fatalf("CreateIndex: %v", err)
+	}
+	if _, err := env.tables.CreateIndex(create, user.ID, project.ID); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("CreateIndex with a taken name = %v, want an already exists error", err)
+	}
+
+	if _, err := env.tables.DropIndex(&DropIndexRequest{Table: "notes", Name: "notes_pkey"}, user.ID, project.ID); err == nil {
+		t.Error("DropIndex of the primary key index succeeded")
+	}
+	if _, err := env.tables.DropIndex(&DropIndexRequest{Table: "notes", Name: "missing_idx"}, user.ID, project.ID); err == nil {
+		t.Error("DropIndex of a missing index succeeded")
+	}
+	if _, err := env.tables.DropIndex(&DropIndexRequest{Table: "notes", Name: "notes_body_idx"}, user.ID, project.ID); err != nil {
+		t.Fatalf("DropIndex: %v", err)
+	}
+
+	listed, err := env.schemas.ListIndexes(user.ID, project.ID, "public", "notes")
+	if err != nil {
+		t.Fatalf("ListIndexes: %v", err)
+	}
+	if len(listed) != 1 || listed[0].Name != "notes_pkey" {
+		t.Errorf("indexes = %+v, want only notes_pkey", listed)
+	}
+
+	entries, err := env.audit.GetProjectAudit(user.ID, project.ID, 10)
+	if err != nil {
+		t.Fatalf("GetProjectAudit: %v", err)
+	}
+	dropped := false
+	for _, entry := range entries {
+		if entry.Operation == models.DDLOperationDropIndex && entry.Success {
+			dropped = entry.Target == "public.notes_body_idx"
+		}
+	}
+	if !dropped {
+		t.Error("dropping notes_body_idx was not audited")
+	}
+}
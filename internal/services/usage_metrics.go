@@ -0,0 +1,145 @@
+package services
+
+import (
+	"backend/internal/apperr"
+	"backend/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultMetricsWindow is the time range of usage metrics returned when none is given
+const defaultMetricsWindow = 24 * time.Hour
+
+// maxMetricsBuckets bounds the number of points in a usage metrics series
+const maxMetricsBuckets = 1000
+
+// metricsGranularities are the bucket sizes picked automatically, smallest first
+var metricsGranularities = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// autoMetricsBuckets is the most points an automatically picked granularity yields
+const autoMetricsBuckets = 300
+
+// UsageMetricsQuery selects the window and bucket size of a usage metrics series. Zero
+// values select the last 24 hours and a granularity giving at most 300 points.
+type UsageMetricsQuery struct {
+	From        time.Time
+	To          time.Time
+	Granularity time.Duration
+}
+
+// InstanceLimits are the resources allocated to a database instance
+type InstanceLimits struct {
+	CPUCores  *int `json:"cpu_cores"`
+	RAMMB     *int `json:"ram_mb"`
+	StorageGB *int `json:"storage_gb"`
+}
+
+// UsageMetricsSeries is the resource usage of a project's database over time. Points
+// cover the whole window at a fixed step; points without samples have null values.
+type UsageMetricsSeries struct {
+	From        time.Time                  `json:"from"`
+	To          time.Time                  `json:"to"`
+	Granularity string                     `json:"granularity"`
+	Limits      InstanceLimits             `json:"limits"`
+	Points      []models.UsageMetricBucket `json:"points"`
+}
+
+// GetUsageMetrics returns the usage of the project's database instance in time buckets:
+// the average CPU and RAM percentage and the peak storage of each bucket
+func (s *ProjectService) GetUsageMetrics(userID uuid.UUID, projectID uuid.UUID, query UsageMetricsQuery) (*UsageMetricsSeries, error) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultMetricsWindow)
+	}
+	if !query.From.Before(query.To) {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "invalid time range: from must be before to")
+	}
+
+	window := query.To.Sub(query.From)
+	if query.Granularity == 0 {
+		query.Granularity = metricsGranularityFor(window)
+	}
+	if query.Granularity < time.Minute || query.Granularity%time.Minute != 0 {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, "invalid granularity: must be a whole number of minutes")
+	}
+	if window/query.Granularity >= maxMetricsBuckets {
+		return nil, apperr.Invalid(apperr.CodeInvalidInput, fmt.Sprintf("invalid granularity: the time range would span more than %d points", maxMetricsBuckets))
+	}
+
+	project, err := s.projectRepo.GetByIDAndUserID(projectID, userID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get project")
+	}
+	if project == nil {
+		return nil, apperr.NotFound(apperr.CodeProjectNotFound, "project not found or not accessible")
+	}
+
+	inst, err := s.dbInstanceRepo.GetByProjectID(projectID)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get database instance")
+	}
+	if inst == nil {
+		return nil, apperr.NotFound(apperr.CodeInstanceNotFound, "project has no database instance")
+	}
+
+	step := int64(query.Granularity / time.Second)
+	buckets, err := s.usageMetricsRepo.AggregateByInstanceID(inst.ID, query.From, query.To, step)
+	if err != nil {
+		return nil, apperr.Internal(err, "failed to get usage metrics")
+	}
+
+	return &UsageMetricsSeries{
+		From:        query.From,
+		To:          query.To,
+		Granularity: query.Granularity.String(),
+		Limits: InstanceLimits{
+			CPUCores:  inst.CPUCores,
+			RAMMB:     inst.RAMMB,
+			StorageGB: inst.StorageGB,
+		},
+		Points: fillMetricsBuckets(buckets, query.From, query.To, query.Granularity),
+	}, nil
+}
+
+// metricsGranularityFor picks the smallest granularity that keeps a window within autoMetricsBuckets points
+func metricsGranularityFor(window time.Duration) time.Duration {
+	for _, granularity := range metricsGranularities {
+		if window/granularity <= autoMetricsBuckets {
+			return granularity
+		}
+	}
+	return metricsGranularities[len(metricsGranularities)-1]
+}
+
+// fillMetricsBuckets returns a point for every bucket between from and to, so charts get
+// gaps where no samples were taken instead of lines drawn across them
+func fillMetricsBuckets(buckets []models.UsageMetricBucket, from time.Time, to time.Time, granularity time.Duration) []models.UsageMetricBucket {
+	byStart := make(map[int64]models.UsageMetricBucket, len(buckets))
+	for _, bucket := range buckets {
+		byStart[bucket.Start.Unix()] = bucket
+	}
+
+	points := []models.UsageMetricBucket{}
+	// Buckets are aligned to the Unix epoch, like those of AggregateByInstanceID
+	step := int64(granularity / time.Second)
+	for start := time.Unix(from.Unix()/step*step, 0); start.Before(to); start = start.Add(granularity) {
+		point, ok := byStart[start.Unix()]
+		if !ok {
+			point = models.UsageMetricBucket{}
+		}
+		point.Start = start.UTC()
+		points = append(points, point)
+	}
+	return points
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestMetricsGranularityFor(t *testing.T) {
+	cases := []struct {
+		window time.Duration
+		want   time.Duration
+	}{
+		{time.Hour, time.Minute},
+		{5 * time.Hour, time.Minute},
+		{24 * time.Hour, 5 * time.Minute},
+		{7 * 24 * time.Hour, time.Hour},
+		{30 * 24 * time.Hour, 6 * time.Hour},
+		{365 * 24 * time.Hour, 24 * time.Hour},
+		{10 * 365 * 24 * time.Hour, 24 * time.Hour},
+	}
+	for _, tc := range cases {
+		if got := metricsGranularityFor(tc.window); got != tc.want {
+			t.Errorf("metricsGranularityFor(%v) = %v, want %v", tc.window, got, tc.want)
+		}
+	}
+}
+
+func TestFillMetricsBucketsLeavesGaps(t *testing.T) {
+	from := time.Date(2026, 3, 1, 10, 2, 0, 0, time.UTC)
+	to := from.Add(20 * time.Minute)
+	cpu := 42.0
+	sampled := models.UsageMetricBucket{Start: time.Date(2026, 3, 1, 10, 10, 0, 0, time.UTC), CPUPercent: &cpu}
+
+	points := fillMetricsBuckets([]models.UsageMetricBucket{sampled}, from, to, 5*time.Minute)
+
+	// Buckets from the one holding from, 10:00, up to the one holding to, 10:20
+	if len(points) != 5 {
+		t.Fatalf("got %d points, want 5: %+v", len(points), points)
+	}
+	for i, point := range points {
+		if want := time.Date(2026, 3, 1, 10, 5*i, 0, 0, time.UTC); !point.Start.Equal(want) {
+			t.Errorf("point %d starts at %v, want %v", i, point.Start, want)
+		}
+		if i == 2 {
+			if point.CPUPercent == nil || *point.CPUPercent != cpu {
+				t.Errorf("point 2 = %+v, want the sampled bucket", point)
+			}
+		} else if point.CPUPercent != nil || point.RAMPercent != nil || point.StorageUsedGB != nil {
+			t.Errorf("point %d = %+v, want an empty bucket", i, point)
+		}
+	}
+}
@@ -11,17 +11,41 @@ import (
 )
 
 type UserService struct {
-	userRepo    *repositories.UserRepository
-	sessionRepo *repositories.SessionRepository
+	userRepo         *repositories.UserRepository
+	sessionRepo      *repositories.SessionRepository
+	projectRepo      *repositories.ProjectRepository
+	queryHistoryRepo *repositories.QueryHistoryRepository
 }
 
-func NewUserService(userRepo *repositories.UserRepository, sessionRepo *repositories.SessionRepository) *UserService {
+func NewUserService(
+	userRepo *repositories.UserRepository,
+	sessionRepo *repositories.SessionRepository,
+	projectRepo *repositories.ProjectRepository,
+	queryHistoryRepo *repositories.QueryHistoryRepository,
+) *UserService {
 	return &UserService{
-		userRepo:    userRepo,
-		sessionRepo: sessionRepo,
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		projectRepo:      projectRepo,
+		queryHistoryRepo: queryHistoryRepo,
 	}
 }
 
+// assignInitialRole applies the role policy for new users: the first user becomes admin,
+// any other user without a role becomes a regular user
+func assignInitialRole(userRepo *repositories.UserRepository, user *models.User) error {
+	userCount, err := userRepo.CountUsers()
+	if err != nil {
+		return err
+	}
+	if userCount == 0 {
+		user.Role = "admin"
+	} else if user.Role == "" {
+		user.Role = "user"
+	}
+	return nil
+}
+
 func (s *UserService) Register(user *models.User) (string, string, uuid.UUID, error) {
 	// 1. Check if it already exists
 	existing, _ := s.userRepo.FindUserByEmail(user.Email)
@@ -43,15 +67,9 @@ func (s *UserService) Register(user *models.User) (string, string, uuid.UUID, er
 	user.Password = "" // Clear plain password
 
 	// 3. Policy: First user becomes admin
-	userCount, err := s.userRepo.CountUsers()
-	if err != nil {
+	if err := assignInitialRole(s.userRepo, user); err != nil {
 		return "", "", uuid.Nil, err
 	}
-	if userCount == 0 {
-		user.Role = "admin"
-	} else if user.Role == "" {
-		user.Role = "user"
-	}
 
 	// 4. Save user in DB
 	if err := s.userRepo.Create(user); err != nil {
@@ -273,11 +291,12 @@ func (s *UserService) DeleteUser(userID uuid.UUID, authenticatedUserID uuid.UUID
 	return s.userRepo.Delete(userID)
 }
 
-// GetAllUsers retrieves all users
-func (s *UserService) GetAllUsers() ([]models.User, error) {
-	users, err := s.userRepo.FindAll()
+// GetAllUsers retrieves a page of the users, optionally only those whose email contains
+// search, and the number of such users in total
+func (s *UserService) GetAllUsers(limit, offset int, search string) ([]models.User, int, error) {
+	users, total, err := s.userRepo.FindAll(limit, offset, search)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// Clear sensitive data before returning
@@ -285,5 +304,45 @@ func (s *UserService) GetAllUsers() ([]models.User, error) {
 		users[i].PasswordHash = ""
 	}
 
-	return users, nil
+	return users, total, nil
+}
+
+// UserDataExport is the archive returned for a user's data-portability request
+type UserDataExport struct {
+	ExportedAt   time.Time             `json:"exported_at"`
+	User         *models.User          `json:"user"`
+	Projects     []models.Project      `json:"projects"`
+	QueryHistory []models.QueryHistory `json:"query_history"`
+}
+
+// ExportUserData assembles the user's profile, project metadata and query history.
+// Secrets (password hash, database credentials) are never included.
+func (s *UserService) ExportUserData(userID uuid.UUID) (*UserDataExport, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if projects == nil {
+		projects = []models.Project{}
+	}
+
+	history, err := s.queryHistoryRepo.GetAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.QueryHistory{}
+	}
+
+	return &UserDataExport{
+		ExportedAt:   time.Now().UTC(),
+		User:         user,
+		Projects:     projects,
+		QueryHistory: history,
+	}, nil
 }
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"backend/internal/repositories"
+	"backend/internal/utils"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExportUserDataOmitsSecretsAndOtherUsers(t *testing.T) {
+	env := newTestEnv(t)
+	users := NewUserService(env.users, repositories.NewSessionRepository(env.pool), env.projectRepo, env.history)
+
+	user := env.createUser(t)
+	project := env.createProject(t, user, "postgres")
+	if _, _, err := env.queries.ExecuteQuery(user.ID, &ExecuteQueryRequest{Query: "SELECT 'mine'"}, project.ID); err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+
+	other := env.createUser(t)
+	otherProject := env.createProject(t, other, "postgres")
+	if _, _, err := env.queries.ExecuteQuery(other.ID, &ExecuteQueryRequest{Query: "SELECT 'theirs'"}, otherProject.ID); err != nil {
+		t.Fatalf("ExecuteQuery: %v", err)
+	}
+
+	export, err := users.ExportUserData(user.ID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+	if export.User.ID != user.ID {
+		t.Errorf("exported user = %s, want %s", export.User.ID, user.ID)
+	}
+	if len(export.Projects) != 1 || export.Projects[0].ID != project.ID {
+		t.Errorf("exported projects = %+v, want only %s", export.Projects, project.ID)
+	}
+	if len(export.QueryHistory) != 1 || export.QueryHistory[0].QueryText != "SELECT 'mine'" {
+		t.Errorf("exported history = %+v, want only the user's query", export.QueryHistory)
+	}
+
+	body, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("marshal export: %v", err)
+	}
+	credential, err := env.credentials.GetLatestByInstanceID(env.instance(t, project).ID)
+	if err != nil || credential == nil {
+		t.Fatalf("GetLatestByInstanceID = %v, %v", credential, err)
+	}
+	password, err := utils.DecryptString(credential.PasswordEncrypted)
+	if err != nil {
+		t.Fatalf("DecryptString: %v", err)
+	}
+
+	for _, secret := range []string{user.PasswordHash, credential.PasswordEncrypted, password, `"password`, otherProject.ID.String(), "theirs"} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("export contains %q: %s", secret, body)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// APIKeyPrefix starts every generated API key, so keys are recognizable in logs and secret scanners
+const APIKeyPrefix = "kdb_"
+
+// apiKeyBytes is the amount of randomness in a generated API key
+const apiKeyBytes = 32
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 of an API key, as stored in api_keys.key_hash.
+// API keys are long random secrets, so a fast unsalted hash is enough and keeps lookups indexable.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
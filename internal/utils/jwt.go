@@ -22,6 +22,12 @@ type Claims struct {
 
 // GenerateJWT creates a signed JWT with expiration.
 func GenerateJWT(userID uuid.UUID, duration time.Duration, secret []byte) (string, error) {
+	token, _, err := GenerateJWTWithID(userID, duration, secret)
+	return token, err
+}
+
+// GenerateJWTWithID creates a signed JWT with expiration and also returns its unique ID (jti).
+func GenerateJWTWithID(userID uuid.UUID, duration time.Duration, secret []byte) (string, string, error) {
 	claims := &Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -31,8 +37,11 @@ func GenerateJWT(userID uuid.UUID, duration time.Duration, secret []byte) (strin
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return token, claims.ID, nil
 }
 
 // VerifyJWT parses and validates a JWT string.
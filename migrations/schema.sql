@@ -41,12 +41,17 @@ CREATE TABLE IF NOT EXISTS sessions (
     refresh_token TEXT NOT NULL,
     is_revoked BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    expires_at TIMESTAMPTZ NOT NULL
+    expires_at TIMESTAMPTZ NOT NULL,
+    jti TEXT,
+    consumed_at TIMESTAMPTZ,
+    user_agent TEXT,
+    ip_address TEXT
 );
 
 CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
 CREATE INDEX IF NOT EXISTS idx_sessions_refresh_token ON sessions(refresh_token);
 CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_jti ON sessions(jti);
 
 -- Projects table
 CREATE TABLE IF NOT EXISTS projects (
@@ -56,7 +61,11 @@ CREATE TABLE IF NOT EXISTS projects (
   description TEXT,
   db_type db_type_t NOT NULL,
   resource_tier resource_tier_t NOT NULL DEFAULT 'free',
-  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+  read_only BOOLEAN NOT NULL DEFAULT FALSE,
+  blocked_keywords TEXT[] NOT NULL DEFAULT '{}',
+  redact_query_text BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
 
 CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects(user_id);
@@ -72,8 +81,12 @@ CREATE TABLE IF NOT EXISTS database_instances (
   ram_mb INT,
   storage_gb INT,
   status instance_status_t NOT NULL DEFAULT 'creating',
-  port INT,
+  port INT NOT NULL,
   container_id TEXT,
+  endpoint TEXT,
+  db_name TEXT,
+  provision_attempts INT NOT NULL DEFAULT 0,
+  last_provision_attempt_at TIMESTAMP WITH TIME ZONE,
   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
   updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
@@ -103,12 +116,14 @@ CREATE TABLE IF NOT EXISTS api_keys (
   description TEXT,
   created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
   expires_at TIMESTAMP WITH TIME ZONE,
-  revoked BOOLEAN NOT NULL DEFAULT FALSE
+  revoked BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_at TIMESTAMP WITH TIME ZONE
 );
 
 CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
 CREATE INDEX IF NOT EXISTS idx_api_keys_revoked ON api_keys(revoked);
 CREATE INDEX IF NOT EXISTS idx_api_keys_expires_at ON api_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
 
 
 -- Query History table
@@ -117,9 +132,12 @@ CREATE TABLE IF NOT EXISTS query_history (
   db_instance_id UUID NOT NULL REFERENCES database_instances(id) ON DELETE CASCADE,
   user_id UUID NOT NULL REFERENCES users(id) ON DELETE SET NULL,
   query_text TEXT NOT NULL,
+  statement_type TEXT,
   executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
   success BOOLEAN,
-  execution_time_ms INT
+  execution_time_ms INT,
+  error_message TEXT,
+  rows_affected BIGINT
 );
 
 CREATE INDEX IF NOT EXISTS idx_query_history_db_instance_id ON query_history(db_instance_id);
@@ -141,3 +159,104 @@ CREATE TABLE IF NOT EXISTS usage_metrics (
 
 CREATE INDEX IF NOT EXISTS idx_usage_metrics_db_instance_id ON usage_metrics(db_instance_id);
 CREATE INDEX IF NOT EXISTS idx_usage_metrics_timestamp ON usage_metrics(timestamp);
+
+
+-- Query Plan Snapshots table
+CREATE TABLE IF NOT EXISTS query_plan_snapshots (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  query_text TEXT NOT NULL,
+  plan JSONB NOT NULL,
+  total_cost DOUBLE PRECISION NOT NULL,
+  plan_rows DOUBLE PRECISION NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  UNIQUE (project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_plan_snapshots_project_id ON query_plan_snapshots(project_id);
+
+-- Saved Queries table
+CREATE TABLE IF NOT EXISTS saved_queries (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  query_text TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  UNIQUE (user_id, project_id, name)
+);
+
+
+-- DDL Audit table
+CREATE TABLE IF NOT EXISTS ddl_audit (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  operation TEXT NOT NULL,
+  target TEXT NOT NULL,
+  sql_text TEXT NOT NULL,
+  success BOOLEAN NOT NULL,
+  error_message TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_project_id ON ddl_audit(project_id);
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_user_id ON ddl_audit(user_id);
+CREATE INDEX IF NOT EXISTS idx_ddl_audit_created_at ON ddl_audit(created_at);
+
+
+-- User Quotas table (per-user overrides of the default quotas)
+CREATE TABLE IF NOT EXISTS user_quotas (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  max_projects INT,
+  max_storage_gb INT,
+  flags TEXT[] NOT NULL DEFAULT '{}',
+  updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+
+-- Backup Schedules table (scheduled pg_dump backups of project databases)
+CREATE TABLE IF NOT EXISTS backup_schedules (
+  project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+  interval_minutes INT NOT NULL CHECK (interval_minutes > 0),
+  retention INT NOT NULL CHECK (retention > 0),
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  last_run_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_schedules_next_run_at ON backup_schedules(next_run_at) WHERE enabled;
+
+
+-- Project Backups table
+CREATE TABLE IF NOT EXISTS project_backups (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  file_path TEXT NOT NULL,
+  size_bytes BIGINT,
+  error_message TEXT,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_backups_project_id ON project_backups(project_id, started_at DESC);
+
+-- Backup Downloads table
+CREATE TABLE IF NOT EXISTS backup_downloads (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  size_bytes BIGINT,
+  error_message TEXT,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_backup_downloads_project_id ON backup_downloads(project_id, started_at DESC);
@@ -417,6 +417,42 @@ DB_ADMIN_PASSWORD=postgres
 ACCESS_TOKEN_SECRET=your-access-token-secret-change-this-in-production
 REFRESH_TOKEN_SECRET=your-refresh-token-secret-change-this-in-production
 
+# Refresh token cookie attributes. Set APP_ENV=development to allow the cookie over
+# plain HTTP on localhost; SameSite=none (cross-site frontends) requires a secure cookie.
+# APP_ENV=development
+# COOKIE_SECURE=true
+# COOKIE_DOMAIN=
+# COOKIE_SAMESITE=lax
+# COOKIE_PATH=/
+
+# Optional: revoke a user's other sessions whenever they log in (default: false)
+# SINGLE_ACTIVE_SESSION=true
+
+# Optional directory scheduled backups are written to (default: backups). pg_dump must be on the PATH.
+# BACKUP_DIR=/var/lib/killua/backups
+
+# Optional time budget of a single SQL editor query, replacing the per-tier budgets (free 10s, basic 30s, premium 2m)
+# QUERY_TIMEOUT_MS=30000
+
+# Optional limits on tables created through the API (defaults: 300 columns, 50 foreign keys)
+# TABLE_MAX_COLUMNS=300
+# TABLE_MAX_FOREIGN_KEYS=50
+
+# Optional caps on the active database instances a user may have per resource tier (defaults: free 1, basic 5, premium 20)
+# TIER_MAX_INSTANCES_FREE=1
+# TIER_MAX_INSTANCES_BASIC=5
+# TIER_MAX_INSTANCES_PREMIUM=20
+
+# Optional key for signing pagination cursors (defaults to ACCESS_TOKEN_SECRET)
+# CURSOR_SECRET=
+
+# Set to true when running several replicas so background jobs run on only one
+# LEADER_ELECTION_ENABLED=false
+
+# Optional: retry creating the containers of instances that failed to provision (default: false)
+# PROVISION_RETRY_ENABLED=true
+# PROVISION_RETRY_MAX_ATTEMPTS=3
+
 GOOGLE_CLIENT_ID=your-google-client-id
 GOOGLE_CLIENT_SECRET=your-google-client-secret
 GOOGLE_REDIRECT_URL=http://localhost:8080/api/v1/auth/google/callback
@@ -435,6 +471,14 @@ ORCHESTRATOR_NETWORK_NAME=dbaas-orchestrator-network
 ORCHESTRATOR_SUBNET_CIDR=172.30.0.0/16
 ORCHESTRATOR_GATEWAY=172.30.0.1
 ORCHESTRATOR_MONITOR_INTERVAL=5
+# Optional deployment name (e.g. staging) prefixing container names
+# ENV_NAME=dev
+# Docker daemon the orchestrator manages, used for container logs, pause and exec (defaults to the local socket)
+# DOCKER_HOST=unix:///var/run/docker.sock
+
+# Optional TLS: serve HTTPS directly (leave unset behind a TLS-terminating proxy)
+# TLS_CERT_FILE=/path/to/cert.pem
+# TLS_KEY_FILE=/path/to/key.pem
 EOF
         
         print_success ".env file created. Please update it with your configuration."